@@ -0,0 +1,194 @@
+package clients
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sp0x/torrentd/config"
+)
+
+const defaultTimeout = 30 * time.Second
+
+// Torrent is sent to a client, either as a .torrent file or as a magnet link.
+type Torrent struct {
+	// Name is used for the files of the watch folder.
+	Name    string
+	Content []byte
+	Magnet  string
+}
+
+// IsMagnet checks if the torrent is a magnet link.
+func (t *Torrent) IsMagnet() bool {
+	return len(t.Content) == 0 && t.Magnet != ""
+}
+
+// AddOptions are the options of a torrent that's added to a client.
+type AddOptions struct {
+	// Category is a category in qBittorrent, a label in Transmission and Deluge, and a subdirectory in a watch folder.
+	Category string
+	// SavePath is where the client saves the download.
+	SavePath string
+	Paused   bool
+}
+
+// Client is a torrent client that torrents can be sent to.
+type Client interface {
+	// Name is the name of the client in the config.
+	Name() string
+	// Add sends a torrent to the client.
+	Add(torrent *Torrent, options *AddOptions) error
+}
+
+// Options are the config values of a client.
+// The `type` is one of qbittorrent, transmission, deluge or watch.
+type Options map[string]string
+
+func (o Options) get(key, defaultValue string) string {
+	if value, ok := o[key]; ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func (o Options) timeout() time.Duration {
+	if value, err := time.ParseDuration(o.get("timeout", "")); err == nil && value > 0 {
+		return value
+	}
+	return defaultTimeout
+}
+
+// defaults fills in the options that aren't given, with the config of the client.
+func (o Options) defaults(options *AddOptions) *AddOptions {
+	result := AddOptions{}
+	if options != nil {
+		result = *options
+	}
+	if result.Category == "" {
+		result.Category = o.get("category", "")
+	}
+	if result.SavePath == "" {
+		result.SavePath = o.get("savepath", "")
+	}
+	if !result.Paused {
+		result.Paused, _ = strconv.ParseBool(o.get("paused", "false"))
+	}
+	return &result
+}
+
+// New creates a client from its options.
+// The `category`, `savepath` and `paused` options are used for the torrents that don't set them.
+func New(name string, options Options) (Client, error) {
+	var client Client
+	var err error
+	switch strings.ToLower(options.get("type", "")) {
+	case "qbittorrent", "qbit":
+		client, err = newQBittorrent(name, options)
+	case "transmission":
+		client, err = newTransmission(name, options)
+	case "deluge":
+		client, err = newDeluge(name, options)
+	case "watch", "folder":
+		client, err = newWatchFolder(name, options)
+	case "":
+		return nil, fmt.Errorf("client `%s` has no type", name)
+	default:
+		return nil, fmt.Errorf("client `%s` has an unknown type `%s`", name, options["type"])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &configuredClient{Client: client, options: options}, nil
+}
+
+// configuredClient adds torrents with the default options of the client.
+type configuredClient struct {
+	Client
+	options Options
+}
+
+func (c *configuredClient) Add(torrent *Torrent, options *AddOptions) error {
+	if torrent == nil || (len(torrent.Content) == 0 && torrent.Magnet == "") {
+		return errors.New("the torrent has no file or magnet link")
+	}
+	return c.Client.Add(torrent, c.options.defaults(options))
+}
+
+// FromConfig creates a client from the `clients` section of the config.
+// If no name is given, the `default_client` is used, or the only configured client.
+func FromConfig(conf config.Config, name string) (Client, error) {
+	configured := configuredClients(conf)
+	if name == "" {
+		name = conf.GetString("default_client")
+	}
+	if name == "" {
+		if len(configured) != 1 {
+			return nil, errors.New("no client given, and there's no `default_client` in the config")
+		}
+		for clientName := range configured {
+			name = clientName
+		}
+	}
+	options, ok := configured[name]
+	if !ok {
+		return nil, fmt.Errorf("client `%s` isn't configured", name)
+	}
+	return New(name, options)
+}
+
+// Names are the names of the configured clients.
+func Names(conf config.Config) []string {
+	configured := configuredClients(conf)
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func configuredClients(conf config.Config) map[string]Options {
+	result := map[string]Options{}
+	for name, value := range toStringMap(conf.Get("clients")) {
+		options := Options{}
+		for key, optionValue := range toStringMap(value) {
+			options[strings.ToLower(key)] = fmt.Sprint(optionValue)
+		}
+		result[name] = options
+	}
+	return result
+}
+
+func toStringMap(value interface{}) map[string]interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		return typedValue
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(typedValue))
+		for key, item := range typedValue {
+			result[fmt.Sprint(key)] = item
+		}
+		return result
+	case map[string]string:
+		result := make(map[string]interface{}, len(typedValue))
+		for key, item := range typedValue {
+			result[key] = item
+		}
+		return result
+	}
+	return nil
+}
+
+// newHTTPClient creates a http client that keeps the session cookies of the torrent client.
+func newHTTPClient(options Options) *http.Client {
+	jar, _ := cookiejar.New(nil)
+	return &http.Client{
+		Jar:     jar,
+		Timeout: options.timeout(),
+	}
+}
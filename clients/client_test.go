@@ -0,0 +1,89 @@
+package clients
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/config/mocks"
+	"github.com/sp0x/torrentd/indexer"
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+func TestFromConfig(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	conf := mocks.NewMockConfig(ctrl)
+	conf.EXPECT().Get("clients").Return(map[string]interface{}{
+		"qbit":  map[string]interface{}{"type": "qbittorrent", "url": "http://localhost:8080"},
+		"watch": map[interface{}]interface{}{"type": "watch", "dir": "/tmp/watch"},
+	}).AnyTimes()
+	conf.EXPECT().GetString("default_client").Return("watch").AnyTimes()
+
+	g.Expect(Names(conf)).To(gomega.Equal([]string{"qbit", "watch"}))
+	client, err := FromConfig(conf, "")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(client.Name()).To(gomega.Equal("watch"))
+	client, err = FromConfig(conf, "qbit")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(client.Name()).To(gomega.Equal("qbit"))
+	_, err = FromConfig(conf, "deluge")
+	g.Expect(err).To(gomega.HaveOccurred())
+	_, err = New("other", Options{"type": "utorrent"})
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestFetch(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	conf := mocks.NewMockConfig(ctrl)
+	scope := indexer.NewMockScope(ctrl)
+	index := indexer.NewMockIndexer(ctrl)
+
+	// Magnet links don't need the index.
+	torrent, err := Fetch(context.Background(), scope, conf, nil, &Source{Link: "magnet:?xt=urn:btih:abc", Name: "name"})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(torrent).To(gomega.Equal(&Torrent{Name: "name", Magnet: "magnet:?xt=urn:btih:abc"}))
+
+	// Files are downloaded through their index.
+	proxy, pipeW := indexer.NewResponseProxy()
+	go func() {
+		proxy.ContentLengthChan <- 7
+		_, _ = pipeW.Write([]byte("content"))
+		_ = pipeW.Close()
+	}()
+	scope.EXPECT().Lookup(conf, "rutracker.org").Return(indexer.IndexCollection{index}, nil)
+	index.EXPECT().Download(gomock.Any(), "http://rutracker.org/dl/1").Return(proxy, nil)
+	item := &search.TorrentResultItem{Title: "name", SourceLink: "http://rutracker.org/dl/1"}
+	item.Site = "rutracker.org"
+	torrent, err = Fetch(context.Background(), scope, conf, nil, ResultSource(item))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(torrent).To(gomega.Equal(&Torrent{Name: "name", Content: []byte("content")}))
+
+	_, err = Fetch(context.Background(), scope, conf, nil, &Source{Link: "http://rutracker.org/dl/1"})
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestCheckSite(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	conf := mocks.NewMockConfig(ctrl)
+	scope := indexer.NewMockScope(ctrl)
+	index := indexer.NewMockIndexer(ctrl)
+	scope.EXPECT().Lookup(conf, "rutracker.org").Return(indexer.IndexCollection{index}, nil).AnyTimes()
+	index.EXPECT().GetDefinition().Return(&indexer.Definition{Site: "rutracker.org", Links: []string{"https://rutracker.org/"}}).AnyTimes()
+	conf.EXPECT().GetSiteOption("rutracker.org", "url").Return("http://rutracker.mirror/", true, nil).AnyTimes()
+
+	for _, link := range []string{"magnet:?xt=urn:btih:abc", "/dl/1", "http://rutracker.org/dl/1", "https://dl.rutracker.org/1", "http://rutracker.mirror/dl/1"} {
+		g.Expect(CheckSite(scope, conf, &Source{Index: "rutracker.org", Link: link})).To(gomega.Succeed(), link)
+	}
+	// Links of other hosts aren't downloaded through the index.
+	for _, link := range []string{"http://169.254.169.254/latest", "http://rutracker.org.evil/dl/1", "//localhost:8080/", "file:///etc/passwd"} {
+		g.Expect(CheckSite(scope, conf, &Source{Index: "rutracker.org", Link: link})).ToNot(gomega.Succeed(), link)
+	}
+}
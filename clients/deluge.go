@@ -0,0 +1,149 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// deluge uses the JSON-RPC API of the Deluge web ui.
+type deluge struct {
+	name     string
+	url      string
+	password string
+	http     *http.Client
+	mux      sync.Mutex
+	loggedIn bool
+	id       int
+}
+
+type delugeRequest struct {
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+	ID     int           `json:"id"`
+}
+
+type delugeResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+		Code    int    `json:"code"`
+	} `json:"error"`
+}
+
+func newDeluge(name string, options Options) (*deluge, error) {
+	rpcURL := options.get("url", "http://localhost:8112/json")
+	parsed, err := url.Parse(rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("client `%s` has an invalid url: %v", name, err)
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		rpcURL = strings.TrimRight(rpcURL, "/") + "/json"
+	}
+	return &deluge{
+		name:     name,
+		url:      rpcURL,
+		password: options.get("password", "deluge"),
+		http:     newHTTPClient(options),
+	}, nil
+}
+
+func (d *deluge) Name() string {
+	return d.name
+}
+
+// Add adds a torrent, the category is set as its label if the label plugin is enabled.
+func (d *deluge) Add(torrent *Torrent, options *AddOptions) error {
+	if err := d.login(); err != nil {
+		return err
+	}
+	torrentOptions := map[string]interface{}{
+		"add_paused": options.Paused,
+	}
+	if options.SavePath != "" {
+		torrentOptions["download_location"] = options.SavePath
+	}
+	var result json.RawMessage
+	var err error
+	if torrent.IsMagnet() {
+		result, err = d.call("core.add_torrent_magnet", torrent.Magnet, torrentOptions)
+	} else {
+		result, err = d.call("core.add_torrent_file", torrentFileName(torrent),
+			base64.StdEncoding.EncodeToString(torrent.Content), torrentOptions)
+	}
+	if err != nil {
+		return err
+	}
+	var torrentID string
+	if err := json.Unmarshal(result, &torrentID); err != nil || torrentID == "" {
+		// Deluge returns no id if the torrent was already added.
+		return errors.New("deluge didn't add the torrent, it might already be added")
+	}
+	if options.Category != "" {
+		if _, err := d.call("label.set_torrent", torrentID, strings.ToLower(options.Category)); err != nil {
+			log.WithFields(log.Fields{"client": d.name, "label": options.Category}).
+				Warningf("Couldn't set the label of the torrent: %v", err)
+		}
+	}
+	return nil
+}
+
+func (d *deluge) login() error {
+	d.mux.Lock()
+	loggedIn := d.loggedIn
+	d.mux.Unlock()
+	if loggedIn {
+		if result, err := d.call("auth.check_session"); err == nil && string(result) == "true" {
+			return nil
+		}
+	}
+	result, err := d.call("auth.login", d.password)
+	if err != nil {
+		return err
+	}
+	if string(result) != "true" {
+		return errors.New("couldn't log in to deluge, check the password")
+	}
+	d.mux.Lock()
+	d.loggedIn = true
+	d.mux.Unlock()
+	return nil
+}
+
+func (d *deluge) call(method string, params ...interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	d.mux.Lock()
+	d.id++
+	request := &delugeRequest{Method: method, Params: params, ID: d.id}
+	d.mux.Unlock()
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := d.http.Post(d.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("deluge responded with status %d", response.StatusCode)
+	}
+	result := &delugeResponse{}
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("deluge error in %s: %s", method, result.Error.Message)
+	}
+	return result.Result, nil
+}
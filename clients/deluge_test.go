@@ -0,0 +1,57 @@
+package clients
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestDeluge_Add(t *testing.T) {
+	g := gomega.NewWithT(t)
+	var methods []string
+	var added []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request delugeRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		methods = append(methods, request.Method)
+		_, noSession := r.Cookie("_session_id")
+		switch {
+		case request.Method == "auth.login":
+			if request.Params[0] != "secret" {
+				_, _ = w.Write([]byte(`{"result": false, "error": null, "id": 1}`))
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "_session_id", Value: "session"})
+			_, _ = w.Write([]byte(`{"result": true, "error": null, "id": 1}`))
+		case noSession != nil:
+			_, _ = w.Write([]byte(`{"result": null, "error": {"message": "Not authenticated", "code": 1}, "id": 1}`))
+		case request.Method == "auth.check_session":
+			_, _ = w.Write([]byte(`{"result": true, "error": null, "id": 1}`))
+		case request.Method == "core.add_torrent_file", request.Method == "core.add_torrent_magnet":
+			added = append(added, request.Params)
+			_, _ = w.Write([]byte(`{"result": "b8a4", "error": null, "id": 1}`))
+		case request.Method == "label.set_torrent":
+			_, _ = w.Write([]byte(`{"result": null, "error": {"message": "Unknown method", "code": 2}, "id": 1}`))
+		}
+	}))
+	defer server.Close()
+	client, err := New("deluge", Options{"type": "deluge", "url": server.URL, "password": "secret"})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	// A missing label plugin doesn't stop the torrent from being added.
+	err = client.Add(&Torrent{Name: "file", Content: []byte("content")}, &AddOptions{Category: "tv", Paused: true})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(methods).To(gomega.Equal([]string{"auth.login", "core.add_torrent_file", "label.set_torrent"}))
+	g.Expect(added[0]).To(gomega.Equal([]interface{}{"file.torrent", "Y29udGVudA==", map[string]interface{}{"add_paused": true}}))
+
+	err = client.Add(&Torrent{Magnet: "magnet:?xt=urn:btih:abc"}, nil)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(methods[3:]).To(gomega.Equal([]string{"auth.check_session", "core.add_torrent_magnet"}))
+
+	client, _ = New("deluge", Options{"type": "deluge", "url": server.URL, "password": "wrong"})
+	err = client.Add(&Torrent{Magnet: "magnet:?xt=urn:btih:abc"}, nil)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
@@ -0,0 +1,136 @@
+package clients
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// qBittorrent uses the WebUI API of qBittorrent.
+type qBittorrent struct {
+	name     string
+	url      string
+	username string
+	password string
+	http     *http.Client
+	mux      sync.Mutex
+	loggedIn bool
+}
+
+func newQBittorrent(name string, options Options) (*qBittorrent, error) {
+	baseURL := strings.TrimRight(options.get("url", "http://localhost:8080"), "/")
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("client `%s` has an invalid url: %v", name, err)
+	}
+	return &qBittorrent{
+		name:     name,
+		url:      baseURL,
+		username: options.get("username", ""),
+		password: options.get("password", ""),
+		http:     newHTTPClient(options),
+	}, nil
+}
+
+func (q *qBittorrent) Name() string {
+	return q.name
+}
+
+// Add adds a torrent, logging in again if the session expired.
+func (q *qBittorrent) Add(torrent *Torrent, options *AddOptions) error {
+	if err := q.login(false); err != nil {
+		return err
+	}
+	status, err := q.add(torrent, options)
+	if err == nil && status == http.StatusForbidden {
+		if err := q.login(true); err != nil {
+			return err
+		}
+		status, err = q.add(torrent, options)
+	}
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("qbittorrent responded with status %d", status)
+	}
+	return nil
+}
+
+func (q *qBittorrent) login(force bool) error {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.loggedIn && !force {
+		return nil
+	}
+	form := url.Values{}
+	form.Set("username", q.username)
+	form.Set("password", q.password)
+	request, err := http.NewRequest("POST", q.url+"/api/v2/auth/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// The WebUI checks that requests come from its own origin.
+	request.Header.Set("Referer", q.url)
+	response, err := q.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != "Ok." {
+		return errors.New("couldn't log in to qbittorrent, check the username and password")
+	}
+	q.loggedIn = true
+	return nil
+}
+
+func (q *qBittorrent) add(torrent *Torrent, options *AddOptions) (int, error) {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	if torrent.IsMagnet() {
+		_ = form.WriteField("urls", torrent.Magnet)
+	} else {
+		file, err := form.CreateFormFile("torrents", torrentFileName(torrent))
+		if err != nil {
+			return 0, err
+		}
+		if _, err := file.Write(torrent.Content); err != nil {
+			return 0, err
+		}
+	}
+	if options.Category != "" {
+		_ = form.WriteField("category", options.Category)
+	}
+	if options.SavePath != "" {
+		_ = form.WriteField("savepath", options.SavePath)
+	}
+	if options.Paused {
+		_ = form.WriteField("paused", "true")
+	}
+	if err := form.Close(); err != nil {
+		return 0, err
+	}
+	request, err := http.NewRequest("POST", q.url+"/api/v2/torrents/add", body)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	request.Header.Set("Referer", q.url)
+	response, err := q.http.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode == http.StatusOK && strings.TrimSpace(string(responseBody)) == "Fails." {
+		return 0, errors.New("qbittorrent couldn't add the torrent")
+	}
+	return response.StatusCode, nil
+}
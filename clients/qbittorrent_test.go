@@ -0,0 +1,82 @@
+package clients
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+// fakeQBittorrent is a WebUI that takes the torrents, after a login.
+type fakeQBittorrent struct {
+	logins  int
+	added   []*http.Request
+	files   [][]byte
+	session string
+}
+
+func (f *fakeQBittorrent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/v2/auth/login":
+		_ = r.ParseForm()
+		if r.FormValue("username") != "admin" || r.FormValue("password") != "secret" {
+			_, _ = w.Write([]byte("Fails."))
+			return
+		}
+		f.logins++
+		f.session = fmt.Sprintf("sid%d", f.logins)
+		http.SetCookie(w, &http.Cookie{Name: "SID", Value: f.session, Path: "/"})
+		_, _ = w.Write([]byte("Ok."))
+	case "/api/v2/torrents/add":
+		cookie, err := r.Cookie("SID")
+		if err != nil || cookie.Value != f.session {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = r.ParseMultipartForm(1024 * 1024)
+		if file, _, err := r.FormFile("torrents"); err == nil {
+			content, _ := ioutil.ReadAll(file)
+			f.files = append(f.files, content)
+		}
+		f.added = append(f.added, r)
+		_, _ = w.Write([]byte("Ok."))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestQBittorrent_Add(t *testing.T) {
+	g := gomega.NewWithT(t)
+	fake := &fakeQBittorrent{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client, err := New("qbit", Options{"type": "qbittorrent", "url": server.URL, "username": "admin", "password": "secret", "category": "movies"})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	err = client.Add(&Torrent{Name: "file", Content: []byte("content")}, &AddOptions{Category: "tv", Paused: true})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(fake.files).To(gomega.Equal([][]byte{[]byte("content")}))
+	g.Expect(fake.added[0].FormValue("category")).To(gomega.Equal("tv"))
+	g.Expect(fake.added[0].FormValue("paused")).To(gomega.Equal("true"))
+
+	// The category of the client is used by default, and the session is reused.
+	err = client.Add(&Torrent{Magnet: "magnet:?xt=urn:btih:abc"}, nil)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(fake.added[1].FormValue("urls")).To(gomega.Equal("magnet:?xt=urn:btih:abc"))
+	g.Expect(fake.added[1].FormValue("category")).To(gomega.Equal("movies"))
+	g.Expect(fake.logins).To(gomega.Equal(1))
+
+	// An expired session is renewed.
+	fake.session = "expired"
+	err = client.Add(&Torrent{Magnet: "magnet:?xt=urn:btih:def"}, nil)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(fake.logins).To(gomega.Equal(2))
+	g.Expect(fake.added).To(gomega.HaveLen(3))
+
+	client, _ = New("qbit", Options{"type": "qbittorrent", "url": server.URL, "username": "admin", "password": "wrong"})
+	err = client.Add(&Torrent{Magnet: "magnet:?xt=urn:btih:abc"}, nil)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
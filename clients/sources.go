@@ -0,0 +1,160 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/torrent"
+)
+
+// Source is where a torrent comes from: a magnet link, or the link of a .torrent file on an index.
+type Source struct {
+	Index string
+	Link  string
+	Name  string
+}
+
+// IsMagnet checks if the source is a magnet link.
+func (s *Source) IsMagnet() bool {
+	return strings.HasPrefix(strings.ToLower(s.Link), "magnet:")
+}
+
+// ResultSource is the source of a result. Its .torrent file is preferred over its magnet link,
+// since clients can start it without looking up the metadata.
+func ResultSource(item *search.TorrentResultItem) *Source {
+	scrapeItem := item.AsScrapeItem()
+	// Stored results only have the links of the torrent, since they shadow the ones of the scraped item.
+	link := firstNonEmpty(item.SourceLink, scrapeItem.SourceLink, item.Link, scrapeItem.Link, item.MagnetLink)
+	index := scrapeItem.Site
+	if scrapeItem.Indexer != nil && scrapeItem.Indexer.Name != "" {
+		index = scrapeItem.Indexer.Name
+	}
+	return &Source{Index: index, Link: link, Name: item.Title}
+}
+
+// FindResult finds a stored result by its UUID.
+func FindResult(store storage.ItemStorage, uuid string) (*search.TorrentResultItem, error) {
+	var found *search.TorrentResultItem
+	store.ForEachInNamespaces(func(_ string, record search.Record) bool {
+		if found != nil {
+			return false
+		}
+		if item, ok := record.(*search.TorrentResultItem); ok && item.UUID() == uuid {
+			found = item
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return nil, fmt.Errorf("no result with the id `%s`", uuid)
+	}
+	return found, nil
+}
+
+// Fetch gets the torrent of a source.
+// Magnet links are used as they are. Files are downloaded through their index, with `Indexer.Download`,
+// so that trackers that need a login work. The cache is optional.
+func Fetch(ctx context.Context, scope indexer.Scope, conf config.Config, cache *torrent.FileCache, src *Source) (*Torrent, error) {
+	if src.IsMagnet() {
+		return &Torrent{Name: src.Name, Magnet: src.Link}, nil
+	}
+	if src.Link == "" {
+		return nil, errors.New("the torrent has no link")
+	}
+	if src.Index == "" {
+		return nil, errors.New("the index of the torrent is needed to download it")
+	}
+	open := func(ctx context.Context) (*indexer.ResponseProxy, error) {
+		indexes, err := scope.Lookup(conf, src.Index)
+		if err != nil {
+			return nil, err
+		}
+		if len(indexes) == 0 {
+			return nil, fmt.Errorf("index `%s` not found", src.Index)
+		}
+		return indexes[0].Download(ctx, src.Link)
+	}
+	var content []byte
+	var err error
+	if cache != nil {
+		content, err = cache.Fetch(ctx, src.Index, src.Link, open)
+	} else {
+		var proxy *indexer.ResponseProxy
+		proxy, err = open(ctx)
+		if err == nil && (proxy == nil || proxy.Reader == nil) {
+			err = errors.New("couldn't open stream for download")
+		}
+		if err == nil {
+			content, err = torrent.ReadDownload(ctx, proxy, torrent.DownloadTimeout, torrent.MaxDownloadSize)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Torrent{Name: src.Name, Content: content}, nil
+}
+
+// CheckSite checks that the link of a source is on the site of its index, so that links from users can't make
+// the index download from other hosts. Relative links are on the site, they're resolved against it.
+func CheckSite(scope indexer.Scope, conf config.Config, src *Source) error {
+	if src.IsMagnet() {
+		return nil
+	}
+	link, err := url.Parse(src.Link)
+	if err != nil {
+		return err
+	}
+	if link.Host == "" && link.Scheme == "" {
+		return nil
+	}
+	if link.Scheme != "http" && link.Scheme != "https" {
+		return fmt.Errorf("the link has an unsupported scheme `%s`", link.Scheme)
+	}
+	indexes, err := scope.Lookup(conf, src.Index)
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		return fmt.Errorf("index `%s` not found", src.Index)
+	}
+	for _, siteLink := range siteLinks(indexes[0], conf) {
+		site, err := url.Parse(siteLink)
+		if err != nil || site.Hostname() == "" {
+			continue
+		}
+		host, siteHost := strings.ToLower(link.Hostname()), strings.ToLower(site.Hostname())
+		if host == siteHost || strings.HasSuffix(host, "."+siteHost) {
+			return nil
+		}
+	}
+	return fmt.Errorf("the link isn't on the site of `%s`", src.Index)
+}
+
+// siteLinks are the links of an index, with the url from its config.
+func siteLinks(index indexer.Indexer, conf config.Config) []string {
+	def := index.GetDefinition()
+	if def == nil {
+		return nil
+	}
+	links := append([]string{}, def.Links...)
+	if configURL, ok, _ := conf.GetSiteOption(def.Site, "url"); ok && configURL != "" {
+		links = append(links, configURL)
+	}
+	return links
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const transmissionSessionHeader = "X-Transmission-Session-Id"
+
+// transmission uses the RPC API of Transmission.
+type transmission struct {
+	name      string
+	url       string
+	username  string
+	password  string
+	http      *http.Client
+	mux       sync.Mutex
+	sessionID string
+}
+
+type transmissionRequest struct {
+	Method    string                 `json:"method"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+type transmissionResponse struct {
+	Result string `json:"result"`
+}
+
+func newTransmission(name string, options Options) (*transmission, error) {
+	rpcURL := options.get("url", "http://localhost:9091/transmission/rpc")
+	parsed, err := url.Parse(rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("client `%s` has an invalid url: %v", name, err)
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		rpcURL = strings.TrimRight(rpcURL, "/") + "/transmission/rpc"
+	}
+	return &transmission{
+		name:     name,
+		url:      rpcURL,
+		username: options.get("username", ""),
+		password: options.get("password", ""),
+		http:     newHTTPClient(options),
+	}, nil
+}
+
+func (t *transmission) Name() string {
+	return t.name
+}
+
+// Add adds a torrent with the `torrent-add` method.
+func (t *transmission) Add(torrent *Torrent, options *AddOptions) error {
+	arguments := map[string]interface{}{
+		"paused": options.Paused,
+	}
+	if torrent.IsMagnet() {
+		arguments["filename"] = torrent.Magnet
+	} else {
+		arguments["metainfo"] = base64.StdEncoding.EncodeToString(torrent.Content)
+	}
+	if options.SavePath != "" {
+		arguments["download-dir"] = options.SavePath
+	}
+	if options.Category != "" {
+		arguments["labels"] = []string{options.Category}
+	}
+	response, err := t.call(&transmissionRequest{Method: "torrent-add", Arguments: arguments})
+	if err != nil {
+		return err
+	}
+	if response.Result != "success" {
+		return fmt.Errorf("transmission couldn't add the torrent: %s", response.Result)
+	}
+	return nil
+}
+
+// call sends a request to the RPC API.
+// Transmission responds with a 409 and a new session id when the session id is missing or expired,
+// the request is repeated with that id.
+func (t *transmission) call(request *transmissionRequest) (*transmissionResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		httpRequest, err := http.NewRequest("POST", t.url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		httpRequest.Header.Set("Content-Type", "application/json")
+		if t.username != "" {
+			httpRequest.SetBasicAuth(t.username, t.password)
+		}
+		t.mux.Lock()
+		httpRequest.Header.Set(transmissionSessionHeader, t.sessionID)
+		t.mux.Unlock()
+		response, err := t.http.Do(httpRequest)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode == http.StatusConflict {
+			_ = response.Body.Close()
+			t.mux.Lock()
+			t.sessionID = response.Header.Get(transmissionSessionHeader)
+			t.mux.Unlock()
+			continue
+		}
+		if response.StatusCode != http.StatusOK {
+			_ = response.Body.Close()
+			return nil, fmt.Errorf("transmission responded with status %d", response.StatusCode)
+		}
+		result := &transmissionResponse{}
+		err = json.NewDecoder(response.Body).Decode(result)
+		_ = response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("couldn't get a session from transmission")
+}
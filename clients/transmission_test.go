@@ -0,0 +1,46 @@
+package clients
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestTransmission_Add(t *testing.T) {
+	g := gomega.NewWithT(t)
+	var requests []transmissionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, _ := r.BasicAuth(); username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(transmissionSessionHeader) != "session" {
+			w.Header().Set(transmissionSessionHeader, "session")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		var request transmissionRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		requests = append(requests, request)
+		_, _ = w.Write([]byte(`{"result": "success", "arguments": {"torrent-added": {"id": 1}}}`))
+	}))
+	defer server.Close()
+	client, err := New("transmission", Options{"type": "transmission", "url": server.URL, "username": "admin", "password": "secret"})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	err = client.Add(&Torrent{Content: []byte("content")}, &AddOptions{Category: "tv", SavePath: "/downloads/tv"})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(requests).To(gomega.HaveLen(1))
+	g.Expect(requests[0].Method).To(gomega.Equal("torrent-add"))
+	g.Expect(requests[0].Arguments["metainfo"]).To(gomega.Equal(base64.StdEncoding.EncodeToString([]byte("content"))))
+	g.Expect(requests[0].Arguments["download-dir"]).To(gomega.Equal("/downloads/tv"))
+	g.Expect(requests[0].Arguments["labels"]).To(gomega.Equal([]interface{}{"tv"}))
+
+	err = client.Add(&Torrent{Magnet: "magnet:?xt=urn:btih:abc"}, nil)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(requests[1].Arguments["filename"]).To(gomega.Equal("magnet:?xt=urn:btih:abc"))
+}
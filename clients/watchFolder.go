@@ -0,0 +1,88 @@
+package clients
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+var rxUnsafeFileName = regexp.MustCompile(`[^\w\-. \[\]()]+`)
+
+// watchFolder writes the torrents to a folder that a client watches.
+// Magnet links are written as .magnet files, which most clients that watch folders can read.
+type watchFolder struct {
+	name string
+	dir  string
+}
+
+func newWatchFolder(name string, options Options) (*watchFolder, error) {
+	dir, err := homedir.Expand(options.get("dir", ""))
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("client `%s` needs a `dir` to write the torrents to", name)
+	}
+	return &watchFolder{name: name, dir: dir}, nil
+}
+
+func (w *watchFolder) Name() string {
+	return w.name
+}
+
+// Add writes the torrent, in the subdirectory of its category if it has one.
+func (w *watchFolder) Add(torrent *Torrent, options *AddOptions) error {
+	dir := w.dir
+	if options.Category != "" {
+		category := safeFileName(options.Category)
+		if category == "" {
+			return errors.New("invalid category")
+		}
+		dir = filepath.Join(dir, category)
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	content := torrent.Content
+	fileName := torrentFileName(torrent)
+	if torrent.IsMagnet() {
+		content = []byte(torrent.Magnet)
+		fileName = strings.TrimSuffix(fileName, ".torrent") + ".magnet"
+	}
+	// The file is written under a hidden name first, so that the client never reads a partial file.
+	tmp, err := ioutil.TempFile(dir, ".torrentd-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, fileName))
+}
+
+func torrentFileName(torrent *Torrent) string {
+	name := safeFileName(torrent.Name)
+	if name == "" {
+		name = "torrent"
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".torrent") {
+		name += ".torrent"
+	}
+	return name
+}
+
+func safeFileName(name string) string {
+	name = rxUnsafeFileName.ReplaceAllString(name, "_")
+	return strings.Trim(name, ". ")
+}
@@ -0,0 +1,35 @@
+package clients
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestWatchFolder_Add(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir, _ := ioutil.TempDir("", "watch-")
+	defer os.RemoveAll(dir)
+	client, err := New("watch", Options{"type": "watch", "dir": dir})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	err = client.Add(&Torrent{Name: "Some/Show S01E01", Content: []byte("content")}, &AddOptions{Category: "tv"})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	content, err := ioutil.ReadFile(filepath.Join(dir, "tv", "Some_Show S01E01.torrent"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(content).To(gomega.Equal([]byte("content")))
+
+	err = client.Add(&Torrent{Name: "Movie", Magnet: "magnet:?xt=urn:btih:abc"}, nil)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	content, err = ioutil.ReadFile(filepath.Join(dir, "Movie.magnet"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(content)).To(gomega.Equal("magnet:?xt=urn:btih:abc"))
+
+	err = client.Add(&Torrent{Name: "empty"}, nil)
+	g.Expect(err).To(gomega.HaveOccurred())
+	_, err = New("watch", Options{"type": "watch"})
+	g.Expect(err).To(gomega.HaveOccurred())
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/sp0x/torrentd/clients"
+	"github.com/sp0x/torrentd/indexer"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/torrent"
+)
+
+var (
+	addClient   string
+	addIndex    string
+	addCategory string
+	addSavePath string
+	addPaused   bool
+)
+
+func init() {
+	cmdAdd := &cobra.Command{
+		Use:   "add <uuid|link>",
+		Short: "Sends a stored result, a link to a torrent or a magnet link to a torrent client.",
+		Long: `Sends a torrent to one of the clients in the clients section of the config.
+The torrent can be the id of a stored result, a magnet link or the link of a .torrent file on an index, which needs --index.`,
+		Args: cobra.ExactArgs(1),
+		Run:  addTorrent,
+	}
+	cmdFlags := cmdAdd.Flags()
+	cmdFlags.StringVar(&addClient, "client", "", "The client to use, by default it's the default_client from the config.")
+	cmdFlags.StringVar(&addIndex, "index", "", "The index to download the link from, so that its login is used.")
+	cmdFlags.StringVar(&addCategory, "category", "", "The category or label of the torrent in the client.")
+	cmdFlags.StringVar(&addSavePath, "savepath", "", "Where the client should save the download.")
+	cmdFlags.BoolVar(&addPaused, "paused", false, "Add the torrent without starting it.")
+	_ = viper.BindEnv("default_client")
+	rootCmd.AddCommand(cmdAdd)
+}
+
+func addTorrent(_ *cobra.Command, args []string) {
+	client, err := clients.FromConfig(&appConfig, addClient)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	src, err := addSource(args[0])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	torrentCache, err := torrent.NewFileCacheFromConfig(&appConfig)
+	if err != nil {
+		log.Warningf("Not using the torrent cache: %v", err)
+	}
+	facade := indexer.NewEmptyFacade(&appConfig)
+	t, err := clients.Fetch(context.Background(), facade.IndexScope, &appConfig, torrentCache, src)
+	if err != nil {
+		fmt.Printf("Couldn't get the torrent: %v\n", err)
+		os.Exit(1)
+	}
+	options := &clients.AddOptions{
+		Category: addCategory,
+		SavePath: addSavePath,
+		Paused:   addPaused,
+	}
+	if err := client.Add(t, options); err != nil {
+		fmt.Printf("Couldn't add the torrent to %s: %v\n", client.Name(), err)
+		os.Exit(1)
+	}
+	fmt.Printf("Added %s to %s\n", addedName(src), client.Name())
+}
+
+// addSource finds the source of a torrent from a link or the id of a stored result.
+func addSource(value string) (*clients.Source, error) {
+	src := &clients.Source{Index: addIndex, Link: value}
+	if src.IsMagnet() || addIndex != "" {
+		return src, nil
+	}
+	store := storage.NewBuilder(&appConfig).
+		WithRecord(&search.TorrentResultItem{}).
+		Build()
+	defer store.Close()
+	item, err := clients.FindResult(store, value)
+	if err != nil {
+		return nil, fmt.Errorf("%v, links of .torrent files need an --index", err)
+	}
+	return clients.ResultSource(item), nil
+}
+
+func addedName(src *clients.Source) string {
+	if src.Name != "" {
+		return src.Name
+	}
+	return src.Link
+}
@@ -4,11 +4,13 @@ import (
 	"os"
 	"path"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/mitchellh/go-homedir"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
 	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer"
 )
 
 var appConfig config.ViperConfig
@@ -45,3 +47,17 @@ func initConfig() {
 
 	log.SetLevel(config.GetMinLogLevel(&appConfig))
 }
+
+// watchForChanges reloads the indexes of the facade when their definitions or the configuration file change.
+func watchForChanges(facade *indexer.Facade) {
+	watcher, err := indexer.NewDefinitionWatcher(facade, append(config.GetDefinitionDirs(), indexer.RemoteDefinitionsDir()))
+	if err != nil {
+		log.Warningf("couldn't watch definitions for changes: %v\n", err)
+		return
+	}
+	viper.OnConfigChange(func(_ fsnotify.Event) {
+		log.Info("configuration changed, reloading indexes")
+		watcher.ReloadConfiguration()
+	})
+	viper.WatchConfig()
+}
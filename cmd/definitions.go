@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer"
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+var (
+	lintFormat        string
+	definitionTestDir string
+	recordQuery       string
+	recordFixtures    bool
+	dumpedFixtures    string
+	scaffoldOutput    string
+	listOutdated      bool
+)
+
+var cmdDefinitions = &cobra.Command{
+	Use:     "definitions",
+	Aliases: []string{"defs"},
+	Short:   "Tools for working with index definitions",
+}
+
+func init() {
+	cmdLint := &cobra.Command{
+		Use:   "lint [file...]",
+		Short: "Validates definition files. If no files are given, all the definitions in the definition directories are checked.",
+		Run:   lintDefinitions,
+	}
+	cmdLint.Flags().StringVar(&lintFormat, "format", "text", "Output format: text or json")
+	cmdDefinitions.AddCommand(cmdLint)
+
+	cmdTest := &cobra.Command{
+		Use:   "test <name>...",
+		Short: "Runs definitions against their recorded http fixtures and checks the results against the expected snapshot.",
+		Args:  cobra.MinimumNArgs(1),
+		Run:   testDefinitions,
+	}
+	testFlags := cmdTest.Flags()
+	testFlags.StringVar(&definitionTestDir, "dir", filepath.Join("definitions", "tests"), "The directory with the fixtures for each definition")
+	testFlags.BoolVar(&recordFixtures, "record", false, "Record new fixtures and a snapshot from the live site")
+	testFlags.StringVar(&recordQuery, "query", "", "The query to record fixtures with")
+	testFlags.StringVar(&dumpedFixtures, "from-dump", "", "Create the fixtures and a snapshot from the requests that were dumped with --dump, in dumps/<host>")
+	cmdDefinitions.AddCommand(cmdTest)
+
+	cmdScaffold := &cobra.Command{
+		Use:   "scaffold <url|file>",
+		Short: "Creates a starter definition from a page with search results, and shows a preview of the results it finds.",
+		Args:  cobra.ExactArgs(1),
+		Run:   scaffoldDefinition,
+	}
+	cmdScaffold.Flags().StringVarP(&scaffoldOutput, "output", "o", "", "The file to write the definition to. By default it's written to stdout.")
+	cmdDefinitions.AddCommand(cmdScaffold)
+
+	cmdUpdate := &cobra.Command{
+		Use:   "update",
+		Short: "Installs the latest definitions from the definition repository, or their pinned versions.",
+		Run:   updateDefinitions,
+	}
+	cmdDefinitions.AddCommand(cmdUpdate)
+
+	cmdList := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Lists the available definitions with their versions.",
+		Run:     listDefinitions,
+	}
+	cmdList.Flags().BoolVar(&listOutdated, "outdated", false, "Only list definitions that have a newer version in the definition repository")
+	cmdDefinitions.AddCommand(cmdList)
+	rootCmd.AddCommand(cmdDefinitions)
+}
+
+func lintDefinitions(_ *cobra.Command, args []string) {
+	files := args
+	if len(files) == 0 {
+		files = findDefinitionFiles(config.GetDefinitionDirs())
+	}
+	var issues []indexer.LintIssue
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Printf("Couldn't read definition %s: %v\n", file, err)
+			os.Exit(1)
+		}
+		lint := indexer.LintDefinition
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if indexer.IsBaseDefinition(name) {
+			lint = indexer.LintBaseDefinition
+		}
+		for _, issue := range lint(src) {
+			issue.File = file
+			issues = append(issues, issue)
+		}
+	}
+	switch lintFormat {
+	case "json":
+		if issues == nil {
+			issues = []indexer.LintIssue{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(issues)
+	default:
+		for _, issue := range issues {
+			fmt.Println(issue.String())
+		}
+		fmt.Printf("%d definitions checked, %d issues found\n", len(files), len(issues))
+	}
+	if indexer.HasLintErrors(issues) {
+		os.Exit(1)
+	}
+}
+
+func testDefinitions(_ *cobra.Command, args []string) {
+	failed := false
+	for _, name := range args {
+		def, err := indexer.GetIndexDefinitionLoader().Load(name)
+		if err != nil {
+			fmt.Printf("Couldn't load definition %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		test := indexer.NewDefinitionTest(def, filepath.Join(definitionTestDir, name), &appConfig)
+		if recordFixtures {
+			snapshot, err := test.Record(recordQuery, nil)
+			if err != nil {
+				fmt.Printf("%s: couldn't record fixtures: %v\n", name, err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s: recorded %d results\n", name, len(snapshot.Results))
+			continue
+		}
+		if dumpedFixtures != "" {
+			snapshot, err := test.Import(recordQuery, dumpedFixtures)
+			if err != nil {
+				fmt.Printf("%s: couldn't import the dumped fixtures: %v\n", name, err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s: imported %d results\n", name, len(snapshot.Results))
+			continue
+		}
+		diffs, err := test.Run()
+		if err != nil {
+			fmt.Printf("%s: FAIL %v\n", name, err)
+			failed = true
+			continue
+		}
+		if len(diffs) > 0 {
+			fmt.Printf("%s: FAIL\n", name)
+			for _, diff := range diffs {
+				fmt.Printf("\t%s\n", diff)
+			}
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: ok\n", name)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func scaffoldDefinition(_ *cobra.Command, args []string) {
+	doc, pageURL, err := indexer.LoadScaffoldPage(args[0])
+	if err != nil {
+		fmt.Printf("Couldn't load page: %v\n", err)
+		os.Exit(1)
+	}
+	scaffold, err := indexer.ScaffoldDefinition(doc, pageURL)
+	if err != nil {
+		fmt.Printf("Couldn't scaffold a definition: %v\n", err)
+		os.Exit(1)
+	}
+	src, err := scaffold.YAML()
+	if err != nil {
+		fmt.Printf("Couldn't create definition: %v\n", err)
+		os.Exit(1)
+	}
+	if scaffoldOutput != "" {
+		if err = ioutil.WriteFile(scaffoldOutput, src, 0644); err != nil {
+			fmt.Printf("Couldn't write definition: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Definition written to %s\n\n", scaffoldOutput)
+	} else {
+		fmt.Printf("%s\n", src)
+	}
+
+	def, err := indexer.ParseDefinition(src)
+	if err != nil {
+		fmt.Printf("Couldn't parse the created definition: %v\n", err)
+		os.Exit(1)
+	}
+	results, err := indexer.PreviewDefinition(def, doc, pageURL, &appConfig)
+	if err != nil {
+		fmt.Printf("Couldn't preview the results: %v\n", err)
+		os.Exit(1)
+	}
+	tabWr := new(tabwriter.Writer)
+	tabWr.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	_, _ = fmt.Fprintf(tabWr, "Title\tSize\tSeeders\tLeechers\tDate\tLink\n")
+	for _, result := range results {
+		torrent, ok := result.(*search.TorrentResultItem)
+		if !ok {
+			continue
+		}
+		published := ""
+		if torrent.PublishDate > 0 {
+			published = time.Unix(torrent.PublishDate, 0).Format("2006-01-02")
+		}
+		_, _ = fmt.Fprintf(tabWr, "%s\t%s\t%d\t%d\t%s\t%s\n",
+			torrent.Title,
+			humanize.Bytes(uint64(torrent.Size)),
+			torrent.Seeders,
+			torrent.Peers-torrent.Seeders,
+			published,
+			torrent.AsScrapeItem().SourceLink)
+	}
+	_ = tabWr.Flush()
+	fmt.Printf("%d results found\n", len(results))
+}
+
+func getRemoteDefinitionLoader() *indexer.RemoteDefinitionLoader {
+	remote, err := indexer.NewRemoteDefinitionLoader(&appConfig)
+	if err != nil {
+		fmt.Printf("Couldn't configure the definition repository: %v\n", err)
+		os.Exit(1)
+	}
+	return remote
+}
+
+func updateDefinitions(_ *cobra.Command, _ []string) {
+	updates, err := getRemoteDefinitionLoader().Update()
+	for _, update := range updates {
+		current := update.Current
+		if current == "" {
+			current = "none"
+		}
+		fmt.Printf("%s: %s -> %s\n", update.Name, current, update.Latest)
+	}
+	if err != nil {
+		fmt.Printf("Couldn't update definitions: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d definitions updated\n", len(updates))
+}
+
+func listDefinitions(_ *cobra.Command, _ []string) {
+	loader := indexer.GetIndexDefinitionLoader()
+	tabWr := new(tabwriter.Writer)
+	tabWr.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	if listOutdated {
+		outdated, err := getRemoteDefinitionLoader().Outdated(loader)
+		if err != nil {
+			fmt.Printf("Couldn't check for newer definitions: %v\n", err)
+			os.Exit(1)
+		}
+		_, _ = fmt.Fprintf(tabWr, "Name\tCurrent\tLatest\tPinned\n")
+		for _, update := range outdated {
+			_, _ = fmt.Fprintf(tabWr, "%s\t%s\t%s\t%v\n", update.Name, update.Current, update.Latest, update.Pinned)
+		}
+		_ = tabWr.Flush()
+		return
+	}
+	names, err := loader.ListAvailableIndexes(nil)
+	if err != nil {
+		fmt.Printf("Couldn't list definitions: %v\n", err)
+		os.Exit(1)
+	}
+	_, _ = fmt.Fprintf(tabWr, "Name\tVersion\tSource\n")
+	for _, name := range names {
+		def, err := loader.Load(name)
+		if err != nil {
+			_, _ = fmt.Fprintf(tabWr, "%s\t\t%v\n", name, err)
+			continue
+		}
+		_, _ = fmt.Fprintf(tabWr, "%s\t%s\t%s\n", name, def.Version, def.Stats().Source)
+	}
+	_ = tabWr.Flush()
+}
+
+// findDefinitionFiles lists all the yaml files in the given directories.
+func findDefinitionFiles(dirs []string) []string {
+	var files []string
+	for _, dir := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+				continue
+			}
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"text/tabwriter"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/sp0x/torrentd/indexer"
+	"github.com/sp0x/torrentd/indexer/cassette"
 	"github.com/sp0x/torrentd/indexer/search"
 	"github.com/sp0x/torrentd/indexer/status"
 	"github.com/sp0x/torrentd/storage/bolt"
@@ -26,6 +29,8 @@ func init() {
 	query := ""
 	workers := 0
 	users := 1
+	record := ""
+	replay := ""
 	cmdFlags := cmdGet.PersistentFlags()
 	cmdFlags.StringVarP(&storage, "storage", "o", "boltdb", `The storage backing to use.
 Currently supported storage backings: boltdb, firebase, sqlite`)
@@ -33,6 +38,8 @@ Currently supported storage backings: boltdb, firebase, sqlite`)
 	cmdFlags.StringVar(&query, "query", "", `Query to use when searching`)
 	cmdFlags.IntVar(&workers, "workers", 0, "The number of parallel searches that can be used.")
 	cmdFlags.IntVar(&users, "users", 1, "The number of user sessions to use in rotation.")
+	cmdFlags.StringVar(&record, "record", "", "Record all the http requests of this session in a cassette directory.")
+	cmdFlags.StringVar(&replay, "replay", "", "Replay a session from a cassette directory, without using the network.")
 	_ = viper.BindEnv("workers")
 	_ = viper.BindEnv("users")
 	firebaseProject := ""
@@ -57,6 +64,10 @@ Currently supported storage backings: boltdb, firebase, sqlite`)
 
 func getCommand(c *cobra.Command, _ []string) {
 	status.SetupPubsub(appConfig.GetString("firebase_project"))
+	if err := setupCassette(c); err != nil {
+		fmt.Printf("Couldn't set up the cassette: %s", err)
+		os.Exit(1)
+	}
 	facade, err := indexer.NewFacadeFromConfiguration(&appConfig)
 	if err != nil {
 		fmt.Printf("Couldn't initialize index facade: %s", err)
@@ -72,7 +83,7 @@ func getCommand(c *cobra.Command, _ []string) {
 	if query.NumberOfPagesToFetch == 0 {
 		query.NumberOfPagesToFetch = 20
 	}
-	results, err := facade.Search(query)
+	results, err := facade.Search(context.Background(), query)
 	if err != nil {
 		log.Error(err)
 		os.Exit(1)
@@ -83,3 +94,26 @@ func getCommand(c *cobra.Command, _ []string) {
 		_ = tabWr.Flush()
 	}
 }
+
+// setupCassette installs a recording or replaying transport, if the session should be recorded or replayed.
+func setupCassette(c *cobra.Command) error {
+	record := c.Flag("record").Value.String()
+	replay := c.Flag("replay").Value.String()
+	var transport http.RoundTripper
+	var err error
+	switch {
+	case record != "" && replay != "":
+		return fmt.Errorf("a session can't be recorded and replayed at the same time")
+	case record != "":
+		transport, err = cassette.NewTransport(record, cassette.ModeRecord, nil)
+	case replay != "":
+		transport, err = cassette.NewTransport(replay, cassette.ModeReplay, nil)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	indexer.SetConfiguredTransport(transport, &appConfig)
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/sp0x/torrentd/server/apikeys"
+	"github.com/sp0x/torrentd/storage/bolt"
+)
+
+var (
+	keyLabel   string
+	keyScopes  string
+	keyIndexes string
+)
+
+var cmdKeys = &cobra.Command{
+	Use:   "keys",
+	Short: "Manages the API keys of the server",
+}
+
+func init() {
+	cmdCreate := &cobra.Command{
+		Use:   "create",
+		Short: "Creates an API key. The secret of the key is only shown once.",
+		Run:   createKey,
+	}
+	createFlags := cmdCreate.Flags()
+	createFlags.StringVar(&keyLabel, "label", "", "A label to know what the key is used for")
+	createFlags.StringVar(&keyScopes, "scopes", "search,download", "The scopes of the key: search, download, admin")
+	createFlags.StringVar(&keyIndexes, "indexes", "", "The only indexes that the key can use, separated with a comma. By default all indexes can be used.")
+	cmdKeys.AddCommand(cmdCreate)
+
+	cmdKeys.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Lists the API keys with their usage.",
+		Run:     listKeys,
+	})
+	cmdKeys.AddCommand(&cobra.Command{
+		Use:   "revoke <id>",
+		Short: "Revokes an API key, so that it can't be used anymore.",
+		Args:  cobra.ExactArgs(1),
+		Run:   revokeKey,
+	})
+	_ = viper.BindEnv("apikeys_db")
+	rootCmd.AddCommand(cmdKeys)
+}
+
+func createKey(_ *cobra.Command, _ []string) {
+	scopes, err := apikeys.ParseScopes(keyScopes)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	var indexes []string
+	for _, index := range strings.Split(keyIndexes, ",") {
+		if index = strings.TrimSpace(index); index != "" {
+			indexes = append(indexes, index)
+		}
+	}
+	store := openKeyStore()
+	defer store.Close()
+	key, secret, err := store.Create(keyLabel, scopes, indexes)
+	if err != nil {
+		fmt.Printf("Couldn't create the key: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Created key %s, this is the only time its secret is shown:\n%s\n", key.KeyID, secret)
+}
+
+func listKeys(_ *cobra.Command, _ []string) {
+	store := openKeyStore()
+	defer store.Close()
+	tabWr := new(tabwriter.Writer)
+	tabWr.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	_, _ = fmt.Fprintf(tabWr, "ID\tLabel\tScopes\tIndexes\tUses\tLast used\tStatus\n")
+	for _, key := range store.List() {
+		scopes := make([]string, len(key.Scopes))
+		for i, scope := range key.Scopes {
+			scopes[i] = string(scope)
+		}
+		indexes := "all"
+		if len(key.Indexes) > 0 {
+			indexes = strings.Join(key.Indexes, ",")
+		}
+		lastUsed := "never"
+		if !key.LastUsed.IsZero() {
+			lastUsed = humanize.Time(key.LastUsed)
+		}
+		status := "active"
+		if key.Revoked {
+			status = "revoked"
+		}
+		_, _ = fmt.Fprintf(tabWr, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			key.KeyID, key.Label, strings.Join(scopes, ","), indexes, key.Uses, lastUsed, status)
+	}
+	_ = tabWr.Flush()
+}
+
+func revokeKey(_ *cobra.Command, args []string) {
+	store := openKeyStore()
+	defer store.Close()
+	if err := store.Revoke(args[0]); err != nil {
+		fmt.Printf("Couldn't revoke the key: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Revoked key %s\n", args[0])
+}
+
+// openKeyStore opens the API keys, unless the server is running.
+// The server locks their database and keeps the keys in memory, so they're managed with its API while it runs.
+func openKeyStore() *apikeys.Store {
+	storageType := appConfig.GetString("storage")
+	if (storageType == "" || storageType == "boltdb") && bolt.IsLocked(apikeys.DatabasePath(&appConfig)) {
+		fmt.Println("The server is running, use the API to manage the keys.")
+		os.Exit(1)
+	}
+	return apikeys.NewStore(&appConfig)
+}
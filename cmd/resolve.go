@@ -28,6 +28,6 @@ func resolveTorrents(_ *cobra.Command, _ []string) {
 		fmt.Printf("Couldn't initialize facade: %s", err)
 		os.Exit(1)
 	}
-	indexes := facade.Indexes
+	indexes := facade.CurrentIndexes()
 	torrent.ResolveTorrents(indexes, &appConfig)
 }
@@ -33,6 +33,14 @@ Currently supported storage backings: boltdb, firebase, sqlite`)
 	_ = viper.BindPFlag("hostname", cmdFlags.Lookup("hostname"))
 
 	_ = viper.BindEnv("api_key")
+	// Download links
+	viper.SetDefault("download_token_ttl", "24h")
+	_ = viper.BindEnv("download_token_ttl")
+	viper.SetDefault("bind_download_tokens", true)
+	_ = viper.BindEnv("bind_download_tokens")
+	// How long the searches of a request can take
+	viper.SetDefault("search_timeout", "60s")
+	_ = viper.BindEnv("search_timeout")
 	// Storage config
 	_ = viper.BindPFlag("storage", cmdFlags.Lookup("storage"))
 	_ = viper.BindEnv("storage")
@@ -52,6 +60,7 @@ func serve(c *cobra.Command, _ []string) {
 		fmt.Printf("Couldn't initialize: %s", err)
 		os.Exit(1)
 	}
+	watchForChanges(facade)
 	// Init the server
 	rserver := server.NewServer(&appConfig)
 	err = rserver.Listen(facade)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -29,7 +30,7 @@ func findSubtitles(_ *cobra.Command, args []string) {
 	}
 	searchQuery := strings.Join(args, " ")
 	subCat := categories.Subtitle
-	results, err := helper.SearchKeywordsWithCategory(searchQuery, 0, subCat)
+	results, err := helper.SearchKeywordsWithCategory(context.Background(), searchQuery, 0, subCat)
 	if err != nil {
 		log.Error("Couldn't search for subtitles.")
 		os.Exit(1)
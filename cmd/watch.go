@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"text/tabwriter"
@@ -9,9 +10,11 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/sp0x/torrentd/grab"
 	"github.com/sp0x/torrentd/indexer"
 	"github.com/sp0x/torrentd/indexer/search"
 	"github.com/sp0x/torrentd/indexer/status"
+	"github.com/sp0x/torrentd/torrent"
 )
 
 func init() {
@@ -43,6 +46,9 @@ Currently supported storage backings: boltdb, firebase, sqlite`)
 	_ = viper.BindPFlag("query", cmdFlags.Lookup("query"))
 	_ = viper.BindEnv("query")
 	_ = viper.BindPFlag("interval", cmdFlags.Lookup("interval"))
+	dryRun := false
+	cmdFlags.BoolVarP(&dryRun, "dry-run", "", false, "Only show what the grab rules would grab.")
+	_ = viper.BindPFlag("grab.dry_run", cmdFlags.Lookup("dry-run"))
 	rootCmd.AddCommand(cmdWatch)
 }
 
@@ -52,6 +58,7 @@ func watchIndex(c *cobra.Command, _ []string) {
 		fmt.Printf("Couldn't initialize: %s", err)
 		return
 	}
+	watchForChanges(facade)
 
 	// Start watching the torrent tracker.
 	status.SetupPubsub(appConfig.GetString("firebase_project"))
@@ -60,6 +67,11 @@ func watchIndex(c *cobra.Command, _ []string) {
 		log.Error(err)
 		os.Exit(1)
 	}
+	grabber, err := newGrabber(facade)
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
 	watchInterval := viper.GetInt("interval")
 	resultChannel := indexer.Watch(facade, query, watchInterval)
 	tabWr := new(tabwriter.Writer)
@@ -75,5 +87,39 @@ func watchIndex(c *cobra.Command, _ []string) {
 			_, _ = fmt.Fprintf(tabWr, "Updated torrent #%s:\t%s\n",
 				item.UUID(), item.String())
 		}
+		if grabber != nil {
+			grabResult(tabWr, grabber, item)
+		}
+		_ = tabWr.Flush()
+	}
+}
+
+// newGrabber creates the grabber for the rules in the config, if there are any.
+func newGrabber(facade *indexer.Facade) (*grab.Grabber, error) {
+	rules, err := grab.LoadRules(&appConfig)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	grabber := grab.NewGrabber(&appConfig, facade.IndexScope, rules, grab.NewHistory(&appConfig))
+	grabber.DryRun = appConfig.GetBool("grab.dry_run")
+	grabber.Cache, err = torrent.NewFileCacheFromConfig(&appConfig)
+	if err != nil {
+		log.Warningf("Not using the torrent cache: %v", err)
+	}
+	return grabber, nil
+}
+
+func grabResult(wr *tabwriter.Writer, grabber *grab.Grabber, item search.ResultItemBase) {
+	grabbed, err := grabber.Handle(context.Background(), item)
+	if err != nil {
+		log.Warning(err)
+	}
+	if grabbed == nil {
+		return
+	}
+	if grabbed.DryRun {
+		_, _ = fmt.Fprintf(wr, "Would grab #%s:\t%s (rule %s)\n", item.UUID(), grabbed.Title, grabbed.Rule)
+	} else {
+		_, _ = fmt.Fprintf(wr, "Grabbed #%s:\t%s (rule %s)\n", item.UUID(), grabbed.Title, grabbed.Rule)
 	}
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/spf13/viper"
@@ -50,3 +51,20 @@ func (v *ViperConfig) GetString(param string) string {
 func (v *ViperConfig) GetBytes(param string) []byte {
 	return []byte(viper.GetString(param))
 }
+
+// SaveSite writes the options of a site to the config file that's in use.
+// Only the site's section is changed, so values that were only set at runtime aren't written.
+func (v *ViperConfig) SaveSite(name string) error {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		return errors.New("no config file is in use")
+	}
+	fileConfig := viper.New()
+	fileConfig.SetConfigFile(file)
+	if err := fileConfig.ReadInConfig(); err != nil {
+		return err
+	}
+	key := fmt.Sprintf("indexers.%s", name)
+	fileConfig.Set(key, viper.GetStringMapString(key))
+	return fileConfig.WriteConfig()
+}
@@ -99,7 +99,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Type of search. Can be caps, search, tvsearch, tv-search, movie, movie-search, moviesearch. Defaults to caps, returning the capabilities.",
+                        "description": "Type of search. Can be caps, search, tvsearch, tv-search, movie, movie-search, moviesearch, music, music-search, book, book-search. Defaults to caps, returning the capabilities.",
                         "name": "t",
                         "in": "query"
                     },
@@ -139,6 +139,42 @@ const docTemplate = `{
                         "name": "rid",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Artist name",
+                        "name": "artist",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Album name",
+                        "name": "album",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record label",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Track name",
+                        "name": "track",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Book author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Book title",
+                        "name": "title",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Season number",
@@ -163,6 +199,12 @@ const docTemplate = `{
                         "name": "offset",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Sort the results by date or seeders, defaults to date",
+                        "name": "sort",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "Minimum age of the torrent",
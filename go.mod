@@ -7,14 +7,15 @@ require (
 	github.com/PaesslerAG/jsonpath v0.1.1
 	github.com/PuerkitoBio/goquery v1.5.1
 	github.com/PuerkitoBio/purell v1.2.0 // indirect
+	github.com/andybalholm/cascadia v1.1.0
 	github.com/bcampbell/fuzzytime v0.0.0-20191010161914-05ea0010feac
 	github.com/bmizerany/assert v0.0.0-20160611221934-b7ed37b82869 // indirect
 	github.com/boltdb/bolt v1.3.1
-	github.com/cardigann/go-imdb-scraper v0.0.0-20170921034215-f67df398e320
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/dustin/go-humanize v1.0.0
 	github.com/emirpasic/gods v1.12.0
 	github.com/f2prateek/train v0.0.0-20170409194429-523ebcaf2f00
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/gin-contrib/pprof v1.3.0
 	github.com/gin-gonic/gin v1.8.2
 	github.com/go-openapi/spec v0.20.7 // indirect
@@ -32,7 +33,6 @@ require (
 	github.com/kr/text v0.2.0 // indirect
 	github.com/lileio/pubsub v0.0.0-20190923214451-d1c628de58cb
 	github.com/mitchellh/go-homedir v1.1.0
-	github.com/mrobinsn/go-tvmaze v1.2.0
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
 	github.com/onsi/ginkgo v1.14.1
 	github.com/onsi/gomega v1.10.2
@@ -58,5 +58,6 @@ require (
 	google.golang.org/api v0.28.0
 	google.golang.org/grpc v1.28.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 	honnef.co/go/tools v0.0.1-2020.1.6 // indirect
 )
@@ -0,0 +1,147 @@
+package grab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sp0x/torrentd/clients"
+)
+
+const (
+	actionDownload = "download"
+	actionClient   = "client"
+	actionWebhook  = "webhook"
+	actionNotify   = "notify"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// Action is done with the results that a rule grabs.
+type Action struct {
+	// Type is one of download, client, webhook or notify.
+	Type string `json:"type"`
+	// Dir is where the download action writes the .torrent files.
+	Dir string `json:"dir"`
+	// Client is the client of the client action, by default it's the `default_client`.
+	Client   string `json:"client"`
+	Category string `json:"category"`
+	SavePath string `json:"savepath"`
+	Paused   bool   `json:"paused"`
+	// URL is where the webhook action posts the grab.
+	URL string `json:"url"`
+}
+
+func (a *Action) validate() error {
+	switch a.Type {
+	case actionDownload:
+		if a.Dir == "" {
+			return errors.New("the download action needs a dir")
+		}
+	case actionWebhook:
+		if a.URL == "" {
+			return errors.New("the webhook action needs a url")
+		}
+	case actionClient, actionNotify:
+	default:
+		return fmt.Errorf("unknown action `%s`", a.Type)
+	}
+	return nil
+}
+
+// needsTorrent checks if the action sends the torrent somewhere.
+func (a *Action) needsTorrent() bool {
+	return a.Type == actionDownload || a.Type == actionClient
+}
+
+// Notifier is told about grabs by the notify action.
+type Notifier interface {
+	Notify(grab *Grab) error
+}
+
+// logNotifier writes the grabs to the log.
+type logNotifier struct{}
+
+func (logNotifier) Notify(grab *Grab) error {
+	log.WithFields(log.Fields{"rule": grab.Rule, "index": grab.Index}).
+		Infof("Grabbed %s", grab.Title)
+	return nil
+}
+
+func (g *Grabber) runAction(ctx context.Context, action *Action, grab *Grab, torrent *clients.Torrent) error {
+	switch action.Type {
+	case actionDownload:
+		folder, err := clients.New("download", clients.Options{"type": "watch", "dir": action.Dir})
+		if err != nil {
+			return err
+		}
+		return folder.Add(torrent, &clients.AddOptions{Category: action.Category})
+	case actionClient:
+		client, err := clients.FromConfig(g.conf, action.Client)
+		if err != nil {
+			return err
+		}
+		return client.Add(torrent, &clients.AddOptions{
+			Category: action.Category,
+			SavePath: action.SavePath,
+			Paused:   action.Paused,
+		})
+	case actionWebhook:
+		return g.postWebhook(ctx, action.URL, grab)
+	case actionNotify:
+		return g.Notifier.Notify(grab)
+	}
+	return fmt.Errorf("unknown action `%s`", action.Type)
+}
+
+// webhookPayload is what the webhook action posts.
+type webhookPayload struct {
+	Rule     string    `json:"rule"`
+	Title    string    `json:"title"`
+	Index    string    `json:"index"`
+	Link     string    `json:"link"`
+	ResultID string    `json:"result_id"`
+	Size     uint64    `json:"size"`
+	Seeders  int       `json:"seeders"`
+	Category int       `json:"category"`
+	Time     time.Time `json:"time"`
+}
+
+func (g *Grabber) postWebhook(ctx context.Context, url string, grab *Grab) error {
+	body, err := json.Marshal(&webhookPayload{
+		Rule:     grab.Rule,
+		Title:    grab.Title,
+		Index:    grab.Index,
+		Link:     grab.Link,
+		ResultID: grab.ResultID,
+		Size:     grab.Size,
+		Seeders:  grab.Seeders,
+		Category: grab.Category,
+		Time:     grab.Time,
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	request, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("the webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package grab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sp0x/torrentd/clients"
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/torrent"
+)
+
+// Grabber runs the actions of the rules on the results that match them.
+// A release is only grabbed once, by the first rule that it matches.
+type Grabber struct {
+	Rules []*Rule
+	// DryRun only logs what would be grabbed, without running the actions or storing the grabs.
+	DryRun   bool
+	Notifier Notifier
+	// Cache is used for the .torrent files, it's optional.
+	Cache   *torrent.FileCache
+	conf    config.Config
+	scope   indexer.Scope
+	history *History
+	mux     sync.Mutex
+	// pending are the releases that are being grabbed, or that were grabbed since the grabber was created.
+	pending map[string]bool
+}
+
+// NewGrabber creates a grabber, the torrents are downloaded through the indexes of the scope.
+func NewGrabber(conf config.Config, scope indexer.Scope, rules []*Rule, history *History) *Grabber {
+	return &Grabber{
+		Rules:    rules,
+		Notifier: logNotifier{},
+		conf:     conf,
+		scope:    scope,
+		history:  history,
+		pending:  make(map[string]bool),
+	}
+}
+
+// Match finds the first rule that a result matches.
+func (g *Grabber) Match(item *search.TorrentResultItem) *Rule {
+	for _, rule := range g.Rules {
+		if rule.Matches(item) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Handle grabs a result if it matches a rule, and if its release wasn't grabbed already.
+// The grab is returned if the result was grabbed, even if some of the actions failed.
+func (g *Grabber) Handle(ctx context.Context, result search.ResultItemBase) (*Grab, error) {
+	item, ok := result.(*search.TorrentResultItem)
+	if !ok {
+		return nil, nil
+	}
+	rule := g.Match(item)
+	if rule == nil {
+		return nil, nil
+	}
+	grab := newGrab(rule, item)
+	if !g.reserve(grab.Key) {
+		return nil, nil
+	}
+	if g.DryRun {
+		grab.DryRun = true
+		log.WithFields(log.Fields{"rule": rule.Name, "index": grab.Index}).
+			Infof("Would grab %s", grab.Title)
+		return grab, nil
+	}
+	grabbed, err := g.runActions(ctx, rule, grab, item)
+	if !grabbed {
+		// The release is tried again the next time it's seen.
+		g.release(grab.Key)
+		return nil, err
+	}
+	// The release stays pending, so it's not grabbed again even if it can't be stored in the history.
+	if g.history != nil {
+		if storeErr := g.history.Add(grab); storeErr != nil {
+			log.Warningf("Couldn't store the grab of %s: %v", grab.Title, storeErr)
+		}
+	}
+	return grab, err
+}
+
+// runActions runs all the actions of a rule, and checks if any of them succeeded.
+func (g *Grabber) runActions(ctx context.Context, rule *Rule, grab *Grab, item *search.TorrentResultItem) (bool, error) {
+	var t *clients.Torrent
+	for _, action := range rule.Actions {
+		if action.needsTorrent() {
+			var err error
+			t, err = clients.Fetch(ctx, g.scope, g.conf, g.Cache, clients.ResultSource(item))
+			if err != nil {
+				return false, fmt.Errorf("couldn't get the torrent of %s: %v", grab.Title, err)
+			}
+			break
+		}
+	}
+	grabbed := false
+	var failures []string
+	for _, action := range rule.Actions {
+		if err := g.runAction(ctx, action, grab, t); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", action.Type, err))
+			continue
+		}
+		grabbed = true
+	}
+	if len(failures) > 0 {
+		return grabbed, fmt.Errorf("actions of rule `%s` failed for %s: %s", rule.Name, grab.Title, strings.Join(failures, "; "))
+	}
+	return grabbed, nil
+}
+
+// reserve marks a release as being grabbed, if it wasn't grabbed already.
+func (g *Grabber) reserve(key string) bool {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	if g.pending[key] || (g.history != nil && g.history.Has(key)) {
+		return false
+	}
+	g.pending[key] = true
+	return true
+}
+
+func (g *Grabber) release(key string) {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+	delete(g.pending, key)
+}
+
+func newGrab(rule *Rule, item *search.TorrentResultItem) *Grab {
+	src := clients.ResultSource(item)
+	return &Grab{
+		Key:       ReleaseKey(item.Title),
+		Rule:      rule.Name,
+		Title:     item.Title,
+		Index:     src.Index,
+		Link:      src.Link,
+		ResultID:  item.UUID(),
+		Size:      uint64(item.Size),
+		Seeders:   item.Seeders,
+		Category:  item.Category,
+		Time:      time.Now(),
+		ModelData: search.ModelData{},
+	}
+}
@@ -0,0 +1,161 @@
+package grab
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/storage/indexing"
+)
+
+func openTestHistory(dbFile string) *History {
+	return newHistory(storage.NewBuilder(nil).
+		WithNamespace(historyNamespace).
+		WithPK(indexing.NewKey("Key")).
+		WithEndpoint(dbFile).
+		WithRecord(&Grab{}).
+		Build())
+}
+
+func newTestItem(title string) *search.TorrentResultItem {
+	item := &search.TorrentResultItem{Title: title, MagnetLink: "magnet:?xt=urn:btih:abc", Seeders: 5}
+	item.Site = "rutracker.org"
+	return item
+}
+
+func TestReleaseKey(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(ReleaseKey("Show.S01E01.1080p.WEB-DL-GRP")).To(Equal("show s01e01 1080p web dl grp"))
+	g.Expect(ReleaseKey("show s01e01 1080p [web dl] grp")).To(Equal(ReleaseKey("Show.S01E01.1080p.WEB-DL-GRP")))
+}
+
+func TestGrabberHandle(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := ioutil.TempDir("", "grabs-")
+	defer os.RemoveAll(dir)
+	dbFile := filepath.Join(dir, "grabs.db")
+
+	var posted []map[string]interface{}
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		posted = append(posted, payload)
+	}))
+	defer hook.Close()
+	rule := &Rule{
+		Name:  "show",
+		Title: "(?i)^show",
+		Actions: []*Action{
+			{Type: actionDownload, Dir: filepath.Join(dir, "torrents")},
+			{Type: actionWebhook, URL: hook.URL},
+		},
+	}
+	g.Expect(rule.compile()).To(Succeed())
+
+	history := openTestHistory(dbFile)
+	grabber := NewGrabber(nil, nil, []*Rule{rule}, history)
+	grabbed, err := grabber.Handle(context.Background(), newTestItem("Other.S01E01"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(grabbed).To(BeNil())
+
+	grabbed, err = grabber.Handle(context.Background(), newTestItem("Show.S01E01.1080p"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(grabbed).ToNot(BeNil())
+	g.Expect(grabbed.Rule).To(Equal("show"))
+	g.Expect(grabbed.Index).To(Equal("rutracker.org"))
+	magnet, err := ioutil.ReadFile(filepath.Join(dir, "torrents", "Show.S01E01.1080p.magnet"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(magnet)).To(ContainSubstring("magnet:?xt=urn:btih:abc"))
+	g.Expect(posted).To(HaveLen(1))
+	g.Expect(posted[0]["title"]).To(Equal("Show.S01E01.1080p"))
+	g.Expect(posted[0]["rule"]).To(Equal("show"))
+
+	// The same release from another index isn't grabbed again.
+	grabbed, err = grabber.Handle(context.Background(), newTestItem("show s01e01 1080p"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(grabbed).To(BeNil())
+	history.Close()
+
+	// Neither after a restart.
+	history = openTestHistory(dbFile)
+	defer history.Close()
+	grabber = NewGrabber(nil, nil, []*Rule{rule}, history)
+	grabbed, err = grabber.Handle(context.Background(), newTestItem("Show.S01E01.1080p"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(grabbed).To(BeNil())
+	g.Expect(posted).To(HaveLen(1))
+}
+
+func TestGrabberDryRun(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := ioutil.TempDir("", "grabs-")
+	defer os.RemoveAll(dir)
+	rule := &Rule{Name: "all", Actions: []*Action{{Type: actionDownload, Dir: filepath.Join(dir, "torrents")}}}
+	g.Expect(rule.compile()).To(Succeed())
+	history := openTestHistory(filepath.Join(dir, "grabs.db"))
+	defer history.Close()
+
+	grabber := NewGrabber(nil, nil, []*Rule{rule}, history)
+	grabber.DryRun = true
+	grabbed, err := grabber.Handle(context.Background(), newTestItem("Show.S01E01"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(grabbed.DryRun).To(BeTrue())
+	grabbed, _ = grabber.Handle(context.Background(), newTestItem("Show.S01E01"))
+	g.Expect(grabbed).To(BeNil())
+	// Nothing is downloaded or stored.
+	_, err = os.Stat(filepath.Join(dir, "torrents"))
+	g.Expect(os.IsNotExist(err)).To(BeTrue())
+	g.Expect(history.Has(ReleaseKey("Show.S01E01"))).To(BeFalse())
+}
+
+func TestGrabberRetriesFailedGrabs(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := ioutil.TempDir("", "grabs-")
+	defer os.RemoveAll(dir)
+	status := http.StatusInternalServerError
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer hook.Close()
+	rule := &Rule{Name: "all", Actions: []*Action{{Type: actionWebhook, URL: hook.URL}}}
+	g.Expect(rule.compile()).To(Succeed())
+	history := openTestHistory(filepath.Join(dir, "grabs.db"))
+	defer history.Close()
+
+	grabber := NewGrabber(nil, nil, []*Rule{rule}, history)
+	grabbed, err := grabber.Handle(context.Background(), newTestItem("Show.S01E01"))
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(grabbed).To(BeNil())
+
+	status = http.StatusOK
+	grabbed, err = grabber.Handle(context.Background(), newTestItem("Show.S01E01"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(grabbed).ToNot(BeNil())
+	g.Expect(history.Has(ReleaseKey("Show.S01E01"))).To(BeTrue())
+}
+
+func TestGrabberNeverGrabsTwice_WithoutHistory(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := ioutil.TempDir("", "grabs-")
+	defer os.RemoveAll(dir)
+	rule := &Rule{Name: "all", Actions: []*Action{{Type: actionDownload, Dir: filepath.Join(dir, "torrents")}}}
+	g.Expect(rule.compile()).To(Succeed())
+
+	// Without a history the grabs can't be stored, like when storing them fails.
+	grabber := NewGrabber(nil, nil, []*Rule{rule}, nil)
+	grabbed, err := grabber.Handle(context.Background(), newTestItem("Show.S01E01"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(grabbed).ToNot(BeNil())
+	grabbed, err = grabber.Handle(context.Background(), newTestItem("Show.S01E01"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(grabbed).To(BeNil())
+}
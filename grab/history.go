@@ -0,0 +1,153 @@
+package grab
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/storage/bolt"
+	"github.com/sp0x/torrentd/storage/indexing"
+)
+
+const historyNamespace = "__grabs"
+
+// Grab is a release that was grabbed by a rule.
+type Grab struct {
+	// Key identifies the release, so that it's only grabbed once, see ReleaseKey.
+	Key       string
+	Rule      string
+	Title     string
+	Index     string
+	Link      string
+	ResultID  string
+	Size      uint64
+	Seeders   int
+	Category  int
+	Time      time.Time
+	DryRun    bool
+	ModelData search.ModelData
+	UUIDValue string
+	RecordID  uint32
+	isNew     bool
+	isUpdate  bool
+}
+
+func (g *Grab) UUID() string {
+	return g.UUIDValue
+}
+
+func (g *Grab) SetUUID(s string) {
+	g.UUIDValue = s
+}
+
+func (g *Grab) GetID() uint32 {
+	return g.RecordID
+}
+
+func (g *Grab) SetID(u uint32) {
+	g.RecordID = u
+}
+
+func (g *Grab) SetState(new, updated bool) {
+	g.isNew = new
+	g.isUpdate = updated
+}
+
+func (g *Grab) IsNew() bool {
+	return g.isNew
+}
+
+func (g *Grab) IsUpdate() bool {
+	return g.isUpdate
+}
+
+// ReleaseKey identifies a release by its title, so that it's the same on every index that has it.
+func ReleaseKey(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// History keeps the releases that were grabbed.
+type History struct {
+	storage storage.ItemStorage
+	mux     sync.RWMutex
+	grabs   map[string]*Grab
+}
+
+// HistoryPath is the database with the grabbed releases.
+// This is the `grab.db` config value, by default it's next to the results database.
+func HistoryPath(conf config.Config) string {
+	endpoint := conf.GetString("grab.db")
+	if endpoint == "" {
+		resultsEndpoint := conf.GetString("storageendpoint")
+		if resultsEndpoint == "" {
+			resultsEndpoint = bolt.GetDefaultDatabasePath()
+		}
+		endpoint = filepath.Join(filepath.Dir(resultsEndpoint), "grabs.db")
+	}
+	return endpoint
+}
+
+// NewHistory opens the history of the grabs.
+func NewHistory(conf config.Config) *History {
+	builder := storage.NewBuilder(conf).
+		WithNamespace(historyNamespace).
+		WithPK(indexing.NewKey("Key")).
+		WithEndpoint(HistoryPath(conf)).
+		WithRecord(&Grab{})
+	if storageType := conf.GetString("storage"); storageType != "" {
+		builder = builder.WithBacking(storageType)
+	}
+	return newHistory(builder.Build())
+}
+
+func newHistory(itemStorage storage.ItemStorage) *History {
+	h := &History{
+		storage: itemStorage,
+		grabs:   make(map[string]*Grab),
+	}
+	itemStorage.ForEachInNamespaces(func(ns string, record search.Record) bool {
+		if ns != historyNamespace {
+			return false
+		}
+		if grab, ok := record.(*Grab); ok {
+			h.grabs[grab.Key] = grab
+		}
+		return true
+	})
+	return h
+}
+
+// Has checks if a release was grabbed.
+func (h *History) Has(key string) bool {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+	_, ok := h.grabs[key]
+	return ok
+}
+
+// Add stores a grab.
+func (h *History) Add(grab *Grab) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if grab.ModelData == nil {
+		grab.ModelData = search.ModelData{}
+	}
+	if err := h.storage.Add(grab); err != nil {
+		return err
+	}
+	h.grabs[grab.Key] = grab
+	return nil
+}
+
+// Close closes the storage.
+func (h *History) Close() {
+	h.storage.Close()
+}
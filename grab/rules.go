@@ -0,0 +1,198 @@
+package grab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer/categories"
+	"github.com/sp0x/torrentd/indexer/release"
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+// Rule picks the results that are grabbed, and what's done with them.
+// Every condition that's set has to match.
+type Rule struct {
+	Name string `json:"name"`
+	// Title is a regular expression that the title has to match.
+	Title string `json:"title"`
+	// Categories are torznab categories, a parent category matches its subcategories too.
+	Categories []int `json:"categories"`
+	MinSize    Size  `json:"min_size"`
+	MaxSize    Size  `json:"max_size"`
+	MinSeeders int   `json:"min_seeders"`
+	// Uploaders are the only uploaders whose results are grabbed.
+	Uploaders []string `json:"uploaders"`
+	// Freeleech only grabs results that don't count towards the download ratio.
+	Freeleech bool `json:"freeleech"`
+	// Resolutions and Qualities are parsed from the title, see release.Info.
+	Resolutions []string  `json:"resolutions"`
+	Qualities   []string  `json:"qualities"`
+	Actions     []*Action `json:"actions"`
+	titleRx     *regexp.Regexp
+}
+
+// Size is a size in bytes, it can be configured as a number or as a string like `1.5GB`.
+type Size uint64
+
+func (s *Size) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch typedValue := value.(type) {
+	case float64:
+		*s = Size(typedValue)
+	case string:
+		bytes, err := humanize.ParseBytes(typedValue)
+		if err != nil {
+			return fmt.Errorf("invalid size %q: %v", typedValue, err)
+		}
+		*s = Size(bytes)
+	case nil:
+		*s = 0
+	default:
+		return fmt.Errorf("invalid size %v", value)
+	}
+	return nil
+}
+
+// LoadRules loads the rules from the `grab.rules` section of the config.
+func LoadRules(conf config.Config) ([]*Rule, error) {
+	value := conf.Get("grab.rules")
+	if value == nil {
+		return nil, nil
+	}
+	// The config has maps with any keys, which json can't encode.
+	data, err := json.Marshal(normalize(value))
+	if err != nil {
+		return nil, err
+	}
+	var rules []*Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid grab rules: %v", err)
+	}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("invalid grab rule `%s`: %v", rule.Name, err)
+		}
+	}
+	return rules, nil
+}
+
+func (r *Rule) compile() error {
+	if r.Title != "" {
+		titleRx, err := regexp.Compile(r.Title)
+		if err != nil {
+			return err
+		}
+		r.titleRx = titleRx
+	}
+	if r.MaxSize > 0 && r.MinSize > r.MaxSize {
+		return errors.New("min_size is bigger than max_size")
+	}
+	if len(r.Actions) == 0 {
+		return errors.New("no actions")
+	}
+	for _, action := range r.Actions {
+		if err := action.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches checks if a result matches all the conditions of the rule.
+func (r *Rule) Matches(item *search.TorrentResultItem) bool {
+	if r.titleRx != nil && !r.titleRx.MatchString(item.Title) {
+		return false
+	}
+	if len(r.Categories) > 0 && !r.matchesCategory(item.Category) {
+		return false
+	}
+	size := Size(item.Size)
+	if (r.MinSize > 0 && size < r.MinSize) || (r.MaxSize > 0 && size > r.MaxSize) {
+		return false
+	}
+	if item.Seeders < r.MinSeeders {
+		return false
+	}
+	if len(r.Uploaders) > 0 && !containsFold(r.Uploaders, item.Author) {
+		return false
+	}
+	if r.Freeleech && item.DownloadVolumeFactor != 0 {
+		return false
+	}
+	if len(r.Resolutions) > 0 || len(r.Qualities) > 0 {
+		resolution, source := releaseQuality(item)
+		if len(r.Resolutions) > 0 && !containsFold(r.Resolutions, resolution) {
+			return false
+		}
+		if len(r.Qualities) > 0 && !containsFold(r.Qualities, source) {
+			return false
+		}
+	}
+	return true
+}
+
+// releaseQuality gets the resolution and source of a result, results stored before they were parsed are parsed here.
+func releaseQuality(item *search.TorrentResultItem) (string, string) {
+	if item.ParsedTitle != "" {
+		return item.Resolution, item.Source
+	}
+	info := release.Parse(item.Title)
+	return info.Resolution, info.Source
+}
+
+func (r *Rule) matchesCategory(id int) bool {
+	for _, categoryID := range r.Categories {
+		if (categories.Category{ID: categoryID}).Contains(id) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, item := range values {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(typedValue))
+		for key, item := range typedValue {
+			result[fmt.Sprint(key)] = normalize(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(typedValue))
+		for key, item := range typedValue {
+			result[key] = normalize(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(typedValue))
+		for i, item := range typedValue {
+			result[i] = normalize(item)
+		}
+		return result
+	}
+	return value
+}
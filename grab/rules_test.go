@@ -0,0 +1,94 @@
+package grab
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/config/mocks"
+	"github.com/sp0x/torrentd/indexer/categories"
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+func TestLoadRules(t *testing.T) {
+	g := NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	conf := mocks.NewMockConfig(ctrl)
+	conf.EXPECT().Get("grab.rules").Return([]interface{}{
+		map[interface{}]interface{}{
+			"title":    "(?i)^show",
+			"min_size": "1GB",
+			"max_size": 5000000000,
+			"actions":  []interface{}{map[interface{}]interface{}{"type": "notify"}},
+		},
+	})
+	rules, err := LoadRules(conf)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rules).To(HaveLen(1))
+	g.Expect(rules[0].Name).To(Equal("rule 1"))
+	g.Expect(rules[0].MinSize).To(Equal(Size(1000000000)))
+	g.Expect(rules[0].MaxSize).To(Equal(Size(5000000000)))
+
+	invalid := []map[string]interface{}{
+		{"title": "(", "actions": []interface{}{map[string]interface{}{"type": "notify"}}},
+		{"min_size": "2GB", "max_size": "1GB", "actions": []interface{}{map[string]interface{}{"type": "notify"}}},
+		{"title": "show"},
+		{"actions": []interface{}{map[string]interface{}{"type": "download"}}},
+		{"actions": []interface{}{map[string]interface{}{"type": "print"}}},
+	}
+	for _, rule := range invalid {
+		conf.EXPECT().Get("grab.rules").Return([]interface{}{rule})
+		_, err = LoadRules(conf)
+		g.Expect(err).To(HaveOccurred(), "%v", rule)
+	}
+
+	conf.EXPECT().Get("grab.rules").Return(nil)
+	rules, err = LoadRules(conf)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rules).To(BeEmpty())
+}
+
+func TestRuleMatches(t *testing.T) {
+	g := NewWithT(t)
+	item := &search.TorrentResultItem{
+		Title:                "Show.S01E01.1080p.WEB-DL.x264-GRP",
+		Category:             categories.CategoryTVHD.ID,
+		Size:                 1500000000,
+		Seeders:              20,
+		Author:               "Uploader",
+		DownloadVolumeFactor: 0,
+	}
+	rules := []struct {
+		rule    Rule
+		matches bool
+	}{
+		{Rule{}, true},
+		{Rule{Title: "(?i)^show\\.s01"}, true},
+		{Rule{Title: "^Other"}, false},
+		{Rule{Categories: []int{categories.CategoryTV.ID}}, true},
+		{Rule{Categories: []int{categories.CategoryMovies.ID}}, false},
+		{Rule{MinSize: 1000000000, MaxSize: 2000000000}, true},
+		{Rule{MaxSize: 1000000000}, false},
+		{Rule{MinSeeders: 20}, true},
+		{Rule{MinSeeders: 21}, false},
+		{Rule{Uploaders: []string{"uploader"}}, true},
+		{Rule{Uploaders: []string{"someone"}}, false},
+		{Rule{Freeleech: true}, true},
+		{Rule{Resolutions: []string{"720p", "1080p"}}, true},
+		{Rule{Resolutions: []string{"2160p"}}, false},
+		{Rule{Qualities: []string{"webdl"}}, true},
+		{Rule{Qualities: []string{"bluray"}}, false},
+	}
+	for _, test := range rules {
+		rule := test.rule
+		g.Expect(rule.compile()).To(HaveOccurred(), "rules without actions are invalid")
+		rule.Actions = []*Action{{Type: actionNotify}}
+		g.Expect(rule.compile()).To(Succeed())
+		g.Expect(rule.Matches(item)).To(Equal(test.matches), "%+v", test.rule)
+	}
+
+	item.DownloadVolumeFactor = 1
+	g.Expect((&Rule{Freeleech: true}).Matches(item)).To(BeFalse())
+}
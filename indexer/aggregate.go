@@ -1,11 +1,11 @@
 package indexer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 
-	"github.com/sp0x/torrentd/indexer/search"
 	"github.com/sp0x/torrentd/storage"
 	"github.com/sp0x/torrentd/torznab"
 )
@@ -133,17 +133,12 @@ func (ag *Aggregate) Site() string {
 //	return results, nil
 //}
 
+// Capabilities are the union of the capabilities of the indexes, see IndexCollection.Capabilities.
 func (ag *Aggregate) Capabilities() torznab.Capabilities {
-	return torznab.Capabilities{
-		SearchModes: []search.Capability{
-			{Key: "movie-search", Available: true, SupportedParams: []string{"q", "imdbid"}},
-			{Key: "tv-search", Available: true, SupportedParams: []string{"q", "season", "ep"}},
-			{Key: "search", Available: true, SupportedParams: []string{"q"}},
-		},
-	}
+	return IndexCollection(ag.Indexes).Capabilities()
 }
 
-func (ag *Aggregate) Download(string) (*ResponseProxy, error) {
+func (ag *Aggregate) Download(context.Context, string) (*ResponseProxy, error) {
 	return nil, errors.New("not implemented")
 }
 
@@ -54,6 +54,9 @@ func (l *AssetLoader) ListAvailableIndexes(selector *Selector) ([]string, error)
 		fname := path.Base(name)
 		fname = strings.ReplaceAll(fname, ".yml", "")
 		fname = strings.ReplaceAll(fname, ".yaml", "")
+		if IsBaseDefinition(fname) {
+			continue
+		}
 		if selector != nil && !selector.Matches(fname) {
 			continue
 		}
@@ -75,6 +78,9 @@ func (l *AssetLoader) ListWithNames(names []string) ([]string, error) {
 		fname := path.Base(name)
 		fname = strings.ReplaceAll(fname, ".yml", "")
 		fname = strings.ReplaceAll(fname, ".yaml", "")
+		if IsBaseDefinition(fname) {
+			continue
+		}
 		results = append(results, fname)
 	}
 	return results, nil
@@ -82,11 +88,15 @@ func (l *AssetLoader) ListWithNames(names []string) ([]string, error) {
 
 // Load a definition with a given name
 func (l *AssetLoader) Load(key string) (*Definition, error) {
+	return loadResolvedDefinition(l, key)
+}
+
+func (l *AssetLoader) loadDefinition(key string) (*Definition, error) {
 	data, err := l.Resolver(key)
 	if err != nil {
 		return nil, err
 	}
-	def, err := ParseDefinition(data)
+	def, err := parseDefinitionSource(data)
 	if err != nil {
 		return def, err
 	}
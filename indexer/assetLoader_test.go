@@ -1,6 +1,7 @@
 package indexer
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -59,3 +60,21 @@ func TestAssetLoader_ShouldWorkWithCommaSelectors(t *testing.T) {
 	g.Expect(err).To(BeNil())
 	g.Expect(len(names)).To(Equal(2))
 }
+
+func TestGetDefaultEmbeddedDefinitionSource_ShouldMapTheCategoriesOfEachDefinition(t *testing.T) {
+	g := NewWithT(t)
+	src := getDefaultEmbeddedDefinitionSource().(*AssetLoader)
+	names, err := src.ListAvailableIndexes(nil)
+	g.Expect(err).To(BeNil())
+
+	for _, name := range names {
+		data, err := src.Resolver(name)
+		g.Expect(err).To(BeNil())
+		if !bytes.Contains(data, []byte("categories:")) {
+			continue
+		}
+		definition, err := ParseDefinition(data)
+		g.Expect(err).To(BeNil(), name)
+		g.Expect(definition.Capabilities.CategoryMap).ToNot(BeEmpty(), name)
+	}
+}
@@ -67,7 +67,6 @@ func createContentFetcher(r *Runner) *source.WebClient {
 	browsr.SetEncoding(r.definition.Encoding)
 	browsr.SetAttribute(browser.SendReferer, true)
 	browsr.SetAttribute(browser.MetaRefreshHandling, true)
-	browsr.SetRateLimit(r.definition.RateLimit)
 
 	transport, err := r.createTransport()
 	if err != nil {
@@ -80,11 +79,10 @@ func createContentFetcher(r *Runner) *source.WebClient {
 
 	switch os.Getenv("DEBUG_HTTP") {
 	case "1", "true", "basic":
-		browsr.SetTransport(train.TransportWith(transport, trainlog.New(os.Stderr, trainlog.Basic)))
+		transport = train.TransportWith(transport, trainlog.New(os.Stderr, trainlog.Basic))
 	case "body":
-		browsr.SetTransport(train.TransportWith(transport, trainlog.New(os.Stderr, trainlog.Body)))
+		transport = train.TransportWith(transport, trainlog.New(os.Stderr, trainlog.Body))
 	case "":
-		browsr.SetTransport(transport)
 	default:
 		panic("Unknown value for DEBUG_HTTP")
 	}
@@ -94,5 +92,7 @@ func createContentFetcher(r *Runner) *source.WebClient {
 		UserAgent:      userAgent,
 	}
 	contentFetcher := source.NewWebContentFetcher(browsr, r, fetchOptions)
+	contentFetcher.SetTransport(transport)
+	contentFetcher.SetRateLimit(r.definition.RateLimit)
 	return contentFetcher
 }
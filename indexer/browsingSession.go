@@ -1,6 +1,7 @@
 package indexer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -70,13 +71,14 @@ func NewSessionMultiplexer(runner *Runner, sessionCount int) (*BrowsingSessionMu
 	return mux, nil
 }
 
-func (b *BrowsingSessionMultiplexer) acquire() (*BrowsingSession, error) {
+// acquire gets the next session, which logs in with the context if it needs to.
+func (b *BrowsingSessionMultiplexer) acquire(ctx context.Context) (*BrowsingSession, error) {
 	if len(b.sessions) == 0 {
 		return nil, nil
 	}
 	session := b.sessions[b.index%len(b.sessions)]
 	b.index++
-	if err := session.setup(); err != nil {
+	if err := session.setup(ctx); err != nil {
 		return nil, err
 	}
 
@@ -86,10 +88,7 @@ func (b *BrowsingSessionMultiplexer) acquire() (*BrowsingSession, error) {
 func newIndexSessionFromRunner(runner *Runner) (*BrowsingSession, error) {
 	definition := runner.definition
 	webFetcher := createContentFetcher(runner)
-	siteConfig, err := runner.options.Config.GetSite(definition.Name)
-	if err != nil {
-		return nil, err
-	}
+	siteConfig := runner.settings
 	if len(siteConfig) == 0 {
 		log.WithFields(log.Fields{"name": definition.Name}).
 			Warning("Couldn't find any configuration for site.")
@@ -136,7 +135,7 @@ func (l BrowsingSession) isLoggedIn() bool {
 	return l.state == LoggedIn
 }
 
-func (l *BrowsingSession) verifyLogin(f source.FetchResult) (bool, error) {
+func (l *BrowsingSession) verifyLogin(ctx context.Context, f source.FetchResult) (bool, error) {
 	testBlock := l.loginBlock.Test
 	if testBlock.IsEmpty() {
 		return true, nil
@@ -150,7 +149,7 @@ func (l *BrowsingSession) verifyLogin(f source.FetchResult) (bool, error) {
 			return false, err
 		}
 
-		r, err := l.contentFetcher.Fetch(source.NewRequestOptions(testURL))
+		r, err := l.contentFetcher.Fetch(ctx, source.NewRequestOptions(testURL))
 		if _, ok := r.(*source.HTMLFetchResult); !ok {
 			return false, errors.New("expected html from login")
 		}
@@ -205,7 +204,7 @@ func (l *BrowsingSession) extractLoginInput() (map[string]string, error) {
 	return result, nil
 }
 
-func (l *BrowsingSession) initLogin() error {
+func (l *BrowsingSession) initLogin(ctx context.Context) error {
 	if l.loginBlock.Init.IsEmpty() {
 		return nil
 	}
@@ -214,7 +213,7 @@ func (l *BrowsingSession) initLogin() error {
 	if err != nil {
 		return err
 	}
-	_, err = l.contentFetcher.Fetch(source.NewRequestOptions(initURL))
+	_, err = l.contentFetcher.Fetch(ctx, source.NewRequestOptions(initURL))
 	return err
 }
 
@@ -223,7 +222,7 @@ func parseWebMethod(s string) string {
 	return s
 }
 
-func (l *BrowsingSession) login() error {
+func (l *BrowsingSession) login(ctx context.Context) error {
 	loginURL, err := l.urlResolver.Resolve(l.loginBlock.Path)
 	if err != nil {
 		return err
@@ -234,7 +233,7 @@ func (l *BrowsingSession) login() error {
 		return err
 	}
 
-	err = l.initLogin()
+	err = l.initLogin(ctx)
 	if err != nil {
 		return err
 	}
@@ -243,7 +242,7 @@ func (l *BrowsingSession) login() error {
 	var loginReqResult source.FetchResult
 	switch parseWebMethod(method) {
 	case "", loginMethodForm:
-		if loginReqResult, err = l.loginViaForm(loginURL, l.loginBlock.FormSelector, loginValues); err != nil {
+		if loginReqResult, err = l.loginViaForm(ctx, loginURL, l.loginBlock.FormSelector, loginValues); err != nil {
 			return err
 		}
 	case loginMethodPost:
@@ -269,7 +268,7 @@ func (l *BrowsingSession) login() error {
 		}
 	}
 	// Check if the login was successful
-	loggedIn, err := l.verifyLogin(loginReqResult)
+	loggedIn, err := l.verifyLogin(ctx, loginReqResult)
 	if err != nil {
 		return err
 	} else if !loggedIn {
@@ -293,8 +292,8 @@ func (l *BrowsingSession) loginViaCookie(loginURL *url.URL, cookie string, value
 	return l.loginViaGet(loginURL, values)
 }
 
-func (l *BrowsingSession) loginViaForm(loginURL *url.URL, formSelector string, vals map[string]string) (source.FetchResult, error) {
-	fetchResult, err := l.contentFetcher.Fetch(source.NewRequestOptions(loginURL))
+func (l *BrowsingSession) loginViaForm(ctx context.Context, loginURL *url.URL, formSelector string, vals map[string]string) (source.FetchResult, error) {
+	fetchResult, err := l.contentFetcher.Fetch(ctx, source.NewRequestOptions(loginURL))
 	if err != nil {
 		return nil, err
 	}
@@ -346,11 +345,11 @@ func (l *BrowsingSession) loginViaGet(loginURL *url.URL, vals map[string]string)
 	return l.contentFetcher.Open(options)
 }
 
-func (l *BrowsingSession) setup() error {
+func (l *BrowsingSession) setup(ctx context.Context) error {
 	if !l.isRequired() {
 		return nil
 	}
-	if err := l.login(); err != nil {
+	if err := l.login(ctx); err != nil {
 		l.logger.WithError(err).Error("Login failed")
 		return err
 	}
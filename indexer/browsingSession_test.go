@@ -1,6 +1,7 @@
 package indexer
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -32,7 +33,7 @@ func newTestingIndex() *Runner {
 			Init: initBlock{},
 		},
 	}
-	runner := NewRunner(indexDef, &RunnerOpts{
+	runner, _ := NewRunner(indexDef, &RunnerOpts{
 		Config:     cfg,
 		CachePages: false,
 		Transport:  nil,
@@ -70,17 +71,17 @@ func TestNewSessionMultiplexer(t *testing.T) {
 	g.Expect(err).To(gomega.BeNil())
 
 	expectLogin(mContentFetcher, "post", "http://example.com/login")
-	s1, err := multiplexer.acquire()
+	s1, err := multiplexer.acquire(context.Background())
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(s1.isLoggedIn())
 
 	expectLogin(mContentFetcher, "post", "http://example.com/login")
-	s2, err := multiplexer.acquire()
+	s2, err := multiplexer.acquire(context.Background())
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(s2.isLoggedIn())
 
 	expectLogin(mContentFetcher, "post", "http://example.com/login")
-	s3, err := multiplexer.acquire()
+	s3, err := multiplexer.acquire(context.Background())
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(s3.isLoggedIn())
 
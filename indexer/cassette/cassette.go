@@ -0,0 +1,446 @@
+package cassette
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	indexFileName = "cassette.yml"
+	redactedValue = "REDACTED"
+)
+
+// Mode is the way a cassette transport handles requests.
+type Mode int
+
+const (
+	// ModeRecord sends requests to the network and records them.
+	ModeRecord Mode = iota
+	// ModeReplay serves recorded responses, without using the network.
+	ModeReplay
+)
+
+// Form fields with these words in their name are not written in cassettes, so they can be shared safely.
+var sensitiveFields = []string{"user", "login", "mail", "pass", "secret", "token"}
+
+// Headers that hold credentials or sessions, which are not written in cassettes.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// Interaction is a single recorded request with its response.
+// The response body is kept in a separate file so it can be inspected and edited easily.
+type Interaction struct {
+	Key            string      `yaml:"key"`
+	Method         string      `yaml:"method"`
+	URL            string      `yaml:"url"`
+	RequestHeaders http.Header `yaml:"request_headers,omitempty"`
+	RequestBody    string      `yaml:"request_body,omitempty"`
+	Status         int         `yaml:"status"`
+	Headers        http.Header `yaml:"headers,omitempty"`
+	BodyFile       string      `yaml:"body,omitempty"`
+	played         bool
+}
+
+// Cassette is a set of recorded http interactions, stored in a directory.
+type Cassette struct {
+	Dir          string         `yaml:"-"`
+	Interactions []*Interaction `yaml:"interactions"`
+	mutex        sync.Mutex
+}
+
+// New creates an empty cassette that will be stored in the given directory.
+func New(dir string) *Cassette {
+	return &Cassette{Dir: dir}
+}
+
+// Load reads a cassette from a directory.
+func Load(dir string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, indexFileName))
+	if err != nil {
+		return nil, err
+	}
+	c := New(dir)
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("couldn't parse cassette %s: %v", dir, err)
+	}
+	return c, nil
+}
+
+// Create makes an empty cassette in a directory.
+// A cassette that's already in the directory is replaced, but directories with other files are refused,
+// so that they aren't lost.
+func Create(dir string) (*Cassette, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return New(dir), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return New(dir), nil
+	}
+	existing, err := Load(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s isn't empty and doesn't have a cassette", dir)
+	}
+	for _, interaction := range existing.Interactions {
+		if interaction.BodyFile == "" {
+			continue
+		}
+		if err = os.Remove(filepath.Join(dir, interaction.BodyFile)); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if err = os.Remove(filepath.Join(dir, indexFileName)); err != nil {
+		return nil, err
+	}
+	return New(dir), nil
+}
+
+// Open reads the cassette in a directory, or creates an empty one if there's none yet.
+func Open(dir string) (*Cassette, error) {
+	if _, err := os.Stat(filepath.Join(dir, indexFileName)); os.IsNotExist(err) {
+		return New(dir), nil
+	}
+	return Load(dir)
+}
+
+// NewTransport creates a transport for a cassette directory.
+// When recording, any existing cassette in the directory is replaced and requests are sent through the given transport.
+func NewTransport(dir string, mode Mode, transport http.RoundTripper) (http.RoundTripper, error) {
+	switch mode {
+	case ModeRecord:
+		c, err := Create(dir)
+		if err != nil {
+			return nil, err
+		}
+		return c.Record(transport), nil
+	case ModeReplay:
+		c, err := Load(dir)
+		if err != nil {
+			return nil, err
+		}
+		return c.Replay(), nil
+	}
+	return nil, fmt.Errorf("unknown cassette mode %d", mode)
+}
+
+// Save writes the index of the cassette. Bodies are written while recording.
+func (c *Cassette) Save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.save()
+}
+
+func (c *Cassette) save() error {
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(c.Dir, indexFileName), data, 0644)
+}
+
+// Record creates a transport that passes requests through the given transport and records them in the cassette.
+// The cassette is saved after every request, so sessions that are interrupted can still be replayed.
+func (c *Cassette) Record(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &recorder{cassette: c, transport: transport}
+}
+
+// Replay creates a transport that serves responses from the cassette, without using the network.
+func (c *Cassette) Replay() http.RoundTripper {
+	return &player{cassette: c}
+}
+
+// CopyTo copies the cassette, with the bodies of its responses, to a new directory.
+// Any existing cassette in that directory is replaced.
+func (c *Cassette) CopyTo(dir string) (*Cassette, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	copied, err := Create(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	for _, interaction := range c.Interactions {
+		if interaction.BodyFile != "" {
+			body, err := ioutil.ReadFile(filepath.Join(c.Dir, interaction.BodyFile))
+			if err != nil {
+				return nil, err
+			}
+			if err = ioutil.WriteFile(filepath.Join(dir, interaction.BodyFile), body, 0644); err != nil {
+				return nil, err
+			}
+		}
+		interactionCopy := *interaction
+		interactionCopy.played = false
+		copied.Interactions = append(copied.Interactions, &interactionCopy)
+	}
+	return copied, copied.save()
+}
+
+// Add records a request with its response, and saves the cassette.
+// The body of the response is stored as it is, so it should already be decoded.
+func (c *Cassette) Add(req *http.Request, requestBody []byte, resp *http.Response, body []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	interaction := &Interaction{
+		Key:            RequestKey(req, requestBody),
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		RequestHeaders: redactHeaders(req.Header),
+		RequestBody:    redactBody(req, requestBody),
+		Status:         resp.StatusCode,
+		Headers:        responseHeaders(resp.Header),
+		played:         true,
+	}
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	if len(body) > 0 {
+		interaction.BodyFile = fmt.Sprintf("%03d_%s.body", len(c.Interactions)+1, strings.ToLower(req.Method))
+		if err := ioutil.WriteFile(filepath.Join(c.Dir, interaction.BodyFile), body, 0644); err != nil {
+			return err
+		}
+	}
+	c.Interactions = append(c.Interactions, interaction)
+	return c.save()
+}
+
+// find gets the first interaction matching the request key that hasn't been played yet.
+// Interactions are played in the order they were recorded, so login flows that request the same page
+// more than once get the same responses. If every match was played, the last one is reused.
+func (c *Cassette) find(key string) *Interaction {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var lastMatch *Interaction
+	for _, interaction := range c.Interactions {
+		if interaction.Key != key {
+			continue
+		}
+		if !interaction.played {
+			interaction.played = true
+			return interaction
+		}
+		lastMatch = interaction
+	}
+	return lastMatch
+}
+
+// RequestKey creates a canonical key for a request.
+// The order of query and form parameters doesn't matter, and sensitive form fields are left out.
+func RequestKey(req *http.Request, body []byte) string {
+	canonical := *req.URL
+	canonical.RawQuery = canonical.Query().Encode()
+	canonical.Fragment = ""
+	key := strings.ToUpper(req.Method) + " " + canonical.String()
+	if len(body) == 0 {
+		return key
+	}
+	if isForm(req) {
+		return key + " " + redactBody(req, body)
+	}
+	return fmt.Sprintf("%s sha1:%x", key, sha1.Sum(body))
+}
+
+func isForm(req *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return mediaType == "application/x-www-form-urlencoded"
+}
+
+// redactBody gets the body of a request, in a form that's safe to store.
+func redactBody(req *http.Request, body []byte) string {
+	if len(body) == 0 || !isForm(req) {
+		return string(body)
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return string(body)
+	}
+	for name := range values {
+		for _, sensitive := range sensitiveFields {
+			if strings.Contains(strings.ToLower(name), sensitive) {
+				values.Set(name, redactedValue)
+			}
+		}
+	}
+	return values.Encode()
+}
+
+func redactHeaders(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range sensitiveHeaders {
+		values := redacted[name]
+		for i, value := range values {
+			values[i] = redactHeader(name, value)
+		}
+	}
+	return redacted
+}
+
+// redactHeader hides the value of a header. Only the values of cookies are hidden, so it's still clear which ones were set.
+func redactHeader(name, value string) string {
+	switch name {
+	case "Cookie":
+		cookies := strings.Split(value, ";")
+		for i, cookie := range cookies {
+			cookies[i] = redactCookie(cookie)
+		}
+		return strings.Join(cookies, ";")
+	case "Set-Cookie":
+		parts := strings.SplitN(value, ";", 2)
+		parts[0] = redactCookie(parts[0])
+		return strings.Join(parts, ";")
+	}
+	return redactedValue
+}
+
+func redactCookie(cookie string) string {
+	separator := strings.Index(cookie, "=")
+	if separator < 0 {
+		return redactedValue
+	}
+	return cookie[:separator+1] + redactedValue
+}
+
+// responseHeaders gets the headers of a response in a form that's safe to store with its decoded body.
+func responseHeaders(header http.Header) http.Header {
+	stored := redactHeaders(header)
+	stored.Del("Content-Encoding")
+	stored.Del("Content-Length")
+	return stored
+}
+
+// decodeBody decodes the body of a response with the encoding it was sent in.
+func decodeBody(encoding string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	switch strings.ToLower(encoding) {
+	case "", "identity":
+		return body, nil
+	case "gzip":
+		gzipReader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		reader = gzipReader
+	case "deflate":
+		reader = flate.NewReader(bytes.NewReader(body))
+	default:
+		return nil, fmt.Errorf("responses with %s encoding can't be recorded", encoding)
+	}
+	return ioutil.ReadAll(reader)
+}
+
+// readRequestBody reads the body of the request, leaving it intact for the next transport.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func (c *Cassette) response(req *http.Request, interaction *Interaction) (*http.Response, error) {
+	var body []byte
+	if interaction.BodyFile != "" {
+		var err error
+		body, err = ioutil.ReadFile(filepath.Join(c.Dir, interaction.BodyFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+	header := http.Header{}
+	for k, v := range interaction.Headers {
+		header[k] = v
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+type recorder struct {
+	cassette  *Cassette
+	transport http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	decoded, err := decodeBody(resp.Header.Get("Content-Encoding"), body)
+	if err != nil {
+		// Recording doesn't change the response, it's only left out of the cassette.
+		log.WithFields(log.Fields{"url": req.URL.String()}).WithError(err).
+			Warn("Couldn't record the response")
+		return resp, nil
+	}
+	if err := r.cassette.Add(req, requestBody, resp, decoded); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type player struct {
+	cassette *Cassette
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (p *player) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := RequestKey(req, requestBody)
+	interaction := p.cassette.find(key)
+	if interaction == nil {
+		return nil, fmt.Errorf("no recorded interaction for %s", key)
+	}
+	return p.cassette.response(req, interaction)
+}
@@ -0,0 +1,165 @@
+package cassette
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestCassette_ShouldReplayRecordedResponses(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = fmt.Fprintf(w, "page %s", r.URL.Query().Get("q"))
+	}))
+	dir, _ := ioutil.TempDir("", "cassette-")
+	defer os.RemoveAll(dir)
+
+	recording := New(dir)
+	client := &http.Client{Transport: recording.Record(nil)}
+	resp, err := client.Get(server.URL + "/search?q=a&page=1")
+	g.Expect(err).To(gomega.BeNil())
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(string(body)).To(gomega.Equal("page a"))
+	g.Expect(recording.Save()).To(gomega.BeNil())
+	server.Close()
+
+	loaded, err := Load(dir)
+	g.Expect(err).To(gomega.BeNil())
+	client = &http.Client{Transport: loaded.Replay()}
+	// The order of the query parameters shouldn't matter.
+	resp, err = client.Get(server.URL + "/search?page=1&q=a")
+	g.Expect(err).To(gomega.BeNil())
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(string(body)).To(gomega.Equal("page a"))
+	g.Expect(resp.Header.Get("Content-Type")).To(gomega.Equal("text/html"))
+
+	_, err = client.Get(server.URL + "/search?q=b")
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestCassette_ShouldReplayLoginWithCookies(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			_ = r.ParseForm()
+			if r.PostForm.Get("password") == "secret" {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			}
+		case "/private":
+			if cookie, err := r.Cookie("session"); err == nil && cookie.Value == "abc" {
+				_, _ = fmt.Fprint(w, "logged in")
+				return
+			}
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	dir, _ := ioutil.TempDir("", "cassette-")
+	defer os.RemoveAll(dir)
+	login := func(transport http.RoundTripper) string {
+		jar, _ := cookiejar.New(nil)
+		client := &http.Client{Transport: transport, Jar: jar}
+		_, err := client.PostForm(server.URL+"/login", url.Values{"username": {"tracker-user"}, "password": {"secret"}})
+		g.Expect(err).To(gomega.BeNil())
+		resp, err := client.Get(server.URL + "/private")
+		g.Expect(err).To(gomega.BeNil())
+		body, _ := ioutil.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	transport, err := NewTransport(dir, ModeRecord, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(login(transport)).To(gomega.Equal("logged in"))
+	server.Close()
+
+	index, _ := ioutil.ReadFile(filepath.Join(dir, indexFileName))
+	g.Expect(string(index)).ToNot(gomega.ContainSubstring("secret"))
+	g.Expect(string(index)).ToNot(gomega.ContainSubstring("tracker-user"))
+	g.Expect(string(index)).ToNot(gomega.ContainSubstring("session=abc"))
+
+	transport, err = NewTransport(dir, ModeReplay, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(login(transport)).To(gomega.Equal("logged in"))
+}
+
+func TestCassette_ShouldStoreDecodedBodies(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		_, _ = fmt.Fprint(writer, "compressed page")
+		_ = writer.Close()
+	}))
+	dir, _ := ioutil.TempDir("", "cassette-")
+	defer os.RemoveAll(dir)
+	get := func(transport http.RoundTripper) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/page", nil)
+		// Asking for an encoding means that the client decodes the body itself.
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := transport.RoundTrip(req)
+		g.Expect(err).To(gomega.BeNil())
+		return resp
+	}
+
+	recording := New(dir)
+	resp := get(recording.Record(nil))
+	g.Expect(resp.Header.Get("Content-Encoding")).To(gomega.Equal("gzip"))
+	server.Close()
+
+	body, _ := ioutil.ReadFile(filepath.Join(dir, recording.Interactions[0].BodyFile))
+	g.Expect(string(body)).To(gomega.Equal("compressed page"))
+	loaded, err := Load(dir)
+	g.Expect(err).To(gomega.BeNil())
+	resp = get(loaded.Replay())
+	g.Expect(resp.Header.Get("Content-Encoding")).To(gomega.BeEmpty())
+	body, _ = ioutil.ReadAll(resp.Body)
+	g.Expect(string(body)).To(gomega.Equal("compressed page"))
+}
+
+func TestCassette_ShouldPassThroughBodiesItCantDecode(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		_, _ = fmt.Fprint(w, "brotli page")
+	}))
+	defer server.Close()
+	dir, _ := ioutil.TempDir("", "cassette-")
+	defer os.RemoveAll(dir)
+
+	recording := New(dir)
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/page", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	resp, err := recording.Record(nil).RoundTrip(req)
+	g.Expect(err).To(gomega.BeNil())
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(string(body)).To(gomega.Equal("brotli page"))
+	g.Expect(recording.Interactions).To(gomega.BeEmpty())
+}
+
+func TestNewTransport_ShouldOnlyReplaceCassettes(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir, _ := ioutil.TempDir("", "cassette-")
+	defer os.RemoveAll(dir)
+	notes := filepath.Join(dir, "notes.txt")
+	g.Expect(ioutil.WriteFile(notes, []byte("notes"), 0644)).To(gomega.BeNil())
+
+	_, err := NewTransport(dir, ModeRecord, nil)
+	g.Expect(err).ToNot(gomega.BeNil())
+	_, err = os.Stat(notes)
+	g.Expect(err).To(gomega.BeNil())
+
+	cassetteDir := filepath.Join(dir, "cassette")
+	g.Expect(New(cassetteDir).Save()).To(gomega.BeNil())
+	_, err = NewTransport(cassetteDir, ModeRecord, nil)
+	g.Expect(err).To(gomega.BeNil())
+}
@@ -202,6 +202,18 @@ func ParentCategory(c *Category) Category {
 	return CategoryOther
 }
 
+// Contains checks if a category is the given one, or if it's one of its subcategories.
+func (c Category) Contains(id int) bool {
+	if c.ID == id {
+		return true
+	}
+	isRoot := ParentCategory(&c).ID == c.ID
+	if !isRoot || id < 0 || id >= CustomCategoryOffset {
+		return false
+	}
+	return ParentCategory(&Category{ID: id}).ID == c.ID
+}
+
 func (slice Categories) Items() []*Category {
 	v := make([]*Category, 0, len(slice))
 	for _, c := range slice {
@@ -93,3 +93,17 @@ func (mr *MockScopeMockRecorder) LookupWithCategories(config, selector, cats int
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LookupWithCategories", reflect.TypeOf((*MockScope)(nil).LookupWithCategories), config, selector, cats)
 }
+
+// Reload mocks base method.
+func (m *MockScope) Reload(config config.Config, def *Definition) map[Indexer]Indexer {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reload", config, def)
+	ret0, _ := ret[0].(map[Indexer]Indexer)
+	return ret0
+}
+
+// Reload indicates an expected call of Reload.
+func (mr *MockScopeMockRecorder) Reload(config, def interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reload", reflect.TypeOf((*MockScope)(nil).Reload), config, def)
+}
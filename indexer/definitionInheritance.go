@@ -0,0 +1,258 @@
+package indexer
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const baseDefinitionPrefix = "_"
+
+// IsBaseDefinition checks if the name of a definition belongs to a base definition, like `_gazelle`.
+// Base definitions only hold the blocks that other definitions extend, so they aren't listed as indexes.
+func IsBaseDefinition(key string) bool {
+	return strings.HasPrefix(key, baseDefinitionPrefix)
+}
+
+// definitionSourceLoader is implemented by loaders that can load a definition without resolving its base.
+type definitionSourceLoader interface {
+	loadDefinition(key string) (*Definition, error)
+}
+
+// loadDefinitionSource loads a definition without resolving its base, if the loader supports it.
+func loadDefinitionSource(loader DefinitionLoader, key string) (*Definition, error) {
+	if sourceLoader, ok := loader.(definitionSourceLoader); ok {
+		return sourceLoader.loadDefinition(key)
+	}
+	return loader.Load(key)
+}
+
+// loadResolvedDefinition loads a definition and merges it with its bases, which are loaded from the same loader.
+func loadResolvedDefinition(loader DefinitionLoader, key string) (*Definition, error) {
+	def, err := loadDefinitionSource(loader, key)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := resolveDefinition(def, key, loader)
+	if err != nil {
+		return nil, err
+	}
+	resolved.stats.Key = key
+	return resolved, nil
+}
+
+// resolveDefinition merges a definition with the chain of definitions that it extends.
+// Mappings are merged deeply, everything else in the definition overrides the value of its base.
+func resolveDefinition(def *Definition, key string, loader DefinitionLoader) (*Definition, error) {
+	if def.Extends == "" {
+		return def, nil
+	}
+	if loader == nil {
+		return nil, fmt.Errorf("definition %s extends %s, but there's no loader for it", def.Name, def.Extends)
+	}
+	var visited []string
+	if key != "" {
+		visited = append(visited, key)
+	}
+	chain := []*Definition{def}
+	for current := def; current.Extends != ""; {
+		name := current.Extends
+		if contains(visited, name) {
+			return nil, fmt.Errorf("definition inheritance cycle: %s", strings.Join(append(visited, name), " -> "))
+		}
+		visited = append(visited, name)
+		base, err := loadDefinitionSource(loader, name)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load base definition %s: %v", name, err)
+		}
+		if base.source == nil {
+			return nil, fmt.Errorf("base definition %s has no source", name)
+		}
+		chain = append(chain, base)
+		current = base
+	}
+
+	merged := chain[len(chain)-1].source
+	for ix := len(chain) - 2; ix >= 0; ix-- {
+		merged = mergeDefinitionSources(merged, chain[ix].source)
+	}
+	src, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := definitionFromSource(merged, src, true)
+	if err != nil {
+		return nil, err
+	}
+	resolved.source = def.source
+	resolved.stats.ModTime = def.stats.ModTime
+	resolved.stats.Source = def.stats.Source
+	return resolved, nil
+}
+
+// mergeDefinitionSources deeply merges the mappings of a definition into the ones of its base.
+// Other values replace the ones in the base, and null values remove them.
+func mergeDefinitionSources(base, override yaml.MapSlice) yaml.MapSlice {
+	merged := make(yaml.MapSlice, len(base), len(base)+len(override))
+	copy(merged, base)
+	for _, item := range override {
+		ix := indexOfSourceKey(merged, item.Key)
+		switch {
+		case ix < 0 && item.Value == nil:
+		case ix < 0:
+			merged = append(merged, item)
+		case item.Value == nil:
+			merged = append(merged[:ix], merged[ix+1:]...)
+		default:
+			baseValue, baseIsMap := merged[ix].Value.(yaml.MapSlice)
+			overrideValue, overrideIsMap := item.Value.(yaml.MapSlice)
+			if baseIsMap && overrideIsMap {
+				merged[ix].Value = mergeDefinitionSources(baseValue, overrideValue)
+			} else {
+				merged[ix].Value = item.Value
+			}
+		}
+	}
+	return merged
+}
+
+func indexOfSourceKey(doc yaml.MapSlice, key interface{}) int {
+	name := fmt.Sprint(key)
+	for ix, item := range doc {
+		if fmt.Sprint(item.Key) == name {
+			return ix
+		}
+	}
+	return -1
+}
+
+func lookupSourceValue(doc yaml.MapSlice, key string) (interface{}, bool) {
+	ix := indexOfSourceKey(doc, key)
+	if ix < 0 {
+		return nil, false
+	}
+	return doc[ix].Value, true
+}
+
+func withoutSourceKey(doc yaml.MapSlice, key string) yaml.MapSlice {
+	ix := indexOfSourceKey(doc, key)
+	if ix < 0 {
+		return doc
+	}
+	result := make(yaml.MapSlice, 0, len(doc)-1)
+	result = append(result, doc[:ix]...)
+	return append(result, doc[ix+1:]...)
+}
+
+// snippetExpander replaces the references to named snippets in a definition.
+// Fields use a snippet with `snippet: <name>` and can override parts of it,
+// filter lists use `- snippet: <name>` which is replaced with all the filters of the snippet.
+type snippetExpander struct {
+	fields  yaml.MapSlice
+	filters yaml.MapSlice
+	changed bool
+}
+
+// expandSnippets replaces the snippet references in a definition with the snippets from its `snippets` block.
+// If the definition has no references, nil is returned.
+func expandSnippets(doc yaml.MapSlice) (yaml.MapSlice, error) {
+	expander := &snippetExpander{}
+	if value, ok := lookupSourceValue(doc, "snippets"); ok {
+		snippets, _ := value.(yaml.MapSlice)
+		if fields, ok := lookupSourceValue(snippets, "fields"); ok {
+			expander.fields, _ = fields.(yaml.MapSlice)
+		}
+		if filters, ok := lookupSourceValue(snippets, "filters"); ok {
+			expander.filters, _ = filters.(yaml.MapSlice)
+		}
+	}
+	expanded, err := expander.expandMap(withoutSourceKey(doc, "snippets"), nil)
+	if err != nil || !expander.changed {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+func (e *snippetExpander) expandValue(value interface{}, stack []string) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case yaml.MapSlice:
+		return e.expandMap(typedValue, stack)
+	case []interface{}:
+		result := make([]interface{}, len(typedValue))
+		for ix, item := range typedValue {
+			expanded, err := e.expandValue(item, stack)
+			if err != nil {
+				return nil, err
+			}
+			result[ix] = expanded
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+func (e *snippetExpander) expandMap(doc yaml.MapSlice, stack []string) (yaml.MapSlice, error) {
+	if name, ok := lookupSourceValue(doc, "snippet"); ok {
+		snippetName := fmt.Sprint(name)
+		if contains(stack, "fields."+snippetName) {
+			return nil, fmt.Errorf("field snippet %s references itself", snippetName)
+		}
+		value, _ := lookupSourceValue(e.fields, snippetName)
+		snippet, ok := value.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("unknown field snippet: %s", snippetName)
+		}
+		e.changed = true
+		// The snippet can use another snippet, so it's expanded again.
+		merged := mergeDefinitionSources(snippet, withoutSourceKey(doc, "snippet"))
+		return e.expandMap(merged, append(stack, "fields."+snippetName))
+	}
+	result := make(yaml.MapSlice, len(doc))
+	for ix, item := range doc {
+		var err error
+		if filters, ok := item.Value.([]interface{}); ok && fmt.Sprint(item.Key) == "filters" {
+			item.Value, err = e.expandFilters(filters, stack)
+		} else {
+			item.Value, err = e.expandValue(item.Value, stack)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result[ix] = item
+	}
+	return result, nil
+}
+
+func (e *snippetExpander) expandFilters(filters []interface{}, stack []string) ([]interface{}, error) {
+	result := make([]interface{}, 0, len(filters))
+	for _, filter := range filters {
+		filterMap, _ := filter.(yaml.MapSlice)
+		name, ok := lookupSourceValue(filterMap, "snippet")
+		if !ok {
+			expanded, err := e.expandValue(filter, stack)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, expanded)
+			continue
+		}
+		snippetName := fmt.Sprint(name)
+		if contains(stack, "filters."+snippetName) {
+			return nil, fmt.Errorf("filter snippet %s references itself", snippetName)
+		}
+		value, _ := lookupSourceValue(e.filters, snippetName)
+		snippet, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unknown filter snippet: %s", snippetName)
+		}
+		e.changed = true
+		expanded, err := e.expandFilters(snippet, append(stack, "filters."+snippetName))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded...)
+	}
+	return result, nil
+}
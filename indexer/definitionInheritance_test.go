@@ -0,0 +1,167 @@
+package indexer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+const baseDefinitionSource = `---
+site: base
+name: base
+links:
+  - http://localhost/
+login:
+  path: /login.php
+  method: post
+  inputs:
+    username: "{{ .Config.username }}"
+    password: "{{ .Config.password }}"
+snippets:
+  filters:
+    cleanup:
+      - name: trim
+        args: " "
+      - name: replace
+        args: ["\n", ""]
+  fields:
+    size:
+      selector: td.size
+      filters:
+        - snippet: cleanup
+search:
+  path: /browse.php
+  rows:
+    selector: table.torrents tr
+  fields:
+    title:
+      selector: td.name
+      filters:
+        - snippet: cleanup
+    size:
+      snippet: size
+    comments:
+      selector: td.comments
+`
+
+const childDefinitionSource = `---
+site: child
+name: child
+extends: _base
+links:
+  - http://child.localhost/
+login:
+  path: /takelogin.php
+search:
+  fields:
+    size:
+      selector: td:nth-child(5)
+    comments: ~
+    seeders:
+      selector: td.seeders
+`
+
+func writeDefinitions(t *testing.T, definitions map[string]string) string {
+	dir, _ := ioutil.TempDir("", "definitions-")
+	for name, src := range definitions {
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".yml"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func fieldNames(def *Definition) []string {
+	var names []string
+	for _, field := range def.Search.Fields {
+		names = append(names, field.Field)
+	}
+	return names
+}
+
+func TestFileIndexLoader_ShouldResolveBaseDefinitions(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir := writeDefinitions(t, map[string]string{"_base": baseDefinitionSource, "child": childDefinitionSource})
+	defer os.RemoveAll(dir)
+	loader := &FileIndexLoader{Directories: []string{dir}}
+
+	g.Expect(loader.ListAvailableIndexes(nil)).To(gomega.Equal([]string{"child"}))
+	def, err := loader.Load("child")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(def.Site).To(gomega.Equal("child"))
+	g.Expect(def.Extends).To(gomega.Equal("_base"))
+	g.Expect(def.Links).To(gomega.Equal(stringorslice{"http://child.localhost/"}))
+	// Mappings are merged, so the inputs of the base are kept.
+	g.Expect(def.Login.Path).To(gomega.Equal("/takelogin.php"))
+	g.Expect(def.Login.Method).To(gomega.Equal("post"))
+	g.Expect(def.Login.Inputs).To(gomega.HaveKey("password"))
+	g.Expect(def.Search.Path).To(gomega.Equal("/browse.php"))
+	g.Expect(def.Search.Rows.Selector).To(gomega.Equal("table.torrents tr"))
+	g.Expect(fieldNames(def)).To(gomega.Equal([]string{"title", "size", "seeders"}))
+
+	size := def.Search.Fields[1].Block
+	g.Expect(size.Selector).To(gomega.Equal("td:nth-child(5)"))
+	g.Expect(size.Filters).To(gomega.HaveLen(2))
+	g.Expect(size.Filters[0].Name).To(gomega.Equal("trim"))
+	g.Expect(def.Stats().Source).To(gomega.HavePrefix("file:"))
+}
+
+func TestMultipleDefinitionLoader_ShouldResolveBasesFromOtherLoaders(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir := writeDefinitions(t, map[string]string{"child": childDefinitionSource})
+	defer os.RemoveAll(dir)
+	embedded := CreateEmbeddedDefinitionSource([]string{"_base.yml"}, func(key string) ([]byte, error) {
+		if key != "_base" {
+			return nil, ErrUnknownIndex
+		}
+		return []byte(baseDefinitionSource), nil
+	})
+	loader := &MultipleDefinitionLoader{&FileIndexLoader{Directories: []string{dir}}, embedded}
+
+	g.Expect(loader.ListAvailableIndexes(nil)).To(gomega.Equal([]string{"child"}))
+	def, err := loader.Load("child")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(def.Login.Method).To(gomega.Equal("post"))
+	g.Expect(fieldNames(def)).To(gomega.Equal([]string{"title", "size", "seeders"}))
+}
+
+func TestDefinitionLoader_ShouldDetectInheritanceCycles(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir := writeDefinitions(t, map[string]string{
+		"first":  "site: first\nextends: second\n",
+		"second": "site: second\nextends: first\n",
+	})
+	defer os.RemoveAll(dir)
+	loader := &FileIndexLoader{Directories: []string{dir}}
+
+	_, err := loader.Load("first")
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("first -> second -> first"))
+}
+
+func TestParseDefinition_ShouldExpandSnippets(t *testing.T) {
+	g := gomega.NewWithT(t)
+	def, err := ParseDefinition([]byte(baseDefinitionSource))
+	g.Expect(err).To(gomega.BeNil())
+
+	title := def.Search.Fields[0].Block
+	g.Expect(title.Filters).To(gomega.HaveLen(2))
+	g.Expect(title.Filters[1].Name).To(gomega.Equal("replace"))
+	size := def.Search.Fields[1].Block
+	g.Expect(size.Selector).To(gomega.Equal("td.size"))
+	g.Expect(size.Filters).To(gomega.HaveLen(2))
+
+	_, err = ParseDefinition([]byte("site: x\nsearch:\n  fields:\n    title:\n      snippet: missing\n"))
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestParseDefinition_ShouldKeepTheHashOfDefinitionsWithoutSnippets(t *testing.T) {
+	g := gomega.NewWithT(t)
+	src := []byte("site: x\nname: x\n")
+	def, err := ParseDefinition(src)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(def.Stats().Size).To(gomega.Equal(int64(len(src))))
+}
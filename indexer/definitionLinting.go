@@ -0,0 +1,437 @@
+package indexer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/andybalholm/cascadia"
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+
+	"github.com/sp0x/torrentd/indexer/categories"
+	"github.com/sp0x/torrentd/indexer/source"
+	"github.com/sp0x/torrentd/indexer/utils"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+var yamlErrorLineRx = regexp.MustCompile(`line (\d+)`)
+
+// LintIssue is a single problem found in a definition.
+type LintIssue struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	path := ""
+	if i.Path != "" {
+		path = i.Path + ": "
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s%s", i.File, i.Line, i.Column, i.Severity, path, i.Message)
+}
+
+// lintSchema describes what's allowed in a node of a definition.
+type lintSchema struct {
+	// keys that are allowed in a mapping
+	keys map[string]*lintSchema
+	// values is used for mappings that can have any key, like inputs or fields
+	values *lintSchema
+	// items is used for sequences. If a mapping is given instead, it's treated as a single item.
+	items *lintSchema
+	// check runs additional validation on the node
+	check func(l *definitionLinter, node *yamlv3.Node, path string)
+}
+
+var (
+	scalarSchema   = &lintSchema{}
+	templateSchema = &lintSchema{check: checkTemplate}
+	filterSchema   = &lintSchema{
+		keys:  map[string]*lintSchema{"name": scalarSchema, "args": scalarSchema, "snippet": scalarSchema},
+		check: checkFilter,
+	}
+	selectorSchema = &lintSchema{
+		keys: map[string]*lintSchema{
+			"selector":     {check: checkSelector},
+			"path":         scalarSchema,
+			"pattern":      templateSchema,
+			"text":         templateSchema,
+			"attribute":    scalarSchema,
+			"remove":       {check: checkSelector},
+			"filters":      {items: filterSchema},
+			"case":         {check: checkCaseSelectors},
+			"filterconfig": {values: scalarSchema},
+			"all":          scalarSchema,
+			"snippet":      scalarSchema,
+		},
+	}
+	inputsSchema = &lintSchema{values: templateSchema}
+	pageSchema   = &lintSchema{keys: map[string]*lintSchema{
+		"path":     scalarSchema,
+		"selector": {check: checkSelector},
+	}}
+	definitionSchema = &lintSchema{keys: map[string]*lintSchema{
+		"site":        scalarSchema,
+		"version":     scalarSchema,
+		"extends":     scalarSchema,
+		"scheme":      scalarSchema,
+		"name":        scalarSchema,
+		"type":        {check: checkIndexType},
+		"description": scalarSchema,
+		"language":    scalarSchema,
+		"encoding":    scalarSchema,
+		"ratelimit":   scalarSchema,
+		"links":       {items: scalarSchema},
+		"settings": {items: &lintSchema{keys: map[string]*lintSchema{
+			"name":     scalarSchema,
+			"type":     {check: checkSettingType},
+			"label":    scalarSchema,
+			"default":  scalarSchema,
+			"required": scalarSchema,
+			"options":  scalarSchema,
+		}}},
+		"caps": {keys: map[string]*lintSchema{
+			"categories": {values: &lintSchema{check: checkCategoryName}},
+			"modes":      {values: &lintSchema{items: scalarSchema}},
+		}},
+		"login": {keys: map[string]*lintSchema{
+			"path":   templateSchema,
+			"form":   {check: checkSelector},
+			"method": scalarSchema,
+			"inputs": inputsSchema,
+			"error": {items: &lintSchema{keys: map[string]*lintSchema{
+				"path":     scalarSchema,
+				"selector": {check: checkSelector},
+				"message":  selectorSchema,
+			}}},
+			"test": pageSchema,
+			"init": {keys: map[string]*lintSchema{"path": scalarSchema}},
+		}},
+		"ratio": withKeys(selectorSchema, map[string]*lintSchema{"path": scalarSchema}),
+		"search": {keys: map[string]*lintSchema{
+			"path":     templateSchema,
+			"method":   scalarSchema,
+			"pagesize": scalarSchema,
+			"pages":    scalarSchema,
+			"inputs":   inputsSchema,
+			"rows": withKeys(selectorSchema, map[string]*lintSchema{
+				"after":       scalarSchema,
+				"remove":      {check: checkSelector},
+				"dateheaders": selectorSchema,
+			}),
+			"fields":  {values: selectorSchema},
+			"context": {values: selectorSchema},
+			"key":     {items: scalarSchema},
+		}},
+		"entities": {items: &lintSchema{keys: map[string]*lintSchema{
+			"name": scalarSchema,
+			"key":  {items: scalarSchema},
+		}}},
+		"snippets": {keys: map[string]*lintSchema{
+			"fields":  {values: selectorSchema},
+			"filters": {values: &lintSchema{items: filterSchema}},
+		}},
+	}}
+)
+
+// withKeys creates a copy of a schema with additional allowed keys.
+func withKeys(schema *lintSchema, keys map[string]*lintSchema) *lintSchema {
+	merged := &lintSchema{keys: map[string]*lintSchema{}, check: schema.check}
+	for k, v := range schema.keys {
+		merged.keys[k] = v
+	}
+	for k, v := range keys {
+		merged.keys[k] = v
+	}
+	return merged
+}
+
+type definitionLinter struct {
+	issues []LintIssue
+}
+
+// LintDefinition checks the source of a definition for problems that would otherwise only show up while scraping.
+// This covers unknown keys, filters and their arguments, categories, templates, selectors and required fields.
+func LintDefinition(src []byte) []LintIssue {
+	return lintDefinition(src, true)
+}
+
+// LintBaseDefinition checks the source of a base definition.
+// Base definitions are only used through other definitions, so they don't need to have all the required fields.
+func LintBaseDefinition(src []byte) []LintIssue {
+	return lintDefinition(src, false)
+}
+
+func lintDefinition(src []byte, checkRequired bool) []LintIssue {
+	linter := &definitionLinter{}
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(src, &root); err != nil {
+		linter.reportError(err)
+		return linter.issues
+	}
+	document := &root
+	if root.Kind == yamlv3.DocumentNode && len(root.Content) > 0 {
+		document = root.Content[0]
+	}
+	linter.walk(document, definitionSchema, "")
+
+	def, err := ParseDefinition(src)
+	if err != nil {
+		linter.reportError(err)
+	} else if checkRequired {
+		linter.checkRequired(def, document)
+	}
+
+	sort.SliceStable(linter.issues, func(i, j int) bool {
+		if linter.issues[i].Line == linter.issues[j].Line {
+			return linter.issues[i].Column < linter.issues[j].Column
+		}
+		return linter.issues[i].Line < linter.issues[j].Line
+	})
+	return linter.issues
+}
+
+// HasLintErrors checks if any of the issues is an error.
+func HasLintErrors(issues []LintIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *definitionLinter) report(node *yamlv3.Node, severity, path, format string, args ...interface{}) {
+	issue := LintIssue{
+		Severity: severity,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if node != nil {
+		issue.Line = node.Line
+		issue.Column = node.Column
+	}
+	l.issues = append(l.issues, issue)
+}
+
+// reportError adds an issue for a parsing error, using the line number from the error message if there is one.
+func (l *definitionLinter) reportError(err error) {
+	issue := LintIssue{Severity: LintError, Message: err.Error()}
+	if match := yamlErrorLineRx.FindStringSubmatch(err.Error()); match != nil {
+		issue.Line, _ = strconv.Atoi(match[1])
+	}
+	l.issues = append(l.issues, issue)
+}
+
+func (l *definitionLinter) walk(node *yamlv3.Node, schema *lintSchema, path string) {
+	if node == nil || schema == nil {
+		return
+	}
+	if node.Kind == yamlv3.AliasNode {
+		node = node.Alias
+	}
+	if schema.check != nil {
+		schema.check(l, node, path)
+	}
+	switch node.Kind {
+	case yamlv3.SequenceNode:
+		if schema.items == nil {
+			if schema.keys != nil || schema.values != nil {
+				l.report(node, LintError, path, "expected a mapping, got a list")
+			}
+			return
+		}
+		for ix, child := range node.Content {
+			l.walk(child, schema.items, fmt.Sprintf("%s[%d]", path, ix))
+		}
+	case yamlv3.MappingNode:
+		if schema.items != nil {
+			l.walk(node, schema.items, path)
+			return
+		}
+		if schema.keys == nil && schema.values == nil {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			childPath := joinLintPath(path, keyNode.Value)
+			if schema.values != nil {
+				l.walk(valueNode, schema.values, childPath)
+				continue
+			}
+			childSchema, ok := schema.keys[keyNode.Value]
+			if !ok {
+				l.report(keyNode, LintWarning, childPath, "unknown key %q", keyNode.Value)
+				continue
+			}
+			l.walk(valueNode, childSchema, childPath)
+		}
+	}
+}
+
+func joinLintPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// checkRequired validates the fields that a definition needs, depending on its scheme.
+func (l *definitionLinter) checkRequired(def *Definition, document *yamlv3.Node) {
+	if def.Site == "" {
+		l.report(document, LintError, "site", "a site is required")
+	}
+	if def.Name == "" {
+		l.report(document, LintError, "name", "a name is required")
+	}
+	if len(def.Links) == 0 {
+		l.report(document, LintError, "links", "at least one link is required")
+	}
+	searchNode := lookupLintNode(document, "search")
+	if searchNode == nil {
+		searchNode = document
+	}
+	if def.Search.Rows.Selector == "" && def.Search.Rows.Path == "" {
+		l.report(searchNode, LintError, "search.rows", "a rows selector or path is required")
+	}
+	if def.Scheme != schemeTorrent {
+		return
+	}
+	fieldsNode := lookupLintNode(document, "search", "fields")
+	if fieldsNode == nil {
+		fieldsNode = searchNode
+	}
+	hasField := func(name string) bool {
+		for _, f := range def.Search.Fields {
+			if f.Field == name {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasField("title") {
+		l.report(fieldsNode, LintError, "search.fields.title", "torrent definitions require a title field")
+	}
+	if !hasField("download") && !hasField("magnet") {
+		l.report(fieldsNode, LintError, "search.fields", "torrent definitions require a download or magnet field")
+	}
+	if len(def.Capabilities.CategoryMap) > 0 && !hasField("category") {
+		l.report(fieldsNode, LintWarning, "search.fields.category", "categories are mapped, but there's no category field")
+	}
+}
+
+// lookupLintNode finds the value node for a path of mapping keys.
+func lookupLintNode(node *yamlv3.Node, keys ...string) *yamlv3.Node {
+	for _, key := range keys {
+		if node == nil || node.Kind != yamlv3.MappingNode {
+			return nil
+		}
+		var next *yamlv3.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		node = next
+	}
+	return node
+}
+
+func checkTemplate(l *definitionLinter, node *yamlv3.Node, path string) {
+	if node.Kind != yamlv3.ScalarNode || !strings.Contains(node.Value, "{{") {
+		return
+	}
+	if _, err := template.New(path).Funcs(utils.GetDefaultFunctionMap()).Parse(node.Value); err != nil {
+		l.report(node, LintError, path, "invalid template: %v", err)
+	}
+}
+
+func checkSelector(l *definitionLinter, node *yamlv3.Node, path string) {
+	if node.Kind != yamlv3.ScalarNode {
+		l.report(node, LintError, path, "a selector must be a string")
+		return
+	}
+	if node.Value == "" {
+		return
+	}
+	if _, err := cascadia.Compile(node.Value); err != nil {
+		l.report(node, LintError, path, "invalid selector %q: %v", node.Value, err)
+	}
+}
+
+func checkCaseSelectors(l *definitionLinter, node *yamlv3.Node, path string) {
+	if node.Kind != yamlv3.MappingNode {
+		l.report(node, LintError, path, "case must be a mapping of selectors to values")
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		checkSelector(l, node.Content[i], joinLintPath(path, node.Content[i].Value))
+	}
+}
+
+func checkCategoryName(l *definitionLinter, node *yamlv3.Node, path string) {
+	for _, cat := range categories.AllCategories {
+		if cat.Name == node.Value {
+			return
+		}
+	}
+	l.report(node, LintError, path, "unknown category %q", node.Value)
+}
+
+func checkSettingType(l *definitionLinter, node *yamlv3.Node, path string) {
+	switch node.Value {
+	case settingText, settingPassword, settingSelect, settingCheckbox, settingInt:
+	default:
+		l.report(node, LintError, path, "unknown setting type %q", node.Value)
+	}
+}
+
+func checkIndexType(l *definitionLinter, node *yamlv3.Node, path string) {
+	switch node.Value {
+	case "public", "private", "semi-private":
+	default:
+		l.report(node, LintError, path, "unknown index type %q, it can be public, private or semi-private", node.Value)
+	}
+}
+
+func checkFilter(l *definitionLinter, node *yamlv3.Node, path string) {
+	if lookupLintNode(node, "snippet") != nil {
+		return
+	}
+	nameNode := lookupLintNode(node, "name")
+	if nameNode == nil {
+		l.report(node, LintError, path, "filter has no name")
+		return
+	}
+	var args interface{}
+	if argsNode := lookupLintNode(node, "args"); argsNode != nil {
+		// Filters receive their arguments the way yaml.v2 decodes them, so we do the same.
+		encoded, err := yamlv3.Marshal(argsNode)
+		if err == nil {
+			err = yaml.Unmarshal(encoded, &args)
+		}
+		if err != nil {
+			l.report(argsNode, LintError, path+".args", "invalid filter arguments: %v", err)
+			return
+		}
+	}
+	filters := &source.FilterService{}
+	if err := filters.Validate(nameNode.Value, args); err != nil {
+		l.report(nameNode, LintError, path, "%v", err)
+	}
+	if argString, ok := args.(string); ok {
+		checkTemplate(l, &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: argString, Line: node.Line, Column: node.Column}, path+".args")
+	}
+}
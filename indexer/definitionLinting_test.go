@@ -0,0 +1,90 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+const validLintDefinition = `---
+site: example
+name: Example
+type: semi-private
+links:
+  - https://example.com/
+caps:
+  categories:
+    1: Movies
+search:
+  path: "/search?q={{ .Keywords }}"
+  rows:
+    selector: table.results tr
+  fields:
+    title:
+      selector: td.title a
+      filters:
+        - name: trim
+          args: " "
+    download:
+      selector: td.dl a
+      attribute: href
+    category:
+      selector: td.cat
+`
+
+func TestLintDefinition_Valid(t *testing.T) {
+	g := gomega.NewWithT(t)
+	issues := LintDefinition([]byte(validLintDefinition))
+	g.Expect(issues).To(gomega.BeEmpty())
+}
+
+func TestLintDefinition_ReportsProblemsWithLines(t *testing.T) {
+	g := gomega.NewWithT(t)
+	src := `---
+site: example
+name: Example
+links:
+  - https://example.com/
+unknownkey: 1
+caps:
+  categories:
+    1: NotACategory
+search:
+  path: "/search?q={{ .Keywords "
+  rows:
+    selector: "table[["
+  fields:
+    title:
+      selector: td
+      filters:
+        - name: nosuchfilter
+        - name: split
+          args: "|"
+scheme: torrent
+type: invite-only
+`
+	issues := LintDefinition([]byte(src))
+	byPath := map[string]LintIssue{}
+	for _, issue := range issues {
+		byPath[issue.Path] = issue
+	}
+	g.Expect(byPath).To(gomega.HaveKey("unknownkey"))
+	g.Expect(byPath["unknownkey"].Severity).To(gomega.Equal(LintWarning))
+	g.Expect(byPath["unknownkey"].Line).To(gomega.Equal(6))
+	g.Expect(byPath["caps.categories.1"].Line).To(gomega.Equal(9))
+	g.Expect(byPath["search.path"].Severity).To(gomega.Equal(LintError))
+	g.Expect(byPath["search.rows.selector"].Line).To(gomega.Equal(13))
+	g.Expect(byPath["search.fields.title.filters[0]"].Message).To(gomega.ContainSubstring("nosuchfilter"))
+	g.Expect(byPath).To(gomega.HaveKey("search.fields.title.filters[1]"))
+	g.Expect(byPath).To(gomega.HaveKey("search.fields"))
+	g.Expect(byPath["type"].Severity).To(gomega.Equal(LintError))
+	g.Expect(HasLintErrors(issues)).To(gomega.BeTrue())
+}
+
+func TestLintDefinition_InvalidYaml(t *testing.T) {
+	g := gomega.NewWithT(t)
+	issues := LintDefinition([]byte("site: a\n  name: [b\n"))
+	g.Expect(issues).To(gomega.HaveLen(1))
+	g.Expect(issues[0].Severity).To(gomega.Equal(LintError))
+	g.Expect(issues[0].Line).ToNot(gomega.BeZero())
+}
@@ -0,0 +1,348 @@
+package indexer
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v2"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/indexer/source"
+)
+
+const (
+	scaffoldMinRows    = 3
+	scaffoldSampleRows = 5
+	scaffoldMaxDepth   = 3
+)
+
+var (
+	scaffoldSizeRx     = regexp.MustCompile(`(?i)^\s*[\d.,]+\s*(b|kb|mb|gb|tb|kib|mib|gib|tib)\s*$`)
+	scaffoldNumberRx   = regexp.MustCompile(`^\s*[\d,]+\s*$`)
+	scaffoldDateRx     = regexp.MustCompile(`(?i)(\d{4}-\d{1,2}-\d{1,2}|\d{1,2}[./]\d{1,2}[./]\d{2,4}|\bago\b|today|yesterday)`)
+	scaffoldClassRx    = regexp.MustCompile(`^[A-Za-z_][\w-]*$`)
+	scaffoldDownloadRx = regexp.MustCompile(`(?i)(\.torrent|download|/dl/|dl\.php)`)
+	scaffoldKeywordsRx = regexp.MustCompile(`(?i)^(q|s|search|query|keywords?|searchstr|term)$`)
+)
+
+// DefinitionScaffold is a starting point for a definition, guessed from a page with search results.
+type DefinitionScaffold struct {
+	URL    *url.URL
+	Rows   string
+	Fields []ScaffoldField
+}
+
+// ScaffoldField is a field that was found in the result rows.
+type ScaffoldField struct {
+	Name      string
+	Selector  string
+	Attribute string
+}
+
+// LoadScaffoldPage reads a search results page from a url or a saved html file.
+// For saved files, the url of the site can't be guessed so a placeholder is used.
+func LoadScaffoldPage(location string) (*goquery.Document, *url.URL, error) {
+	if _, err := os.Stat(location); err == nil {
+		content, err := ioutil.ReadFile(location)
+		if err != nil {
+			return nil, nil, err
+		}
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(content)))
+		if err != nil {
+			return nil, nil, err
+		}
+		pageURL, _ := url.Parse("https://example.com/search")
+		return doc, pageURL, nil
+	}
+	pageURL, err := url.Parse(location)
+	if err != nil || pageURL.Host == "" {
+		return nil, nil, fmt.Errorf("%q is neither a file, nor a url", location)
+	}
+	resp, err := http.Get(pageURL.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("couldn't fetch %s: %s", location, resp.Status)
+	}
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, resp.Request.URL, nil
+}
+
+// ScaffoldDefinition looks for the repeating rows in a page with search results and guesses the fields in them.
+func ScaffoldDefinition(doc *goquery.Document, pageURL *url.URL) (*DefinitionScaffold, error) {
+	rows, rowsSelector := findResultRows(doc)
+	if rows == nil || rows.Length() == 0 {
+		return nil, errors.New("couldn't find any repeating rows with links")
+	}
+	scaffold := &DefinitionScaffold{
+		URL:  pageURL,
+		Rows: rowsSelector,
+	}
+	scaffold.Fields = guessRowFields(rows)
+	return scaffold, nil
+}
+
+// findResultRows finds the biggest group of similar sibling elements that contain links.
+// Groups with more cells in each row are preferred, so that tables win over menus.
+func findResultRows(doc *goquery.Document) (*goquery.Selection, string) {
+	var bestRows *goquery.Selection
+	var bestSelector string
+	bestScore := 0
+	doc.Find("body *").Each(func(_ int, parent *goquery.Selection) {
+		groups := map[string][]*html.Node{}
+		parent.Children().Each(func(_ int, child *goquery.Selection) {
+			if child.Find("a[href]").Length() == 0 {
+				return
+			}
+			signature := elementSelector(child)
+			groups[signature] = append(groups[signature], child.Get(0))
+		})
+		for signature, nodes := range groups {
+			if len(nodes) < scaffoldMinRows {
+				continue
+			}
+			rows := parent.Children().FilterNodes(nodes...)
+			cells := rows.Children().Length() / len(nodes)
+			score := len(nodes) * (cells + 1)
+			if score <= bestScore {
+				continue
+			}
+			bestScore = score
+			bestRows = rows
+			bestSelector = ancestorsSelector(parent) + " > " + signature
+			if goquery.NodeName(rows) == "tr" {
+				bestSelector += ":has(td)"
+			}
+		}
+	})
+	if bestRows == nil {
+		return nil, ""
+	}
+	// Use the selector to get the rows, so that it's checked against the page.
+	return doc.Find(bestSelector), bestSelector
+}
+
+// elementSelector creates a selector from the tag and the classes of an element.
+func elementSelector(s *goquery.Selection) string {
+	selector := goquery.NodeName(s)
+	classes := strings.Fields(s.AttrOr("class", ""))
+	sort.Strings(classes)
+	for _, class := range classes {
+		if scaffoldClassRx.MatchString(class) {
+			selector += "." + class
+		}
+	}
+	return selector
+}
+
+// ancestorsSelector creates a selector for an element, using a few of its parents.
+// It stops at the first element with an id, since that's specific enough.
+func ancestorsSelector(s *goquery.Selection) string {
+	var parts []string
+	for current := s; current.Length() > 0 && len(parts) < scaffoldMaxDepth; current = current.Parent() {
+		name := goquery.NodeName(current)
+		if name == "body" || name == "html" {
+			break
+		}
+		if id, ok := current.Attr("id"); ok && scaffoldClassRx.MatchString(id) {
+			parts = append([]string{name + "#" + id}, parts...)
+			break
+		}
+		parts = append([]string{elementSelector(current)}, parts...)
+	}
+	return strings.Join(parts, " > ")
+}
+
+// guessRowFields looks at the cells of the first rows and guesses which fields they contain.
+func guessRowFields(rows *goquery.Selection) []ScaffoldField {
+	var fields []ScaffoldField
+	sample := rows.Slice(0, minInt(rows.Length(), scaffoldSampleRows))
+	// Rows that have more cells are better examples, since some might be headers or separators.
+	first := sample.First()
+	sample.Each(func(_ int, row *goquery.Selection) {
+		if row.Children().Length() > first.Children().Length() {
+			first = row
+		}
+	})
+	matchesSample := func(selector string, test func(string) bool) bool {
+		matching := 0
+		sample.Each(func(_ int, row *goquery.Selection) {
+			if test(strings.TrimSpace(row.Find(selector).First().Text())) {
+				matching++
+			}
+		})
+		return matching*2 > sample.Length()
+	}
+
+	if first.Find(`a[href^="magnet:"]`).Length() > 0 {
+		fields = append(fields, ScaffoldField{Name: "magnet", Selector: `a[href^="magnet:"]`, Attribute: "href"})
+	}
+	first.Find("a[href]").EachWithBreak(func(_ int, link *goquery.Selection) bool {
+		match := scaffoldDownloadRx.FindString(link.AttrOr("href", ""))
+		if match == "" {
+			return true
+		}
+		selector := fmt.Sprintf(`a[href*=%q]`, strings.ToLower(match))
+		fields = append(fields, ScaffoldField{Name: "download", Selector: selector, Attribute: "href"})
+		return false
+	})
+
+	// The title is usually the link with the longest text, that isn't a download link.
+	var titleLink *goquery.Selection
+	first.Find("a[href]").Each(func(_ int, link *goquery.Selection) {
+		href := link.AttrOr("href", "")
+		if strings.HasPrefix(href, "magnet:") || scaffoldDownloadRx.MatchString(href) {
+			return
+		}
+		if titleLink == nil || len(strings.TrimSpace(link.Text())) > len(strings.TrimSpace(titleLink.Text())) {
+			titleLink = link
+		}
+	})
+	titleCell := -1
+	if titleLink != nil {
+		selector := "a"
+		first.Children().EachWithBreak(func(ix int, cell *goquery.Selection) bool {
+			switch {
+			case cell.Get(0) == titleLink.Get(0):
+				selector = cellSelector(cell, ix)
+			case cell.Contains(titleLink.Get(0)):
+				selector = cellSelector(cell, ix) + " " + elementSelector(titleLink)
+			default:
+				return true
+			}
+			titleCell = ix
+			return false
+		})
+		fields = append(fields,
+			ScaffoldField{Name: "title", Selector: selector},
+			ScaffoldField{Name: "details", Selector: selector, Attribute: "href"})
+	}
+
+	var numberCells []string
+	foundSize, foundDate := false, false
+	first.Children().Each(func(ix int, cell *goquery.Selection) {
+		if ix == titleCell {
+			return
+		}
+		selector := cellSelector(cell, ix)
+		switch {
+		case !foundSize && matchesSample(selector, scaffoldSizeRx.MatchString):
+			foundSize = true
+			fields = append(fields, ScaffoldField{Name: "size", Selector: selector})
+		case !foundDate && matchesSample(selector, scaffoldDateRx.MatchString):
+			foundDate = true
+			fields = append(fields, ScaffoldField{Name: "date", Selector: selector})
+		case matchesSample(selector, scaffoldNumberRx.MatchString):
+			numberCells = append(numberCells, selector)
+		}
+	})
+	// Seeders usually come right before the leechers, and they're the first counts in the row.
+	for ix, name := range []string{"seeders", "leechers"} {
+		if ix < len(numberCells) {
+			fields = append(fields, ScaffoldField{Name: name, Selector: numberCells[ix]})
+		}
+	}
+	return fields
+}
+
+func cellSelector(cell *goquery.Selection, ix int) string {
+	return fmt.Sprintf("%s:nth-child(%d)", goquery.NodeName(cell), ix+1)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// YAML creates the source of a starter definition from the scaffold.
+func (d *DefinitionScaffold) YAML() ([]byte, error) {
+	host := strings.TrimPrefix(d.URL.Hostname(), "www.")
+	siteURL := url.URL{Scheme: d.URL.Scheme, Host: d.URL.Host, Path: "/"}
+	inputs := yaml.MapSlice{}
+	query := d.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := query.Get(key)
+		if scaffoldKeywordsRx.MatchString(key) {
+			value = "{{ .Keywords }}"
+		}
+		inputs = append(inputs, yaml.MapItem{Key: key, Value: value})
+	}
+	fields := yaml.MapSlice{}
+	for _, field := range d.Fields {
+		block := yaml.MapSlice{{Key: "selector", Value: field.Selector}}
+		if field.Attribute != "" {
+			block = append(block, yaml.MapItem{Key: "attribute", Value: field.Attribute})
+		}
+		fields = append(fields, yaml.MapItem{Key: field.Name, Value: block})
+	}
+	searchPath := d.URL.Path
+	if searchPath == "" {
+		searchPath = "/"
+	}
+	def := yaml.MapSlice{
+		{Key: "site", Value: host},
+		{Key: "scheme", Value: schemeTorrent},
+		{Key: "name", Value: host},
+		{Key: "language", Value: "en-us"},
+		{Key: "links", Value: []string{siteURL.String()}},
+		{Key: "search", Value: yaml.MapSlice{
+			{Key: "path", Value: searchPath},
+			{Key: "inputs", Value: inputs},
+			{Key: "rows", Value: yaml.MapSlice{{Key: "selector", Value: d.Rows}}},
+			{Key: "fields", Value: fields},
+		}},
+	}
+	return yaml.Marshal(def)
+}
+
+// PreviewDefinition runs a definition over a page that was already fetched, using the same extraction as searches.
+func PreviewDefinition(def *Definition, doc *goquery.Document, pageURL *url.URL, conf config.Config) ([]search.ResultItemBase, error) {
+	runner, err := NewRunner(def, &RunnerOpts{Config: conf, UserSessions: 1})
+	if err != nil {
+		return nil, err
+	}
+	runner.urlResolver = &staticURLResolver{base: pageURL}
+	items, err := runner.extractScrapeItems(&source.HTMLFetchResult{DOM: doc}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		return nil, errors.New("no rows were found")
+	}
+	return runner.processScrapedItems(items, &scrapeContext{query: search.NewQuery()}), nil
+}
+
+// staticURLResolver resolves urls relative to a page, without checking if the site is reachable.
+type staticURLResolver struct {
+	base *url.URL
+}
+
+func (s *staticURLResolver) Resolve(partialURL string) (*url.URL, error) {
+	partial, err := url.Parse(partialURL)
+	if err != nil {
+		return nil, err
+	}
+	return s.base.ResolveReference(partial), nil
+}
@@ -0,0 +1,66 @@
+package indexer
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+const scaffoldPage = `<html><body>
+<div id="menu"><ul><li><a href="/">Home</a></li><li><a href="/browse">Browse</a></li><li><a href="/forum">Forum</a></li></ul></div>
+<table class="list" id="results"><tbody>
+<tr><th><a href="?sort=name">Name</a></th><th>Size</th><th>Added</th><th>S</th><th>L</th><th></th></tr>
+<tr><td><a class="title" href="/details/1">Ubuntu 20.04 Desktop</a></td><td>2.5 GB</td><td>2020-07-01</td><td>100</td><td>5</td><td><a href="/download/1.torrent">dl</a></td></tr>
+<tr><td><a class="title" href="/details/2">Ubuntu 18.04 Server</a></td><td>900 MB</td><td>2020-06-01</td><td>10</td><td>1</td><td><a href="/download/2.torrent">dl</a></td></tr>
+<tr><td><a class="title" href="/details/3">Debian 10</a></td><td>3.1 GB</td><td>2 days ago</td><td>1,200</td><td>30</td><td><a href="/download/3.torrent">dl</a></td></tr>
+</tbody></table>
+</body></html>`
+
+func TestScaffoldDefinition_ShouldFindRowsAndFields(t *testing.T) {
+	g := gomega.NewWithT(t)
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(scaffoldPage))
+	pageURL, _ := url.Parse("https://tracker.example/search.php?q=ubuntu&cat=0")
+
+	scaffold, err := ScaffoldDefinition(doc, pageURL)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(scaffold.Rows).To(gomega.Equal("table#results > tbody > tr:has(td)"))
+	fields := map[string]string{}
+	for _, field := range scaffold.Fields {
+		fields[field.Name] = field.Selector
+	}
+	g.Expect(fields["title"]).To(gomega.Equal("td:nth-child(1) a.title"))
+	g.Expect(fields["size"]).To(gomega.Equal("td:nth-child(2)"))
+	g.Expect(fields["date"]).To(gomega.Equal("td:nth-child(3)"))
+	g.Expect(fields["seeders"]).To(gomega.Equal("td:nth-child(4)"))
+	g.Expect(fields["leechers"]).To(gomega.Equal("td:nth-child(5)"))
+	g.Expect(fields).To(gomega.HaveKey("download"))
+
+	src, err := scaffold.YAML()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(src)).To(gomega.ContainSubstring("q: '{{ .Keywords }}'"))
+	def, err := ParseDefinition(src)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(def.Links).To(gomega.ContainElement("https://tracker.example/"))
+
+	results, err := PreviewDefinition(def, doc, pageURL, &config.ViperConfig{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(results).To(gomega.HaveLen(3))
+	first := results[0].(*search.TorrentResultItem)
+	g.Expect(first.Title).To(gomega.Equal("Ubuntu 20.04 Desktop"))
+	g.Expect(first.Seeders).To(gomega.Equal(100))
+	g.Expect(first.AsScrapeItem().SourceLink).To(gomega.Equal("https://tracker.example/download/1.torrent"))
+}
+
+func TestScaffoldDefinition_ShouldFailWithoutRows(t *testing.T) {
+	g := gomega.NewWithT(t)
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(`<html><body><p>nothing</p></body></html>`))
+	pageURL, _ := url.Parse("https://tracker.example/")
+	_, err := ScaffoldDefinition(doc, pageURL)
+	g.Expect(err).ToNot(gomega.BeNil())
+}
@@ -0,0 +1,190 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer/cassette"
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+const (
+	definitionSnapshotFile = "expected.yml"
+	definitionCassetteDir  = "cassette"
+)
+
+// DefinitionSnapshot is the expected outcome of searching with a definition.
+type DefinitionSnapshot struct {
+	Query   string           `yaml:"query"`
+	Results []SnapshotResult `yaml:"results"`
+}
+
+// SnapshotResult holds the fields of a result that are checked in definition tests.
+type SnapshotResult struct {
+	Title    string `yaml:"title"`
+	Size     uint32 `yaml:"size,omitempty"`
+	Category int    `yaml:"category,omitempty"`
+	Link     string `yaml:"link,omitempty"`
+}
+
+// DefinitionTest runs a definition against http fixtures recorded in a directory, and compares the results
+// with a snapshot that's stored next to them.
+type DefinitionTest struct {
+	Definition *Definition
+	Dir        string
+	Config     config.Config
+}
+
+// NewDefinitionTest creates a test for a definition, with its fixtures and snapshot in the given directory.
+func NewDefinitionTest(def *Definition, dir string, conf config.Config) *DefinitionTest {
+	return &DefinitionTest{
+		Definition: def,
+		Dir:        dir,
+		Config:     conf,
+	}
+}
+
+// Record runs the query against the live site, saving all the requests that were made and the results as a snapshot.
+func (t *DefinitionTest) Record(query string, transport http.RoundTripper) (*DefinitionSnapshot, error) {
+	cassetteDir := filepath.Join(t.Dir, definitionCassetteDir)
+	recording, err := cassette.Create(cassetteDir)
+	if err != nil {
+		return nil, err
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	snapshot, err := t.search(query, recording.Record(transport))
+	if err != nil {
+		return nil, err
+	}
+	if err = recording.Save(); err != nil {
+		return nil, err
+	}
+	return snapshot, t.saveSnapshot(snapshot)
+}
+
+// Import creates the fixtures from a cassette that was recorded before, like the ones that `--dump` writes for each site.
+// The query is replayed with the cassette, and the results are saved as the snapshot.
+func (t *DefinitionTest) Import(query, cassetteDir string) (*DefinitionSnapshot, error) {
+	recording, err := cassette.Load(cassetteDir)
+	if err != nil {
+		return nil, err
+	}
+	fixtures, err := recording.CopyTo(filepath.Join(t.Dir, definitionCassetteDir))
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := t.search(query, fixtures.Replay())
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, t.saveSnapshot(snapshot)
+}
+
+// Run replays the recorded fixtures and returns the differences from the expected snapshot.
+func (t *DefinitionTest) Run() ([]string, error) {
+	expected, err := t.loadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	recording, err := cassette.Load(filepath.Join(t.Dir, definitionCassetteDir))
+	if err != nil {
+		return nil, err
+	}
+	actual, err := t.search(expected.Query, recording.Replay())
+	if err != nil {
+		return nil, err
+	}
+	return diffSnapshots(expected, actual), nil
+}
+
+func (t *DefinitionTest) saveSnapshot(snapshot *DefinitionSnapshot) error {
+	data, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(t.Dir, definitionSnapshotFile), data, 0644)
+}
+
+func (t *DefinitionTest) loadSnapshot() (*DefinitionSnapshot, error) {
+	data, err := ioutil.ReadFile(filepath.Join(t.Dir, definitionSnapshotFile))
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &DefinitionSnapshot{}
+	if err = yaml.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("couldn't parse snapshot: %v", err)
+	}
+	return snapshot, nil
+}
+
+func (t *DefinitionTest) search(query string, transport http.RoundTripper) (*DefinitionSnapshot, error) {
+	runner, err := NewRunner(t.Definition, &RunnerOpts{
+		Config:       t.Config,
+		Transport:    transport,
+		UserSessions: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	q, err := search.NewQueryFromQueryString(query)
+	if err != nil {
+		return nil, err
+	}
+	results, err := runner.Search(context.Background(), q, nil)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &DefinitionSnapshot{Query: query}
+	for _, item := range results {
+		snapshot.Results = append(snapshot.Results, newSnapshotResult(item))
+	}
+	return snapshot, nil
+}
+
+func newSnapshotResult(item search.ResultItemBase) SnapshotResult {
+	torrent, ok := item.(*search.TorrentResultItem)
+	if !ok {
+		return SnapshotResult{Title: item.String()}
+	}
+	link := torrent.AsScrapeItem().SourceLink
+	if link == "" {
+		link = torrent.MagnetLink
+	}
+	return SnapshotResult{
+		Title:    torrent.Title,
+		Size:     torrent.Size,
+		Category: torrent.Category,
+		Link:     link,
+	}
+}
+
+func diffSnapshots(expected, actual *DefinitionSnapshot) []string {
+	var diffs []string
+	if len(expected.Results) != len(actual.Results) {
+		diffs = append(diffs, fmt.Sprintf("expected %d results, got %d", len(expected.Results), len(actual.Results)))
+	}
+	for i := 0; i < len(expected.Results) && i < len(actual.Results); i++ {
+		exp, act := expected.Results[i], actual.Results[i]
+		if exp.Title != act.Title {
+			diffs = append(diffs, fmt.Sprintf("result %d: expected title %q, got %q", i, exp.Title, act.Title))
+		}
+		if exp.Size != act.Size {
+			diffs = append(diffs, fmt.Sprintf("result %d: expected size %d, got %d", i, exp.Size, act.Size))
+		}
+		if exp.Category != act.Category {
+			diffs = append(diffs, fmt.Sprintf("result %d: expected category %d, got %d", i, exp.Category, act.Category))
+		}
+		if exp.Link != act.Link {
+			diffs = append(diffs, fmt.Sprintf("result %d: expected link %q, got %q", i, exp.Link, act.Link))
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,120 @@
+package indexer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer/cassette"
+	"github.com/sp0x/torrentd/indexer/source"
+)
+
+const definitionTestsDir = "../definitions/tests"
+
+func getFixtureDefinition(siteURL string) *Definition {
+	return &Definition{
+		Site:   "fixture",
+		Name:   "fixture",
+		Scheme: schemeTorrent,
+		Links:  []string{siteURL},
+		Search: searchBlock{
+			Path:   "/search",
+			Inputs: map[string]string{"q": "{{ .Keywords }}"},
+			Rows: rowsBlock{
+				SelectorBlock: source.SelectorBlock{Selector: "tr.row"},
+			},
+			Fields: fieldsListBlock{
+				{Field: "title", Block: source.SelectorBlock{Selector: "td.title"}},
+				{Field: "download", Block: source.SelectorBlock{Selector: "a", Attribute: "href"}},
+			},
+		},
+	}
+}
+
+func TestDefinitionTest_ShouldReplayRecordedFixtures(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<table>
+			<tr class="row"><td class="title">Ubuntu 20.04</td><td><a href="/dl/1.torrent">dl</a></td></tr>
+			<tr class="row"><td class="title">Ubuntu 18.04</td><td><a href="/dl/2.torrent">dl</a></td></tr>
+		</table>`)
+	}))
+	testsDir, _ := ioutil.TempDir("", "definition-tests-")
+	defer os.RemoveAll(testsDir)
+	cfg := &config.ViperConfig{}
+
+	test := NewDefinitionTest(getFixtureDefinition(server.URL), testsDir, cfg)
+	recorded, err := test.Record("ubuntu", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(recorded.Results).To(gomega.HaveLen(2))
+	server.Close()
+
+	// The server is gone, so the results can only come from the fixtures.
+	diffs, err := NewDefinitionTest(getFixtureDefinition(server.URL), testsDir, cfg).Run()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(diffs).To(gomega.BeEmpty())
+
+	// Changing the definition should show up as a difference.
+	changed := getFixtureDefinition(server.URL)
+	changed.Search.Fields[0].Block.Selector = "td:last-child"
+	diffs, err = NewDefinitionTest(changed, testsDir, cfg).Run()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(diffs).ToNot(gomega.BeEmpty())
+}
+
+func TestDefinitionTest_ShouldImportDumpedFixtures(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<table>
+			<tr class="row"><td class="title">Ubuntu 20.04</td><td><a href="/dl/1.torrent">dl</a></td></tr>
+		</table>`)
+	}))
+	dumpsDir, _ := ioutil.TempDir("", "definition-dumps-")
+	defer os.RemoveAll(dumpsDir)
+	testsDir, _ := ioutil.TempDir("", "definition-tests-")
+	defer os.RemoveAll(testsDir)
+	cfg := &config.ViperConfig{}
+	// Dumps are kept in a cassette for each site, like the one that's recorded here.
+	dumps := cassette.New(dumpsDir)
+	_, err := NewDefinitionTest(getFixtureDefinition(server.URL), "", cfg).search("ubuntu", dumps.Record(nil))
+	g.Expect(err).To(gomega.BeNil())
+	server.Close()
+
+	imported, err := NewDefinitionTest(getFixtureDefinition(server.URL), testsDir, cfg).Import("ubuntu", dumpsDir)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(imported.Results).To(gomega.HaveLen(1))
+
+	diffs, err := NewDefinitionTest(getFixtureDefinition(server.URL), testsDir, cfg).Run()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(diffs).To(gomega.BeEmpty())
+}
+
+// TestDefinitionFixtures runs every definition that has recorded fixtures in the definitions directory.
+func TestDefinitionFixtures(t *testing.T) {
+	dirs, err := ioutil.ReadDir(definitionTestsDir)
+	if err != nil {
+		t.Fatalf("couldn't read the definition fixtures: %v", err)
+	}
+	loader := &FileIndexLoader{Directories: []string{filepath.Dir(definitionTestsDir)}}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		name := dir.Name()
+		t.Run(name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			def, err := loader.Load(name)
+			g.Expect(err).To(gomega.BeNil())
+			diffs, err := NewDefinitionTest(def, filepath.Join(definitionTestsDir, name), &config.ViperConfig{}).Run()
+			g.Expect(err).To(gomega.BeNil())
+			g.Expect(diffs).To(gomega.BeEmpty())
+		})
+	}
+}
@@ -1,7 +1,7 @@
 package indexer
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/url"
@@ -19,8 +19,10 @@ func (r *Runner) downloadsNeedResolution() bool {
 	return false
 }
 
-func (r *Runner) Open(scrapeResultItem search.ResultItemBase) (*ResponseProxy, error) {
-	_, err := r.sessions.acquire()
+// Open opens the download of a result, the download is streamed from the response of the index
+// and it's cancelled with the context.
+func (r *Runner) Open(ctx context.Context, scrapeResultItem search.ResultItemBase) (*ResponseProxy, error) {
+	_, err := r.sessions.acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +36,7 @@ func (r *Runner) Open(scrapeResultItem search.ResultItemBase) (*ResponseProxy, e
 		if err != nil {
 			return nil, err
 		}
-		result, err := r.contentFetcher.Fetch(source.NewRequestOptions(scrapeLink))
+		result, err := r.contentFetcher.Fetch(ctx, source.NewRequestOptions(scrapeLink))
 		if err != nil {
 			return nil, err
 		}
@@ -52,46 +54,37 @@ func (r *Runner) Open(scrapeResultItem search.ResultItemBase) (*ResponseProxy, e
 		return nil, err
 	}
 
-	cf := r.contentFetcher.Clone()
-	if _, err := cf.Open(&source.RequestOptions{
-		NoEncoding: true,
-		URL:        fullURL,
-		Method:     "get",
-	}); err != nil {
+	response, err := r.contentFetcher.OpenStream(ctx, source.NewRequestOptions(fullURL))
+	if err != nil {
 		return nil, err
 	}
+	if response.StatusCode >= 400 {
+		_ = response.Body.Close()
+		return nil, fmt.Errorf("download failed with status %d", response.StatusCode)
+	}
 
 	responsePx, pipeW := NewResponseProxy()
-
-	// Start a goroutine and write the response of the download to the pipe
+	// The length is -1 if it's unknown, or if the body is decoded.
+	responsePx.ContentLengthChan <- response.ContentLength
+	// Stream the download to the pipe, if it fails the reader gets the error.
 	go func() {
 		defer func() {
-			errx := pipeW.Close()
-			if errx != nil {
-				fmt.Printf("%v", errx)
-			}
+			_ = response.Body.Close()
 		}()
-
-		downloadBuffer := bytes.NewBuffer([]byte{})
-		n, err := cf.Download(downloadBuffer)
+		n, err := io.Copy(pipeW, response.Body)
 		if err != nil {
 			r.logger.Errorf("Error downloading: %v", err)
 		} else {
-			responsePx.ContentLengthChan <- n
-			_, err = io.Copy(pipeW, downloadBuffer)
-			if err != nil {
-				r.logger.Errorf("Error piping download: %v", err)
-				return
-			}
 			r.logger.WithFields(logrus.Fields{"url": fullURL}).
 				Infof("Downloaded %d bytes", n)
 		}
+		_ = pipeW.CloseWithError(err)
 	}()
 	return responsePx, nil
 }
 
-func (r *Runner) Download(url string) (*ResponseProxy, error) {
+func (r *Runner) Download(ctx context.Context, url string) (*ResponseProxy, error) {
 	srcItem := search.ScrapeResultItem{}
 	srcItem.SourceLink = url
-	return r.Open(&srcItem)
+	return r.Open(ctx, &srcItem)
 }
@@ -1,8 +1,10 @@
 package indexer
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -27,6 +29,16 @@ type Facade struct {
 	workerCount int
 	storage     storage.ItemStorage
 	logger      *log.Logger
+	indexesLock sync.RWMutex
+	// Enricher is optional, it sets the titles of ids in queries, and adds info to the results before they're stored.
+	Enricher Enricher
+}
+
+// Enricher adds info to the queries and results of searches.
+type Enricher interface {
+	// EnrichQuery sets the title of the ids of a query, for the indexes that can't search by id.
+	EnrichQuery(ctx context.Context, query *search.Query) error
+	EnrichResult(item search.ResultItemBase)
 }
 
 // GenericSearchOptions options for the search.
@@ -113,8 +125,15 @@ func NewAggregateFacadeWithCategories(indexNames string, cfg config.Config, cats
 	return facade, nil
 }
 
+// CurrentIndexes gets the indexes of the facade, they're swapped when their definitions are reloaded.
+func (f *Facade) CurrentIndexes() IndexCollection {
+	f.indexesLock.RLock()
+	defer f.indexesLock.RUnlock()
+	return f.Indexes
+}
+
 func (f *Facade) OpenStorage() storage.ItemStorage {
-	return getMultiIndexDatabase(f.Indexes, f.Config)
+	return getMultiIndexDatabase(f.CurrentIndexes(), f.Config)
 }
 
 func (f *Facade) ensureDatabaseConnection() {
@@ -124,42 +143,105 @@ func (f *Facade) ensureDatabaseConnection() {
 	f.storage = f.OpenStorage()
 }
 
-func (f *Facade) Search(query *search.Query) (chan []search.ResultItemBase, error) {
+// Search searches the indexes, the pages of results are sent to the channel as they're found.
+// Once the context is done, the pages that are left aren't searched and the channel is closed.
+func (f *Facade) Search(ctx context.Context, query *search.Query) (chan []search.ResultItemBase, error) {
+	results, _, err := f.SearchWithErrors(ctx, query)
+	return results, err
+}
+
+// SearchWithErrors searches like Search, and also gets the errors of the indexes that failed.
+// Indexes that are still searched when the context is done fail with the error of the context.
+func (f *Facade) SearchWithErrors(ctx context.Context, query *search.Query) (chan []search.ResultItemBase, *IndexErrors, error) {
+	return f.SearchWithProgress(ctx, query, nil)
+}
+
+// SearchWithProgress searches like SearchWithErrors, and calls progress with the pages, the errors and the completion of each index.
+// It's called by the workers, so the search waits for it.
+func (f *Facade) SearchWithProgress(ctx context.Context, query *search.Query, progress func(IndexProgress)) (chan []search.ResultItemBase, *IndexErrors, error) {
 	f.ensureDatabaseConnection()
 	itemKey := indexing.NewKey("LocalID")
 	err := f.storage.SetKey(itemKey)
 	if err != nil {
 		log.WithFields(log.Fields{}).Errorf("Couldn't get item index: %s\n", err)
-		return nil, err
+		return nil, nil, err
 	}
 
-	workerPool := f.createWorkerPool(f.Indexes, f.storage, query, f.workerCount)
+	indexes := f.CurrentIndexes()
+	workerPool := f.createWorkerPool(ctx, f.indexQueries(ctx, indexes, query), f.storage, query, f.workerCount, progress)
 
-	f.feedWorkerPool(workerPool)
+	// The pool is fed in the background, since the workers wait for their results to be read.
+	go f.feedWorkerPool(workerPool)
 
-	return workerPool.resultsChannel, nil
+	return workerPool.resultsChannel, workerPool.errors, nil
+}
+
+// indexQueries decides the query that each index searches with.
+// Indexes that don't have the search mode or the categories of the query are skipped.
+// Indexes that can search for the ids of the query get it as it is, the others get it with the title of the ids.
+// If the title can't be found, these indexes are skipped, unless the query has keywords.
+func (f *Facade) indexQueries(ctx context.Context, indexes IndexCollection, query *search.Query) map[Indexer]*search.Query {
+	queries := make(map[Indexer]*search.Query, len(indexes))
+	var titleQuery *search.Query
+	resolved := false
+	for _, index := range indexes {
+		if !supportsQuery(index, query) {
+			log.WithFields(log.Fields{"index": index.Site(), "mode": query.SearchMode(), "categories": query.Categories}).
+				Debug("Skipping index that doesn't support the query")
+			continue
+		}
+		if len(query.IDParams()) == 0 || searchesByID(index, query) {
+			queries[index] = query
+			continue
+		}
+		if !resolved {
+			titleQuery = f.titleQuery(ctx, query)
+			resolved = true
+		}
+		if titleQuery != nil {
+			queries[index] = titleQuery
+		}
+	}
+	return queries
+}
+
+// titleQuery copies a query, with the title of its ids.
+func (f *Facade) titleQuery(ctx context.Context, query *search.Query) *search.Query {
+	titleQuery := *query
+	if f.Enricher != nil {
+		err := f.Enricher.EnrichQuery(ctx, &titleQuery)
+		if err == nil {
+			return &titleQuery
+		}
+		log.WithFields(log.Fields{"query": query.Encode()}).
+			Warningf("Couldn't find the title of the query, searching with its keywords: %v", err)
+	}
+	if query.QueryString == "" && query.Series == "" && query.Movie == "" {
+		return nil
+	}
+	return &titleQuery
 }
 
 // SearchWithKeywords performs a search for a given page
-func (f *Facade) SearchWithKeywords(query string, startingPage uint, pageCount uint) (chan []search.ResultItemBase, error) {
+func (f *Facade) SearchWithKeywords(ctx context.Context, query string, startingPage uint, pageCount uint) (chan []search.ResultItemBase, error) {
 	queryObj, err := search.NewQueryFromQueryString(query)
 	if err != nil {
 		return nil, err
 	}
 	queryObj.Page = startingPage
 	queryObj.NumberOfPagesToFetch = pageCount
-	return f.Search(queryObj)
+	return f.Search(ctx, queryObj)
 }
 
 // SearchKeywordsWithCategory Search for *keywords* matching the needed category.
-func (f *Facade) SearchKeywordsWithCategory(query string, page uint, cat categories.Category) (chan []search.ResultItemBase, error) {
+func (f *Facade) SearchKeywordsWithCategory(ctx context.Context, query string, page uint, cat categories.Category) (chan []search.ResultItemBase, error) {
 	queryObj, err := search.NewQueryFromQueryString(query)
 	if err != nil {
 		return nil, err
 	}
 	queryObj.Page = page
 	queryObj.Categories = []int{cat.ID}
-	return f.Search(queryObj)
+	return f.Search(ctx, queryObj)
 }
 
 // GetDefaultSearchOptions gets the default search options
@@ -170,3 +252,28 @@ func (f *Facade) GetDefaultSearchOptions() *GenericSearchOptions {
 		MaxRequestsPerSecond: 1,
 	}
 }
+
+// LoadDefinition loads the definition of an index, using the configured definition loader.
+func (f *Facade) LoadDefinition(name string) (*Definition, error) {
+	return getConfiguredIndexLoader(f.Config).Load(name)
+}
+
+// AvailableIndexes lists the names of the indexes that the configured definition loader can load.
+func (f *Facade) AvailableIndexes() ([]string, error) {
+	return getConfiguredIndexLoader(f.Config).ListAvailableIndexes(nil)
+}
+
+// WithIndexes creates a facade that searches only through the given indexes.
+// It shares the scope, config and storage of this facade, so that the storage isn't opened twice.
+func (f *Facade) WithIndexes(indexes IndexCollection) *Facade {
+	f.ensureDatabaseConnection()
+	return &Facade{
+		Indexes:     indexes,
+		IndexScope:  f.IndexScope,
+		Config:      f.Config,
+		workerCount: f.workerCount,
+		storage:     f.storage,
+		logger:      f.logger,
+		Enricher:    f.Enricher,
+	}
+}
@@ -0,0 +1,232 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sp0x/torrentd/indexer/categories"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/storage/indexing"
+	"github.com/sp0x/torrentd/torznab"
+)
+
+type fakeEnricher struct {
+	title string
+	calls int
+}
+
+func (e *fakeEnricher) EnrichQuery(_ context.Context, query *search.Query) error {
+	e.calls++
+	if e.title == "" {
+		return errors.New("not found")
+	}
+	query.Movie = e.title
+	return nil
+}
+
+func (e *fakeEnricher) EnrichResult(search.ResultItemBase) {}
+
+func movieCapabilities() torznab.Capabilities {
+	return torznab.Union(torznab.ServerInfo{}, torznab.Capabilities{
+		SearchModes: []search.Capability{
+			{Key: "search", Available: true, SupportedParams: []string{"q"}},
+			{Key: "movie-search", Available: true, SupportedParams: []string{"q"}},
+		},
+		Categories: categories.CreateCategorySet([]categories.Category{categories.CategoryMoviesHD}),
+	})
+}
+
+func TestFacade_indexQueries(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	native := NewMockIndexer(ctrl)
+	nativeDef, _ := ParseDefinition([]byte("site: native\ncaps:\n  modes:\n    movie-search: [q, imdbid]\n"))
+	native.EXPECT().GetDefinition().Return(nativeDef).AnyTimes()
+	native.EXPECT().Capabilities().Return(nativeDef.Capabilities.ToTorznab()).AnyTimes()
+	keywords := NewMockIndexer(ctrl)
+	keywords.EXPECT().GetDefinition().Return(&Definition{Site: "keywords"}).AnyTimes()
+	keywords.EXPECT().Capabilities().Return(movieCapabilities()).AnyTimes()
+	other := NewMockIndexer(ctrl)
+	other.EXPECT().GetDefinition().Return(&Definition{Site: "other"}).AnyTimes()
+	other.EXPECT().Capabilities().Return(movieCapabilities()).AnyTimes()
+	enricher := &fakeEnricher{title: "The Matrix"}
+	facade := &Facade{Enricher: enricher}
+
+	query := search.NewQuery()
+	query.Type = "movie"
+	query.IMDBID = "tt0133093"
+	queries := facade.indexQueries(context.Background(), IndexCollection{native, keywords, other}, query)
+	g.Expect(queries[native]).To(gomega.BeIdenticalTo(query))
+	g.Expect(queries[native].Movie).To(gomega.BeEmpty())
+	g.Expect(queries[keywords].Movie).To(gomega.Equal("The Matrix"))
+	g.Expect(queries[keywords].IMDBID).To(gomega.Equal("tt0133093"))
+	g.Expect(queries[other]).To(gomega.BeIdenticalTo(queries[keywords]))
+	g.Expect(enricher.calls).To(gomega.Equal(1))
+
+	// Without the title, the indexes that can't search by id are skipped, unless there are keywords.
+	enricher.title = ""
+	queries = facade.indexQueries(context.Background(), IndexCollection{native, keywords}, query)
+	g.Expect(queries).To(gomega.HaveLen(1))
+	g.Expect(queries).To(gomega.HaveKey(native))
+	query.QueryString = "matrix"
+	queries = facade.indexQueries(context.Background(), IndexCollection{native, keywords}, query)
+	g.Expect(queries[keywords].Keywords()).To(gomega.Equal("matrix"))
+
+	// Queries without ids are the same for every index.
+	query = search.NewQuery()
+	query.QueryString = "matrix"
+	queries = facade.indexQueries(context.Background(), IndexCollection{native, keywords}, query)
+	g.Expect(queries[native]).To(gomega.BeIdenticalTo(query))
+	g.Expect(queries[keywords]).To(gomega.BeIdenticalTo(query))
+}
+
+func TestFacade_indexQueriesSkipsUnsupportedQueries(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	movies := NewMockIndexer(ctrl)
+	movies.EXPECT().Capabilities().Return(movieCapabilities()).AnyTimes()
+	movies.EXPECT().Site().Return("movies").AnyTimes()
+	music := NewMockIndexer(ctrl)
+	music.EXPECT().Capabilities().Return(torznab.Capabilities{
+		SearchModes: []search.Capability{
+			{Key: "search", Available: true, SupportedParams: []string{"q"}},
+			{Key: "music-search", Available: true, SupportedParams: []string{"q", "artist"}},
+		},
+		Categories: categories.CreateCategorySet([]categories.Category{categories.CategoryAudio}),
+	}).AnyTimes()
+	music.EXPECT().Site().Return("music").AnyTimes()
+	facade := &Facade{}
+	indexes := IndexCollection{movies, music}
+
+	query := search.NewQuery()
+	query.QueryString = "matrix"
+	g.Expect(facade.indexQueries(context.Background(), indexes, query)).To(gomega.HaveLen(2))
+
+	query.Type = "movie"
+	queries := facade.indexQueries(context.Background(), indexes, query)
+	g.Expect(queries).To(gomega.HaveLen(1))
+	g.Expect(queries).To(gomega.HaveKey(movies))
+
+	// The parent categories match their subcategories, and the other way around.
+	query.Type = "search"
+	query.Categories = []int{categories.CategoryMovies.ID}
+	queries = facade.indexQueries(context.Background(), indexes, query)
+	g.Expect(queries).To(gomega.HaveLen(1))
+	g.Expect(queries).To(gomega.HaveKey(movies))
+	query.Categories = []int{categories.CategoryAudioMP3.ID}
+	queries = facade.indexQueries(context.Background(), indexes, query)
+	g.Expect(queries).To(gomega.HaveLen(1))
+	g.Expect(queries).To(gomega.HaveKey(music))
+}
+
+func TestFacade_SearchWithProgress(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dir, _ := ioutil.TempDir("", "facade-")
+	defer os.RemoveAll(dir)
+	resultStorage := storage.NewBuilder(nil).
+		WithNamespace("results").
+		WithPK(indexing.NewKey("LocalID")).
+		WithEndpoint(filepath.Join(dir, "results.db")).
+		WithRecord(&search.TorrentResultItem{}).
+		Build()
+	defer resultStorage.Close()
+
+	working := NewMockIndexer(ctrl)
+	working.EXPECT().Capabilities().Return(movieCapabilities()).AnyTimes()
+	working.EXPECT().Site().Return("working").AnyTimes()
+	working.EXPECT().GetDefinition().Return(&Definition{Site: "working"}).AnyTimes()
+	working.EXPECT().Search(gomock.Any(), gomock.Any(), gomock.Any()).Return([]search.ResultItemBase{
+		&search.TorrentResultItem{ScrapeResultItem: search.ScrapeResultItem{ScrapeLocalData: search.ScrapeLocalData{LocalID: "1", Site: "working"}}, Title: "The Matrix"},
+	}, nil)
+	failing := NewMockIndexer(ctrl)
+	failing.EXPECT().Capabilities().Return(movieCapabilities()).AnyTimes()
+	failing.EXPECT().Site().Return("failing").AnyTimes()
+	failing.EXPECT().GetDefinition().Return(&Definition{Site: "failing"}).AnyTimes()
+	failing.EXPECT().Search(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("login failed"))
+	facade := &Facade{
+		Indexes:     IndexCollection{working, failing},
+		workerCount: 2,
+		storage:     resultStorage,
+		logger:      log.New(),
+	}
+
+	query := search.NewQuery()
+	query.QueryString = "matrix"
+	query.NumberOfPagesToFetch = 1
+	progress := make(map[Indexer][]IndexProgress)
+	resultsChan, indexErrors, err := facade.SearchWithProgress(context.Background(), query, func(step IndexProgress) {
+		progress[step.Index] = append(progress[step.Index], step)
+	})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	var results []search.ResultItemBase
+	for page := range resultsChan {
+		results = append(results, page...)
+	}
+	g.Expect(results).To(gomega.HaveLen(1))
+	g.Expect(indexErrors.All()).To(gomega.HaveLen(1))
+	g.Expect(indexErrors.All()["failing"]).To(gomega.MatchError("login failed"))
+
+	// Each index has its page and then its completion.
+	g.Expect(progress[working]).To(gomega.HaveLen(2))
+	g.Expect(progress[working][0].Results).To(gomega.HaveLen(1))
+	g.Expect(progress[working][1].Done).To(gomega.BeTrue())
+	g.Expect(progress[failing]).To(gomega.HaveLen(2))
+	g.Expect(progress[failing][0].Err).To(gomega.MatchError("login failed"))
+	g.Expect(progress[failing][1].Done).To(gomega.BeTrue())
+}
+
+func TestFacade_SearchStopsWhenItsContextIsDone(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dir, _ := ioutil.TempDir("", "facade-")
+	defer os.RemoveAll(dir)
+	resultStorage := storage.NewBuilder(nil).
+		WithNamespace("results").
+		WithPK(indexing.NewKey("LocalID")).
+		WithEndpoint(filepath.Join(dir, "results.db")).
+		WithRecord(&search.TorrentResultItem{}).
+		Build()
+	defer resultStorage.Close()
+
+	slow := NewMockIndexer(ctrl)
+	slow.EXPECT().Capabilities().Return(movieCapabilities()).AnyTimes()
+	slow.EXPECT().Site().Return("slow").AnyTimes()
+	slow.EXPECT().GetDefinition().Return(&Definition{Site: "slow"}).AnyTimes()
+	slow.EXPECT().Search(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ *search.Query, _ *workerJob) ([]search.ResultItemBase, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	facade := &Facade{
+		Indexes:     IndexCollection{slow},
+		workerCount: 1,
+		storage:     resultStorage,
+		logger:      log.New(),
+	}
+
+	query := search.NewQuery()
+	query.QueryString = "matrix"
+	query.NumberOfPagesToFetch = 10
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	resultsChan, indexErrors, err := facade.SearchWithErrors(ctx, query)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	// The pages that are left aren't searched.
+	g.Eventually(resultsChan, time.Second).Should(gomega.BeClosed())
+	g.Expect(indexErrors.All()).To(gomega.Equal(map[string]error{"slow": context.DeadlineExceeded}))
+}
@@ -67,6 +67,9 @@ func (fs *FileIndexLoader) ListAvailableIndexes(selector *Selector) ([]string, e
 	defs := fs.walkDirectories()
 	results := make([]string, 0, len(defs))
 	for name := range defs {
+		if IsBaseDefinition(name) {
+			continue
+		}
 		if selector != nil && !selector.Matches(name) {
 			continue
 		}
@@ -79,7 +82,7 @@ func (fs *FileIndexLoader) ListWithNames(names []string) ([]string, error) {
 	defs := fs.walkDirectories()
 	results := make([]string, 0, len(defs))
 	for k := range defs {
-		if !contains(names, k) {
+		if IsBaseDefinition(k) || !contains(names, k) {
 			continue
 		}
 		results = append(results, k)
@@ -98,6 +101,10 @@ func (fs *FileIndexLoader) String() string {
 
 // Load - Load a definition of an Indexer from it's name
 func (fs *FileIndexLoader) Load(key string) (*Definition, error) {
+	return loadResolvedDefinition(fs, key)
+}
+
+func (fs *FileIndexLoader) loadDefinition(key string) (*Definition, error) {
 	defs := fs.walkDirectories()
 	fileName, ok := defs[key]
 	if !ok {
@@ -109,7 +116,7 @@ func (fs *FileIndexLoader) Load(key string) (*Definition, error) {
 		return nil, err
 	}
 
-	def, err := ParseDefinitionFile(f)
+	def, err := parseDefinitionFile(f)
 	if err != nil {
 		return def, err
 	}
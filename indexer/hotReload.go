@@ -0,0 +1,218 @@
+package indexer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sp0x/torrentd/config"
+)
+
+// Reload replaces the runners that use an older version of the definition, or an older site configuration.
+// Collections are copied instead of being changed, so searches that are already running keep their runners.
+// The result maps each replaced index to its replacement.
+func (c *indexMap) Reload(conf config.Config, def *Definition) map[Indexer]Indexer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	swapped := map[Indexer]Indexer{}
+	for key, collection := range c.indexes {
+		var updated IndexCollection
+		for ix, index := range collection {
+			runner, ok := index.(*Runner)
+			if !ok || runner.definition.Name != def.Name {
+				continue
+			}
+			replacement, ok := swapped[index]
+			if !ok {
+				if !runner.needsReload(conf, def) {
+					continue
+				}
+				var err error
+				replacement, err = NewRunner(def, runner.options)
+				if err != nil {
+					log.WithFields(log.Fields{"name": def.Name}).WithError(err).
+						Warn("Couldn't reload index, the current one is kept")
+					continue
+				}
+				swapped[index] = replacement
+			}
+			if updated == nil {
+				updated = make(IndexCollection, len(collection))
+				copy(updated, collection)
+			}
+			updated[ix] = replacement
+		}
+		if updated != nil {
+			c.indexes[key] = updated
+		}
+	}
+	return swapped
+}
+
+// needsReload checks if the runner uses a different definition, or different site settings than the given ones.
+func (r *Runner) needsReload(conf config.Config, def *Definition) bool {
+	if r.definition.Stats().Hash != def.Stats().Hash {
+		return true
+	}
+	siteConfig, err := conf.GetSite(def.Name)
+	if err != nil {
+		return false
+	}
+	settings, err := def.ResolveSettings(siteConfig)
+	if err != nil {
+		// The new runner can't be created, this is reported while reloading.
+		return true
+	}
+	if len(r.settings) == 0 && len(settings) == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(r.settings, settings)
+}
+
+// reload swaps the indexes of the facade that use the given definition, if it changed.
+func (f *Facade) reload(def *Definition) {
+	swapped := f.IndexScope.Reload(f.Config, def)
+	if len(swapped) == 0 {
+		return
+	}
+	f.indexesLock.Lock()
+	defer f.indexesLock.Unlock()
+	indexes := make(IndexCollection, len(f.Indexes))
+	for ix, index := range f.Indexes {
+		if replacement, ok := swapped[index]; ok {
+			index = replacement
+		}
+		indexes[ix] = index
+	}
+	f.Indexes = indexes
+	f.logger.WithFields(log.Fields{"name": def.Name, "hash": def.Stats().Hash}).
+		Info("Reloaded index")
+}
+
+// ReloadIndex recreates the runners of an index, if its definition or site settings changed.
+func (f *Facade) ReloadIndex(name string) error {
+	def, err := f.LoadDefinition(name)
+	if err != nil {
+		return err
+	}
+	f.reload(def)
+	return nil
+}
+
+// DefinitionWatcher reloads the indexes of a facade when their definition files change.
+type DefinitionWatcher struct {
+	facade  *Facade
+	loader  DefinitionLoader
+	watcher *fsnotify.Watcher
+	logger  *log.Logger
+}
+
+// NewDefinitionWatcher starts watching the given directories for changes in definitions.
+// Directories that don't exist are skipped.
+func NewDefinitionWatcher(facade *Facade, dirs []string) (*DefinitionWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return nil, err
+		}
+	}
+	w := &DefinitionWatcher{
+		facade:  facade,
+		loader:  getConfiguredIndexLoader(facade.Config),
+		watcher: watcher,
+		logger:  facade.logger,
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *DefinitionWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			ext := filepath.Ext(event.Name)
+			if ext != ".yml" && ext != ".yaml" {
+				continue
+			}
+			w.reloadDefinition(strings.TrimSuffix(filepath.Base(event.Name), ext))
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.WithError(err).Warn("Error while watching definitions")
+		}
+	}
+}
+
+func (w *DefinitionWatcher) reloadDefinition(key string) {
+	if IsBaseDefinition(key) {
+		w.reloadAllDefinitions()
+		return
+	}
+	def, err := w.loader.Load(key)
+	if err != nil {
+		// Editors might write the file in parts, the next write would reload it.
+		w.logger.WithFields(log.Fields{"name": key}).WithError(err).
+			Warn("Couldn't reload definition")
+		return
+	}
+	w.facade.reload(def)
+}
+
+// reloadAllDefinitions loads the definitions of all the indexes again.
+// This is used when a base definition changes, since any of them could extend it.
+func (w *DefinitionWatcher) reloadAllDefinitions() {
+	reloaded := map[string]bool{}
+	for _, collection := range w.facade.IndexScope.Indexes() {
+		for _, index := range collection {
+			// The loaders know the definitions by their keys, which can be different from their names.
+			def := index.GetDefinition()
+			key := def.Stats().Key
+			if key == "" {
+				key = def.Name
+			}
+			if reloaded[key] {
+				continue
+			}
+			reloaded[key] = true
+			w.reloadDefinition(key)
+		}
+	}
+}
+
+// ReloadConfiguration recreates the indexes whose site configuration changed, so that they use the new credentials.
+func (w *DefinitionWatcher) ReloadConfiguration() {
+	reloaded := map[string]bool{}
+	for _, collection := range w.facade.IndexScope.Indexes() {
+		for _, index := range collection {
+			def := index.GetDefinition()
+			if reloaded[def.Name] {
+				continue
+			}
+			reloaded[def.Name] = true
+			w.facade.reload(def)
+		}
+	}
+}
+
+// Close stops watching for changes.
+func (w *DefinitionWatcher) Close() error {
+	return w.watcher.Close()
+}
@@ -0,0 +1,126 @@
+package indexer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/config"
+)
+
+const reloadDefinition = `---
+site: reloading
+name: reloading
+links:
+  - http://localhost/
+search:
+  path: /search
+  rows:
+    selector: tr
+  fields:
+    title:
+      selector: td
+`
+
+var changedReloadDefinition = strings.Replace(reloadDefinition, "selector: td\n", "selector: td.title\n", 1)
+
+func getReloadingFacade(t *testing.T) (*Facade, string) {
+	dir, _ := ioutil.TempDir("", "definitions-")
+	writeReloadDefinition(t, dir, reloadDefinition)
+	cfg := &config.ViperConfig{}
+	loader := &FileIndexLoader{Directories: []string{dir}}
+	setConfiguredIndexLoader(loader, cfg)
+	facade := NewEmptyFacade(cfg)
+	indexes, err := facade.IndexScope.Lookup(cfg, "reloading")
+	if err != nil {
+		t.Fatal(err)
+	}
+	facade.Indexes = indexes
+	return facade, dir
+}
+
+func writeReloadDefinition(t *testing.T, dir, src string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, "reloading.yml"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexMap_Reload_ShouldSwapChangedRunners(t *testing.T) {
+	g := gomega.NewWithT(t)
+	facade, dir := getReloadingFacade(t)
+	defer os.RemoveAll(dir)
+	defer setConfiguredIndexLoader(nil, facade.Config)
+	original := facade.Indexes
+
+	// The same definition shouldn't replace anything.
+	sameDef, _ := ParseDefinition([]byte(reloadDefinition))
+	g.Expect(facade.IndexScope.Reload(facade.Config, sameDef)).To(gomega.BeEmpty())
+
+	changedDef, _ := ParseDefinition([]byte(changedReloadDefinition))
+	swapped := facade.IndexScope.Reload(facade.Config, changedDef)
+	g.Expect(swapped).To(gomega.HaveLen(1))
+	g.Expect(swapped).To(gomega.HaveKey(original[0]))
+
+	indexes, err := facade.IndexScope.Lookup(facade.Config, "reloading")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(indexes[0]).ToNot(gomega.BeIdenticalTo(original[0]))
+	g.Expect(indexes[0].GetDefinition().Stats().Hash).To(gomega.Equal(changedDef.Stats().Hash))
+	// Searches that already have the old collection keep using it.
+	g.Expect(original[0].GetDefinition().Stats().Hash).To(gomega.Equal(sameDef.Stats().Hash))
+}
+
+func TestDefinitionWatcher_ShouldReloadChangedFiles(t *testing.T) {
+	g := gomega.NewWithT(t)
+	facade, dir := getReloadingFacade(t)
+	defer os.RemoveAll(dir)
+	defer setConfiguredIndexLoader(nil, facade.Config)
+	original := facade.Indexes[0]
+
+	watcher, err := NewDefinitionWatcher(facade, []string{dir})
+	g.Expect(err).To(gomega.BeNil())
+	defer watcher.Close()
+	writeReloadDefinition(t, dir, changedReloadDefinition)
+
+	g.Eventually(func() Indexer {
+		return facade.CurrentIndexes()[0]
+	}, 5*time.Second, 50*time.Millisecond).ShouldNot(gomega.BeIdenticalTo(original))
+}
+
+func TestDefinitionWatcher_ShouldReloadTheIndexesOfAChangedBase(t *testing.T) {
+	g := gomega.NewWithT(t)
+	dir, _ := ioutil.TempDir("", "definitions-")
+	defer os.RemoveAll(dir)
+	base := strings.Replace(reloadDefinition, "site: reloading\nname: reloading\n", "", 1)
+	write := func(name, src string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("_reloadbase.yml", base)
+	// The name of the definition isn't the name of its file.
+	write("reloading.yml", "---\nsite: reloading\nname: Reloading Index\nextends: _reloadbase\n")
+	cfg := &config.ViperConfig{}
+	setConfiguredIndexLoader(&FileIndexLoader{Directories: []string{dir}}, cfg)
+	defer setConfiguredIndexLoader(nil, cfg)
+	facade := NewEmptyFacade(cfg)
+	indexes, err := facade.IndexScope.Lookup(cfg, "reloading")
+	g.Expect(err).To(gomega.BeNil())
+	facade.Indexes = indexes
+	original := indexes[0]
+	g.Expect(original.GetDefinition().Stats().Key).To(gomega.Equal("reloading"))
+
+	watcher, err := NewDefinitionWatcher(facade, []string{dir})
+	g.Expect(err).To(gomega.BeNil())
+	defer watcher.Close()
+	write("_reloadbase.yml", strings.Replace(base, "selector: td\n", "selector: td.title\n", 1))
+
+	g.Eventually(func() Indexer {
+		return facade.CurrentIndexes()[0]
+	}, 5*time.Second, 50*time.Millisecond).ShouldNot(gomega.BeIdenticalTo(original))
+	g.Expect(facade.CurrentIndexes()[0].GetDefinition().Name).To(gomega.Equal("Reloading Index"))
+}
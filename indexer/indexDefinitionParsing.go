@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -26,13 +27,16 @@ const (
 )
 
 type Definition struct {
-	Site         string            `yaml:"site"`
-	Version      string            `yaml:"version"`
+	Site    string `yaml:"site"`
+	Version string `yaml:"version"`
+	// Extends is the name of the base definition that this one inherits from.
+	Extends      string            `yaml:"extends"`
 	Scheme       string            `yaml:"scheme"`
-	Settings     []settingsField   `yaml:"settings"`
+	Settings     []SettingsField   `yaml:"settings"`
 	Name         string            `yaml:"name"`
 	Description  string            `yaml:"description"`
 	Language     string            `yaml:"language"`
+	Type         string            `yaml:"type"`
 	Links        stringorslice     `yaml:"links"`
 	Capabilities capabilitiesBlock `yaml:"caps"`
 	Login        loginBlock        `yaml:"login"`
@@ -44,6 +48,8 @@ type Definition struct {
 	Entities []entityBlock `yaml:"entities"`
 	// The ms to wait between each request.
 	RateLimit int `yaml:"ratelimit"`
+	// The source of the definition, without its bases.
+	source yaml.MapSlice
 }
 
 type DefinitionStats struct {
@@ -51,6 +57,9 @@ type DefinitionStats struct {
 	ModTime time.Time
 	Hash    string
 	Source  string
+	// Key is the name that the loaders know the definition by, like the name of its file.
+	// It can be different from the name of the definition.
+	Key string
 }
 
 func (id *Definition) Stats() DefinitionStats {
@@ -76,6 +85,28 @@ func (id *Definition) createNewResultItem() search.ResultItemBase {
 }
 
 // getSearchEntity gets the entity that's returned from a search.
+// registration tells if users can register in the index, by the type of the index.
+// Public indexes don't need an account, unless they have a login, and private ones are invite only.
+// Indexes without a type are public if they don't have a login, and private otherwise.
+func (id *Definition) registration() torznab.Registration {
+	indexType := id.Type
+	if indexType == "" {
+		indexType = "public"
+		if !id.Login.IsEmpty() {
+			indexType = "private"
+		}
+	}
+	switch indexType {
+	case "public":
+		open := !id.Login.IsEmpty()
+		return torznab.Registration{Available: open, Open: open}
+	case "semi-private":
+		return torznab.Registration{Available: true, Open: true}
+	default:
+		return torznab.Registration{Available: true}
+	}
+}
+
 func (id *Definition) getSearchEntity() *entityBlock {
 	entity := &entityBlock{}
 	entity.Name = searchEntity
@@ -93,20 +124,35 @@ func (id *Definition) getSearchEntity() *entityBlock {
 	return entity
 }
 
-type settingsField struct {
-	Name  string `yaml:"name"`
-	Type  string `yaml:"type"`
-	Label string `yaml:"label"`
+// SettingsField is a setting that an index can be configured with.
+type SettingsField struct {
+	Name    string `yaml:"name" json:"name"`
+	Type    string `yaml:"type" json:"type"`
+	Label   string `yaml:"label" json:"label"`
+	Default string `yaml:"default" json:"default,omitempty"`
+	// Required settings must have a value in the configuration, or a default.
+	Required bool `yaml:"required" json:"required"`
+	// Options are the values that can be used for select settings.
+	Options settingOptions `yaml:"options" json:"options,omitempty"`
 }
 
 // ParseDefinitionFile loads an Indexer's definition from a file
 func ParseDefinitionFile(f *os.File) (*Definition, error) {
+	def, err := parseDefinitionFile(f)
+	if err != nil {
+		return nil, err
+	}
+	return resolveDefinition(def, "", GetIndexDefinitionLoader())
+}
+
+// parseDefinitionFile loads a definition from a file, without resolving its base.
+func parseDefinitionFile(f *os.File) (*Definition, error) {
 	b, err := ioutil.ReadFile(f.Name())
 	if err != nil {
 		return nil, err
 	}
 
-	def, err := ParseDefinition(b)
+	def, err := parseDefinitionSource(b)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +166,34 @@ func ParseDefinitionFile(f *os.File) (*Definition, error) {
 	return def, err
 }
 
+// ParseDefinition parses the source of a definition.
+// If the definition extends another one, the base is loaded with the current definition loader.
 func ParseDefinition(src []byte) (*Definition, error) {
+	def, err := parseDefinitionSource(src)
+	if err != nil {
+		return nil, err
+	}
+	return resolveDefinition(def, "", GetIndexDefinitionLoader())
+}
+
+// parseDefinitionSource parses a definition without resolving its base.
+func parseDefinitionSource(src []byte) (*Definition, error) {
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, err
+	}
+	// Snippets can come from the base, so they're expanded after the base is resolved.
+	_, hasBase := lookupSourceValue(doc, "extends")
+	def, err := definitionFromSource(doc, src, !hasBase)
+	if err != nil {
+		return nil, err
+	}
+	def.source = doc
+	return def, nil
+}
+
+// definitionFromSource creates a definition from a parsed document, optionally expanding its snippets first.
+func definitionFromSource(doc yaml.MapSlice, src []byte, expand bool) (*Definition, error) {
 	def := Definition{
 		Language:     "en-us",
 		Encoding:     "utf-8",
@@ -132,12 +205,23 @@ func ParseDefinition(src []byte) (*Definition, error) {
 		Search: searchBlock{},
 	}
 
+	if expand {
+		expanded, err := expandSnippets(doc)
+		if err != nil {
+			return nil, err
+		}
+		if expanded != nil {
+			if src, err = yaml.Marshal(expanded); err != nil {
+				return nil, err
+			}
+		}
+	}
 	if err := yaml.Unmarshal(src, &def); err != nil {
 		return nil, err
 	}
 
 	if len(def.Settings) == 0 {
-		def.Settings = defaultSettingsFields()
+		def.Settings = defaultSettingsFields(&def.Login)
 	}
 
 	def.stats = DefinitionStats{
@@ -151,10 +235,13 @@ func ParseDefinition(src []byte) (*Definition, error) {
 	return &def, nil
 }
 
-func defaultSettingsFields() []settingsField {
-	return []settingsField{
-		{Name: "username", Label: "Username", Type: "text"},
-		{Name: "password", Label: "Password", Type: "password"},
+// defaultSettingsFields are the settings of definitions that don't have any.
+// The credentials are only required if the site needs a login.
+func defaultSettingsFields(login *loginBlock) []SettingsField {
+	required := !login.IsEmpty() && login.Method != loginMethodCookie
+	return []SettingsField{
+		{Name: "username", Label: "Username", Type: settingText, Required: required},
+		{Name: "password", Label: "Password", Type: settingPassword, Required: required},
 	}
 }
 
@@ -341,7 +428,7 @@ type capabilitiesBlock struct {
 // UnmarshalYAML implements the Unmarshaller interface.
 func (c *capabilitiesBlock) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var intermediate struct {
-		Categories map[string]string        `yaml:"indexCategories"`
+		Categories map[string]string        `yaml:"categories"`
 		Modes      map[string]stringorslice `yaml:"modes"`
 	}
 
@@ -371,8 +458,11 @@ func (c *capabilitiesBlock) UnmarshalYAML(unmarshal func(interface{}) error) err
 		c.SearchModes = []search.Capability{}
 
 		for key, supported := range intermediate.Modes {
-			c.SearchModes = append(c.SearchModes, search.Capability{Key: key, Available: true, SupportedParams: supported})
+			c.SearchModes = append(c.SearchModes, search.Capability{Key: search.SearchMode(key), Available: true, SupportedParams: supported})
 		}
+		sort.Slice(c.SearchModes, func(i, j int) bool {
+			return c.SearchModes[i].Key < c.SearchModes[j].Key
+		})
 
 		return nil
 	}
@@ -412,9 +502,62 @@ func (c *capabilitiesBlock) ToTorznab() torznab.Capabilities {
 		})
 	}
 
+	// Music and books are searched with their names as the keywords
+	if caps.HasMusic() {
+		caps.SearchModes = append(caps.SearchModes, search.Capability{
+			Key:             "music-search",
+			Available:       true,
+			SupportedParams: []string{"q", "artist", "album"},
+		})
+	}
+
+	if caps.HasBooks() {
+		caps.SearchModes = append(caps.SearchModes, search.Capability{
+			Key:             "book-search",
+			Available:       true,
+			SupportedParams: []string{"q", "author", "title"},
+		})
+	}
+
+	// The modes of the definition replace the default ones
+	for _, declared := range c.SearchModes {
+		mode := search.Capability{
+			Key:             declared.Key,
+			Available:       declared.Available,
+			SupportedParams: append([]string{}, declared.SupportedParams...),
+		}
+		replaced := false
+		for idx := range caps.SearchModes {
+			if caps.SearchModes[idx].Key == mode.Key {
+				caps.SearchModes[idx] = mode
+				replaced = true
+			}
+		}
+		if !replaced {
+			caps.SearchModes = append(caps.SearchModes, mode)
+		}
+	}
+
 	return caps
 }
 
+// supportsAnyParam checks if the definition declares one of the params for a search mode.
+func (c *capabilitiesBlock) supportsAnyParam(mode string, params []string) bool {
+	for _, declared := range c.SearchModes {
+		if declared.Key != mode {
+			continue
+		}
+		for _, supported := range declared.SupportedParams {
+			for _, param := range params {
+				if supported == param {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 // GetLocalCategoriesMatchingQuery returns a slice of local indexCategories that should be searched
 func GetLocalCategoriesMatchingQuery(query *search.Query, caps *capabilitiesBlock) []string {
 	var localCats []string
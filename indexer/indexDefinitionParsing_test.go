@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/torznab"
 )
 
 func TestIndexerDefinition_getSearchEntity(t *testing.T) {
@@ -16,3 +18,71 @@ func TestIndexerDefinition_getSearchEntity(t *testing.T) {
 	searchEntity = ixdef.getSearchEntity()
 	g.Expect(searchEntity.IndexKey[0]).To(gomega.Equal("LocalID"))
 }
+
+func TestCapabilitiesBlock_DeclaredModes(t *testing.T) {
+	g := gomega.NewWithT(t)
+	def, err := ParseDefinition([]byte(`site: x
+caps:
+  categories:
+    1: Movies
+    2: TV
+  modes:
+    search: [q]
+    movie: [q, imdbid]
+    tv-search: [q, season, ep, tvdbid]
+`))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	caps := def.Capabilities.ToTorznab()
+	_, params := caps.HasSearchMode("movie-search")
+	g.Expect(params).To(gomega.Equal([]string{"q", "imdbid"}))
+	_, params = caps.HasSearchMode("tv-search")
+	g.Expect(params).To(gomega.Equal([]string{"q", "season", "ep", "tvdbid"}))
+
+	g.Expect(def.Capabilities.supportsAnyParam("movie-search", []string{"tmdbid", "imdbid"})).To(gomega.BeTrue())
+	g.Expect(def.Capabilities.supportsAnyParam("search", []string{"imdbid"})).To(gomega.BeFalse())
+	g.Expect(def.Capabilities.supportsAnyParam("tv-search", []string{"tvmazeid"})).To(gomega.BeFalse())
+
+	// The runner advertises only the params that the definition declares.
+	runnerCaps := (&Runner{definition: def}).Capabilities()
+	_, params = runnerCaps.HasSearchMode("movie-search")
+	g.Expect(params).To(gomega.Equal([]string{"q", "imdbid"}))
+	_, params = runnerCaps.HasSearchMode("search")
+	g.Expect(params).To(gomega.Equal([]string{"q"}))
+}
+
+func TestCapabilitiesBlock_MusicAndBookModes(t *testing.T) {
+	g := gomega.NewWithT(t)
+	def, err := ParseDefinition([]byte(`site: x
+caps:
+  categories:
+    1: Audio
+    2: Books/Ebook
+  modes:
+    music: [q, artist, album, label]
+`))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	caps := def.Capabilities.ToTorznab()
+	available, params := caps.HasSearchMode("music-search")
+	g.Expect(available).To(gomega.BeTrue())
+	g.Expect(params).To(gomega.Equal([]string{"q", "artist", "album", "label"}))
+	_, params = caps.HasSearchMode("book-search")
+	g.Expect(params).To(gomega.Equal([]string{"q", "author", "title"}))
+	available, _ = caps.HasSearchMode("tv-search")
+	g.Expect(available).To(gomega.BeFalse())
+}
+
+func TestDefinition_registration(t *testing.T) {
+	g := gomega.NewWithT(t)
+	for source, registration := range map[string]torznab.Registration{
+		"site: x\n":                                       {},
+		"site: x\nlogin:\n  path: /login\n":               {Available: true},
+		"site: x\ntype: public\nlogin:\n  path: /login\n": {Available: true, Open: true},
+		"site: x\ntype: semi-private\n":                   {Available: true, Open: true},
+		"site: x\ntype: private\n":                        {Available: true},
+	} {
+		def, err := ParseDefinition([]byte(source))
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		g.Expect(def.registration()).To(gomega.Equal(registration), source)
+		g.Expect((&Runner{definition: def}).Capabilities().Registration).To(gomega.Equal(registration), source)
+	}
+}
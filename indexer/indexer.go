@@ -1,6 +1,7 @@
 package indexer
 
 import (
+	"context"
 	"io"
 
 	"github.com/sp0x/torrentd/indexer/search"
@@ -15,16 +16,21 @@ type Info interface {
 	GetLink() string
 }
 
+// ResponseProxy streams a download.
+// If the download fails while it's streamed, reading from it returns the error.
+// Closing the reader stops the download.
 type ResponseProxy struct {
-	Reader            io.ReadCloser
+	Reader io.ReadCloser
+	// ContentLengthChan gets the length of the content once the download starts, or -1 if it isn't known.
 	ContentLengthChan chan int64
 }
 
 func NewResponseProxy() (*ResponseProxy, *io.PipeWriter) {
 	pipeR, pipeW := io.Pipe()
 	return &ResponseProxy{
-		Reader:            pipeR,
-		ContentLengthChan: make(chan int64),
+		Reader: pipeR,
+		// The length is buffered, so that the download doesn't wait for someone to read it.
+		ContentLengthChan: make(chan int64, 1),
 	}, pipeW
 }
 
@@ -32,14 +38,17 @@ func NewResponseProxy() (*ResponseProxy, *io.PipeWriter) {
 type Indexer interface {
 	Info() Info
 	GetDefinition() *Definition
-	Search(query *search.Query, srch *workerJob) ([]search.ResultItemBase, error)
-	Download(urlStr string) (*ResponseProxy, error)
+	// Search searches a page of the query, it stops once the context is done.
+	Search(ctx context.Context, query *search.Query, srch *workerJob) ([]search.ResultItemBase, error)
+	// Download opens a link of the index, the download is cancelled with the context.
+	Download(ctx context.Context, urlStr string) (*ResponseProxy, error)
 	Capabilities() torznab.Capabilities
 	GetEncoding() string
-	Open(s search.ResultItemBase) (*ResponseProxy, error)
+	// Open opens the download of a result, the download is cancelled with the context.
+	Open(ctx context.Context, s search.ResultItemBase) (*ResponseProxy, error)
 	// HealthCheck if the Indexer works.
-	// This might be needed to validate the search result extraction.
-	HealthCheck() error
+	// This might be needed to validate the search result extraction, the check is cancelled with the context.
+	HealthCheck(ctx context.Context) error
 	// The maximum number of pages we can search
 	MaxSearchPages() uint
 	SearchIsSinglePaged() bool
@@ -5,6 +5,7 @@
 package indexer
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -130,18 +131,18 @@ func (mr *MockIndexerMockRecorder) Capabilities() *gomock.Call {
 }
 
 // Download mocks base method.
-func (m *MockIndexer) Download(urlStr string) (*ResponseProxy, error) {
+func (m *MockIndexer) Download(ctx context.Context, urlStr string) (*ResponseProxy, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Download", urlStr)
+	ret := m.ctrl.Call(m, "Download", ctx, urlStr)
 	ret0, _ := ret[0].(*ResponseProxy)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Download indicates an expected call of Download.
-func (mr *MockIndexerMockRecorder) Download(urlStr interface{}) *gomock.Call {
+func (mr *MockIndexerMockRecorder) Download(ctx, urlStr interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Download", reflect.TypeOf((*MockIndexer)(nil).Download), urlStr)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Download", reflect.TypeOf((*MockIndexer)(nil).Download), ctx, urlStr)
 }
 
 // Errors mocks base method.
@@ -201,17 +202,17 @@ func (mr *MockIndexerMockRecorder) GetStorage() *gomock.Call {
 }
 
 // HealthCheck mocks base method.
-func (m *MockIndexer) HealthCheck() error {
+func (m *MockIndexer) HealthCheck(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "HealthCheck")
+	ret := m.ctrl.Call(m, "HealthCheck", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // HealthCheck indicates an expected call of HealthCheck.
-func (mr *MockIndexerMockRecorder) HealthCheck() *gomock.Call {
+func (mr *MockIndexerMockRecorder) HealthCheck(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HealthCheck", reflect.TypeOf((*MockIndexer)(nil).HealthCheck))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HealthCheck", reflect.TypeOf((*MockIndexer)(nil).HealthCheck), ctx)
 }
 
 // Info mocks base method.
@@ -243,33 +244,33 @@ func (mr *MockIndexerMockRecorder) MaxSearchPages() *gomock.Call {
 }
 
 // Open mocks base method.
-func (m *MockIndexer) Open(s search.ResultItemBase) (*ResponseProxy, error) {
+func (m *MockIndexer) Open(ctx context.Context, s search.ResultItemBase) (*ResponseProxy, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Open", s)
+	ret := m.ctrl.Call(m, "Open", ctx, s)
 	ret0, _ := ret[0].(*ResponseProxy)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Open indicates an expected call of Open.
-func (mr *MockIndexerMockRecorder) Open(s interface{}) *gomock.Call {
+func (mr *MockIndexerMockRecorder) Open(ctx, s interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Open", reflect.TypeOf((*MockIndexer)(nil).Open), s)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Open", reflect.TypeOf((*MockIndexer)(nil).Open), ctx, s)
 }
 
 // Search mocks base method.
-func (m *MockIndexer) Search(query *search.Query, srch *workerJob) ([]search.ResultItemBase, error) {
+func (m *MockIndexer) Search(ctx context.Context, query *search.Query, srch *workerJob) ([]search.ResultItemBase, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Search", query, srch)
+	ret := m.ctrl.Call(m, "Search", ctx, query, srch)
 	ret0, _ := ret[0].([]search.ResultItemBase)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Search indicates an expected call of Search.
-func (mr *MockIndexerMockRecorder) Search(query, srch interface{}) *gomock.Call {
+func (mr *MockIndexerMockRecorder) Search(ctx, query, srch interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockIndexer)(nil).Search), query, srch)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockIndexer)(nil).Search), ctx, query, srch)
 }
 
 // SearchIsSinglePaged mocks base method.
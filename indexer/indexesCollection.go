@@ -1,8 +1,10 @@
 package indexer
 
 import (
+	"context"
 	"errors"
 	"strings"
+	"sync"
 
 	"github.com/sp0x/torrentd/indexer/search"
 	"golang.org/x/sync/errgroup"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/sp0x/torrentd/config"
 	"github.com/sp0x/torrentd/indexer/categories"
+	"github.com/sp0x/torrentd/torznab"
 )
 
 //go:generate mockgen -source indexesCollection.go -destination=creation_mocks.go -package=indexer
@@ -19,6 +22,7 @@ type Scope interface {
 	LookupWithCategories(config config.Config, selector *Selector, cats []categories.Category) (IndexCollection, error)
 	LookupAll(config config.Config, selector *Selector) (IndexCollection, error)
 	Indexes() map[string]IndexCollection
+	Reload(config config.Config, def *Definition) map[Indexer]Indexer
 }
 
 type IndexCollection []Indexer
@@ -32,13 +36,13 @@ func (i IndexCollection) Name() string {
 }
 
 // HealthCheck checks all indexMap, if they can be searched.
-func (i IndexCollection) HealthCheck() error {
+func (i IndexCollection) HealthCheck(ctx context.Context) error {
 	errorGroup := errgroup.Group{}
 	for _, ixr := range i {
 		indexerID := ixr.Info().GetID()
 		// Run the Indexes in a goroutine
 		errorGroup.Go(func() error {
-			err := ixr.HealthCheck()
+			err := ixr.HealthCheck(ctx)
 			if err != nil {
 				log.Warnf("Indexes %q failed: %s", indexerID, err)
 				return nil
@@ -54,13 +58,13 @@ func (i IndexCollection) HealthCheck() error {
 }
 
 // Open try to open a scraping item from a collection of indexes
-func (i IndexCollection) Open(scrapeItem search.ResultItemBase) (*ResponseProxy, error) {
+func (i IndexCollection) Open(ctx context.Context, scrapeItem search.ResultItemBase) (*ResponseProxy, error) {
 	// Find the Indexes
 	scrapeItemRoot := scrapeItem.AsScrapeItem()
 	for _, index := range i {
 		nfo := index.Info()
 		if nfo.GetTitle() == scrapeItemRoot.Site {
-			return index.Open(scrapeItem)
+			return index.Open(ctx, scrapeItem)
 		}
 	}
 	return nil, errors.New("couldn't find Indexes")
@@ -94,9 +98,32 @@ func (i IndexCollection) HasCategories(categories []categories.Category) bool {
 	return false
 }
 
+// Capabilities are the union of the categories and search modes of the indexes.
+// A single index has its own capabilities.
+func (i IndexCollection) Capabilities() torznab.Capabilities {
+	if len(i) == 1 {
+		return i[0].Capabilities()
+	}
+	all := make([]torznab.Capabilities, len(i))
+	for idx, index := range i {
+		all[idx] = index.Capabilities()
+	}
+	return torznab.Union(torznab.ServerInfo{Title: i.Name()}, all...)
+}
+
+// supportsQuery checks if an index has the search mode and one of the categories of a query.
+func supportsQuery(index Indexer, query *search.Query) bool {
+	caps := index.Capabilities()
+	if available, _ := caps.HasSearchMode(query.SearchMode()); !available {
+		return false
+	}
+	return caps.SupportsCategories(query.Categories)
+}
+
 type indexMap struct {
 	indexes map[string]IndexCollection
 	loader  DefinitionLoader
+	mutex   sync.RWMutex
 }
 
 // NewScope creates a new scope for indexes that can be or are loaded
@@ -112,7 +139,13 @@ func NewScope(definitionLoader DefinitionLoader) Scope {
 
 // Indexes returns the currently loaded indexMap
 func (c *indexMap) Indexes() map[string]IndexCollection {
-	return c.indexes
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	indexes := make(map[string]IndexCollection, len(c.indexes))
+	for key, collection := range c.indexes {
+		indexes[key] = collection
+	}
+	return indexes
 }
 
 // Lookup finds the matching Indexer.
@@ -120,21 +153,31 @@ func (c *indexMap) Lookup(config config.Config, indexSelectionKey string) (Index
 	// If we already have that indexer running, we don't create a new one.
 	selector := newIndexSelector(indexSelectionKey)
 	log.Debugf("Looking up scoped index: %v\n", selector)
-	if _, ok := c.indexes[indexSelectionKey]; !ok {
-		var indexes []Indexer
-		var err error
-		// If we're looking up an aggregate indexes, we just create an aggregate
-		if selector.isAggregate() {
-			indexes, err = c.LookupAll(config, selector)
-		} else {
-			indexes, err = NewIndexRunnerByNameOrSelector(selector.Value(), config)
-		}
-		if err != nil {
-			return nil, err
-		}
-		c.indexes[indexSelectionKey] = indexes
+	c.mutex.RLock()
+	existing, ok := c.indexes[indexSelectionKey]
+	c.mutex.RUnlock()
+	if ok {
+		return existing, nil
+	}
+	var indexes IndexCollection
+	var err error
+	// If we're looking up an aggregate indexes, we just create an aggregate
+	if selector.isAggregate() {
+		indexes, err = c.LookupAll(config, selector)
+	} else {
+		indexes, err = NewIndexRunnerByNameOrSelector(selector.Value(), config)
 	}
-	return c.indexes[indexSelectionKey], nil
+	if err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	// Another lookup might have created the indexes in the meantime.
+	if existing, ok := c.indexes[indexSelectionKey]; ok {
+		return existing, nil
+	}
+	c.indexes[indexSelectionKey] = indexes
+	return indexes, nil
 }
 
 // LookupWithCategories creates a new aggregate with the indexMap that match a set of indexCategories
@@ -186,6 +229,12 @@ func (c *indexMap) LookupAll(config config.Config, selector *Selector) (IndexCol
 		indexConfig, _ := config.GetSite(key) // Search all the configured indexMap
 		if indexConfig != nil {
 			index, err := c.Lookup(config, key)
+			if settingsErr, ok := err.(*SettingsError); ok {
+				// Indexes that need settings which aren't configured can't be used, but the rest can.
+				log.WithFields(log.Fields{"index": key}).
+					Debugf("Skipping index for the aggregate: %v", settingsErr)
+				continue
+			}
 			if err != nil {
 				return nil, err
 			}
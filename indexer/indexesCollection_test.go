@@ -1,12 +1,14 @@
 package indexer
 
 import (
+	"encoding/xml"
 	"testing"
 	"time"
 
 	"github.com/onsi/gomega"
 
 	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/torznab"
 )
 
 func TestIndexCollection_CreateAggregate_ShouldNotHang(t *testing.T) {
@@ -35,3 +37,54 @@ func TestIndexCollection_CreateAggregate_ShouldNotHang(t *testing.T) {
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(indexes).ToNot(gomega.BeNil())
 }
+
+func TestIndexCollection_Capabilities(t *testing.T) {
+	g := gomega.NewWithT(t)
+	movies, _ := ParseDefinition([]byte(`site: movies
+name: Movies
+type: semi-private
+links:
+  - https://movies.example/
+caps:
+  categories:
+    1: Movies/HD
+  modes:
+    movie-search: [q, imdbid]
+`))
+	music, _ := ParseDefinition([]byte(`site: music
+name: Music
+caps:
+  categories:
+    1: Audio
+    2: Movies/HD
+  modes:
+    search: [q, year]
+`))
+	moviesRunner := &Runner{definition: movies}
+	indexes := IndexCollection{moviesRunner, &Runner{definition: music}}
+
+	caps := IndexCollection{moviesRunner}.Capabilities()
+	g.Expect(caps.Server.Title).To(gomega.Equal("Movies"))
+	g.Expect(caps.Server.URL).To(gomega.Equal("https://movies.example/"))
+
+	caps = indexes.Capabilities()
+	g.Expect(caps.Server.Title).To(gomega.Equal("Movies,Music"))
+	g.Expect(caps.Categories).To(gomega.HaveLen(2))
+	_, params := caps.HasSearchMode("search")
+	g.Expect(params).To(gomega.Equal([]string{"q", "year"}))
+	available, _ := caps.HasSearchMode("music-search")
+	g.Expect(available).To(gomega.BeTrue())
+	available, _ = caps.HasSearchMode("tv-search")
+	g.Expect(available).To(gomega.BeFalse())
+	g.Expect((&Aggregate{Indexes: indexes}).Capabilities()).To(gomega.Equal(caps))
+
+	output, err := xml.Marshal(caps)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(output)).To(gomega.ContainSubstring(`<server title="Movies,Music"></server><limits max="100" default="20"></limits><registration available="yes" open="yes"></registration>`))
+	g.Expect(string(output)).To(gomega.ContainSubstring(`<category id="2040" name="Movies/HD"></category><category id="3000" name="Audio"></category>`))
+
+	// The default limit of the union is valid for all the indexes.
+	caps = torznab.Union(torznab.ServerInfo{}, torznab.Capabilities{Limits: torznab.Limits{Max: 100, Default: 50}},
+		torznab.Capabilities{Limits: torznab.Limits{Max: 50, Default: 20}}, torznab.Capabilities{})
+	g.Expect(caps.Limits).To(gomega.Equal(torznab.Limits{Max: 100, Default: 20}))
+}
@@ -3,6 +3,7 @@ package indexer
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -13,6 +14,7 @@ func defaultMultiLoader() *MultipleDefinitionLoader {
 	return &MultipleDefinitionLoader{
 		defaultFsLoader(),
 		embeddedLoader(),
+		defaultRemoteLoader(),
 		// escLoader{http.Dir("")},
 	}
 }
@@ -64,22 +66,26 @@ func (ml MultipleDefinitionLoader) String() string {
 	return "loaders[" + str + "]"
 }
 
-// Load an indexer with the matching name
+// Load an indexer with the matching name.
+// The bases of the definition can come from any of the loaders.
 func (ml MultipleDefinitionLoader) Load(key string) (*Definition, error) {
+	return loadResolvedDefinition(ml, key)
+}
+
+func (ml MultipleDefinitionLoader) loadDefinition(key string) (*Definition, error) {
 	var def *Definition
 	// Go over each loader, until we reach the one that contains the definition for the indexer.
 	for _, loader := range ml {
 		if loader == nil {
 			continue
 		}
-		loaded, err := loader.Load(key)
+		loaded, err := loadDefinitionSource(loader, key)
 		if err != nil {
 			log.WithFields(log.Fields{"index": key, "loader": loader, "error": err}).
 				Debugf("Couldn't load the Indexes using specific loader.")
 			continue
 		}
-		// If it's newer than our last one
-		if def == nil || loaded.Stats().ModTime.After(def.Stats().ModTime) { // If no definition is loaded so far, or the new one is newer
+		if def == nil || isNewerDefinition(loaded, def) {
 			def = loaded
 		}
 	}
@@ -94,3 +100,27 @@ func (ml MultipleDefinitionLoader) Load(key string) (*Definition, error) {
 
 	return def, nil
 }
+
+// isNewerDefinition checks if a definition should be used instead of another one.
+// Definitions from the definition directories of the user are always used over remote and embedded ones,
+// so that their local changes aren't replaced by an update.
+// Between remote and embedded definitions, the ones with a higher version are newer,
+// and versioned definitions are newer than ones without a version.
+// Otherwise the definition that was modified last is newer.
+func isNewerDefinition(def, other *Definition) bool {
+	isLocal, otherIsLocal := def.isLocal(), other.isLocal()
+	if isLocal != otherIsLocal {
+		return isLocal
+	}
+	if !isLocal {
+		if cmp := compareVersions(def.Version, other.Version); cmp != 0 {
+			return cmp > 0
+		}
+	}
+	return def.Stats().ModTime.After(other.Stats().ModTime)
+}
+
+// isLocal checks if a definition was loaded from the definition directories of the user.
+func (id *Definition) isLocal() bool {
+	return strings.HasPrefix(id.Stats().Source, "file:")
+}
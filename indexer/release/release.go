@@ -0,0 +1,316 @@
+package release
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	releaseinfo "github.com/sp0x/mediareleaseinfo"
+)
+
+// Info is what can be told about a release from its name.
+type Info struct {
+	// Title is the name of the series or the movie, alternative names are separated by ` / `.
+	Title string
+	Year  int
+	// Season and Episode are 0 when they aren't in the name, a season pack has no episode.
+	Season  int
+	Episode int
+	// LastEpisode is the last episode of a release with more episodes, like S01E01-E03.
+	LastEpisode int
+	// AbsoluteEpisode is the number of the episode in the whole series, it's used by anime.
+	AbsoluteEpisode int
+	// AirDate is the date of a daily show, as yyyy-mm-dd.
+	AirDate string
+	// Resolution is one of 2160p, 1080p, 720p, 576p or 480p.
+	Resolution string
+	// Source is where the release was made from: bluray, remux, webdl, webrip, hdtv, dvd, hdrip or cam.
+	Source string
+	// Codec is one of h264, h265, xvid, av1 or vp9.
+	Codec string
+	// Audio is one of truehd, dts-hd, dts, eac3, ac3, aac, flac, mp3 or opus.
+	Audio string
+	// Group is the group that made the release.
+	Group string
+	// Language is the language of the release, when it's not only english, or multi if there are more.
+	Language string
+	Proper   bool
+	Repack   bool
+}
+
+type pattern struct {
+	name string
+	rx   *regexp.Regexp
+}
+
+var (
+	rxResolution = regexp.MustCompile(`(?i)\b(2160p|4k|uhd|1080[pi]|720p|576p|480p)\b`)
+	sources      = []pattern{
+		{"remux", regexp.MustCompile(`(?i)\b(bd)?remux\b`)},
+		{"bluray", regexp.MustCompile(`(?i)\b(blu-?ray|bdrip|brrip|bd(25|50))\b`)},
+		{"webdl", regexp.MustCompile(`(?i)\b(web-?dl|web|amzn|dsnp|hmax|atvp)\b`)},
+		{"webrip", regexp.MustCompile(`(?i)\bweb-?rip\b`)},
+		{"hdtv", regexp.MustCompile(`(?i)\b(hdtv|pdtv|satrip|dvb)\b`)},
+		{"dvd", regexp.MustCompile(`(?i)\b(dvd-?rip|dvd(5|9)?|dvdscr)\b`)},
+		{"hdrip", regexp.MustCompile(`(?i)\bhd-?rip\b`)},
+		{"cam", regexp.MustCompile(`(?i)\b(cam(rip)?|hdcam|telesync|hdts)\b`)},
+	}
+	codecs = []pattern{
+		{"h265", regexp.MustCompile(`(?i)\b([xh]\.?265|hevc)\b`)},
+		{"h264", regexp.MustCompile(`(?i)\b([xh]\.?264|avc)\b`)},
+		{"xvid", regexp.MustCompile(`(?i)\b(xvid|divx)\b`)},
+		{"av1", regexp.MustCompile(`(?i)\bav1\b`)},
+		{"vp9", regexp.MustCompile(`(?i)\bvp9\b`)},
+	}
+	audios = []pattern{
+		{"truehd", regexp.MustCompile(`(?i)\b(truehd|atmos)\b`)},
+		{"dts-hd", regexp.MustCompile(`(?i)\bdts-?(hd|x|ma)\b`)},
+		{"dts", regexp.MustCompile(`(?i)\bdts\b`)},
+		{"eac3", regexp.MustCompile(`(?i)\b(ddp|e-?ac-?3|dd\+)(\d[ .]?\d)?`)},
+		{"ac3", regexp.MustCompile(`(?i)\b(ac-?3|dd(\d[ .]?\d)?)\b`)},
+		{"aac", regexp.MustCompile(`(?i)\baac(\d[ .]?\d)?\b`)},
+		{"flac", regexp.MustCompile(`(?i)\bflac\b`)},
+		{"mp3", regexp.MustCompile(`(?i)\bmp3\b`)},
+		{"opus", regexp.MustCompile(`(?i)\bopus\b`)},
+	}
+	languages = []pattern{
+		{"multi", regexp.MustCompile(`(?i)\b(multi|dual[ .-]?audio)\b`)},
+		{"russian", regexp.MustCompile(`(?i)\b(rus|russian)\b`)},
+		{"french", regexp.MustCompile(`(?i)\b(french|truefrench|vff|vostfr)\b`)},
+		{"german", regexp.MustCompile(`(?i)\b(german|ger)\b`)},
+		{"spanish", regexp.MustCompile(`(?i)\b(spanish|castellano|esp)\b`)},
+		{"italian", regexp.MustCompile(`(?i)\b(italian|ita)\b`)},
+		{"japanese", regexp.MustCompile(`(?i)\b(japanese|jap|jpn)\b`)},
+		{"korean", regexp.MustCompile(`(?i)\b(korean|kor)\b`)},
+		{"bulgarian", regexp.MustCompile(`(?i)\b(bulgarian|bgaudio)\b`)},
+	}
+	// The release info parser reads years and other numbers as episodes, so it's only used for names that have
+	// an episode like S01E02 or 1x02, a season like S01, or an air date.
+	rxEpisodeMarker = regexp.MustCompile(`(?i)\bs\d{1,2}(?:[ .]?e\d{1,3}|\b)|\b\d{1,2}x\d{2,3}\b`)
+	rxAirDate       = regexp.MustCompile(`\b(?:19|20)\d{2}[.\- ]\d{2}[.\- ]\d{2}\b`)
+	rxSeason        = regexp.MustCompile(`(?i)(\bseason[ .]?(\d{1,2})\b|сезон\W{0,3}(\d{1,2}))`)
+	rxEpisodes      = regexp.MustCompile(`(?i)сери[яийе]\W{0,3}(\d{1,4})(?:\s*-\s*(\d{1,4}))?`)
+	rxAbsolute      = regexp.MustCompile(`(?i)\s-\s(\d{1,4})(?:v\d)?\b`)
+	rxYear          = regexp.MustCompile(`\b(19\d{2}|20\d{2})\b`)
+	rxProper        = regexp.MustCompile(`(?i)\bproper\b`)
+	rxRepack        = regexp.MustCompile(`(?i)\b(repack|rerip)\b`)
+	rxTitleEnd      = regexp.MustCompile(`[\[(]`)
+	rxLeadingGroup  = regexp.MustCompile(`^\s*\[[^\]]+\]\s*`)
+	rxTitleSpaces   = regexp.MustCompile(`[._\s]+`)
+)
+
+// Parse gets the info from the name of a release.
+// The episodes and the group are parsed with the release info parser, the rest of the names that it doesn't know,
+// like movies, anime and the ones of russian trackers, are parsed here.
+func Parse(title string) *Info {
+	info := &Info{Group: parseGroup(title)}
+	name := title
+	if match := rxLeadingGroup.FindString(name); match != "" {
+		name = name[len(match):]
+	}
+	// The title is everything before the first thing that's known.
+	titleEnd := len(name)
+	markAt := func(index int) {
+		if index > 0 && index < titleEnd {
+			titleEnd = index
+		}
+	}
+	if match := rxResolution.FindStringSubmatchIndex(name); match != nil {
+		info.Resolution = normalizeResolution(name[match[2]:match[3]])
+		markAt(match[0])
+	}
+	// A WEB-Rip also looks like a WEB release, so a webdl match is only kept if no rip matches after it.
+	for _, source := range sources {
+		if index := source.rx.FindStringIndex(name); index != nil {
+			info.Source = source.name
+			markAt(index[0])
+			if source.name != "webdl" {
+				break
+			}
+		}
+	}
+	info.Codec = firstMatch(codecs, name, markAt)
+	info.Audio = firstMatch(audios, name, markAt)
+	seriesTitle, parsed := parseEpisode(info, name, markAt)
+	if !parsed {
+		parseOtherEpisode(info, name, markAt)
+	}
+	if info.AirDate == "" {
+		// A year at the start is a part of the title.
+		for _, match := range rxYear.FindAllStringSubmatchIndex(name, -1) {
+			if match[0] > 0 {
+				info.Year, _ = strconv.Atoi(name[match[2]:match[3]])
+				markAt(match[0])
+				break
+			}
+		}
+	}
+	if index := rxProper.FindStringIndex(name); index != nil {
+		info.Proper = true
+		markAt(index[0])
+	}
+	if index := rxRepack.FindStringIndex(name); index != nil {
+		info.Repack = true
+		markAt(index[0])
+	}
+	if index := rxTitleEnd.FindStringIndex(name); index != nil {
+		markAt(index[0])
+	}
+	// Languages are common words, so they're only looked for after the title, or as its last word.
+	releaseTitle := cleanTitle(name[:titleEnd])
+	info.Language = firstMatch(languages, name[titleEnd:], func(int) {})
+	if words := strings.Fields(releaseTitle); len(words) > 1 && titleEnd < len(name) {
+		if language := firstMatch(languages, words[len(words)-1], func(int) {}); language != "" {
+			releaseTitle = strings.Join(words[:len(words)-1], " ")
+			if info.Language == "" {
+				info.Language = language
+			}
+		}
+	}
+	if seriesTitle != "" {
+		releaseTitle = seriesTitle
+	}
+	info.Title = releaseTitle
+	return info
+}
+
+// parseEpisode gets the season, the episodes or the air date of a name with the release info parser,
+// and the title of the series. It's false if the name has no episode that the parser can be trusted with.
+func parseEpisode(info *Info, name string, markAt func(int)) (string, bool) {
+	marker := rxEpisodeMarker.FindStringIndex(name)
+	date := rxAirDate.FindStringIndex(name)
+	if marker == nil && date == nil {
+		return "", false
+	}
+	episode, err := releaseinfo.Parse(name)
+	if err != nil || episode == nil {
+		return "", false
+	}
+	switch {
+	case episode.AirDate != "" && date != nil:
+		info.AirDate = episode.AirDate
+		markAt(date[0])
+	case marker != nil && episode.SeasonNumber > 0:
+		info.Season = episode.SeasonNumber
+		if count := len(episode.EpisodeNumbers); count > 0 {
+			info.Episode = episode.EpisodeNumbers[0]
+			if count > 1 {
+				info.LastEpisode = episode.EpisodeNumbers[count-1]
+			}
+		}
+		markAt(marker[0])
+	default:
+		return "", false
+	}
+	return cleanTitle(episode.SeriesTitle), true
+}
+
+// parseOtherEpisode gets the episodes that the release info parser doesn't know,
+// like the seasons and series of russian trackers, and the absolute episodes of anime.
+func parseOtherEpisode(info *Info, name string, markAt func(int)) {
+	if match := rxSeason.FindStringSubmatchIndex(name); match != nil {
+		for group := 2; group < len(match)/2; group++ {
+			if match[group*2] >= 0 {
+				info.Season = submatchInt(name, match, group)
+				break
+			}
+		}
+		markAt(match[0])
+	}
+	if match := rxEpisodes.FindStringSubmatchIndex(name); match != nil {
+		info.Episode = submatchInt(name, match, 1)
+		if last := submatchInt(name, match, 2); last > info.Episode {
+			info.LastEpisode = last
+		}
+		markAt(match[0])
+		return
+	}
+	if info.Season != 0 {
+		return
+	}
+	for _, match := range rxAbsolute.FindAllStringSubmatchIndex(name, -1) {
+		// Years aren't episodes, like in `Movie - 2019`.
+		if number := name[match[2]:match[3]]; !rxYear.MatchString(number) {
+			info.AbsoluteEpisode = submatchInt(name, match, 1)
+			markAt(match[0])
+			return
+		}
+	}
+}
+
+// submatchInt gets the number of a group of a match, or 0 if the group didn't match.
+func submatchInt(name string, match []int, group int) int {
+	if match[group*2] < 0 {
+		return 0
+	}
+	number, _ := strconv.Atoi(name[match[group*2]:match[group*2+1]])
+	return number
+}
+
+func firstMatch(patterns []pattern, name string, markAt func(int)) string {
+	for _, p := range patterns {
+		if index := p.rx.FindStringIndex(name); index != nil {
+			markAt(index[0])
+			return p.name
+		}
+	}
+	return ""
+}
+
+// parseGroup gets the group of a release with the release info parser.
+// Sources, codecs and audio at the end of a name, like the DTS in `Movie.2020.1080p.BluRay.x264-DTS`, aren't groups.
+func parseGroup(title string) string {
+	group := releaseinfo.ParseReleaseGroup(title)
+	for _, patterns := range [][]pattern{sources, codecs, audios} {
+		for _, p := range patterns {
+			if p.rx.MatchString(group) {
+				return ""
+			}
+		}
+	}
+	return group
+}
+
+func cleanTitle(title string) string {
+	title = rxTitleSpaces.ReplaceAllString(title, " ")
+	return strings.Trim(title, " -–:/")
+}
+
+func normalizeResolution(value string) string {
+	switch value = strings.ToLower(value); value {
+	case "4k", "uhd":
+		return "2160p"
+	case "1080i":
+		return "1080p"
+	}
+	return value
+}
+
+// MatchesTitle checks if a parsed title is the wanted one, or has it as one of its alternative names.
+func MatchesTitle(title, wanted string) bool {
+	wanted = NormalizeTitle(wanted)
+	if wanted == "" {
+		return true
+	}
+	for _, name := range strings.Split(title, "/") {
+		if NormalizeTitle(name) == wanted {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeTitle makes titles comparable, ignoring the case, punctuation and a year at the end.
+func NormalizeTitle(title string) string {
+	title = strings.ReplaceAll(strings.ToLower(title), "ё", "е")
+	title = strings.ReplaceAll(title, "&", " and ")
+	title = strings.ReplaceAll(title, "'", "")
+	words := strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 1 && rxYear.MatchString(words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,77 @@
+package release
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestParse(t *testing.T) {
+	g := gomega.NewWithT(t)
+	cases := map[string]Info{
+		"The.Expanse.S05E01.1080p.WEB-DL.DDP5.1.H.264-NTb": {
+			Title: "The Expanse", Season: 5, Episode: 1, Resolution: "1080p", Source: "webdl",
+			Codec: "h264", Audio: "eac3", Group: "NTb",
+		},
+		"The.Expanse.S05E01.720p.WEBRip.x264-GalaxyTV": {
+			Title: "The Expanse", Season: 5, Episode: 1, Resolution: "720p", Source: "webrip", Codec: "h264", Group: "GalaxyTV",
+		},
+		"Dune.2021.2160p.UHD.BluRay.REMUX.HDR.HEVC.Atmos-EPSiLON": {
+			Title: "Dune", Year: 2021, Resolution: "2160p", Source: "remux", Codec: "h265", Audio: "truehd", Group: "EPSiLON",
+		},
+		"Dune (2021) BDRip 1080i": {Title: "Dune", Year: 2021, Resolution: "1080p", Source: "bluray"},
+		"Some Show S01E02 HDTV x264": {
+			Title: "Some Show", Season: 1, Episode: 2, Source: "hdtv", Codec: "h264",
+		},
+		"Фильм / Movie (2020) DVDRip": {Title: "Фильм / Movie", Year: 2020, Source: "dvd"},
+		"Movie 2022 HDCAM":            {Title: "Movie", Year: 2022, Source: "cam"},
+		"Just a title":                {Title: "Just a title"},
+		"Show.S02.1080p.BluRay.x265.10bit.AAC5.1-RARBG": {
+			Title: "Show", Season: 2, Resolution: "1080p", Source: "bluray", Codec: "h265", Audio: "aac", Group: "RARBG",
+		},
+		"Show.3x07.PROPER.HDTV.XviD-LOL[ettv]": {
+			Title: "Show", Season: 3, Episode: 7, Source: "hdtv", Codec: "xvid", Group: "LOL", Proper: true,
+		},
+		"Movie.2019.FRENCH.REPACK.1080p.WEB.H264-GRP": {
+			Title: "Movie", Year: 2019, Resolution: "1080p", Source: "webdl", Codec: "h264", Group: "GRP",
+			Language: "french", Repack: true,
+		},
+		"The Italian Job 2003 MULTI 720p": {Title: "The Italian Job", Year: 2003, Resolution: "720p", Language: "multi"},
+		"1917 (2019) 1080p":               {Title: "1917", Year: 2019, Resolution: "1080p"},
+		"Сериал / Show / Сезон: 1 / Серии: 1-10 из 10 [2020, WEB-DL 1080p]": {
+			Title: "Сериал / Show", Season: 1, Episode: 1, LastEpisode: 10, Year: 2020, Resolution: "1080p", Source: "webdl",
+		},
+		"Show.S01E01-E03.720p.HDTV.x264-GRP": {
+			Title: "Show", Season: 1, Episode: 1, LastEpisode: 3, Resolution: "720p", Source: "hdtv", Codec: "h264", Group: "GRP",
+		},
+		"Show.S01E04E05.720p": {Title: "Show", Season: 1, Episode: 4, LastEpisode: 5, Resolution: "720p"},
+		"Show 2x01-02":        {Title: "Show", Season: 2, Episode: 1, LastEpisode: 2},
+		"The.Daily.Show.2023.10.15.720p.WEB.h264-GRP": {
+			Title: "The Daily Show", AirDate: "2023-10-15", Resolution: "720p", Source: "webdl", Codec: "h264", Group: "GRP",
+		},
+		"[SubsPlease] Anime Show - 1005 (1080p) [ABCD1234].mkv": {
+			Title: "Anime Show", AbsoluteEpisode: 1005, Resolution: "1080p", Group: "SubsPlease",
+		},
+		"Movie - 2019 720p": {Title: "Movie", Year: 2019, Resolution: "720p"},
+		// Years aren't read as episodes, and audio at the end isn't a group.
+		"Movie.2020.1080p.BluRay.x264-DTS": {Title: "Movie", Year: 2020, Resolution: "1080p", Source: "bluray", Codec: "h264", Audio: "dts"},
+		"Show.Season.2.720p":               {Title: "Show", Season: 2, Resolution: "720p"},
+		"Show.S01E01.1080p.WEB-DL.DD5.1-NTb.mkv": {
+			Title: "Show", Season: 1, Episode: 1, Resolution: "1080p", Source: "webdl", Audio: "ac3", Group: "NTb",
+		},
+	}
+	for title, expected := range cases {
+		g.Expect(*Parse(title)).To(gomega.Equal(expected), title)
+	}
+}
+
+func TestMatchesTitle(t *testing.T) {
+	g := gomega.NewWithT(t)
+	g.Expect(MatchesTitle("The Expanse", "the expanse")).To(gomega.BeTrue())
+	g.Expect(MatchesTitle("Marvel's Agents of S.H.I.E.L.D", "Marvels Agents of S H I E L D")).To(gomega.BeTrue())
+	g.Expect(MatchesTitle("Сериал / Show", "Show")).To(gomega.BeTrue())
+	g.Expect(MatchesTitle("Doctor Who 2005", "Doctor Who")).To(gomega.BeTrue())
+	g.Expect(MatchesTitle("Law & Order", "Law and Order")).To(gomega.BeTrue())
+	g.Expect(MatchesTitle("The Expanse", "Expanse Rising")).To(gomega.BeFalse())
+	g.Expect(MatchesTitle("Anything", "")).To(gomega.BeTrue())
+}
@@ -0,0 +1,392 @@
+package indexer
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/sp0x/torrentd/config"
+)
+
+const (
+	remoteIndexFile     = "index.yml"
+	remoteSignatureFile = "index.yml.sig"
+	gitRepositoryPrefix = "git+"
+	// The directory inside the definitions cache, that holds the checkout of git repositories.
+	gitCheckoutDir = ".repository"
+)
+
+var errNoRepositoryKey = errors.New("no public key is configured for the definition repository")
+
+// RemoteIndex lists the definitions that a repository contains.
+// A definition can be listed multiple times, once for each of its versions.
+type RemoteIndex struct {
+	Definitions []RemoteDefinition `yaml:"definitions"`
+}
+
+// RemoteDefinition is a single version of a definition in a repository.
+type RemoteDefinition struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	// File is the path of the definition, relative to the repository.
+	File   string `yaml:"file"`
+	SHA256 string `yaml:"sha256"`
+}
+
+// DefinitionUpdate describes a definition that has a different version in the repository.
+type DefinitionUpdate struct {
+	Name    string
+	Current string
+	Latest  string
+	Pinned  bool
+}
+
+// RemoteDefinitionLoader loads definitions that are synced from a remote repository.
+// The repository is either a http(s) or file:// location with an index, or a git repository prefixed with `git+`.
+// The index of the repository must be signed with the key of the repository.
+type RemoteDefinitionLoader struct {
+	Repository string
+	// PublicKey is used to verify the signature of the repository index.
+	PublicKey ed25519.PublicKey
+	// Pins holds the versions that definitions are kept at, by definition name.
+	Pins map[string]string
+	// Dir is where the synced definitions are stored.
+	Dir    string
+	client *http.Client
+}
+
+// RemoteDefinitionsDir is the directory where definitions from the remote repository are stored.
+func RemoteDefinitionsDir() string {
+	return config.GetCachePath("definitions")
+}
+
+func defaultRemoteLoader() DefinitionLoader {
+	return &RemoteDefinitionLoader{Dir: RemoteDefinitionsDir()}
+}
+
+// NewRemoteDefinitionLoader creates a loader for the repository in the `definitions` section of the configuration.
+func NewRemoteDefinitionLoader(conf config.Config) (*RemoteDefinitionLoader, error) {
+	l := &RemoteDefinitionLoader{
+		Repository: conf.GetString("definitions.repository"),
+		Pins:       map[string]string{},
+		Dir:        RemoteDefinitionsDir(),
+	}
+	if key := conf.GetString("definitions.key"); key != "" {
+		keyData, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(keyData) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid definition repository key: %q", key)
+		}
+		l.PublicKey = keyData
+	}
+	if pins, ok := conf.Get("definitions.pins").(map[string]interface{}); ok {
+		for name, version := range pins {
+			l.Pins[name] = fmt.Sprint(version)
+		}
+	}
+	return l, nil
+}
+
+func (l *RemoteDefinitionLoader) files() *FileIndexLoader {
+	return &FileIndexLoader{Directories: []string{l.Dir}}
+}
+
+// ListAvailableIndexes lists the definitions that are synced.
+func (l *RemoteDefinitionLoader) ListAvailableIndexes(selector *Selector) ([]string, error) {
+	return l.files().ListAvailableIndexes(selector)
+}
+
+// Load a synced definition by its name.
+func (l *RemoteDefinitionLoader) Load(key string) (*Definition, error) {
+	return loadResolvedDefinition(l, key)
+}
+
+func (l *RemoteDefinitionLoader) loadDefinition(key string) (*Definition, error) {
+	def, err := l.files().loadDefinition(key)
+	if err != nil {
+		return nil, err
+	}
+	def.stats.Source = "remote:" + strings.TrimPrefix(def.stats.Source, "file:")
+	return def, nil
+}
+
+func (l *RemoteDefinitionLoader) String() string {
+	return "remote{" + l.Repository + "}"
+}
+
+// FetchIndex gets the index of the repository and verifies its signature.
+func (l *RemoteDefinitionLoader) FetchIndex() (*RemoteIndex, error) {
+	if l.Repository == "" {
+		return nil, errors.New("no definition repository is configured")
+	}
+	if len(l.PublicKey) == 0 {
+		return nil, errNoRepositoryKey
+	}
+	if l.isGit() {
+		if err := l.syncGit(); err != nil {
+			return nil, err
+		}
+	}
+	src, err := l.read(remoteIndexFile)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := l.read(remoteSignatureFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get the signature of the repository index: %v", err)
+	}
+	signature, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil || !ed25519.Verify(l.PublicKey, src, signature) {
+		return nil, errors.New("the signature of the repository index is invalid")
+	}
+	index := &RemoteIndex{}
+	if err := yaml.Unmarshal(src, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Update installs the latest version of each definition in the repository, or its pinned version.
+// The result contains the definitions that were installed.
+func (l *RemoteDefinitionLoader) Update() ([]DefinitionUpdate, error) {
+	index, err := l.FetchIndex()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(l.Dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	var updates []DefinitionUpdate
+	for _, name := range index.names() {
+		pin, pinned := l.Pins[name]
+		candidate := index.candidate(name, pin)
+		if candidate == nil {
+			log.WithFields(log.Fields{"name": name, "version": pin}).
+				Warn("Pinned definition version isn't in the repository")
+			continue
+		}
+		current := ""
+		if installed, err := l.loadDefinition(name); err == nil {
+			current = installed.Version
+			if current == candidate.Version && strings.EqualFold(candidate.SHA256, sha256OfFile(l.installedPath(name))) {
+				continue
+			}
+		}
+		if err := l.install(candidate); err != nil {
+			return updates, err
+		}
+		updates = append(updates, DefinitionUpdate{
+			Name:    name,
+			Current: current,
+			Latest:  candidate.Version,
+			Pinned:  pinned,
+		})
+	}
+	return updates, nil
+}
+
+// Outdated lists the definitions which have a newer version in the repository, than the one the given loader uses.
+// Pinned definitions are only compared with their pinned version.
+func (l *RemoteDefinitionLoader) Outdated(current DefinitionLoader) ([]DefinitionUpdate, error) {
+	index, err := l.FetchIndex()
+	if err != nil {
+		return nil, err
+	}
+	var outdated []DefinitionUpdate
+	for _, name := range index.names() {
+		pin, pinned := l.Pins[name]
+		candidate := index.candidate(name, pin)
+		if candidate == nil {
+			continue
+		}
+		version := ""
+		if def, err := current.Load(name); err == nil {
+			version = def.Version
+		}
+		if compareVersions(candidate.Version, version) <= 0 {
+			continue
+		}
+		outdated = append(outdated, DefinitionUpdate{
+			Name:    name,
+			Current: version,
+			Latest:  candidate.Version,
+			Pinned:  pinned,
+		})
+	}
+	return outdated, nil
+}
+
+func (l *RemoteDefinitionLoader) install(remote *RemoteDefinition) error {
+	src, err := l.read(remote.File)
+	if err != nil {
+		return err
+	}
+	if sum := fmt.Sprintf("%x", sha256.Sum256(src)); sum != strings.ToLower(remote.SHA256) {
+		return fmt.Errorf("checksum of definition %s %s doesn't match the repository index", remote.Name, remote.Version)
+	}
+	def, err := parseDefinitionSource(src)
+	if err != nil {
+		return fmt.Errorf("couldn't parse definition %s %s: %v", remote.Name, remote.Version, err)
+	}
+	if def.Version != remote.Version {
+		return fmt.Errorf("definition %s has version %q, but the repository index lists it as %q",
+			remote.Name, def.Version, remote.Version)
+	}
+	// Write to a temporary file first, so that a watcher never sees a partial definition.
+	tmp, err := ioutil.TempFile(l.Dir, "."+remote.Name)
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(src); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), l.installedPath(remote.Name))
+}
+
+func (l *RemoteDefinitionLoader) installedPath(name string) string {
+	return filepath.Join(l.Dir, name+".yml")
+}
+
+func (l *RemoteDefinitionLoader) isGit() bool {
+	return strings.HasPrefix(l.Repository, gitRepositoryPrefix)
+}
+
+// syncGit clones the repository, or updates the existing checkout of it.
+func (l *RemoteDefinitionLoader) syncGit() error {
+	repository := strings.TrimPrefix(l.Repository, gitRepositoryPrefix)
+	checkout := filepath.Join(l.Dir, gitCheckoutDir)
+	var commands [][]string
+	if _, err := os.Stat(filepath.Join(checkout, ".git")); err == nil {
+		commands = [][]string{
+			{"-C", checkout, "fetch", "--depth", "1", repository},
+			{"-C", checkout, "reset", "--hard", "FETCH_HEAD"},
+		}
+	} else {
+		_ = os.RemoveAll(checkout)
+		commands = [][]string{{"clone", "--depth", "1", repository, checkout}}
+	}
+	for _, args := range commands {
+		output, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+	return nil
+}
+
+// read gets a file from the repository.
+func (l *RemoteDefinitionLoader) read(name string) ([]byte, error) {
+	if l.isGit() {
+		return ioutil.ReadFile(filepath.Join(l.Dir, gitCheckoutDir, filepath.FromSlash(name)))
+	}
+	if l.client == nil {
+		transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+		transport.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+		l.client = &http.Client{Transport: transport, Timeout: 30 * time.Second}
+	}
+	location := strings.TrimSuffix(l.Repository, "/") + "/" + strings.TrimPrefix(name, "/")
+	resp, err := l.client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("couldn't get %s: %s", location, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// names lists the names of the definitions in the index.
+func (i *RemoteIndex) names() []string {
+	var names []string
+	for _, def := range i.Definitions {
+		if !contains(names, def.Name) {
+			names = append(names, def.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// candidate finds the version of a definition that should be installed.
+// That's the pinned version if there is one, otherwise it's the newest version.
+func (i *RemoteIndex) candidate(name, pin string) *RemoteDefinition {
+	var result *RemoteDefinition
+	for ix := range i.Definitions {
+		def := &i.Definitions[ix]
+		if def.Name != name {
+			continue
+		}
+		if pin != "" {
+			if def.Version == pin {
+				return def
+			}
+			continue
+		}
+		if result == nil || compareVersions(def.Version, result.Version) > 0 {
+			result = def
+		}
+	}
+	return result
+}
+
+// compareVersions compares dotted versions like `1.10.2`, numeric parts are compared as numbers.
+// It returns a positive number if `a` is newer than `b`, a negative one if it's older and 0 if they're the same.
+func compareVersions(a, b string) int {
+	splitVersion := func(version string) []string {
+		version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+		if version == "" {
+			return nil
+		}
+		return strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '-' })
+	}
+	aParts, bParts := splitVersion(a), splitVersion(b)
+	for ix := 0; ix < len(aParts) || ix < len(bParts); ix++ {
+		if ix >= len(aParts) {
+			return -1
+		}
+		if ix >= len(bParts) {
+			return 1
+		}
+		aNum, aErr := strconv.Atoi(aParts[ix])
+		bNum, bErr := strconv.Atoi(bParts[ix])
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum > bNum {
+				return 1
+			}
+			return -1
+		case (aErr != nil || bErr != nil) && aParts[ix] != bParts[ix]:
+			return strings.Compare(aParts[ix], bParts[ix])
+		}
+	}
+	return 0
+}
+
+func sha256OfFile(name string) string {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(src))
+}
@@ -0,0 +1,204 @@
+package indexer
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+const remoteDefinition = `---
+site: remotesite
+name: remotesite
+version: %s
+links:
+  - http://localhost/
+search:
+  path: /search
+  rows:
+    selector: tr
+  fields:
+    title:
+      selector: td
+`
+
+type testRepository struct {
+	t    *testing.T
+	dir  string
+	key  ed25519.PrivateKey
+	defs []RemoteDefinition
+}
+
+func newTestRepository(t *testing.T) (*testRepository, ed25519.PublicKey) {
+	dir, _ := ioutil.TempDir("", "definition-repository-")
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testRepository{t: t, dir: dir, key: private}, public
+}
+
+// publish adds a version of the test definition to the repository and signs the index.
+func (r *testRepository) publish(version string) {
+	src := []byte(fmt.Sprintf(remoteDefinition, version))
+	file := filepath.Join("remotesite", version+".yml")
+	_ = os.MkdirAll(filepath.Join(r.dir, "remotesite"), os.ModePerm)
+	r.write(file, src)
+	r.defs = append(r.defs, RemoteDefinition{
+		Name:    "remotesite",
+		Version: version,
+		File:    filepath.ToSlash(file),
+		SHA256:  fmt.Sprintf("%x", sha256.Sum256(src)),
+	})
+	index, _ := yaml.Marshal(&RemoteIndex{Definitions: r.defs})
+	r.write(remoteIndexFile, index)
+	r.write(remoteSignatureFile, []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(r.key, index))))
+}
+
+func (r *testRepository) write(name string, src []byte) {
+	if err := ioutil.WriteFile(filepath.Join(r.dir, name), src, 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func newTestRemoteLoader(repository string, key ed25519.PublicKey) *RemoteDefinitionLoader {
+	dir, _ := ioutil.TempDir("", "remote-definitions-")
+	return &RemoteDefinitionLoader{Repository: repository, PublicKey: key, Dir: dir, Pins: map[string]string{}}
+}
+
+func TestRemoteDefinitionLoader_Update(t *testing.T) {
+	g := gomega.NewWithT(t)
+	repo, key := newTestRepository(t)
+	defer os.RemoveAll(repo.dir)
+	repo.publish("1.0")
+	loader := newTestRemoteLoader("file://"+filepath.ToSlash(repo.dir), key)
+	defer os.RemoveAll(loader.Dir)
+
+	updates, err := loader.Update()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updates).To(gomega.Equal([]DefinitionUpdate{{Name: "remotesite", Latest: "1.0"}}))
+	def, err := loader.Load("remotesite")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(def.Version).To(gomega.Equal("1.0"))
+	g.Expect(def.Stats().Source).To(gomega.HavePrefix("remote:"))
+
+	// Nothing changed, so nothing is installed.
+	updates, err = loader.Update()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updates).To(gomega.BeEmpty())
+
+	repo.publish("1.10")
+	outdated, err := loader.Outdated(loader)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(outdated).To(gomega.Equal([]DefinitionUpdate{{Name: "remotesite", Current: "1.0", Latest: "1.10"}}))
+
+	// A pinned definition stays at its version.
+	loader.Pins["remotesite"] = "1.0"
+	outdated, err = loader.Outdated(loader)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(outdated).To(gomega.BeEmpty())
+	updates, err = loader.Update()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updates).To(gomega.BeEmpty())
+
+	delete(loader.Pins, "remotesite")
+	updates, err = loader.Update()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updates).To(gomega.Equal([]DefinitionUpdate{{Name: "remotesite", Current: "1.0", Latest: "1.10"}}))
+	def, _ = loader.Load("remotesite")
+	g.Expect(def.Version).To(gomega.Equal("1.10"))
+}
+
+func TestRemoteDefinitionLoader_ShouldVerifyTheRepository(t *testing.T) {
+	g := gomega.NewWithT(t)
+	repo, key := newTestRepository(t)
+	defer os.RemoveAll(repo.dir)
+	repo.publish("1.0")
+
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	loader := newTestRemoteLoader("file://"+filepath.ToSlash(repo.dir), otherKey)
+	defer os.RemoveAll(loader.Dir)
+	_, err := loader.Update()
+	g.Expect(err).ToNot(gomega.BeNil())
+
+	loader.PublicKey = nil
+	_, err = loader.Update()
+	g.Expect(err).To(gomega.Equal(errNoRepositoryKey))
+
+	// A definition that doesn't match the signed index isn't installed.
+	loader.PublicKey = key
+	repo.write(repo.defs[0].File, []byte(fmt.Sprintf(remoteDefinition, "6.6.6")))
+	_, err = loader.Update()
+	g.Expect(err).ToNot(gomega.BeNil())
+	_, err = loader.Load("remotesite")
+	g.Expect(err).To(gomega.Equal(ErrUnknownIndex))
+}
+
+func TestRemoteDefinitionLoader_ShouldSyncGitRepositories(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+	g := gomega.NewWithT(t)
+	repo, key := newTestRepository(t)
+	defer os.RemoveAll(repo.dir)
+	commit := func(version string) {
+		repo.publish(version)
+		for _, args := range [][]string{
+			{"init", "-q"},
+			{"add", "-A"},
+			{"-c", "user.name=test", "-c", "user.email=test@localhost", "commit", "-q", "-m", version},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = repo.dir
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v\n%s", args, err, output)
+			}
+		}
+	}
+	commit("1.0")
+	loader := newTestRemoteLoader("git+file://"+filepath.ToSlash(repo.dir), key)
+	defer os.RemoveAll(loader.Dir)
+
+	_, err := loader.Update()
+	g.Expect(err).To(gomega.BeNil())
+	commit("2.0")
+	updates, err := loader.Update()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(updates).To(gomega.Equal([]DefinitionUpdate{{Name: "remotesite", Current: "1.0", Latest: "2.0"}}))
+	// The checkout isn't listed as a definition.
+	g.Expect(loader.ListAvailableIndexes(nil)).To(gomega.Equal([]string{"remotesite"}))
+}
+
+func TestCompareVersions(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	g.Expect(compareVersions("1.10", "1.9")).To(gomega.BeNumerically(">", 0))
+	g.Expect(compareVersions("v2.0", "2.0")).To(gomega.Equal(0))
+	g.Expect(compareVersions("1.0", "1.0.1")).To(gomega.BeNumerically("<", 0))
+	g.Expect(compareVersions("1.0", "")).To(gomega.BeNumerically(">", 0))
+	g.Expect(compareVersions("1.0-beta", "1.0-alpha")).To(gomega.BeNumerically(">", 0))
+}
+
+func TestMultipleDefinitionLoader_ShouldPreferHigherVersions(t *testing.T) {
+	g := gomega.NewWithT(t)
+	newer, _ := ParseDefinition([]byte(fmt.Sprintf(remoteDefinition, "2.0")))
+	older, _ := ParseDefinition([]byte(fmt.Sprintf(remoteDefinition, "1.0")))
+
+	g.Expect(isNewerDefinition(newer, older)).To(gomega.BeTrue())
+	g.Expect(isNewerDefinition(older, newer)).To(gomega.BeFalse())
+
+	// The definitions of the user are used, even without a version.
+	local, _ := ParseDefinition([]byte(fmt.Sprintf(remoteDefinition, "")))
+	local.stats.Source = "file:remotesite.yml"
+	newer.stats.Source = "remote:remotesite.yml"
+	g.Expect(isNewerDefinition(local, newer)).To(gomega.BeTrue())
+	g.Expect(isNewerDefinition(newer, local)).To(gomega.BeFalse())
+}
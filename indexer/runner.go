@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 
@@ -50,11 +49,12 @@ type Runner struct {
 	failingSearchFields map[string]fieldBlock
 	lastVerified        time.Time
 	contentFetcher      source.ContentFetcher
-	context             context.Context
 	errors              cache.LRUCache
 	sessions            *BrowsingSessionMultiplexer
 	statusReporter      *StatusReporter
 	urlResolver         IURLResolver
+	// The site settings, with their defaults.
+	settings map[string]string
 }
 
 type scrapeContext struct {
@@ -97,6 +97,16 @@ func setConfiguredIndexLoader(loader DefinitionLoader, conf config.Config) {
 	conf.Set("indexLoader", loader)
 }
 
+// SetConfiguredTransport sets the http transport that all the runners created with this configuration will use.
+func SetConfiguredTransport(transport http.RoundTripper, conf config.Config) {
+	conf.Set("transport", transport)
+}
+
+func getConfiguredTransport(conf config.Config) http.RoundTripper {
+	transport, _ := conf.Get("transport").(http.RoundTripper)
+	return transport
+}
+
 // NewIndexRunnerByNameOrSelector creates a new Indexer or aggregate Indexer with the given configuration.
 func NewIndexRunnerByNameOrSelector(indexerName string, config config.Config) (IndexCollection, error) {
 	def, err := getConfiguredIndexLoader(config).Load(indexerName)
@@ -105,7 +115,10 @@ func NewIndexRunnerByNameOrSelector(indexerName string, config config.Config) (I
 		return nil, err
 	}
 
-	index := NewRunner(def, runnerOptsFromConfig(config))
+	index, err := NewRunner(def, runnerOptsFromConfig(config))
+	if err != nil {
+		return nil, err
+	}
 	return IndexCollection{index}, nil
 }
 
@@ -122,26 +135,35 @@ func runnerOptsFromConfig(config config.Config) *RunnerOpts {
 	opts := &RunnerOpts{
 		Config:       config,
 		UserSessions: userSessions,
+		Transport:    getConfiguredTransport(config),
 	}
 	return opts
 }
 
 // NewRunner Start a runner for a given indexer.
-func NewRunner(def *Definition, opts *RunnerOpts) *Runner {
+// The configured site settings are validated against the settings of the definition.
+func NewRunner(def *Definition, opts *RunnerOpts) (*Runner, error) {
+	siteConfig, err := opts.Config.GetSite(def.Name)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := def.ResolveSettings(siteConfig)
+	if err != nil {
+		return nil, err
+	}
 	logger := log.New().WithFields(log.Fields{"site": def.Site})
 	logger.Level = log.GetLevel()
 	// Use an optimistic cache instead.
 	errorCache, _ := cache.NewTTL(10, errorTTL)
-	indexCtx := context.Background()
 
 	runner := &Runner{
 		options:             opts,
 		definition:          def,
 		logger:              logger,
 		failingSearchFields: make(map[string]fieldBlock),
-		context:             indexCtx,
 		errors:              errorCache,
-		statusReporter:      &StatusReporter{context: indexCtx, indexDefinition: def, errors: errorCache},
+		statusReporter:      &StatusReporter{indexDefinition: def, errors: errorCache},
+		settings:            settings,
 	}
 	runner.contentFetcher = createContentFetcher(runner)
 	connectivity, _ := cache.NewConnectivityCache(runner.contentFetcher)
@@ -151,13 +173,12 @@ func NewRunner(def *Definition, opts *RunnerOpts) *Runner {
 		connectivity.Invalidate(options.URL.String())
 	})
 
-	if sessionsMx, err := NewSessionMultiplexer(runner, opts.UserSessions); err != nil {
-		fmt.Printf("Couldn't create index sessions: %v", err)
-		os.Exit(1)
-	} else {
-		runner.sessions = sessionsMx
+	sessionsMx, err := NewSessionMultiplexer(runner, opts.UserSessions)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create index sessions: %v", err)
 	}
-	return runner
+	runner.sessions = sessionsMx
+	return runner, nil
 }
 
 func (r *Runner) GetStorage() storage.ItemStorage {
@@ -200,31 +221,28 @@ func getIndexDatabase(name string, searchEntityBlock *entityBlock, conf config.C
 }
 
 // Capabilities gets the torznab formatted capabilities of this Indexer.
+// Only the params that the definition declares are advertised, queries with other ids are searched with their titles.
 func (r *Runner) Capabilities() torznab.Capabilities {
 	caps := r.definition.Capabilities.ToTorznab()
-
-	for idx, mode := range caps.SearchModes {
-		switch mode.Key {
-		case "search":
-			caps.SearchModes[idx].SupportedParams = append(
-				caps.SearchModes[idx].SupportedParams,
-				"imdbid", "tvdbid", "tvmazeid")
-
-		case "movie-search":
-			caps.SearchModes[idx].SupportedParams = append(
-				caps.SearchModes[idx].SupportedParams,
-				"imdbid")
-
-		case "tv-search":
-			caps.SearchModes[idx].SupportedParams = append(
-				caps.SearchModes[idx].SupportedParams,
-				"tvdbid", "tvmazeid", "rid")
-		}
+	caps.Server = torznab.ServerInfo{
+		Title:     r.definition.Name,
+		Strapline: r.definition.Description,
 	}
-
+	if len(r.definition.Links) > 0 {
+		caps.Server.URL = r.definition.Links[0]
+	}
+	caps.Limits = torznab.Limits{Max: search.MaxLimit, Default: search.DefaultLimit}
+	caps.Registration = r.definition.registration()
 	return caps
 }
 
+// searchesByID checks if the definition of an index can search for one of the ids of a query,
+// so that the query doesn't need the title of the ids.
+func searchesByID(index Indexer, query *search.Query) bool {
+	definition := index.GetDefinition()
+	return definition != nil && definition.Capabilities.supportsAnyParam(query.SearchMode(), query.IDParams())
+}
+
 // GetEncoding returns the encoding that's set to be used in this index.
 // This can be changed in the index's definition.
 func (r *Runner) GetEncoding() string {
@@ -232,13 +250,13 @@ func (r *Runner) GetEncoding() string {
 }
 
 // HealthCheck checks if the index can be searched.
-// Health checks for each index have a duration of 1 day.
-func (r *Runner) HealthCheck() error {
+// Health checks for each index have a duration of 1 day, the search of the check is cancelled with the context.
+func (r *Runner) HealthCheck(ctx context.Context) error {
 	verifiedSpan := time.Since(r.lastVerified)
 	if verifiedSpan < indexVerificationSpan {
 		return nil
 	}
-	results, err := r.Search(search.NewQuery(), nil)
+	results, err := r.Search(ctx, search.NewQuery(), nil)
 	if err != nil {
 		return err
 	}
@@ -268,12 +286,17 @@ func (r *Runner) HealthCheck() error {
 //}
 
 // Search searches the index for the given query.
-func (r *Runner) Search(query *search.Query, job *workerJob) ([]search.ResultItemBase, error) {
+// The errors of searches that were cancelled or timed out aren't noted, since the index didn't fail.
+func (r *Runner) Search(ctx context.Context, query *search.Query, job *workerJob) ([]search.ResultItemBase, error) {
 	var err error
 	errType := status.LoginError
-	defer func() { r.noteError(errType, err) }()
+	defer func() {
+		if ctx.Err() == nil {
+			r.noteError(ctx, errType, err)
+		}
+	}()
 
-	session, err := r.sessions.acquire()
+	session, err := r.sessions.acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -287,7 +310,7 @@ func (r *Runner) Search(query *search.Query, job *workerJob) ([]search.ResultIte
 	}
 	startedOn := time.Now()
 
-	fetchResult, err := r.contentFetcher.Fetch(requestOptions)
+	fetchResult, err := r.contentFetcher.Fetch(ctx, requestOptions)
 	if err != nil {
 		errType = status.ContentError
 		return nil, err
@@ -316,7 +339,7 @@ func (r *Runner) Search(query *search.Query, job *workerJob) ([]search.ResultIte
 		}).
 		Infof("Query returned %d results", len(results))
 
-	status.PublishSchemeStatus(r.context, generateSchemeOkStatus(r.definition, results))
+	status.PublishSchemeStatus(ctx, generateSchemeOkStatus(r.definition, results))
 	return results, nil
 }
 
@@ -333,13 +356,16 @@ func (r *Runner) processScrapedItems(scrapeItems source.RawScrapeItems, rowConte
 			r.logger.Errorf("Couldn't extract item: %v", err)
 			continue
 		}
+		if torrentItem, ok := item.(*search.TorrentResultItem); ok && !series.MatchesQuery(rowContext.query, torrentItem) {
+			continue
+		}
 
 		results = append(results, item)
 	}
 	return results
 }
 
-func (r *Runner) resolveItemCategory(query *search.Query, localCats []string, item search.ResultItemBase) bool {
+func (r *Runner) resolveItemCategory(localCats []string, item search.ResultItemBase) bool {
 	if !itemMatchesScheme("torrent", item) {
 		return false
 	}
@@ -358,7 +384,7 @@ func (r *Runner) resolveItemCategory(query *search.Query, localCats []string, it
 	}
 	// Try to map the category from the Indexes to the global indexCategories
 	r.populateCategory(item)
-	return !series.IsSeriesAndNotMatching(query, torrentItem)
+	return true
 }
 
 func (r *Runner) clearDom(dom *goquery.Selection) error {
@@ -480,7 +506,8 @@ func (r *Runner) extractDateHeader(selection source.RawScrapeItem) (time.Time, e
 	return utils.ParseFuzzyTime(dv, time.Now(), true)
 }
 
-func (r *Runner) Ratio() (string, error) {
+// Ratio gets the ratio of the account in the index, the page of the ratio is fetched with the context.
+func (r *Runner) Ratio(ctx context.Context) (string, error) {
 	if r.definition.Ratio.TextVal != "" {
 		return r.definition.Ratio.TextVal, nil
 	}
@@ -489,7 +516,7 @@ func (r *Runner) Ratio() (string, error) {
 		return "unknown", nil
 	}
 
-	if _, err := r.sessions.acquire(); err != nil {
+	if _, err := r.sessions.acquire(ctx); err != nil {
 		return errorValue, err
 	}
 
@@ -498,7 +525,7 @@ func (r *Runner) Ratio() (string, error) {
 		return errorValue, err
 	}
 
-	resultData, err := r.contentFetcher.Fetch(source.NewRequestOptions(ratioURL))
+	resultData, err := r.contentFetcher.Fetch(ctx, source.NewRequestOptions(ratioURL))
 	if err != nil {
 		r.logger.WithError(err).Warn("Failed to open page")
 		return errorValue, nil
@@ -529,8 +556,8 @@ func (r *Runner) Errors() []string {
 	return r.statusReporter.GetErrors()
 }
 
-func (r *Runner) noteError(errorType string, err error) {
-	r.statusReporter.Error(NewError(errorType, err))
+func (r *Runner) noteError(ctx context.Context, errorType string, err error) {
+	r.statusReporter.Error(ctx, NewError(errorType, err))
 }
 
 // region Status messages
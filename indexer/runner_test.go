@@ -1,6 +1,7 @@
 package indexer
 
 import (
+	"context"
 	"errors"
 	"net/url"
 	"strings"
@@ -51,7 +52,7 @@ func getSUT(ctrl *gomock.Controller) *Runner {
 	cfg := &config.ViperConfig{}
 	cfg.Set("db", tempfile())
 	cfg.Set("storage", "boltdb")
-	index := NewRunner(getIndexDefinition(), &RunnerOpts{
+	index, _ := NewRunner(getIndexDefinition(), &RunnerOpts{
 		Config:     cfg,
 		CachePages: false,
 		Transport:  nil,
@@ -73,7 +74,7 @@ func getSUT(ctrl *gomock.Controller) *Runner {
 			<div>b<div class="a">d<a href="/lol">sd</a></div></div>
 			<div class="b"><a>val1</a><p>parrot</p></div>`))
 	fetchResult := &source.HTMLFetchResult{DOM: dom}
-	contentFetcher.EXPECT().Fetch(gomock.Any()).
+	contentFetcher.EXPECT().Fetch(gomock.Any(), gomock.Any()).
 		AnyTimes().
 		Return(fetchResult, nil)
 
@@ -95,7 +96,7 @@ func Test_Should_NotBeAbleToSearch_WithoutURLs(t *testing.T) {
 	urlResolveMock.Return(nil, errors.New("err")).Times(1)
 
 	fields, page := iter.Next()
-	_, err := index.Search(search.NewQuery(), newWorkerJob(nil, index, fields, page))
+	_, err := index.Search(context.Background(), search.NewQuery(), newWorkerJob(nil, nil, index, fields, page))
 
 	g.Expect(err).ToNot(gomega.BeNil())
 }
@@ -114,7 +115,7 @@ func Test_Given_UrlResolverCanNotResolveUrls_Search_Should_ErrorOut(t *testing.T
 	index.definition.Links = []string{}
 
 	fields, page := iter.Next()
-	_, err := index.Search(search.NewQuery(), newWorkerJob(nil, index, fields, page))
+	_, err := index.Search(context.Background(), search.NewQuery(), newWorkerJob(nil, nil, index, fields, page))
 
 	g.Expect(err).ToNot(gomega.BeNil())
 }
@@ -138,7 +139,7 @@ func TestRunner_Search(t *testing.T) {
 	iter := search.NewIterator(search.NewQuery())
 	fields, page := iter.Next()
 
-	results, err := index.Search(search.NewQuery(), newWorkerJob(iter, index, fields, page))
+	results, err := index.Search(context.Background(), search.NewQuery(), newWorkerJob(nil, iter, index, fields, page))
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(results).ToNot(gomega.BeNil())
 	g.Expect(len(results) > 0).To(gomega.BeTrue())
@@ -187,7 +188,7 @@ func Test_ShouldUseUniqueIndexes(t *testing.T) {
 
 	iter := search.NewIterator(search.NewQuery())
 	fields, page := iter.Next()
-	results, err := index.Search(search.NewQuery(), newWorkerJob(nil, index, fields, page))
+	results, err := index.Search(context.Background(), search.NewQuery(), newWorkerJob(nil, nil, index, fields, page))
 
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(results).ToNot(gomega.BeNil())
@@ -222,7 +223,7 @@ func TestRunner_testUrlWorks_ShouldWorkWithOptimisticCaching(t *testing.T) {
 
 func getSearchTemplateDataForNextPage(iter *search.SearchStateIterator, query *search.Query) *SearchTemplateData {
 	fields, page := iter.Next()
-	searchTemplateData := newSearchTemplateData(query, newWorkerJob(nil, nil, fields, page), nil)
+	searchTemplateData := newSearchTemplateData(query, newWorkerJob(nil, nil, nil, fields, page), nil)
 
 	return searchTemplateData
 }
@@ -6,12 +6,27 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/sp0x/torrentd/indexer/categories"
 )
 
+const (
+	// SortByDate sorts the newest results first, it's the default.
+	SortByDate = "date"
+	// SortBySeeders sorts the results with the most seeders first.
+	SortBySeeders = "seeders"
+)
+
+const (
+	// DefaultLimit is the number of results of the torznab queries that have no limit.
+	DefaultLimit = 20
+	// MaxLimit is the highest limit that a torznab query can have.
+	MaxLimit = 100
+)
+
 type Query struct {
 	Type                                         string
 	QueryString, Series, Ep, Season, Movie, Year string
@@ -19,6 +34,12 @@ type Query struct {
 	Extended                                     bool
 	Categories                                   []int
 	APIKey                                       string
+	// Sort is the order of the results of several indexes, see SortByDate and SortBySeeders.
+	Sort string
+
+	// music and book params
+	Artist, Album, Label, Track string
+	Author, Title               string
 
 	// identifier types
 	TVDBID               string
@@ -26,6 +47,7 @@ type Query struct {
 	IMDBID               string
 	TVMazeID             string
 	TraktID              string
+	TMDBID               string
 	Fields               map[string]interface{}
 	StopOnStale          bool
 	NumberOfPagesToFetch uint
@@ -123,7 +145,7 @@ func queryUsesPatterns(query string) bool {
 func getDefaultQuery() *Query {
 	q := &Query{}
 	q.Fields = make(map[string]interface{})
-	q.Limit = 20
+	q.Limit = DefaultLimit
 	return q
 }
 
@@ -151,6 +173,24 @@ func NewQueryFromUrl(v url.Values) (*Query, error) {
 		case "movie":
 			query.Movie = strings.Join(vals, " ")
 
+		case "artist":
+			query.Artist = strings.Join(vals, " ")
+
+		case "album":
+			query.Album = strings.Join(vals, " ")
+
+		case "label":
+			query.Label = strings.Join(vals, " ")
+
+		case "track":
+			query.Track = strings.Join(vals, " ")
+
+		case "author":
+			query.Author = strings.Join(vals, " ")
+
+		case "title":
+			query.Title = strings.Join(vals, " ")
+
 		case "year":
 			if len(vals) > 1 {
 				return query, errors.New("multiple year parameters not allowed")
@@ -184,6 +224,9 @@ func NewQueryFromUrl(v url.Values) (*Query, error) {
 				return query, err
 			}
 			query.Limit = uint(limit)
+			if query.Limit > MaxLimit {
+				query.Limit = MaxLimit
+			}
 
 		case "offset":
 			if len(vals) > 1 {
@@ -195,6 +238,15 @@ func NewQueryFromUrl(v url.Values) (*Query, error) {
 			}
 			query.Offset = uint(offset)
 
+		case "sort":
+			if len(vals) > 1 {
+				return query, errors.New("multiple sort parameters not allowed")
+			}
+			if vals[0] != SortByDate && vals[0] != SortBySeeders {
+				return query, fmt.Errorf("unknown sort %q", vals[0])
+			}
+			query.Sort = vals[0]
+
 		case "extended":
 			if len(vals) > 1 {
 				return query, errors.New("multiple extended parameters not allowed")
@@ -241,6 +293,12 @@ func NewQueryFromUrl(v url.Values) (*Query, error) {
 			}
 			query.IMDBID = vals[0]
 
+		case "tmdbid":
+			if len(vals) > 1 {
+				return query, errors.New("multiple tmdbid parameters not allowed")
+			}
+			query.TMDBID = vals[0]
+
 		default:
 			log.Warningf("Unknown torznab request key %q\n", k)
 		}
@@ -262,7 +320,14 @@ func splitInts(s, delim string) (i []int, err error) {
 
 // Episode returns either the season + episode in the format S00E00 or just the season as S00 if
 // no episode has been specified.
+// Daily shows are returned as 2006.01.02, and episodes without a season as their absolute number, like 05.
 func (query *Query) Episode() (s string) {
+	if airDate := query.AirDate(); airDate != "" {
+		return strings.ReplaceAll(airDate, "-", ".")
+	}
+	if query.Season == "" && query.Ep != "" {
+		return fmt.Sprintf("%02s", query.Ep)
+	}
 	if query.Season != "" {
 		s += fmt.Sprintf("S%02s", query.Season)
 	}
@@ -272,6 +337,20 @@ func (query *Query) Episode() (s string) {
 	return s
 }
 
+// AirDate returns the date of the episode of a daily show as 2006-01-02, or nothing if the query isn't for one.
+// Daily shows are searched with the year as the season and the month/day as the episode.
+func (query *Query) AirDate() string {
+	parts := strings.Split(query.Ep, "/")
+	if len(query.Season) != 4 || len(parts) != 2 {
+		return ""
+	}
+	date, err := time.Parse("2006-1-2", fmt.Sprintf("%s-%s-%s", query.Season, parts[0], parts[1]))
+	if err != nil {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
+
 // AddCategory adds a category to the query
 func (query *Query) AddCategory(cat categories.Category) {
 	if query.Categories == nil {
@@ -320,6 +399,13 @@ func (query *Query) Keywords() string {
 		tokens = append(tokens, query.Movie)
 	}
 
+	// The label isn't a part of the release names, so it's only used by the indexes that declare it.
+	for _, token := range []string{query.Artist, query.Album, query.Track, query.Author, query.Title} {
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+
 	if query.Year != "" {
 		tokens = append(tokens, query.Year)
 	}
@@ -365,6 +451,30 @@ func (query *Query) Encode() string {
 		v.Set("series", query.Series)
 	}
 
+	if query.Artist != "" {
+		v.Set("artist", query.Artist)
+	}
+
+	if query.Album != "" {
+		v.Set("album", query.Album)
+	}
+
+	if query.Label != "" {
+		v.Set("label", query.Label)
+	}
+
+	if query.Track != "" {
+		v.Set("track", query.Track)
+	}
+
+	if query.Author != "" {
+		v.Set("author", query.Author)
+	}
+
+	if query.Title != "" {
+		v.Set("title", query.Title)
+	}
+
 	if query.Offset != 0 {
 		v.Set("offset", strconv.Itoa(int(query.Offset)))
 	}
@@ -377,6 +487,10 @@ func (query *Query) Encode() string {
 		v.Set("extended", "1")
 	}
 
+	if query.Sort != "" {
+		v.Set("sort", query.Sort)
+	}
+
 	if query.APIKey != "" {
 		v.Set("apikey", query.APIKey)
 	}
@@ -411,6 +525,10 @@ func (query *Query) Encode() string {
 		v.Set("imdbid", query.IMDBID)
 	}
 
+	if query.TMDBID != "" {
+		v.Set("tmdbid", query.TMDBID)
+	}
+
 	return v.Encode()
 }
 
@@ -426,3 +544,42 @@ func (query *Query) UniqueKey() interface{} {
 func (query *Query) HasEnoughResults(numberOfResults uint) bool {
 	return query.Limit > 0 && numberOfResults >= query.Limit
 }
+
+// SearchMode gets the torznab search mode of a query type, the aliases like `tvsearch` and `music` are supported.
+func SearchMode(queryType string) string {
+	switch queryType {
+	case "":
+		return "search"
+	case "tvsearch":
+		return "tv-search"
+	case "movie", "moviesearch":
+		return "movie-search"
+	case "music", "musicsearch":
+		return "music-search"
+	case "book", "booksearch":
+		return "book-search"
+	}
+	return queryType
+}
+
+// SearchMode gets the torznab search mode of the query.
+func (query *Query) SearchMode() string {
+	return SearchMode(query.Type)
+}
+
+// IDParams are the torznab params of the ids that the query has, like `imdbid`.
+// Ids that are 0 are ignored, since clients send them when they don't have them.
+func (query *Query) IDParams() []string {
+	var params []string
+	add := func(param, value string) {
+		if value != "" && value != "0" {
+			params = append(params, param)
+		}
+	}
+	add("imdbid", query.IMDBID)
+	add("tvdbid", query.TVDBID)
+	add("tvmazeid", query.TVMazeID)
+	add("rid", query.TVRageID)
+	add("tmdbid", query.TMDBID)
+	return params
+}
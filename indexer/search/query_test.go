@@ -1,6 +1,7 @@
 package search
 
 import (
+	"net/url"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -42,3 +43,43 @@ func TestParseQueryString_Given_RangeQuery_Then_ItShouldBeParsed(t *testing.T) {
 	g.Expect(rangeField[0]).To(BeEquivalentTo("1"))
 	g.Expect(rangeField[1]).To(BeEquivalentTo("200"))
 }
+
+func TestQuery_Episode(t *testing.T) {
+	g := NewGomegaWithT(t)
+	query := &Query{Season: "3", Ep: "5"}
+	g.Expect(query.Episode()).To(Equal("S03E05"))
+	g.Expect(query.AirDate()).To(BeEmpty())
+	query = &Query{Season: "3"}
+	g.Expect(query.Episode()).To(Equal("S03"))
+	query = &Query{Season: "2023", Ep: "10/5"}
+	g.Expect(query.AirDate()).To(Equal("2023-10-05"))
+	g.Expect(query.Episode()).To(Equal("2023.10.05"))
+	query = &Query{Ep: "5"}
+	g.Expect(query.Episode()).To(Equal("05"))
+	query = &Query{Season: "2023", Ep: "13/5"}
+	g.Expect(query.AirDate()).To(BeEmpty())
+}
+
+func TestQuery_MusicAndBookParams(t *testing.T) {
+	g := NewGomegaWithT(t)
+	query, err := NewQueryFromUrl(url.Values{"t": {"music"}, "artist": {"Daft Punk"}, "album": {"Discovery"}, "label": {"Virgin"}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(query.SearchMode()).To(Equal("music-search"))
+	g.Expect(query.Keywords()).To(Equal("Daft Punk Discovery"))
+	g.Expect(query.Encode()).To(ContainSubstring("label=Virgin"))
+
+	query, err = NewQueryFromUrl(url.Values{"t": {"book"}, "author": {"Frank Herbert"}, "title": {"Dune"}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(query.SearchMode()).To(Equal("book-search"))
+	g.Expect(query.Keywords()).To(Equal("Frank Herbert Dune"))
+}
+
+func TestQuery_Sort(t *testing.T) {
+	g := NewGomegaWithT(t)
+	query, err := NewQueryFromUrl(url.Values{"sort": {"seeders"}, "limit": {"500"}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(query.Sort).To(Equal(SortBySeeders))
+	g.Expect(query.Limit).To(BeEquivalentTo(MaxLimit))
+	_, err = NewQueryFromUrl(url.Values{"sort": {"size"}})
+	g.Expect(err).To(HaveOccurred())
+}
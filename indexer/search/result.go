@@ -154,6 +154,14 @@ func (i *ScrapeResultItem) Equals(other interface{}) bool {
 	return true
 }
 
+// IndexName is the name of the index that found the result, or its site if the index isn't known.
+func (i *ScrapeResultItem) IndexName() string {
+	if i.Indexer != nil {
+		return i.Indexer.Name
+	}
+	return i.Site
+}
+
 func (i *ScrapeResultItem) String() string {
 	return fmt.Sprintf("%s: %s", i.LocalID, i.Link)
 }
@@ -30,3 +30,12 @@ func TestExternalResultItem_Equals(t *testing.T) {
 	b.ModelData["a"] = 3
 	g.Expect(a.Equals(b)).To(gomega.BeTrue())
 }
+
+func TestScrapeResultItem_IndexName(t *testing.T) {
+	g := gomega.NewWithT(t)
+	item := &ScrapeResultItem{}
+	item.Site = "zamunda.net"
+	g.Expect(item.IndexName()).To(gomega.Equal("zamunda.net"))
+	item.SetIndexer(&ResultIndexer{Name: "zamunda"})
+	g.Expect(item.IndexName()).To(gomega.Equal("zamunda"))
+}
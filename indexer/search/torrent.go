@@ -41,6 +41,27 @@ type TorrentResultItem struct {
 	Announce          string
 	Publisher         string
 	PublishedWith     string
+
+	// The release info parsed from the title, see release.Parse.
+	ParsedTitle string
+	Year        int
+	Season      int
+	Episode     int
+	// LastEpisode is set for releases with more episodes, like S01E01-E03.
+	LastEpisode     int
+	AbsoluteEpisode int
+	AirDate         string
+	Resolution      string
+	Source          string
+	Codec           string
+	Audio           string
+	Group           string
+	Language        string
+	Proper          bool
+	Repack          bool
+	// The ids of the series or movie, see metadata.Resolver.
+	IMDBID string
+	TVDBID string
 }
 
 func (t *TorrentResultItem) String() string {
@@ -115,12 +136,35 @@ func (t TorrentResultItem) MarshalXML(e *xml.Encoder, _ xml.StartElement) error
 	attribs = append(attribs, torznabAttribute{Name: "minimumseedtime", Value: fmt.Sprint(t.MinimumSeedTime)})
 	attribs = append(attribs, torznabAttribute{Name: "downloadvolumefactor", Value: fmt.Sprint(t.DownloadVolumeFactor)})
 	attribs = append(attribs, torznabAttribute{Name: "uploadvolumefactor", Value: fmt.Sprint(t.UploadVolumeFactor)})
+	if t.Season != 0 {
+		attribs = append(attribs, torznabAttribute{Name: "season", Value: strconv.Itoa(t.Season)})
+	}
+	if episode := t.episodeAttribute(); episode != "" {
+		attribs = append(attribs, torznabAttribute{Name: "episode", Value: episode})
+	}
+	if t.IMDBID != "" {
+		attribs = append(attribs, torznabAttribute{Name: "imdbid", Value: t.IMDBID})
+	}
+	if t.TVDBID != "" {
+		attribs = append(attribs, torznabAttribute{Name: "tvdbid", Value: t.TVDBID})
+	}
 
 	itemView.TorznabAttributes = attribs
 	_ = e.Encode(itemView)
 	return nil
 }
 
+// episodeAttribute gets the episode for torznab, releases with more episodes have their first one.
+func (t *TorrentResultItem) episodeAttribute() string {
+	switch {
+	case t.Episode != 0:
+		return strconv.Itoa(t.Episode)
+	case t.AbsoluteEpisode != 0:
+		return strconv.Itoa(t.AbsoluteEpisode)
+	}
+	return ""
+}
+
 // Equals checks if the other object is equal.
 func (t *TorrentResultItem) Equals(other interface{}) bool {
 	otherTItem, isOkType := other.(*TorrentResultItem)
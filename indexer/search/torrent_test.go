@@ -0,0 +1,37 @@
+package search
+
+import (
+	"encoding/xml"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestTorrentResultItem_MarshalXML_EpisodeAttributes(t *testing.T) {
+	g := NewGomegaWithT(t)
+	item := &TorrentResultItem{Title: "Show.S03E05-E06", Season: 3, Episode: 5, LastEpisode: 6}
+	data, err := xml.Marshal(item)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(ContainSubstring(`<torznab:attr name="season" value="3"></torznab:attr>`))
+	g.Expect(string(data)).To(ContainSubstring(`<torznab:attr name="episode" value="5"></torznab:attr>`))
+
+	item = &TorrentResultItem{Title: "Movie.2020"}
+	data, err = xml.Marshal(item)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).ToNot(ContainSubstring(`name="season"`))
+	g.Expect(string(data)).ToNot(ContainSubstring(`name="episode"`))
+}
+
+func TestTorrentResultItem_MarshalXML_IDAttributes(t *testing.T) {
+	g := NewGomegaWithT(t)
+	item := &TorrentResultItem{Title: "Show.S03E05", IMDBID: "tt0903747", TVDBID: "81189"}
+	data, err := xml.Marshal(item)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).To(ContainSubstring(`<torznab:attr name="imdbid" value="tt0903747"></torznab:attr>`))
+	g.Expect(string(data)).To(ContainSubstring(`<torznab:attr name="tvdbid" value="81189"></torznab:attr>`))
+
+	item = &TorrentResultItem{Title: "Movie.2020"}
+	data, err = xml.Marshal(item)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(string(data)).ToNot(ContainSubstring(`name="imdbid"`))
+}
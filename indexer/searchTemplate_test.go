@@ -20,7 +20,7 @@ func Test_IsComplete_Given_RangeFieldIsUsed_Then_IsComplete_ShouldBeTrue_IfRange
 
 	for i := 1; i < 11; i++ {
 		fields, page := iter.Next()
-		data.Search = newWorkerJob(nil, nil, fields, page)
+		data.Search = newWorkerJob(nil, nil, nil, fields, page)
 		val, _ := data.GetSearchFieldValue("rangeField")
 		g.Expect(val).To(gomega.Equal(fmt.Sprintf("%03d", i)))
 	}
@@ -38,8 +38,18 @@ func Test_ApplyField_Given_RangeField_Should_ReturnCorrectValues(t *testing.T) {
 
 	for i := 1; i < 11; i++ {
 		fields, page := iter.Next()
-		data.Search = newWorkerJob(nil, nil, fields, page)
+		data.Search = newWorkerJob(nil, nil, nil, fields, page)
 		val, _ := data.GetSearchFieldValue("rangeField")
 		g.Expect(val).To(gomega.Equal(fmt.Sprintf("%03d", i)))
 	}
 }
+
+func TestSearchTemplateData_QueryIDs(t *testing.T) {
+	g := gomega.NewWithT(t)
+	query := search.NewQuery()
+	query.IMDBID = "tt0133093"
+	data := newSearchTemplateData(query, nil, nil)
+	value, err := data.ApplyTo("search", "{{ if .Query.IMDBID }}imdb:{{ .Query.IMDBID }}{{ else }}{{ .Keywords }}{{ end }}")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(value).To(gomega.Equal("imdb:tt0133093"))
+}
@@ -0,0 +1,130 @@
+package indexer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	settingText     = "text"
+	settingPassword = "password"
+	settingSelect   = "select"
+	settingCheckbox = "checkbox"
+	settingInt      = "int"
+)
+
+type settingOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// settingOptions are the options of a select setting.
+// They can be a list of values, or a mapping of values to their labels.
+type settingOptions []settingOption
+
+// UnmarshalYAML implements the Unmarshaller interface.
+func (o *settingOptions) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var mapping yaml.MapSlice
+	if err := unmarshal(&mapping); err == nil {
+		for _, item := range mapping {
+			*o = append(*o, settingOption{Value: fmt.Sprint(item.Key), Label: fmt.Sprint(item.Value)})
+		}
+		return nil
+	}
+
+	var values []string
+	if err := unmarshal(&values); err == nil {
+		for _, value := range values {
+			*o = append(*o, settingOption{Value: value, Label: value})
+		}
+		return nil
+	}
+
+	return errors.New("failed to unmarshal setting options")
+}
+
+func (o settingOptions) values() []string {
+	values := make([]string, len(o))
+	for ix, option := range o {
+		values[ix] = option.Value
+	}
+	return values
+}
+
+// SettingsError lists the problems with the configured settings of an index.
+type SettingsError struct {
+	Index    string
+	Problems []string
+}
+
+func (e *SettingsError) Error() string {
+	return fmt.Sprintf("index %s has invalid settings: %s", e.Index, strings.Join(e.Problems, "; "))
+}
+
+// ResolveSettings validates the configured values of an index against its settings, and fills in the defaults.
+// Checkboxes that are off are left empty, so that they can be used in template conditions.
+// Values that aren't in the settings are kept, because templates might still use them.
+func (id *Definition) ResolveSettings(values map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(values))
+	for key, value := range values {
+		resolved[key] = value
+	}
+	var problems []string
+	for _, setting := range id.Settings {
+		value, err := setting.resolve(values[setting.Name])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s %v", setting.Name, err))
+			continue
+		}
+		if value == "" {
+			delete(resolved, setting.Name)
+			continue
+		}
+		resolved[setting.Name] = value
+	}
+	if len(problems) > 0 {
+		return resolved, &SettingsError{Index: id.Name, Problems: problems}
+	}
+	return resolved, nil
+}
+
+func (s *SettingsField) resolve(value string) (string, error) {
+	if value == "" {
+		value = s.Default
+	}
+	if value == "" {
+		if s.Required {
+			return "", errors.New("is required")
+		}
+		return "", nil
+	}
+	switch s.Type {
+	case "", settingText, settingPassword:
+		return value, nil
+	case settingInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("must be a number, got %q", value)
+		}
+		return value, nil
+	case settingCheckbox:
+		checked, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("must be true or false, got %q", value)
+		}
+		if !checked {
+			return "", nil
+		}
+		return "true", nil
+	case settingSelect:
+		if !contains(s.Options.values(), value) {
+			return "", fmt.Errorf("must be one of %s, got %q", strings.Join(s.Options.values(), ", "), value)
+		}
+		return value, nil
+	default:
+		return "", fmt.Errorf("has an unknown type %q", s.Type)
+	}
+}
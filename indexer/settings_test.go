@@ -0,0 +1,115 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/config"
+)
+
+const settingsDefinition = `---
+site: settingstest
+name: settingstest
+links:
+  - http://localhost/
+login:
+  path: /login.php
+  method: post
+  inputs:
+    username: "{{ .Config.username }}"
+    password: "{{ .Config.password }}"
+settings:
+  - name: username
+    type: text
+    required: true
+  - name: password
+    type: password
+    required: true
+  - name: sort
+    type: select
+    default: date
+    options:
+      date: Date
+      seeders: Seeders
+  - name: pages
+    type: int
+    default: 2
+  - name: striprussian
+    type: checkbox
+search:
+  path: /search
+  rows:
+    selector: tr
+  fields:
+    title:
+      selector: td
+`
+
+func TestDefinition_ResolveSettings(t *testing.T) {
+	g := gomega.NewWithT(t)
+	def, err := ParseDefinition([]byte(settingsDefinition))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(def.Settings[2].Options).To(gomega.Equal(settingOptions{
+		{Value: "date", Label: "Date"},
+		{Value: "seeders", Label: "Seeders"},
+	}))
+
+	settings, err := def.ResolveSettings(map[string]string{
+		"username":     "user",
+		"password":     "pass",
+		"striprussian": "false",
+		"other":        "value",
+	})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(settings).To(gomega.Equal(map[string]string{
+		"username": "user",
+		"password": "pass",
+		"sort":     "date",
+		"pages":    "2",
+		"other":    "value",
+	}))
+
+	_, err = def.ResolveSettings(map[string]string{
+		"username":     "user",
+		"sort":         "size",
+		"pages":        "many",
+		"striprussian": "maybe",
+	})
+	g.Expect(err).ToNot(gomega.BeNil())
+	settingsErr, ok := err.(*SettingsError)
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(settingsErr.Problems).To(gomega.Equal([]string{
+		"password is required",
+		`sort must be one of date, seeders, got "size"`,
+		`pages must be a number, got "many"`,
+		`striprussian must be true or false, got "maybe"`,
+	}))
+}
+
+func TestDefinition_ShouldOnlyRequireCredentialsForLogins(t *testing.T) {
+	g := gomega.NewWithT(t)
+	public, _ := ParseDefinition([]byte("site: public\nname: public\n"))
+	private, _ := ParseDefinition([]byte("site: private\nname: private\nlogin:\n  path: /login\n  method: post\n"))
+
+	_, err := public.ResolveSettings(nil)
+	g.Expect(err).To(gomega.BeNil())
+	_, err = private.ResolveSettings(nil)
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestNewRunner_ShouldValidateSettings(t *testing.T) {
+	g := gomega.NewWithT(t)
+	def, _ := ParseDefinition([]byte(settingsDefinition))
+	cfg := &config.ViperConfig{}
+
+	_, err := NewRunner(def, &RunnerOpts{Config: cfg})
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("index settingstest has invalid settings: username is required"))
+
+	_ = cfg.SetSiteOption("settingstest", "username", "user")
+	_ = cfg.SetSiteOption("settingstest", "password", "pass")
+	runner, err := NewRunner(def, &RunnerOpts{Config: cfg})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(runner.settings).To(gomega.HaveKeyWithValue("sort", "date"))
+}
@@ -178,3 +178,74 @@ func filterRegexp(pattern string, value string) (string, error) {
 
 	return matches[0], nil
 }
+
+// Validate checks if a filter with the given name exists and that the arguments have the shape it expects.
+// This mirrors the argument handling in `Filter` so definitions can be checked without any input values.
+func (f *FilterService) Validate(fType string, args interface{}) error {
+	switch fType {
+	case filterQueryString, "trim", "append", "prepend", "urlarg":
+		return requireFilterArgs(fType, args, "string")
+	case "regexp":
+		if err := requireFilterArgs(fType, args, "string"); err != nil {
+			return err
+		}
+		_, err := regexp.Compile(args.(string))
+		return err
+	case filterDate, filterTime, filterDateAlt:
+		if args == nil {
+			return nil
+		}
+		return requireFilterArgs(fType, args, "string")
+	case "bool", "whitespace", "urldecode", "size", "number", "timeago", "fuzzytime", "reltime":
+		return nil
+	case "split":
+		return requireFilterArgs(fType, args, "string", "int")
+	case "replace":
+		return requireFilterArgs(fType, args, "string", "string")
+	case "re_replace":
+		if err := requireFilterArgs(fType, args, "string", "string"); err != nil {
+			return err
+		}
+		_, err := regexp.Compile(args.([]interface{})[0].(string))
+		return err
+	case "mapreplace":
+		if _, ok := args.(map[interface{}]interface{}); !ok {
+			return fmt.Errorf("filter %q requires a map argument", fType)
+		}
+		return nil
+	}
+	return errors.New("Unknown filter " + fType)
+}
+
+// requireFilterArgs checks the arguments against a list of expected types.
+// A single expected type means a scalar argument, more than one means a list.
+func requireFilterArgs(fType string, args interface{}, types ...string) error {
+	if len(types) == 1 {
+		if !isFilterArgOfType(args, types[0]) {
+			return fmt.Errorf("filter %q requires a %s argument", fType, types[0])
+		}
+		return nil
+	}
+	argList, ok := args.([]interface{})
+	if !ok || len(argList) != len(types) {
+		return fmt.Errorf("filter %q requires %d arguments", fType, len(types))
+	}
+	for ix, argType := range types {
+		if !isFilterArgOfType(argList[ix], argType) {
+			return fmt.Errorf("filter %q requires a %s argument at idx %d", fType, argType, ix)
+		}
+	}
+	return nil
+}
+
+func isFilterArgOfType(arg interface{}, argType string) bool {
+	switch argType {
+	case "string":
+		_, ok := arg.(string)
+		return ok
+	case "int":
+		_, ok := arg.(int)
+		return ok
+	}
+	return false
+}
@@ -17,3 +17,14 @@ func Test_invokeFilter_ShouldHandleDateparse(t *testing.T) {
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(result).ToNot(gomega.BeNil())
 }
+
+func TestFilterService_Validate(t *testing.T) {
+	g := gomega.NewWithT(t)
+	f := FilterService{}
+	g.Expect(f.Validate("trim", " ")).To(gomega.BeNil())
+	g.Expect(f.Validate("trim", nil)).ToNot(gomega.BeNil())
+	g.Expect(f.Validate("split", []interface{}{"|", 0})).To(gomega.BeNil())
+	g.Expect(f.Validate("split", "|")).ToNot(gomega.BeNil())
+	g.Expect(f.Validate("regexp", "(a")).ToNot(gomega.BeNil())
+	g.Expect(f.Validate("nosuchfilter", nil)).ToNot(gomega.BeNil())
+}
@@ -5,7 +5,9 @@
 package mocks
 
 import (
+	context "context"
 	io "io"
+	http "net/http"
 	url "net/url"
 	reflect "reflect"
 
@@ -410,18 +412,18 @@ func (mr *MockContentFetcherMockRecorder) Download(buffer interface{}) *gomock.C
 }
 
 // Fetch mocks base method.
-func (m *MockContentFetcher) Fetch(target *source.RequestOptions) (source.FetchResult, error) {
+func (m *MockContentFetcher) Fetch(ctx context.Context, target *source.RequestOptions) (source.FetchResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Fetch", target)
+	ret := m.ctrl.Call(m, "Fetch", ctx, target)
 	ret0, _ := ret[0].(source.FetchResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Fetch indicates an expected call of Fetch.
-func (mr *MockContentFetcherMockRecorder) Fetch(target interface{}) *gomock.Call {
+func (mr *MockContentFetcherMockRecorder) Fetch(ctx, target interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fetch", reflect.TypeOf((*MockContentFetcher)(nil).Fetch), target)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fetch", reflect.TypeOf((*MockContentFetcher)(nil).Fetch), ctx, target)
 }
 
 // Open mocks base method.
@@ -439,6 +441,21 @@ func (mr *MockContentFetcherMockRecorder) Open(options interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Open", reflect.TypeOf((*MockContentFetcher)(nil).Open), options)
 }
 
+// OpenStream mocks base method.
+func (m *MockContentFetcher) OpenStream(ctx context.Context, options *source.RequestOptions) (*http.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStream", ctx, options)
+	ret0, _ := ret[0].(*http.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStream indicates an expected call of OpenStream.
+func (mr *MockContentFetcherMockRecorder) OpenStream(ctx, options interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStream", reflect.TypeOf((*MockContentFetcher)(nil).OpenStream), ctx, options)
+}
+
 // Post mocks base method.
 func (m *MockContentFetcher) Post(options *source.RequestOptions) (source.FetchResult, error) {
 	m.ctrl.T.Helper()
@@ -1,40 +1,50 @@
 package source
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/sp0x/surf/browser"
 	"github.com/sp0x/surf/jar"
+
+	"github.com/sp0x/torrentd/indexer/cassette"
 )
 
 const dumpFormatHTML = "html"
 
+// dumpedCassettes guards the cassettes that dumped requests are added to, since they're shared by all clients.
+var dumpedCassettes sync.Mutex
+
 type dumpData struct {
 	ResponseBodyFile string
 	RequestBodyFile  string
-	State            *jar.State
-	Browser          browser.Browsable
+	// CassetteDir is the cassette that the request is added to, so that dumps can be replayed in definition tests.
+	CassetteDir string
+	State       *jar.State
+	Browser     browser.Browsable
 }
 
 func (d *dumpData) Write() {
-	responseFileWriter, err := os.Create(d.ResponseBodyFile)
-	if err != nil {
-		logrus.Warnf("could not dump response %s", d.ResponseBodyFile)
-		return
-	}
-
-	n, err := d.Browser.Download(responseFileWriter)
+	responseBody := &bytes.Buffer{}
+	n, err := d.Browser.Download(responseBody)
 	if err != nil {
 		logrus.Warnf("could not dump response body %s. %v", d.ResponseBodyFile, err)
 	} else {
 		logrus.Debugf("written response body with size %d bytes", n)
 	}
+	if err = ioutil.WriteFile(d.ResponseBodyFile, responseBody.Bytes(), 0644); err != nil {
+		logrus.Warnf("could not dump response %s", d.ResponseBodyFile)
+		return
+	}
+	d.record(responseBody.Bytes())
 	requestExtension := path.Ext(d.RequestBodyFile)
 	if requestExtension != "" && requestExtension != "." {
 		requestFileWriter, _ := os.Create(d.RequestBodyFile)
@@ -57,15 +67,35 @@ func (d *dumpData) Write() {
 	}
 }
 
+// record adds the request and its response to the cassette of the dumps.
+func (d *dumpData) record(responseBody []byte) {
+	request := d.State.Request
+	var requestBody []byte
+	if request.GetBody != nil {
+		if body, err := request.GetBody(); err == nil {
+			requestBody, _ = ioutil.ReadAll(body)
+		}
+	}
+	dumpedCassettes.Lock()
+	defer dumpedCassettes.Unlock()
+	recording, err := cassette.Open(d.CassetteDir)
+	if err == nil {
+		err = recording.Add(request, requestBody, d.State.Response, responseBody)
+	}
+	if err != nil {
+		logrus.Warnf("could not add the request to the dumped cassette %s. %v", d.CassetteDir, err)
+	}
+}
+
 func (w *WebClient) dumpFetchData() {
 	if !w.options.ShouldDumpData {
 		return
 	}
 	browserState := w.Browser.State()
 	request := browserState.Request
-	dirPath := path.Join("dumps", request.Host)
+	cassetteDir := path.Join("dumps", request.Host)
 	requestURL := request.URL.Path
-	dirPath = path.Join(dirPath,
+	dirPath := path.Join(cassetteDir,
 		strings.ReplaceAll(fmt.Sprintf("%s_%s", request.Method, requestURL), "/", "_"))
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
 		_ = os.MkdirAll(dirPath, 007)
@@ -80,6 +110,7 @@ func (w *WebClient) dumpFetchData() {
 	dump := dumpData{
 		ResponseBodyFile: responseBodyPath,
 		RequestBodyFile:  requestBodyPath,
+		CassetteDir:      cassetteDir,
 		State:            browserState,
 		Browser:          w.Browser,
 	}
@@ -1,30 +1,69 @@
 package series
 
 import (
+	"strconv"
+
 	log "github.com/sirupsen/logrus"
-	releaseinfo "github.com/sp0x/mediareleaseinfo"
 
+	"github.com/sp0x/torrentd/indexer/release"
 	"github.com/sp0x/torrentd/indexer/search"
 )
 
-// Checks if the result is a series result, and ignores it if the title of the series is different.
-func IsSeriesAndNotMatching(query *search.Query, item *search.TorrentResultItem) bool {
-	if query.Series != "" {
-		info, err := releaseinfo.Parse(item.Title)
-		if err != nil {
-			log.
-				WithFields(log.Fields{"title": item.Title}).
-				WithError(err).
-				Warn("Failed to parse show title, skipping")
-			return true
-		}
+// MatchesQuery checks if the release of a result is the series, season and episode that the query is for.
+// Season packs are only returned when a season is searched for, while releases with more episodes match any of them.
+// Daily shows are matched by their air date, and episodes without a season by their absolute number.
+func MatchesQuery(query *search.Query, item *search.TorrentResultItem) bool {
+	if query.Series != "" && item.ParsedTitle != "" && !release.MatchesTitle(item.ParsedTitle, query.Series) {
+		log.
+			WithFields(log.Fields{"got": item.ParsedTitle, "expected": query.Series}).
+			Debugf("Series search skipping non-matching series")
+		return false
+	}
+	if !matchesEpisode(query, item) {
+		log.
+			WithFields(log.Fields{"title": item.Title, "expected": query.Episode()}).
+			Debugf("Series search skipping non-matching episode")
+		return false
+	}
+	return true
+}
 
-		if info != nil && !info.SeriesTitleInfo.Equal(query.Series) {
-			log.
-				WithFields(log.Fields{"got": info.SeriesTitleInfo.TitleWithoutYear, "expected": query.Series}).
-				Debugf("Series search skipping non-matching series")
-			return true
+func matchesEpisode(query *search.Query, item *search.TorrentResultItem) bool {
+	if airDate := query.AirDate(); airDate != "" {
+		return item.AirDate == airDate
+	}
+	season, hasSeason := number(query.Season)
+	episode, hasEpisode := number(query.Ep)
+	switch {
+	case hasSeason && hasEpisode:
+		return item.Season == season && containsEpisode(item.Episode, item.LastEpisode, episode)
+	case hasSeason:
+		return item.Season == season
+	case hasEpisode:
+		if item.AbsoluteEpisode != 0 {
+			return item.AbsoluteEpisode == episode
 		}
+		return item.Season == 0 && containsEpisode(item.Episode, item.LastEpisode, episode)
+	}
+	return true
+}
+
+// containsEpisode checks if the episodes from first to last have the wanted one.
+// Season packs have no episodes.
+func containsEpisode(first, last, episode int) bool {
+	if first == 0 {
+		return false
+	}
+	if last < first {
+		last = first
+	}
+	return first <= episode && episode <= last
+}
+
+func number(value string) (int, bool) {
+	if value == "" {
+		return 0, false
 	}
-	return false
+	n, err := strconv.Atoi(value)
+	return n, err == nil
 }
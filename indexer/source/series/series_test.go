@@ -0,0 +1,55 @@
+package series
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+func TestMatchesQuery(t *testing.T) {
+	g := gomega.NewWithT(t)
+	episode := &search.TorrentResultItem{Title: "The.Expanse.S05E01.1080p", ParsedTitle: "The Expanse", Season: 5, Episode: 1}
+	pack := &search.TorrentResultItem{Title: "The.Expanse.S05.1080p", ParsedTitle: "The Expanse", Season: 5}
+	multi := &search.TorrentResultItem{Title: "The.Expanse.S05E02-E04", ParsedTitle: "The Expanse", Season: 5, Episode: 2, LastEpisode: 4}
+	other := &search.TorrentResultItem{Title: "Other.S05E01", ParsedTitle: "Other", Season: 5, Episode: 1}
+
+	query := search.NewQuery()
+	g.Expect(MatchesQuery(query, other)).To(gomega.BeTrue())
+
+	query.Series = "the expanse"
+	g.Expect(MatchesQuery(query, episode)).To(gomega.BeTrue())
+	g.Expect(MatchesQuery(query, other)).To(gomega.BeFalse())
+
+	// Season packs are only wanted for seasons.
+	query.Season = "05"
+	g.Expect(MatchesQuery(query, episode)).To(gomega.BeTrue())
+	g.Expect(MatchesQuery(query, pack)).To(gomega.BeTrue())
+	g.Expect(MatchesQuery(query, multi)).To(gomega.BeTrue())
+	query.Ep = "1"
+	g.Expect(MatchesQuery(query, episode)).To(gomega.BeTrue())
+	g.Expect(MatchesQuery(query, pack)).To(gomega.BeFalse())
+	g.Expect(MatchesQuery(query, multi)).To(gomega.BeFalse())
+	query.Ep = "3"
+	g.Expect(MatchesQuery(query, episode)).To(gomega.BeFalse())
+	g.Expect(MatchesQuery(query, multi)).To(gomega.BeTrue())
+	query.Season = "4"
+	g.Expect(MatchesQuery(query, multi)).To(gomega.BeFalse())
+}
+
+func TestMatchesQuery_DailyAndAbsolute(t *testing.T) {
+	g := gomega.NewWithT(t)
+	daily := &search.TorrentResultItem{ParsedTitle: "The Daily Show", AirDate: "2023-10-05"}
+	query := &search.Query{Series: "The Daily Show", Season: "2023", Ep: "10/5"}
+	g.Expect(MatchesQuery(query, daily)).To(gomega.BeTrue())
+	query.Ep = "10/6"
+	g.Expect(MatchesQuery(query, daily)).To(gomega.BeFalse())
+
+	anime := &search.TorrentResultItem{ParsedTitle: "Anime Show", AbsoluteEpisode: 1005}
+	query = &search.Query{Series: "Anime Show", Ep: "1005"}
+	g.Expect(MatchesQuery(query, anime)).To(gomega.BeTrue())
+	query.Ep = "1004"
+	g.Expect(MatchesQuery(query, anime)).To(gomega.BeFalse())
+	g.Expect(MatchesQuery(query, &search.TorrentResultItem{ParsedTitle: "Anime Show", Episode: 1004})).To(gomega.BeTrue())
+}
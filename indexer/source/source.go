@@ -1,6 +1,7 @@
 package source
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -118,12 +119,15 @@ type FetchResult interface {
 //go:generate mockgen -source source.go -destination=mocks/source.go -package=mocks
 type ContentFetcher interface {
 	Cleanup()
-	Fetch(target *RequestOptions) (FetchResult, error)
+	// Fetch gets the content of a request, it's cancelled with the context.
+	Fetch(ctx context.Context, target *RequestOptions) (FetchResult, error)
 	Post(options *RequestOptions) (FetchResult, error)
 	URL() *url.URL
 	Clone() ContentFetcher
 	Open(options *RequestOptions) (FetchResult, error)
 	Download(buffer io.Writer) (int64, error)
+	// OpenStream gets the live response of a request, it's cancelled with the context.
+	OpenStream(ctx context.Context, options *RequestOptions) (*http.Response, error)
 	SetErrorHandler(callback func(options *RequestOptions))
 }
 
@@ -1,13 +1,19 @@
 package source
 
 import (
+	"compress/flate"
+	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/sp0x/surf/browser"
@@ -24,12 +30,125 @@ type WebClient struct {
 	Cacher       ContentCacher
 	options      FetchOptions
 	errorHandler func(options *RequestOptions)
+	// transport sends the requests with the context of their fetch, it's nil until SetTransport is used.
+	transport *contextTransport
+	// browserLock guards the state of the browser, which the fetches copy and set, it's set with the transport.
+	browserLock *sync.Mutex
+	// ctx and fetchID are set in the copy of the client that does a fetch.
+	ctx     context.Context
+	fetchID string
+}
+
+// fetchHeader marks the requests of a fetch, so that the transport sends them with the context of the fetch.
+const fetchHeader = "X-Torrentd-Fetch"
+
+// contextTransport sends the requests of a browser with the context of their fetch, so they're cancelled with it.
+// The browser doesn't take contexts, so each fetch marks its requests and the context is looked up for every request.
+// The rate limit of the index is kept here, so that it's shared by the fetches.
+type contextTransport struct {
+	base      http.RoundTripper
+	lock      sync.Mutex
+	fetches   map[string]context.Context
+	lastID    uint64
+	rateLimit time.Duration
+	next      time.Time
+}
+
+// begin registers the context of a fetch and returns the id that marks its requests.
+func (t *contextTransport) begin(ctx context.Context) string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.fetches == nil {
+		t.fetches = make(map[string]context.Context)
+	}
+	t.lastID++
+	id := strconv.FormatUint(t.lastID, 10)
+	t.fetches[id] = ctx
+	return id
+}
+
+func (t *contextTransport) end(id string) {
+	t.lock.Lock()
+	delete(t.fetches, id)
+	t.lock.Unlock()
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if id := req.Header.Get(fetchHeader); id != "" {
+		t.lock.Lock()
+		ctx := t.fetches[id]
+		t.lock.Unlock()
+		if ctx != nil {
+			req = req.WithContext(ctx)
+		}
+		// The request is a copy, so the header can be removed without changing the one of the browser.
+		req.Header = req.Header.Clone()
+		req.Header.Del(fetchHeader)
+	}
+	if err := t.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// wait waits for the turn of a request, so that there's at least the rate limit between the requests.
+func (t *contextTransport) wait(ctx context.Context) error {
+	t.lock.Lock()
+	if t.rateLimit <= 0 {
+		t.lock.Unlock()
+		return nil
+	}
+	now := time.Now()
+	turn := t.next
+	if turn.Before(now) {
+		turn = now
+	}
+	t.next = turn.Add(t.rateLimit)
+	t.lock.Unlock()
+	delay := turn.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
 func (w *WebClient) SetErrorHandler(callback func(options *RequestOptions)) {
 	w.errorHandler = callback
 }
 
+// SetTransport sets the transport of the browser, so that fetches can be cancelled with their context.
+func (w *WebClient) SetTransport(transport http.RoundTripper) {
+	w.transport = &contextTransport{base: transport}
+	w.browserLock = &sync.Mutex{}
+	w.Browser.SetTransport(w.transport)
+}
+
+// SetRateLimit sets the ms between the requests of the client.
+// With a transport the limit is shared by the fetches, which don't run in the browser itself.
+func (w *WebClient) SetRateLimit(msBetweenRequests int) {
+	if w.transport == nil {
+		w.Browser.SetRateLimit(msBetweenRequests)
+		return
+	}
+	w.transport.lock.Lock()
+	w.transport.rateLimit = time.Duration(msBetweenRequests) * time.Millisecond
+	w.transport.lock.Unlock()
+}
+
+// reportError calls the error handler, unless the request failed because its fetch was cancelled.
+func (w *WebClient) reportError(req *RequestOptions) {
+	if w.errorHandler != nil && (w.ctx == nil || w.ctx.Err() == nil) {
+		w.errorHandler(req)
+	}
+}
+
 func NewWebContentFetcher(browser browser.Browsable,
 	contentCache ContentCacher,
 	options FetchOptions) *WebClient {
@@ -52,32 +171,65 @@ func (w *WebClient) Cleanup() {
 }
 
 // Gets the content from which we'll extract the search results
-func (w *WebClient) Fetch(req *RequestOptions) (FetchResult, error) {
+func (w *WebClient) Fetch(ctx context.Context, req *RequestOptions) (FetchResult, error) {
 	if req == nil {
 		return nil, errors.New("req is required for searching")
 	}
-	defer func() {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, done := w.forFetch(ctx)
+	defer done()
+	return f.fetch(ctx, req)
+}
+
+// forFetch gets the client that does a fetch, it's a copy with its own tab of the browser, so that fetches don't
+// change each other's state. The returned func keeps the state of the fetch in the browser of the client.
+func (w *WebClient) forFetch(ctx context.Context) (*WebClient, func()) {
+	if w.transport == nil {
 		// After we're done we'll cleanup the history of the browser.
-		w.Cleanup()
-	}()
+		return w, w.Cleanup
+	}
+	f := &WebClient{}
+	*f = *w
+	f.Browser = w.newTab()
+	f.ctx = ctx
+	f.fetchID = w.transport.begin(ctx)
+	f.Browser.SetHeadersJar(f.headers(""))
+	return f, func() {
+		w.transport.end(f.fetchID)
+		w.browserLock.Lock()
+		w.Browser.SetState(f.Browser.State())
+		w.browserLock.Unlock()
+	}
+}
+
+// newTab copies the browser with its own history, since the history of a browser isn't safe for concurrent use.
+func (w *WebClient) newTab() browser.Browsable {
+	if w.browserLock != nil {
+		w.browserLock.Lock()
+		defer w.browserLock.Unlock()
+	}
+	tab := w.Browser.NewTab()
+	tab.SetHistoryJar(jar.NewMemoryHistory())
+	return tab
+}
+
+func (w *WebClient) fetch(ctx context.Context, req *RequestOptions) (FetchResult, error) {
 	var err error
 	var result FetchResult
 	switch req.Method {
 	case "", searchMethodGet:
 		if err = w.get(req); err != nil {
-			if w.errorHandler != nil {
-				w.errorHandler(req)
-			}
-			return nil, err
+			w.reportError(req)
+			return nil, fetchError(ctx, err)
 		}
 		result = extractResponseResult(w.Browser)
 	case searchMethodPost:
 		postResult, err := w.Post(req)
 		if err != nil {
-			if w.errorHandler != nil {
-				w.errorHandler(req)
-			}
-			return nil, err
+			w.reportError(req)
+			return nil, fetchError(ctx, err)
 		}
 		result = postResult
 
@@ -89,6 +241,14 @@ func (w *WebClient) Fetch(req *RequestOptions) (FetchResult, error) {
 	return result, nil
 }
 
+// fetchError is the error of the context if the fetch failed because it's done.
+func fetchError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
 func extractResponseResult(browser browser.Browsable) FetchResult {
 	state := browser.State()
 	if state.Response == nil {
@@ -139,9 +299,7 @@ func (w *WebClient) get(req *RequestOptions) error {
 	}
 
 	if err = w.handleMetaRefreshHeader(req); err != nil {
-		if w.errorHandler != nil {
-			w.errorHandler(req)
-		}
+		w.reportError(req)
 		return err
 	}
 	return nil
@@ -156,15 +314,25 @@ func (w *WebClient) applyOptions(reqOptions *RequestOptions) {
 	}
 
 	if referer != "" {
-		w.Browser.SetHeadersJar(http.Header{
-			"referer": []string{referer},
-		})
+		w.Browser.SetHeadersJar(w.headers(referer))
 	}
 	if reqOptions.CookieJar != nil {
 		w.Browser.SetCookieJar(reqOptions.CookieJar)
 	}
 }
 
+// headers are the headers of the requests, with the mark of the fetch that sends them.
+func (w *WebClient) headers(referer string) http.Header {
+	headers := http.Header{}
+	if referer != "" {
+		headers.Set("referer", referer)
+	}
+	if w.fetchID != "" {
+		headers.Set(fetchHeader, w.fetchID)
+	}
+	return headers
+}
+
 func (w *WebClient) URL() *url.URL {
 	return w.Browser.Url()
 }
@@ -172,7 +340,10 @@ func (w *WebClient) URL() *url.URL {
 func (w *WebClient) Clone() ContentFetcher {
 	f := &WebClient{}
 	*f = *w
-	f.Browser = f.Browser.NewTab()
+	f.Browser = w.newTab()
+	if w.browserLock != nil {
+		f.browserLock = &sync.Mutex{}
+	}
 	return f
 }
 
@@ -191,6 +362,80 @@ func (w *WebClient) Download(buffer io.Writer) (int64, error) {
 	return w.Browser.Download(buffer)
 }
 
+// OpenStream sends a request with the cookies of the browser and returns the live response, so that its body can be
+// streamed instead of being read into memory. The request is cancelled with the context.
+// Compressed bodies are decoded, their length isn't known until they're read, so the content length is -1 for them.
+func (w *WebClient) OpenStream(ctx context.Context, opts *RequestOptions) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, opts.URL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if w.options.UserAgent != "" {
+		req.Header.Set("User-Agent", w.options.UserAgent)
+	}
+	if opts.Referer != nil {
+		req.Header.Set("Referer", opts.Referer.String())
+	}
+	var transport http.RoundTripper = http.DefaultTransport
+	if w.transport != nil {
+		transport = w.transport
+	}
+	jar := w.Browser.CookieJar()
+	if opts.CookieJar != nil {
+		jar = opts.CookieJar
+	}
+	client := &http.Client{Transport: transport, Jar: jar}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fetchError(ctx, err)
+	}
+	if err := decodeResponse(resp); err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// decodeResponse replaces the body of a compressed response with its decoded content.
+func decodeResponse(resp *http.Response) error {
+	encoding := resp.Header.Get("Content-Encoding")
+	var decoded io.ReadCloser
+	switch encoding {
+	case "":
+		if resp.Uncompressed {
+			resp.ContentLength = -1
+		}
+		return nil
+	case "gzip":
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		decoded = reader
+	case "deflate":
+		decoded = flate.NewReader(resp.Body)
+	default:
+		return fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	resp.Body = &decodedBody{ReadCloser: decoded, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	return nil
+}
+
+// decodedBody closes the raw body of a response along with its decoder.
+type decodedBody struct {
+	io.ReadCloser
+	raw io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	_ = b.ReadCloser.Close()
+	return b.raw.Close()
+}
+
 func (w *WebClient) Post(reqOps *RequestOptions) (FetchResult, error) {
 	urlStr := reqOps.URL
 	values := reqOps.Values
@@ -225,9 +470,7 @@ func (w *WebClient) handleMetaRefreshHeader(reqOptions *RequestOptions) error {
 
 			err := w.get(reqOptions)
 			if err != nil {
-				if w.errorHandler != nil {
-					w.errorHandler(reqOptions)
-				}
+				w.reportError(reqOptions)
 			}
 			return err
 		}
@@ -0,0 +1,213 @@
+package source
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/sp0x/surf"
+)
+
+func TestWebClient_FetchIsCancelledWithItsContext(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte("<html><body><a>result</a></body></html>"))
+	}))
+	defer server.Close()
+	client := NewWebContentFetcher(surf.NewBrowser(), nil, FetchOptions{})
+	client.SetTransport(http.DefaultTransport)
+	failed := 0
+	client.SetErrorHandler(func(*RequestOptions) {
+		failed++
+	})
+
+	slowURL, _ := url.Parse(server.URL + "/slow")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := client.Fetch(ctx, NewRequestOptions(slowURL))
+	g.Expect(err).To(gomega.Equal(context.DeadlineExceeded))
+	g.Expect(time.Since(started)).To(gomega.BeNumerically("<", 5*time.Second))
+	// The site didn't fail, so it isn't reported.
+	g.Expect(failed).To(gomega.Equal(0))
+
+	_, err = client.Fetch(ctx, NewRequestOptions(slowURL))
+	g.Expect(err).To(gomega.Equal(context.DeadlineExceeded))
+
+	pageURL, _ := url.Parse(server.URL + "/")
+	result, err := client.Fetch(context.Background(), NewRequestOptions(pageURL))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(result.Find("a").Length()).To(gomega.Equal(1))
+}
+
+func TestWebClient_FetchesRunConcurrentlyWithTheirOwnContext(t *testing.T) {
+	g := gomega.NewWithT(t)
+	arrived := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		_, _ = w.Write([]byte("<html><body><a>" + r.URL.Path + "</a></body></html>"))
+	}))
+	defer server.Close()
+	client := NewWebContentFetcher(surf.NewBrowser(), nil, FetchOptions{})
+	client.SetTransport(http.DefaultTransport)
+
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancelledURL, _ := url.Parse(server.URL + "/cancelled")
+	pageURL, _ := url.Parse(server.URL + "/page")
+	cancelledErr := make(chan error, 1)
+	go func() {
+		_, err := client.Fetch(cancelledCtx, NewRequestOptions(cancelledURL))
+		cancelledErr <- err
+	}()
+	pageResult := make(chan FetchResult, 1)
+	go func() {
+		result, _ := client.Fetch(context.Background(), NewRequestOptions(pageURL))
+		pageResult <- result
+	}()
+	// Both requests are sent before either of them is answered.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-arrived:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the fetches weren't sent concurrently")
+		}
+	}
+	cancel()
+	g.Expect(<-cancelledErr).To(gomega.Equal(context.Canceled))
+	close(release)
+	result := <-pageResult
+	g.Expect(result).ToNot(gomega.BeNil())
+	g.Expect(result.Find("a").Length()).To(gomega.Equal(1))
+}
+
+func TestWebClient_ConcurrentFetchesGetTheirOwnPages(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body><a>" + r.URL.Path + "</a></body></html>"))
+	}))
+	defer server.Close()
+	client := NewWebContentFetcher(surf.NewBrowser(), nil, FetchOptions{})
+	client.SetTransport(http.DefaultTransport)
+
+	var wg sync.WaitGroup
+	texts := make([]string, 8)
+	errs := make([]error, 8)
+	for i := range texts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pageURL, _ := url.Parse(fmt.Sprintf("%s/page%d", server.URL, i))
+			result, err := client.Fetch(context.Background(), NewRequestOptions(pageURL))
+			errs[i] = err
+			if err == nil {
+				texts[i] = result.(*HTMLFetchResult).DOM.Find("a").Text()
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := range texts {
+		g.Expect(errs[i]).ToNot(gomega.HaveOccurred())
+		g.Expect(texts[i]).To(gomega.Equal(fmt.Sprintf("/page%d", i)))
+	}
+	// The client has the state of one of the fetches.
+	g.Expect(client.URL().Path).To(gomega.HavePrefix("/page"))
+}
+
+func TestWebClient_SetRateLimitIsSharedByTheFetches(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+	client := NewWebContentFetcher(surf.NewBrowser(), nil, FetchOptions{})
+	client.SetTransport(http.DefaultTransport)
+	client.SetRateLimit(100)
+
+	pageURL, _ := url.Parse(server.URL + "/")
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := client.Fetch(context.Background(), NewRequestOptions(pageURL))
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+	}
+	g.Expect(time.Since(started)).To(gomega.BeNumerically(">=", 200*time.Millisecond))
+}
+
+func TestWebClient_OpenStreamDecodesCompressedBodies(t *testing.T) {
+	g := gomega.NewWithT(t)
+	content := []byte("d8:announce3:url4:infod4:name4:testee")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			_, _ = w.Write(content)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		_, _ = writer.Write(content)
+		_ = writer.Close()
+	}))
+	defer server.Close()
+	client := NewWebContentFetcher(surf.NewBrowser(), nil, FetchOptions{})
+
+	plainURL, _ := url.Parse(server.URL + "/plain")
+	response, err := client.OpenStream(context.Background(), NewRequestOptions(plainURL))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	body, _ := ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	g.Expect(body).To(gomega.Equal(content))
+	g.Expect(response.ContentLength).To(gomega.Equal(int64(len(content))))
+
+	// The length of the compressed body isn't the one that's read.
+	gzipURL, _ := url.Parse(server.URL + "/gzip")
+	response, err = client.OpenStream(context.Background(), NewRequestOptions(gzipURL))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	body, _ = ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	g.Expect(body).To(gomega.Equal(content))
+	g.Expect(response.ContentLength).To(gomega.Equal(int64(-1)))
+}
+
+func TestWebClient_OpenStreamIsCancelledWithItsContext(t *testing.T) {
+	g := gomega.NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("d8:announce"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client := NewWebContentFetcher(surf.NewBrowser(), nil, FetchOptions{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	streamURL, _ := url.Parse(server.URL + "/")
+	response, err := client.OpenStream(ctx, NewRequestOptions(streamURL))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	defer func() {
+		_ = response.Body.Close()
+	}()
+	// The start of the body is streamed before the rest is sent.
+	start := make([]byte, len("d8:announce"))
+	_, err = io.ReadFull(response.Body, start)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	cancel()
+	_, err = ioutil.ReadAll(response.Body)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
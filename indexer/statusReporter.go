@@ -9,7 +9,6 @@ import (
 )
 
 type StatusReporter struct {
-	context         context.Context
 	indexDefinition *Definition
 	errors          cache.LRUCache
 }
@@ -30,11 +29,12 @@ func NewError(errType string, err error) *ReportableError {
 	}
 }
 
-func (r *StatusReporter) Error(err *ReportableError) {
+// Error publishes the error with the context of the search that failed, and keeps it in the errors of the index.
+func (r *StatusReporter) Error(ctx context.Context, err *ReportableError) {
 	if err == nil {
 		return
 	}
-	status.PublishSchemeError(r.context, generateSchemeErrorStatus(err.Type, err.error, r.indexDefinition))
+	status.PublishSchemeError(ctx, generateSchemeErrorStatus(err.Type, err.error, r.indexDefinition))
 
 	errorID := r.errors.Len()
 	r.errors.Add(errorID, err)
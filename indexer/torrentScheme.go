@@ -8,6 +8,7 @@ import (
 	"github.com/dustin/go-humanize"
 
 	"github.com/sp0x/torrentd/indexer/formatting"
+	"github.com/sp0x/torrentd/indexer/release"
 	"github.com/sp0x/torrentd/indexer/search"
 	"github.com/sp0x/torrentd/indexer/utils"
 )
@@ -17,7 +18,28 @@ func (r *Runner) populateTorrentData(resultItem search.ResultItemBase, context *
 	item := resultItem.(*search.TorrentResultItem)
 
 	item.Fingerprint = formatting.GetResultFingerprint(item.Title)
-	r.resolveItemCategory(context.query, context.indexCategories, item)
+	populateReleaseInfo(item)
+	r.resolveItemCategory(context.indexCategories, item)
+}
+
+// populateReleaseInfo sets the info that's parsed from the title of the release.
+func populateReleaseInfo(item *search.TorrentResultItem) {
+	info := release.Parse(item.Title)
+	item.ParsedTitle = info.Title
+	item.Year = info.Year
+	item.Season = info.Season
+	item.Episode = info.Episode
+	item.LastEpisode = info.LastEpisode
+	item.AbsoluteEpisode = info.AbsoluteEpisode
+	item.AirDate = info.AirDate
+	item.Resolution = info.Resolution
+	item.Source = info.Source
+	item.Codec = info.Codec
+	item.Audio = info.Audio
+	item.Group = info.Group
+	item.Language = info.Language
+	item.Proper = info.Proper
+	item.Repack = info.Repack
 }
 
 func (r *Runner) populateTorrentItemField(
@@ -0,0 +1,25 @@
+package indexer
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+func TestPopulateReleaseInfo(t *testing.T) {
+	g := gomega.NewWithT(t)
+	item := &search.TorrentResultItem{Title: "The.Expanse.S05E01.REPACK.1080p.WEB-DL.DDP5.1.H.264-NTb"}
+	populateReleaseInfo(item)
+	g.Expect(item.ParsedTitle).To(gomega.Equal("The Expanse"))
+	g.Expect(item.Season).To(gomega.Equal(5))
+	g.Expect(item.Episode).To(gomega.Equal(1))
+	g.Expect(item.Resolution).To(gomega.Equal("1080p"))
+	g.Expect(item.Source).To(gomega.Equal("webdl"))
+	g.Expect(item.Codec).To(gomega.Equal("h264"))
+	g.Expect(item.Audio).To(gomega.Equal("eac3"))
+	g.Expect(item.Group).To(gomega.Equal("NTb"))
+	g.Expect(item.Repack).To(gomega.BeTrue())
+	g.Expect(item.Proper).To(gomega.BeFalse())
+}
@@ -1,6 +1,7 @@
 package indexer
 
 import (
+	"context"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -14,10 +15,10 @@ func GetAllPagesFromIndex(facade *Facade, query *search.Query) <-chan search.Res
 	if query == nil {
 		query = search.NewQuery()
 	}
-	maxPages := facade.Indexes.MaxSearchPages()
+	maxPages := facade.CurrentIndexes().MaxSearchPages()
 	query.NumberOfPagesToFetch = maxPages
 	query.StopOnStale = true
-	resultsChan, _ := facade.Search(query)
+	resultsChan, _ := facade.Search(context.Background(), query)
 	go func() {
 		for items := range resultsChan {
 			for _, item := range items {
@@ -37,11 +38,11 @@ func Watch(facade *Facade, initialQuery *search.Query, intervalSec int) <-chan s
 		initialQuery = search.NewQuery()
 	}
 	startingPage := initialQuery.Page
-	initialQuery.NumberOfPagesToFetch = facade.Indexes.MaxSearchPages()
+	initialQuery.NumberOfPagesToFetch = facade.CurrentIndexes().MaxSearchPages()
 	initialQuery.StopOnStale = true
 	go func() {
 		for {
-			results, err := facade.Search(initialQuery)
+			results, err := facade.Search(context.Background(), initialQuery)
 			if err != nil {
 				switch err.(type) {
 				case *LoginError:
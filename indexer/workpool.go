@@ -1,6 +1,7 @@
 package indexer
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -11,12 +12,72 @@ import (
 )
 
 type indexWorkerPool struct {
+	// ctx stops the search once it's done, the jobs that are left aren't searched.
+	ctx                 context.Context
 	storage             storage.ItemStorage
 	completionWaitGroup sync.WaitGroup
 	iterators           map[Indexer]*search.SearchStateIterator
 	query               *search.Query
 	workChannel         chan *workerJob
 	resultsChannel      chan []search.ResultItemBase
+	// queries are the queries of each index, see Facade.indexQueries.
+	queries map[Indexer]*search.Query
+	errors  *IndexErrors
+	// progress is optional, it gets the pages, the errors and the completion of each index.
+	progress     func(IndexProgress)
+	progressLock sync.Mutex
+	pendingJobs  map[Indexer]int
+	finished     map[Indexer]bool
+}
+
+// IndexProgress is a step in the search of an index, see Facade.SearchWithProgress.
+// It's either a searched page with its results or its error, or the completion of the index.
+type IndexProgress struct {
+	Index   Indexer
+	Page    uint
+	Results []search.ResultItemBase
+	Err     error
+	// Done is set once the index won't be searched anymore.
+	Done bool
+}
+
+// IndexErrors are the errors of the indexes that failed in a search, the other indexes still have their results.
+type IndexErrors struct {
+	lock   sync.Mutex
+	errors map[string]error
+}
+
+func newIndexErrors() *IndexErrors {
+	return &IndexErrors{errors: make(map[string]error)}
+}
+
+// add keeps the first error of an index.
+func (e *IndexErrors) add(index Indexer, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	site := index.Site()
+	if _, exists := e.errors[site]; !exists {
+		e.errors[site] = err
+	}
+}
+
+func (e *IndexErrors) has(index Indexer) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	_, exists := e.errors[index.Site()]
+	return exists
+}
+
+// All gets the errors by the site of their index.
+// They're complete once the results channel of the search is closed.
+func (e *IndexErrors) All() map[string]error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	all := make(map[string]error, len(e.errors))
+	for site, err := range e.errors {
+		all[site] = err
+	}
+	return all
 }
 
 type workerJob struct {
@@ -43,50 +104,62 @@ func newWorkerJob(pool *indexWorkerPool,
 
 // feedWorkerPool Iterate over the index search iterators and add the data to the work channel
 func (f *Facade) feedWorkerPool(pool *indexWorkerPool) {
+	defer close(pool.workChannel)
 	for !pool.isComplete() {
 		for indexForIterator, iterator := range pool.iterators {
-			if iterator.IsComplete() || pool.isComplete() {
+			if iterator.IsComplete() || pool.errors.has(indexForIterator) || pool.isComplete() {
 				continue
 			}
 
+			// The job is pending before the iterator moves to the next page, so that the index isn't done early.
+			pool.startJob(indexForIterator)
 			fields, page := iterator.Next()
 			nextJob := newWorkerJob(pool, iterator, indexForIterator, fields, page)
 			f.logger.Debugf("Adding job %v to work channel", nextJob)
-			pool.workChannel <- nextJob
+			select {
+			case pool.workChannel <- nextJob:
+			case <-pool.ctx.Done():
+				// The workers might be stuck, so the job isn't added once the search is cancelled.
+				pool.finishJob(nextJob, nil, nil, false)
+				return
+			}
 			if iterator.IsComplete() {
 				f.logger.Debugf("Completed iterator %p for index %v", iterator, indexForIterator.GetDefinition().Name)
 			}
 		}
 	}
-	close(pool.workChannel)
 }
 
 func (p *indexWorkerPool) isComplete() bool {
-	if len(p.iterators) == 0 {
+	if len(p.iterators) == 0 || p.ctx.Err() != nil {
+		return true
+	}
+	if p.hasEnoughResults() {
 		return true
 	}
-	totalItemsDiscovered := uint(0)
-	copletedAllIterators := true
-
-	for _, iterator := range p.iterators {
-		totalItemsDiscovered += iterator.GetItemsDiscoveredCount()
-		if p.query.HasEnoughResults(totalItemsDiscovered) {
-			return true
-		}
 
-		if copletedAllIterators && !iterator.IsComplete() {
-			copletedAllIterators = false
+	for index, iterator := range p.iterators {
+		// The indexes that failed aren't searched again
+		if !iterator.IsComplete() && !p.errors.has(index) {
+			return false
 		}
 	}
+	return true
+}
 
-	return copletedAllIterators
+// hasEnoughResults checks if the indexes found all the results that the query needs.
+func (p *indexWorkerPool) hasEnoughResults() bool {
+	totalItemsDiscovered := uint(0)
+	for _, iterator := range p.iterators {
+		totalItemsDiscovered += iterator.GetItemsDiscoveredCount()
+	}
+	return p.query.HasEnoughResults(totalItemsDiscovered)
 }
 
 //region Workers
 
 func (f *Facade) runWorker(
 	id int,
-	query *search.Query,
 	pool *indexWorkerPool,
 	wg *sync.WaitGroup,
 	resultStorage storage.ItemStorage,
@@ -94,16 +167,37 @@ func (f *Facade) runWorker(
 	resultsChannel chan<- []search.ResultItemBase) {
 
 	for workJob := range workChannel {
-		if pool.isComplete() || workJob.Iterator.IsComplete() {
-			break
+		// The jobs that are left once the search is cancelled fail with the error of its context.
+		if err := pool.ctx.Err(); err != nil {
+			pool.errors.add(workJob.Index, err)
+			pool.finishJob(workJob, nil, err, true)
+			continue
+		}
+		// The jobs that are left once there are enough results are skipped.
+		// The iterators are complete once their last page is added, so the pool can be complete while its jobs are searched.
+		if pool.hasEnoughResults() {
+			pool.finishJob(workJob, nil, nil, false)
+			continue
 		}
 		log.Debugf("Got work job: %v", workJob)
-		searchResults, err := workJob.Index.Search(query, workJob)
+		searchResults, err := workJob.Index.Search(pool.ctx, pool.queryFor(workJob.Index), workJob)
 		if err != nil {
-			log.WithFields(log.Fields{}).Errorf("Couldn't persist item: %s\n", err)
+			logger := log.WithFields(log.Fields{"index": workJob.Index.Site()})
+			if pool.ctx.Err() != nil {
+				logger.Debugf("Search stopped: %s\n", err)
+			} else {
+				logger.Warningf("Couldn't search: %s\n", err)
+			}
+			pool.errors.add(workJob.Index, err)
+			pool.finishJob(workJob, nil, err, true)
 			continue
 		}
 
+		if f.Enricher != nil {
+			for _, item := range searchResults {
+				f.Enricher.EnrichResult(item)
+			}
+		}
 		if saveResultsOnDiscovery {
 			saveDiscoveredItems(searchResults, resultStorage)
 		}
@@ -111,11 +205,13 @@ func (f *Facade) runWorker(
 		workJob.Iterator.UpdateIteratorState(searchResults)
 
 		if searchResults != nil {
-			resultsChannel <- searchResults
-		}
-		if workJob.Iterator.IsComplete() {
-			break
+			// The results aren't read anymore once the search is cancelled.
+			select {
+			case resultsChannel <- searchResults:
+			case <-pool.ctx.Done():
+			}
 		}
+		pool.finishJob(workJob, searchResults, nil, true)
 	}
 
 	wg.Done()
@@ -133,32 +229,93 @@ func saveDiscoveredItems(searchResults []search.ResultItemBase, resultStorage st
 }
 
 /// createWorkerPool Creates a pool of workers that run in the background using work and results channels
-func (f Facade) createWorkerPool(indexes []Indexer, resultStorage storage.ItemStorage, query *search.Query, workerCount int) *indexWorkerPool {
-	workerPool := &indexWorkerPool{}
+func (f *Facade) createWorkerPool(ctx context.Context, queries map[Indexer]*search.Query, resultStorage storage.ItemStorage, query *search.Query,
+	workerCount int, progress func(IndexProgress)) *indexWorkerPool {
+	workerPool := &indexWorkerPool{ctx: ctx}
 	workerPool.workChannel = make(chan *workerJob, workerCount)
 	workerPool.resultsChannel = make(chan []search.ResultItemBase, workerCount)
 	workerPool.storage = resultStorage
 	workerPool.iterators = make(map[Indexer]*search.SearchStateIterator)
 	workerPool.query = query
+	workerPool.queries = queries
+	workerPool.errors = newIndexErrors()
+	workerPool.progress = progress
+	workerPool.pendingJobs = make(map[Indexer]int)
+	workerPool.finished = make(map[Indexer]bool)
 
 	for workerNumber := 0; workerNumber < workerCount; workerNumber++ {
 		workerPool.completionWaitGroup.Add(1)
-		go f.runWorker(workerNumber, query, workerPool, &workerPool.completionWaitGroup, resultStorage, workerPool.workChannel, workerPool.resultsChannel)
+		go f.runWorker(workerNumber, workerPool, &workerPool.completionWaitGroup, resultStorage, workerPool.workChannel, workerPool.resultsChannel)
 	}
 
-	for _, index := range indexes {
-		workerPool.iterators[index] = search.NewIterator(query)
+	for index, indexQuery := range queries {
+		workerPool.iterators[index] = search.NewIterator(indexQuery)
 	}
 
 	go func() {
 		// Wait for pool to be complete and close the results channel
 		workerPool.completionWaitGroup.Wait()
+		workerPool.finishAll()
 		close(workerPool.resultsChannel)
 	}()
 
 	return workerPool
 }
 
+// queryFor gets the query that an index searches with.
+func (p *indexWorkerPool) queryFor(index Indexer) *search.Query {
+	if indexQuery, ok := p.queries[index]; ok {
+		return indexQuery
+	}
+	return p.query
+}
+
+func (p *indexWorkerPool) startJob(index Indexer) {
+	p.progressLock.Lock()
+	defer p.progressLock.Unlock()
+	p.pendingJobs[index]++
+}
+
+// finishJob reports the page of a job if it was searched, and the completion of its index if it has no more jobs.
+// The progress is reported with the lock, so that the steps of an index are in their order.
+func (p *indexWorkerPool) finishJob(job *workerJob, results []search.ResultItemBase, err error, searched bool) {
+	p.progressLock.Lock()
+	defer p.progressLock.Unlock()
+	p.pendingJobs[job.Index]--
+	if searched && p.progress != nil {
+		p.progress(IndexProgress{Index: job.Index, Page: job.Page, Results: results, Err: err})
+	}
+	if p.pendingJobs[job.Index] > 0 || p.finished[job.Index] {
+		return
+	}
+	if job.Iterator.IsComplete() || p.errors.has(job.Index) || p.hasEnoughResults() {
+		p.finish(job.Index)
+	}
+}
+
+// finishAll reports the completion of the indexes that were stopped with the search.
+// If the search was cancelled, the indexes that didn't search all their pages fail with the error of its context.
+func (p *indexWorkerPool) finishAll() {
+	p.progressLock.Lock()
+	defer p.progressLock.Unlock()
+	ctxErr := p.ctx.Err()
+	for index, iterator := range p.iterators {
+		if ctxErr != nil && !iterator.IsComplete() && !p.hasEnoughResults() {
+			p.errors.add(index, ctxErr)
+		}
+		if !p.finished[index] {
+			p.finish(index)
+		}
+	}
+}
+
+func (p *indexWorkerPool) finish(index Indexer) {
+	p.finished[index] = true
+	if p.progress != nil {
+		p.progress(IndexProgress{Index: index, Done: true})
+	}
+}
+
 //endregion
 
 //region Worker job
@@ -0,0 +1,173 @@
+package metadata
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer/release"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/storage/bolt"
+	"github.com/sp0x/torrentd/storage/indexing"
+)
+
+const (
+	cacheNamespace = "__metadata"
+	// notFoundTTL is how long the titles that no provider knows are cached, they might be added later.
+	notFoundTTL = 24 * time.Hour
+)
+
+// cacheEntry is the metadata of an id or a title, it has no metadata if no provider knew it.
+type cacheEntry struct {
+	Key       string
+	Metadata  *Metadata
+	Time      time.Time
+	ModelData search.ModelData
+	UUIDValue string
+	RecordID  uint32
+	isNew     bool
+	isUpdate  bool
+}
+
+func (e *cacheEntry) UUID() string {
+	return e.UUIDValue
+}
+
+func (e *cacheEntry) SetUUID(s string) {
+	e.UUIDValue = s
+}
+
+func (e *cacheEntry) GetID() uint32 {
+	return e.RecordID
+}
+
+func (e *cacheEntry) SetID(u uint32) {
+	e.RecordID = u
+}
+
+func (e *cacheEntry) SetState(new, updated bool) {
+	e.isNew = new
+	e.isUpdate = updated
+}
+
+func (e *cacheEntry) IsNew() bool {
+	return e.isNew
+}
+
+func (e *cacheEntry) IsUpdate() bool {
+	return e.isUpdate
+}
+
+// Cache keeps the metadata that the providers found, so that it's only fetched once.
+type Cache struct {
+	storage storage.ItemStorage
+	mux     sync.RWMutex
+	entries map[string]*cacheEntry
+}
+
+// CachePath is the database with the cached metadata.
+// This is the `metadata.db` config value, by default it's next to the results database.
+func CachePath(conf config.Config) string {
+	endpoint := conf.GetString("metadata.db")
+	if endpoint == "" {
+		resultsEndpoint := conf.GetString("storageendpoint")
+		if resultsEndpoint == "" {
+			resultsEndpoint = bolt.GetDefaultDatabasePath()
+		}
+		endpoint = filepath.Join(filepath.Dir(resultsEndpoint), "metadata.db")
+	}
+	return endpoint
+}
+
+// NewCache opens the metadata cache.
+func NewCache(conf config.Config) *Cache {
+	builder := storage.NewBuilder(conf).
+		WithNamespace(cacheNamespace).
+		WithPK(indexing.NewKey("Key")).
+		WithEndpoint(CachePath(conf)).
+		WithRecord(&cacheEntry{})
+	if storageType := conf.GetString("storage"); storageType != "" {
+		builder = builder.WithBacking(storageType)
+	}
+	return newCache(builder.Build())
+}
+
+func newCache(itemStorage storage.ItemStorage) *Cache {
+	c := &Cache{
+		storage: itemStorage,
+		entries: make(map[string]*cacheEntry),
+	}
+	itemStorage.ForEachInNamespaces(func(ns string, record search.Record) bool {
+		if ns != cacheNamespace {
+			return false
+		}
+		if entry, ok := record.(*cacheEntry); ok {
+			c.entries[entry.Key] = entry
+		}
+		return true
+	})
+	return c
+}
+
+// Get finds the cached metadata of a key.
+// The metadata is nil if the key is cached as not found.
+func (c *Cache) Get(key string) (*Metadata, bool) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	entry, ok := c.entries[key]
+	if !ok || (entry.Metadata == nil && time.Since(entry.Time) > notFoundTTL) {
+		return nil, false
+	}
+	if entry.Metadata == nil {
+		return nil, true
+	}
+	found := *entry.Metadata
+	return &found, true
+}
+
+// Set caches the metadata of a key, nil is used for the keys that weren't found.
+func (c *Cache) Set(key string, found *Metadata) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	entry := &cacheEntry{
+		Key:       key,
+		Metadata:  found,
+		Time:      time.Now(),
+		ModelData: search.ModelData{},
+	}
+	if err := c.storage.Add(entry); err != nil {
+		return err
+	}
+	c.entries[key] = entry
+	return nil
+}
+
+// Close closes the storage.
+func (c *Cache) Close() {
+	c.storage.Close()
+}
+
+// idKeys are the cache keys of the ids of a title.
+// TMDB has separate ids for movies and shows, so its keys have the kind.
+func idKeys(kind Kind, ids IDs) []string {
+	var keys []string
+	add := func(service, id string) {
+		if id != "" {
+			keys = append(keys, service+":"+id)
+		}
+	}
+	add("imdb", ids.IMDBID)
+	add("tvdb", ids.TVDBID)
+	add("tvmaze", ids.TVMazeID)
+	add("tvrage", ids.TVRageID)
+	add("tmdb:"+string(kind), ids.TMDBID)
+	return keys
+}
+
+// titleKey is the cache key of a title search.
+func titleKey(kind Kind, title string, year int) string {
+	return fmt.Sprintf("title:%s:%s:%d", kind, release.NormalizeTitle(title), year)
+}
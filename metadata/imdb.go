@@ -0,0 +1,169 @@
+package metadata
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sp0x/torrentd/indexer/release"
+)
+
+// imdbDataset finds titles in the `title.basics.tsv` file of the IMDb datasets, it can be gzipped.
+// The file is read in the background once the provider is created, only movies and series are kept.
+// Until it's read, lookups wait for it only as long as their context allows, so searches aren't blocked by it.
+type imdbDataset struct {
+	path    string
+	ready   chan struct{}
+	err     error
+	byID    map[string]*Metadata
+	byTitle map[string][]*Metadata
+}
+
+// The columns of title.basics.tsv
+const (
+	imdbColumnID = iota
+	imdbColumnType
+	imdbColumnPrimaryTitle
+	imdbColumnOriginalTitle
+	imdbColumnIsAdult
+	imdbColumnStartYear
+	imdbColumnCount
+)
+
+var imdbTitleKinds = map[string]Kind{
+	"movie":        Movie,
+	"tvMovie":      Movie,
+	"tvSeries":     Show,
+	"tvMiniSeries": Show,
+}
+
+func newIMDBDataset(path string) *imdbDataset {
+	d := &imdbDataset{path: path, ready: make(chan struct{})}
+	go func() {
+		d.err = d.read()
+		close(d.ready)
+	}()
+	return d
+}
+
+func (d *imdbDataset) Name() string {
+	return "imdb"
+}
+
+func (d *imdbDataset) Offline() bool {
+	return true
+}
+
+func (d *imdbDataset) Lookup(ctx context.Context, ids IDs) (*Metadata, error) {
+	if ids.IMDBID == "" {
+		return nil, ErrNotFound
+	}
+	if err := d.wait(ctx); err != nil {
+		return nil, err
+	}
+	found, ok := d.byID[ids.IMDBID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	result := *found
+	return &result, nil
+}
+
+// Find prefers titles from the same year, and then the ones that are a year off,
+// since releases often have the year of their premiere in another country.
+func (d *imdbDataset) Find(ctx context.Context, kind Kind, title string, year int) (*Metadata, error) {
+	if err := d.wait(ctx); err != nil {
+		return nil, err
+	}
+	var best *Metadata
+	bestDistance := -1
+	for _, candidate := range d.byTitle[release.NormalizeTitle(title)] {
+		if candidate.Kind != kind {
+			continue
+		}
+		distance := 0
+		if year != 0 {
+			distance = candidate.Year - year
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance > 1 {
+				continue
+			}
+		}
+		if best == nil || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best == nil {
+		return nil, ErrNotFound
+	}
+	result := *best
+	return &result, nil
+}
+
+// wait waits until the file is read, or until the context is done.
+// The error of the context isn't a miss, so the title isn't cached as not found while the file is read.
+func (d *imdbDataset) wait(ctx context.Context) error {
+	select {
+	case <-d.ready:
+		return d.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *imdbDataset) read() error {
+	file, err := os.Open(d.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if strings.HasSuffix(d.path, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	d.byID = make(map[string]*Metadata)
+	d.byTitle = make(map[string][]*Metadata)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		columns := strings.Split(scanner.Text(), "\t")
+		if len(columns) < imdbColumnCount || columns[imdbColumnIsAdult] == "1" {
+			continue
+		}
+		kind, ok := imdbTitleKinds[columns[imdbColumnType]]
+		if !ok {
+			continue
+		}
+		title := &Metadata{
+			Kind:  kind,
+			Title: columns[imdbColumnPrimaryTitle],
+			IDs:   IDs{IMDBID: columns[imdbColumnID]},
+		}
+		// Missing values are \N
+		title.Year, _ = strconv.Atoi(columns[imdbColumnStartYear])
+		d.byID[title.IMDBID] = title
+		d.index(title.Title, title)
+		if original := columns[imdbColumnOriginalTitle]; original != title.Title {
+			d.index(original, title)
+		}
+	}
+	return scanner.Err()
+}
+
+func (d *imdbDataset) index(name string, title *Metadata) {
+	key := release.NormalizeTitle(name)
+	if key != "" {
+		d.byTitle[key] = append(d.byTitle[key], title)
+	}
+}
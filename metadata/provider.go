@@ -0,0 +1,118 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kind is the type of a title.
+type Kind string
+
+const (
+	Movie Kind = "movie"
+	Show  Kind = "show"
+)
+
+// ErrNotFound is returned by the providers that don't know a title.
+var ErrNotFound = errors.New("title not found")
+
+// IDs are the ids of a title in other services.
+type IDs struct {
+	IMDBID   string
+	TVDBID   string
+	TVMazeID string
+	TVRageID string
+	TMDBID   string
+}
+
+// IsEmpty checks if none of the ids are set.
+func (ids IDs) IsEmpty() bool {
+	return ids == IDs{}
+}
+
+// merge fills in the ids that aren't set, with the other ones.
+func (ids IDs) merge(other IDs) IDs {
+	if ids.IMDBID == "" {
+		ids.IMDBID = other.IMDBID
+	}
+	if ids.TVDBID == "" {
+		ids.TVDBID = other.TVDBID
+	}
+	if ids.TVMazeID == "" {
+		ids.TVMazeID = other.TVMazeID
+	}
+	if ids.TVRageID == "" {
+		ids.TVRageID = other.TVRageID
+	}
+	if ids.TMDBID == "" {
+		ids.TMDBID = other.TMDBID
+	}
+	return ids
+}
+
+// Metadata is the info of a movie or a show.
+type Metadata struct {
+	Kind  Kind
+	Title string
+	Year  int
+	IDs
+}
+
+// MetadataProvider finds the info of movies and shows.
+// Providers return ErrNotFound if they don't know a title, so that the next one can be used.
+type MetadataProvider interface {
+	// Name is the name of the provider in the config.
+	Name() string
+	// Offline providers don't make any requests, so they're also used for the results of searches.
+	Offline() bool
+	// Lookup finds a title by one of its ids.
+	Lookup(ctx context.Context, ids IDs) (*Metadata, error)
+	// Find finds a title by its name, the year is only used if it's given.
+	Find(ctx context.Context, kind Kind, title string, year int) (*Metadata, error)
+}
+
+// NormalizeIMDBID makes IMDb ids like `0111161` look like `tt0111161`.
+func NormalizeIMDBID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" || id == "0" {
+		return ""
+	}
+	if !strings.HasPrefix(id, "tt") {
+		id = "tt" + id
+	}
+	return id
+}
+
+// normalizeID clears the ids that torznab clients send as 0 when they don't have them.
+func normalizeID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "0" {
+		return ""
+	}
+	return id
+}
+
+// getJSON fetches a json response, a 404 is reported as ErrNotFound.
+func getJSON(ctx context.Context, client *http.Client, url string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
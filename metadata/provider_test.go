@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestNormalizeIMDBID(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(NormalizeIMDBID("0111161")).To(Equal("tt0111161"))
+	g.Expect(NormalizeIMDBID("tt0111161")).To(Equal("tt0111161"))
+	g.Expect(NormalizeIMDBID("0")).To(BeEmpty())
+	g.Expect(NormalizeIMDBID("")).To(BeEmpty())
+}
+
+func TestTVMaze(t *testing.T) {
+	g := NewWithT(t)
+	const show = `{"id": 169, "name": "Breaking Bad", "premiered": "2008-01-20",
+		"externals": {"tvrage": 18164, "thetvdb": 81189, "imdb": "tt0903747"}}`
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RequestURI())
+		switch r.URL.RequestURI() {
+		case "/lookup/shows?thetvdb=81189", "/singlesearch/shows?q=breaking+bad":
+			_, _ = w.Write([]byte(show))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	provider := newTVMaze(server.URL)
+
+	found, err := provider.Lookup(context.Background(), IDs{TVDBID: "81189"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*found).To(Equal(Metadata{
+		Kind: Show, Title: "Breaking Bad", Year: 2008,
+		IDs: IDs{IMDBID: "tt0903747", TVDBID: "81189", TVMazeID: "169", TVRageID: "18164"},
+	}))
+
+	_, err = provider.Lookup(context.Background(), IDs{IMDBID: "tt0133093"})
+	g.Expect(err).To(Equal(ErrNotFound))
+
+	found, err = provider.Find(context.Background(), Show, "breaking bad", 2008)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found.TVDBID).To(Equal("81189"))
+	_, err = provider.Find(context.Background(), Show, "breaking bad", 2012)
+	g.Expect(err).To(Equal(ErrNotFound))
+	_, err = provider.Find(context.Background(), Movie, "breaking bad", 0)
+	g.Expect(err).To(Equal(ErrNotFound))
+	g.Expect(requests).To(HaveLen(4))
+}
+
+func TestTMDB(t *testing.T) {
+	g := NewWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("api_key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/find/tt0133093":
+			_, _ = w.Write([]byte(`{"movie_results": [{"id": 603, "title": "The Matrix", "release_date": "1999-03-30"}]}`))
+		case "/search/tv":
+			_, _ = w.Write([]byte(`{"results": [{"id": 1, "name": "Breaking News"}, {"id": 1396, "name": "Breaking Bad", "first_air_date": "2008-01-20"}]}`))
+		case "/movie/603/external_ids":
+			_, _ = w.Write([]byte(`{"imdb_id": "tt0133093"}`))
+		case "/tv/1396/external_ids":
+			_, _ = w.Write([]byte(`{"imdb_id": "tt0903747", "tvdb_id": 81189}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	found, err := newTMDB(server.URL, "key").Lookup(context.Background(), IDs{IMDBID: "tt0133093"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*found).To(Equal(Metadata{Kind: Movie, Title: "The Matrix", Year: 1999, IDs: IDs{IMDBID: "tt0133093", TMDBID: "603"}}))
+
+	found, err = newTMDB(server.URL, "key").Find(context.Background(), Show, "Breaking Bad", 0)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*found).To(Equal(Metadata{Kind: Show, Title: "Breaking Bad", Year: 2008, IDs: IDs{IMDBID: "tt0903747", TVDBID: "81189", TMDBID: "1396"}}))
+
+	_, err = newTMDB(server.URL, "wrong").Lookup(context.Background(), IDs{IMDBID: "tt0133093"})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err).ToNot(Equal(ErrNotFound))
+}
+
+func TestIMDBDataset(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := ioutil.TempDir("", "imdb-")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "title.basics.tsv.gz")
+	file, _ := os.Create(path)
+	writer := gzip.NewWriter(file)
+	_, _ = writer.Write([]byte("tconst\ttitleType\tprimaryTitle\toriginalTitle\tisAdult\tstartYear\tendYear\truntimeMinutes\tgenres\n" +
+		"tt0133093\tmovie\tThe Matrix\tThe Matrix\t0\t1999\t\\N\t136\tAction,Sci-Fi\n" +
+		"tt0109830\tvideo\tThe Matrix\tThe Matrix\t0\t1993\t\\N\t\\N\t\\N\n" +
+		"tt0903747\ttvSeries\tBreaking Bad\tBreaking Bad\t0\t2008\t2013\t49\tCrime\n" +
+		"tt0118799\tmovie\tLife Is Beautiful\tLa vita è bella\t0\t1997\t\\N\t116\tComedy\n"))
+	_ = writer.Close()
+	_ = file.Close()
+	provider := newIMDBDataset(path)
+
+	found, err := provider.Lookup(context.Background(), IDs{IMDBID: "tt0903747"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(*found).To(Equal(Metadata{Kind: Show, Title: "Breaking Bad", Year: 2008, IDs: IDs{IMDBID: "tt0903747"}}))
+
+	found, err = provider.Find(context.Background(), Movie, "the matrix", 1999)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found.IMDBID).To(Equal("tt0133093"))
+	found, err = provider.Find(context.Background(), Movie, "La vita è bella", 1998)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(found.Title).To(Equal("Life Is Beautiful"))
+
+	_, err = provider.Find(context.Background(), Movie, "the matrix", 2003)
+	g.Expect(err).To(Equal(ErrNotFound))
+	_, err = provider.Find(context.Background(), Movie, "breaking bad", 0)
+	g.Expect(err).To(Equal(ErrNotFound))
+
+	_, err = newIMDBDataset(filepath.Join(dir, "missing.tsv")).Lookup(context.Background(), IDs{IMDBID: "tt0133093"})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestIMDBDataset_ShouldNotBlock_WhileItsRead(t *testing.T) {
+	g := NewWithT(t)
+	// The file of this dataset is never read.
+	provider := &imdbDataset{ready: make(chan struct{})}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := provider.Lookup(ctx, IDs{IMDBID: "tt0133093"})
+	g.Expect(err).To(Equal(context.DeadlineExceeded))
+	_, err = provider.Find(ctx, Movie, "the matrix", 1999)
+	g.Expect(err).To(Equal(context.DeadlineExceeded))
+}
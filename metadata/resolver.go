@@ -0,0 +1,254 @@
+package metadata
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer/categories"
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+const (
+	defaultTimeout   = 5 * time.Second
+	defaultProviders = "imdb,tvmaze,tmdb"
+)
+
+// Resolver finds the metadata of ids and titles with its providers, in their order.
+// The metadata is cached, so the providers are only used for the ids and titles that aren't known yet.
+type Resolver struct {
+	providers []MetadataProvider
+	cache     *Cache
+	// timeout is how long each provider can take.
+	timeout time.Duration
+}
+
+// NewResolver creates a resolver, the cache is optional.
+func NewResolver(cache *Cache, timeout time.Duration, providers ...MetadataProvider) *Resolver {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Resolver{providers: providers, cache: cache, timeout: timeout}
+}
+
+// FromConfig creates a resolver with the `metadata` section of the config.
+// The `providers` are a comma separated list of imdb, tvmaze and tmdb.
+// The imdb provider needs the `imdb_dataset` file and tmdb needs the `tmdb_api_key`, they're skipped without them.
+// With `offline` only the dataset and the cache are used.
+func FromConfig(conf config.Config) *Resolver {
+	names := conf.GetString("metadata.providers")
+	if names == "" {
+		names = defaultProviders
+	}
+	offline := conf.GetBool("metadata.offline")
+	var providers []MetadataProvider
+	for _, name := range strings.Split(names, ",") {
+		var provider MetadataProvider
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "imdb":
+			if dataset, _ := homedir.Expand(conf.GetString("metadata.imdb_dataset")); dataset != "" {
+				provider = newIMDBDataset(dataset)
+			}
+		case "tvmaze":
+			provider = newTVMaze(tvMazeURL)
+		case "tmdb":
+			if apiKey := conf.GetString("tmdb_api_key"); apiKey != "" {
+				provider = newTMDB(tmdbURL, apiKey)
+			}
+		case "":
+		default:
+			log.Warningf("Unknown metadata provider `%s`", name)
+		}
+		if provider != nil && (!offline || provider.Offline()) {
+			providers = append(providers, provider)
+		}
+	}
+	timeout, _ := time.ParseDuration(conf.GetString("metadata.timeout"))
+	return NewResolver(NewCache(conf), timeout, providers...)
+}
+
+// Close closes the cache.
+func (r *Resolver) Close() {
+	if r.cache != nil {
+		r.cache.Close()
+	}
+}
+
+// Lookup finds a title by its ids, with all the providers.
+// TMDB ids are for movies, like in torznab.
+func (r *Resolver) Lookup(ctx context.Context, ids IDs) (*Metadata, error) {
+	found, err := r.resolve(ctx, idKeys(Movie, ids), r.providers, func(ctx context.Context, provider MetadataProvider) (*Metadata, error) {
+		return provider.Lookup(ctx, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	found.IDs = found.IDs.merge(ids)
+	return found, nil
+}
+
+// Find finds a title by its name, only the offline providers are used if `offline` is set.
+func (r *Resolver) Find(ctx context.Context, kind Kind, title string, year int, offline bool) (*Metadata, error) {
+	providers := r.providers
+	if offline {
+		providers = nil
+		for _, provider := range r.providers {
+			if provider.Offline() {
+				providers = append(providers, provider)
+			}
+		}
+	}
+	return r.resolve(ctx, []string{titleKey(kind, title, year)}, providers, func(ctx context.Context, provider MetadataProvider) (*Metadata, error) {
+		return provider.Find(ctx, kind, title, year)
+	})
+}
+
+// resolve finds the metadata of the cache keys, with the cache or with the first provider that knows it.
+// The keys are only cached as not found if all the providers were used and none of them failed.
+func (r *Resolver) resolve(ctx context.Context, keys []string, providers []MetadataProvider,
+	call func(context.Context, MetadataProvider) (*Metadata, error)) (*Metadata, error) {
+	if len(keys) == 0 {
+		return nil, ErrNotFound
+	}
+	if r.cache != nil {
+		cachedMisses := 0
+		for _, key := range keys {
+			found, ok := r.cache.Get(key)
+			if ok && found != nil {
+				return found, nil
+			} else if ok {
+				cachedMisses++
+			}
+		}
+		if cachedMisses == len(keys) {
+			return nil, ErrNotFound
+		}
+	}
+	var lastErr error
+	for _, provider := range providers {
+		providerCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		found, err := call(providerCtx, provider)
+		cancel()
+		if err == nil {
+			r.store(keys, found)
+			return found, nil
+		}
+		if err != ErrNotFound {
+			log.WithFields(log.Fields{"provider": provider.Name(), "keys": keys}).
+				Debugf("Couldn't get metadata: %v", err)
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	if len(providers) == len(r.providers) {
+		r.store(keys, nil)
+	}
+	return nil, ErrNotFound
+}
+
+// store caches the metadata under the keys it was found with, and under its own ids and title.
+func (r *Resolver) store(keys []string, found *Metadata) {
+	if r.cache == nil {
+		return
+	}
+	if found != nil {
+		keys = append(keys, idKeys(found.Kind, found.IDs)...)
+		keys = append(keys, titleKey(found.Kind, found.Title, found.Year), titleKey(found.Kind, found.Title, 0))
+	}
+	for _, key := range keys {
+		if err := r.cache.Set(key, found); err != nil {
+			log.Warningf("Couldn't cache the metadata of %s: %v", key, err)
+		}
+	}
+}
+
+// QueryIDs gets the ids of a torznab query.
+func QueryIDs(query *search.Query) IDs {
+	return IDs{
+		IMDBID:   NormalizeIMDBID(query.IMDBID),
+		TVDBID:   normalizeID(query.TVDBID),
+		TVMazeID: normalizeID(query.TVMazeID),
+		TVRageID: normalizeID(query.TVRageID),
+		TMDBID:   normalizeID(query.TMDBID),
+	}
+}
+
+// EnrichQuery sets the name of the series or movie of a query, if it's for the id of a title.
+// The query isn't changed if the title can't be found, so it's searched with its keywords.
+func (r *Resolver) EnrichQuery(ctx context.Context, query *search.Query) error {
+	ids := QueryIDs(query)
+	if ids.IsEmpty() {
+		return nil
+	}
+	found, err := r.Lookup(ctx, ids)
+	if err != nil {
+		return err
+	}
+	switch found.Kind {
+	case Show:
+		query.Series = found.Title
+	case Movie:
+		query.Movie = found.Title
+		if found.Year != 0 {
+			query.Year = strconv.Itoa(found.Year)
+		}
+	}
+	return nil
+}
+
+// EnrichResult adds the IMDb and TVDB ids of the title of a result, before it's stored.
+// Only the cache and the offline providers are used, so that searches aren't slowed down.
+func (r *Resolver) EnrichResult(result search.ResultItemBase) {
+	item, ok := result.(*search.TorrentResultItem)
+	if !ok || item.ParsedTitle == "" || item.IMDBID != "" || item.TVDBID != "" {
+		return
+	}
+	kind, ok := resultKind(item)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	// Titles can have alternative names, like `Russian name / English name`.
+	for _, title := range strings.Split(item.ParsedTitle, "/") {
+		found, err := r.Find(ctx, kind, strings.TrimSpace(title), item.Year, true)
+		if err != nil {
+			continue
+		}
+		item.IMDBID = found.IMDBID
+		item.TVDBID = found.TVDBID
+		return
+	}
+}
+
+// resultKind guesses if a result is a movie or a show, by its category or its episode info.
+func resultKind(item *search.TorrentResultItem) (Kind, bool) {
+	switch {
+	case categories.CategoryTV.Contains(item.Category):
+		return Show, true
+	case categories.CategoryMovies.Contains(item.Category):
+		return Movie, true
+	case item.Season != 0 || item.Episode != 0 || item.AbsoluteEpisode != 0 || item.AirDate != "":
+		return Show, true
+	}
+	return "", false
+}
+
+// MatchesQuery checks if a result has one of the IMDb or TVDB ids of a query.
+func MatchesQuery(query *search.Query, item *search.TorrentResultItem) bool {
+	ids := QueryIDs(query)
+	switch {
+	case ids.IMDBID != "" && NormalizeIMDBID(item.IMDBID) == ids.IMDBID:
+		return true
+	case ids.TVDBID != "" && item.TVDBID == ids.TVDBID:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/indexer/categories"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/storage/indexing"
+)
+
+// fakeProvider knows the given titles, or fails with its error.
+type fakeProvider struct {
+	name    string
+	offline bool
+	titles  []*Metadata
+	err     error
+	// block waits until the request times out
+	block bool
+	calls int
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Offline() bool {
+	return p.offline
+}
+
+func (p *fakeProvider) Lookup(ctx context.Context, ids IDs) (*Metadata, error) {
+	return p.find(ctx, func(title *Metadata) bool {
+		return (ids.IMDBID != "" && title.IMDBID == ids.IMDBID) || (ids.TVDBID != "" && title.TVDBID == ids.TVDBID)
+	})
+}
+
+func (p *fakeProvider) Find(ctx context.Context, kind Kind, name string, year int) (*Metadata, error) {
+	return p.find(ctx, func(title *Metadata) bool {
+		return title.Kind == kind && title.Title == name && (year == 0 || title.Year == year)
+	})
+}
+
+func (p *fakeProvider) find(ctx context.Context, matches func(*Metadata) bool) (*Metadata, error) {
+	p.calls++
+	if p.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	for _, title := range p.titles {
+		if matches(title) {
+			found := *title
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func openTestCache(dbFile string) *Cache {
+	return newCache(storage.NewBuilder(nil).
+		WithNamespace(cacheNamespace).
+		WithPK(indexing.NewKey("Key")).
+		WithEndpoint(dbFile).
+		WithRecord(&cacheEntry{}).
+		Build())
+}
+
+var (
+	testShow  = &Metadata{Kind: Show, Title: "Breaking Bad", Year: 2008, IDs: IDs{IMDBID: "tt0903747", TVDBID: "81189"}}
+	testMovie = &Metadata{Kind: Movie, Title: "The Matrix", Year: 1999, IDs: IDs{IMDBID: "tt0133093"}}
+)
+
+func TestResolver_EnrichQuery(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := ioutil.TempDir("", "metadata-")
+	defer os.RemoveAll(dir)
+	dbFile := filepath.Join(dir, "metadata.db")
+
+	failing := &fakeProvider{name: "failing", err: errors.New("unavailable")}
+	provider := &fakeProvider{name: "provider", titles: []*Metadata{testShow, testMovie}}
+	cache := openTestCache(dbFile)
+	resolver := NewResolver(cache, time.Second, failing, provider)
+
+	query := search.NewQuery()
+	query.TVDBID = "81189"
+	query.Season = "1"
+	g.Expect(resolver.EnrichQuery(context.Background(), query)).To(Succeed())
+	g.Expect(query.Series).To(Equal("Breaking Bad"))
+	g.Expect(query.TVDBID).To(Equal("81189"))
+
+	query = search.NewQuery()
+	query.IMDBID = "0133093"
+	g.Expect(resolver.EnrichQuery(context.Background(), query)).To(Succeed())
+	g.Expect(query.Movie).To(Equal("The Matrix"))
+	g.Expect(query.Year).To(Equal("1999"))
+	g.Expect(provider.calls).To(Equal(2))
+
+	// The titles are cached, also by their other ids.
+	cache.Close()
+	provider.calls = 0
+	resolver = NewResolver(openTestCache(dbFile), time.Second, provider)
+	defer resolver.Close()
+	query = search.NewQuery()
+	query.IMDBID = "tt0903747"
+	g.Expect(resolver.EnrichQuery(context.Background(), query)).To(Succeed())
+	g.Expect(query.Series).To(Equal("Breaking Bad"))
+	g.Expect(provider.calls).To(Equal(0))
+}
+
+func TestResolver_EnrichQueryKeepsTheQueryOnFailures(t *testing.T) {
+	g := NewWithT(t)
+	slow := &fakeProvider{name: "slow", block: true}
+	resolver := NewResolver(nil, 10*time.Millisecond, slow)
+
+	query := search.NewQuery()
+	query.QueryString = "breaking bad"
+	query.TVDBID = "81189"
+	err := resolver.EnrichQuery(context.Background(), query)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(query.Series).To(BeEmpty())
+	g.Expect(query.Keywords()).To(Equal("breaking bad"))
+
+	// Queries without ids aren't looked up.
+	query = search.NewQuery()
+	query.TVDBID = "0"
+	g.Expect(resolver.EnrichQuery(context.Background(), query)).To(Succeed())
+	g.Expect(slow.calls).To(Equal(1))
+}
+
+func TestResolver_CachesMissesOnlyIfNoProviderFailed(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := ioutil.TempDir("", "metadata-")
+	defer os.RemoveAll(dir)
+
+	failing := &fakeProvider{name: "failing", err: errors.New("unavailable")}
+	empty := &fakeProvider{name: "empty"}
+	resolver := NewResolver(openTestCache(filepath.Join(dir, "metadata.db")), time.Second, failing, empty)
+	defer resolver.Close()
+
+	_, err := resolver.Lookup(context.Background(), IDs{IMDBID: "tt1"})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err).ToNot(Equal(ErrNotFound))
+	_, err = resolver.Lookup(context.Background(), IDs{IMDBID: "tt1"})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(empty.calls).To(Equal(2))
+
+	failing.err = nil
+	_, err = resolver.Lookup(context.Background(), IDs{IMDBID: "tt1"})
+	g.Expect(err).To(Equal(ErrNotFound))
+	_, err = resolver.Lookup(context.Background(), IDs{IMDBID: "tt1"})
+	g.Expect(err).To(Equal(ErrNotFound))
+	g.Expect(empty.calls).To(Equal(3))
+}
+
+func TestResolver_EnrichResult(t *testing.T) {
+	g := NewWithT(t)
+	online := &fakeProvider{name: "online", titles: []*Metadata{testShow}}
+	offline := &fakeProvider{name: "offline", offline: true, titles: []*Metadata{testMovie}}
+	resolver := NewResolver(nil, time.Second, online, offline)
+
+	movie := &search.TorrentResultItem{ParsedTitle: "Матрица / The Matrix", Year: 1999, Category: categories.CategoryMoviesHD.ID}
+	resolver.EnrichResult(movie)
+	g.Expect(movie.IMDBID).To(Equal("tt0133093"))
+
+	// Only the offline providers are used for results.
+	show := &search.TorrentResultItem{ParsedTitle: "Breaking Bad", Season: 1, Episode: 2}
+	resolver.EnrichResult(show)
+	g.Expect(show.IMDBID).To(BeEmpty())
+	g.Expect(online.calls).To(Equal(0))
+
+	// Results that aren't movies or shows are skipped.
+	other := &search.TorrentResultItem{ParsedTitle: "The Matrix", Category: categories.CategoryAudio.ID}
+	resolver.EnrichResult(other)
+	g.Expect(other.IMDBID).To(BeEmpty())
+}
+
+func TestMatchesQuery(t *testing.T) {
+	g := NewWithT(t)
+	item := &search.TorrentResultItem{IMDBID: "tt0903747", TVDBID: "81189"}
+	query := search.NewQuery()
+	g.Expect(MatchesQuery(query, item)).To(BeFalse())
+	query.IMDBID = "0903747"
+	g.Expect(MatchesQuery(query, item)).To(BeTrue())
+	query.IMDBID = ""
+	query.TVDBID = "81189"
+	g.Expect(MatchesQuery(query, item)).To(BeTrue())
+	query.TVDBID = "1"
+	g.Expect(MatchesQuery(query, item)).To(BeFalse())
+}
@@ -0,0 +1,153 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sp0x/torrentd/indexer/release"
+)
+
+const tmdbURL = "https://api.themoviedb.org/3"
+
+// tmdb finds movies and shows with the TMDB api, it needs an api key.
+type tmdb struct {
+	url    string
+	apiKey string
+	http   *http.Client
+}
+
+type tmdbTitle struct {
+	ID           int    `json:"id"`
+	Title        string `json:"title"`
+	Name         string `json:"name"`
+	ReleaseDate  string `json:"release_date"`
+	FirstAirDate string `json:"first_air_date"`
+}
+
+type tmdbFindResponse struct {
+	MovieResults []tmdbTitle `json:"movie_results"`
+	TVResults    []tmdbTitle `json:"tv_results"`
+}
+
+type tmdbSearchResponse struct {
+	Results []tmdbTitle `json:"results"`
+}
+
+type tmdbExternalIDs struct {
+	IMDBID string `json:"imdb_id"`
+	TVDBID int    `json:"tvdb_id"`
+}
+
+func newTMDB(baseURL, apiKey string) *tmdb {
+	return &tmdb{url: strings.TrimRight(baseURL, "/"), apiKey: apiKey, http: &http.Client{}}
+}
+
+func (t *tmdb) Name() string {
+	return "tmdb"
+}
+
+func (t *tmdb) Offline() bool {
+	return false
+}
+
+func (t *tmdb) Lookup(ctx context.Context, ids IDs) (*Metadata, error) {
+	switch {
+	case ids.TMDBID != "":
+		movie := &tmdbTitle{}
+		if err := t.get(ctx, "/movie/"+url.PathEscape(ids.TMDBID), nil, movie); err != nil {
+			return nil, err
+		}
+		return t.withExternalIDs(ctx, movie.metadata(Movie))
+	case ids.IMDBID != "":
+		return t.find(ctx, ids.IMDBID, "imdb_id")
+	case ids.TVDBID != "":
+		return t.find(ctx, ids.TVDBID, "tvdb_id")
+	}
+	return nil, ErrNotFound
+}
+
+func (t *tmdb) Find(ctx context.Context, kind Kind, title string, year int) (*Metadata, error) {
+	endpoint, yearParam := "/search/movie", "year"
+	if kind == Show {
+		endpoint, yearParam = "/search/tv", "first_air_date_year"
+	}
+	params := url.Values{"query": {title}}
+	if year != 0 {
+		params.Set(yearParam, strconv.Itoa(year))
+	}
+	response := &tmdbSearchResponse{}
+	if err := t.get(ctx, endpoint, params, response); err != nil {
+		return nil, err
+	}
+	for _, result := range response.Results {
+		found := result.metadata(kind)
+		if release.MatchesTitle(found.Title, title) {
+			return t.withExternalIDs(ctx, found)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// find looks up a title by the id of another service.
+func (t *tmdb) find(ctx context.Context, id, source string) (*Metadata, error) {
+	response := &tmdbFindResponse{}
+	if err := t.get(ctx, "/find/"+url.PathEscape(id), url.Values{"external_source": {source}}, response); err != nil {
+		return nil, err
+	}
+	var found *Metadata
+	switch {
+	case len(response.MovieResults) > 0:
+		found = response.MovieResults[0].metadata(Movie)
+	case len(response.TVResults) > 0:
+		found = response.TVResults[0].metadata(Show)
+	default:
+		return nil, ErrNotFound
+	}
+	return t.withExternalIDs(ctx, found)
+}
+
+// withExternalIDs adds the IMDb and TVDB ids of a title, which aren't in the search results.
+func (t *tmdb) withExternalIDs(ctx context.Context, found *Metadata) (*Metadata, error) {
+	endpoint := fmt.Sprintf("/movie/%s/external_ids", found.TMDBID)
+	if found.Kind == Show {
+		endpoint = fmt.Sprintf("/tv/%s/external_ids", found.TMDBID)
+	}
+	external := &tmdbExternalIDs{}
+	if err := t.get(ctx, endpoint, nil, external); err != nil {
+		return nil, err
+	}
+	found.IMDBID = external.IMDBID
+	if external.TVDBID != 0 {
+		found.TVDBID = strconv.Itoa(external.TVDBID)
+	}
+	return found, nil
+}
+
+func (t *tmdb) get(ctx context.Context, endpoint string, params url.Values, result interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("api_key", t.apiKey)
+	return getJSON(ctx, t.http, t.url+endpoint+"?"+params.Encode(), result)
+}
+
+func (t *tmdbTitle) metadata(kind Kind) *Metadata {
+	result := &Metadata{
+		Kind:  kind,
+		Title: t.Title,
+		IDs:   IDs{TMDBID: strconv.Itoa(t.ID)},
+	}
+	date := t.ReleaseDate
+	if kind == Show {
+		result.Title = t.Name
+		date = t.FirstAirDate
+	}
+	if len(date) >= 4 {
+		result.Year, _ = strconv.Atoi(date[:4])
+	}
+	return result
+}
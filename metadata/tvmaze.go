@@ -0,0 +1,100 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sp0x/torrentd/indexer/release"
+)
+
+const tvMazeURL = "https://api.tvmaze.com"
+
+// tvMaze finds shows with the TVMaze api, it doesn't know movies.
+type tvMaze struct {
+	url  string
+	http *http.Client
+}
+
+type tvMazeShow struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Premiered string `json:"premiered"`
+	Externals struct {
+		TVRage  int    `json:"tvrage"`
+		TheTVDB int    `json:"thetvdb"`
+		IMDB    string `json:"imdb"`
+	} `json:"externals"`
+}
+
+func newTVMaze(baseURL string) *tvMaze {
+	return &tvMaze{url: strings.TrimRight(baseURL, "/"), http: &http.Client{}}
+}
+
+func (t *tvMaze) Name() string {
+	return "tvmaze"
+}
+
+func (t *tvMaze) Offline() bool {
+	return false
+}
+
+func (t *tvMaze) Lookup(ctx context.Context, ids IDs) (*Metadata, error) {
+	var endpoint string
+	switch {
+	case ids.TVMazeID != "":
+		endpoint = "/shows/" + url.PathEscape(ids.TVMazeID)
+	case ids.TVDBID != "":
+		endpoint = "/lookup/shows?thetvdb=" + url.QueryEscape(ids.TVDBID)
+	case ids.TVRageID != "":
+		endpoint = "/lookup/shows?tvrage=" + url.QueryEscape(ids.TVRageID)
+	case ids.IMDBID != "":
+		endpoint = "/lookup/shows?imdb=" + url.QueryEscape(ids.IMDBID)
+	default:
+		return nil, ErrNotFound
+	}
+	show := &tvMazeShow{}
+	if err := getJSON(ctx, t.http, t.url+endpoint, show); err != nil {
+		return nil, err
+	}
+	return show.metadata(), nil
+}
+
+func (t *tvMaze) Find(ctx context.Context, kind Kind, title string, year int) (*Metadata, error) {
+	if kind != Show {
+		return nil, ErrNotFound
+	}
+	show := &tvMazeShow{}
+	if err := getJSON(ctx, t.http, t.url+"/singlesearch/shows?q="+url.QueryEscape(title), show); err != nil {
+		return nil, err
+	}
+	result := show.metadata()
+	// The search is fuzzy, so only the shows with the same name are used.
+	if !release.MatchesTitle(result.Title, title) || (year != 0 && result.Year != 0 && result.Year != year) {
+		return nil, ErrNotFound
+	}
+	return result, nil
+}
+
+func (s *tvMazeShow) metadata() *Metadata {
+	result := &Metadata{
+		Kind:  Show,
+		Title: s.Name,
+		IDs: IDs{
+			IMDBID:   s.Externals.IMDB,
+			TVMazeID: strconv.Itoa(s.ID),
+		},
+	}
+	if s.Externals.TheTVDB != 0 {
+		result.TVDBID = strconv.Itoa(s.Externals.TheTVDB)
+	}
+	if s.Externals.TVRage != 0 {
+		result.TVRageID = strconv.Itoa(s.Externals.TVRage)
+	}
+	if len(s.Premiered) >= 4 {
+		result.Year, _ = strconv.Atoi(s.Premiered[:4])
+	}
+	return result
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sp0x/torrentd/indexer"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/server/apikeys"
+)
+
+type indexSummary struct {
+	Name string `json:"name"`
+	// Loaded is true if the index is already running in our scope.
+	Loaded bool `json:"loaded"`
+	// Configured is true if there are any settings for the index in the config.
+	Configured bool     `json:"configured"`
+	Problems   []string `json:"problems,omitempty"`
+}
+
+type searchResult struct {
+	Title     string `json:"title"`
+	Site      string `json:"site"`
+	Category  int    `json:"category"`
+	Size      uint32 `json:"size"`
+	Seeders   int    `json:"seeders"`
+	Peers     int    `json:"peers"`
+	Published int64  `json:"published"`
+	Link      string `json:"link"`
+	Details   string `json:"details"`
+}
+
+type searchResponse struct {
+	Query   string         `json:"query"`
+	Results []searchResult `json:"results"`
+}
+
+// listIndexes godoc
+// @Summary      List indexes
+// @Description  List all the indexes that can be used, with the problems of their configured settings
+// @Tags         indexes
+// @Accept       */*
+// @Produce      json
+// @Success      200  {array}  indexSummary
+// @Router       /api/indexes [get]
+func (s *Server) listIndexes(c *gin.Context) {
+	names, err := s.indexerFacade.AvailableIndexes()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	loaded := s.indexerFacade.IndexScope.Indexes()
+	summaries := make([]indexSummary, 0, len(names))
+	for _, name := range names {
+		_, isLoaded := loaded[name]
+		def, siteConfig, err := s.indexSiteConfig(name)
+		summary := indexSummary{
+			Name:       name,
+			Loaded:     isLoaded,
+			Configured: len(siteConfig) > 0,
+		}
+		if err != nil {
+			summary.Problems = []string{err.Error()}
+		} else if summary.Configured {
+			summary.Problems = settingsProblems(def, siteConfig)
+		}
+		summaries = append(summaries, summary)
+	}
+	c.JSON(http.StatusOK, summaries)
+}
+
+// indexSiteConfig loads the definition of an index and its site config.
+// The config of a site is under the name of its definition, like the runners read it, not under the name of its file.
+func (s *Server) indexSiteConfig(name string) (*indexer.Definition, map[string]string, error) {
+	def, err := s.indexerFacade.LoadDefinition(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	siteConfig, err := s.config.GetSite(def.Name)
+	if err != nil {
+		return def, nil, err
+	}
+	return def, siteConfig, nil
+}
+
+// settingsProblems validates the configured settings of an index.
+func settingsProblems(def *indexer.Definition, siteConfig map[string]string) []string {
+	if _, err := def.ResolveSettings(siteConfig); err != nil {
+		if settingsErr, ok := err.(*indexer.SettingsError); ok {
+			return settingsErr.Problems
+		}
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// searchIndexes godoc
+// @Summary      Search
+// @Description  Search through the loaded indexes, or only through the given ones
+// @Tags         search
+// @Accept       */*
+// @param 	  	 q query string false "Search query"
+// @param 	  	 indexes query string false "Index name(s) to search through"
+// @param 	  	 cat query string false "Categories"
+// @param 	  	 limit query string false "Limit the number of results, defaults to 20"
+// @param 	  	 apikey query string true "API key"
+// @Produce      json
+// @Success      200  {object}  searchResponse
+// @Router       /api/search [get]
+func (s *Server) searchIndexes(c *gin.Context) {
+	facade, query, key, ok := s.apiSearchQuery(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := s.searchContext(c.Request)
+	defer cancel()
+	resultsChan, err := facade.Search(ctx, query)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var results []search.ResultItemBase
+	for resultPage := range resultsChan {
+		results = append(results, resultPage...)
+	}
+	// Only the links of the results that are sent are signed.
+	if query.Limit > 0 && uint(len(results)) > query.Limit {
+		results = results[:query.Limit]
+	}
+	results, err = s.rewriteLinks(c.Request, results, key)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response := searchResponse{Query: query.QueryString, Results: []searchResult{}}
+	for _, item := range results {
+		response.Results = append(response.Results, newSearchResult(item))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// apiSearchQuery authorizes a search of the api and parses its query.
+// The search goes through the `indexes` param, or through all the loaded indexes.
+// It responds with the error if the search can't be done.
+func (s *Server) apiSearchQuery(c *gin.Context) (*indexer.Facade, *search.Query, *apikeys.Key, bool) {
+	values := c.Request.URL.Query()
+	indexNames := values.Get("indexes")
+	searchedIndexes := indexNames
+	if searchedIndexes == "" {
+		searchedIndexes = s.indexerFacade.CurrentIndexes().Name()
+	}
+	key, err := s.authorize(requestAPIKey(c), apikeys.ScopeSearch, searchedIndexes)
+	if err != nil {
+		c.JSON(authStatus(err), gin.H{"error": err.Error()})
+		return nil, nil, nil, false
+	}
+	values.Del("indexes")
+	query, err := search.NewQueryFromUrl(values)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, nil, false
+	}
+	query.Type = "search"
+
+	facade := s.indexerFacade
+	if indexNames != "" {
+		indexes, err := facade.IndexScope.Lookup(s.config, indexNames)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return nil, nil, nil, false
+		}
+		facade = facade.WithIndexes(indexes)
+	}
+	return facade, query, key, true
+}
+
+func newSearchResult(item search.ResultItemBase) searchResult {
+	scrapeItem := item.AsScrapeItem()
+	result := searchResult{
+		Title:     item.String(),
+		Site:      scrapeItem.Site,
+		Published: scrapeItem.PublishDate,
+		Link:      scrapeItem.Link,
+	}
+	if torrent, ok := item.(*search.TorrentResultItem); ok {
+		result.Title = torrent.Title
+		result.Category = torrent.Category
+		result.Size = torrent.Size
+		result.Seeders = torrent.Seeders
+		result.Peers = torrent.Peers
+		result.Details = torrent.Comments
+	}
+	return result
+}
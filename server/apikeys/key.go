@@ -0,0 +1,157 @@
+package apikeys
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+// Scope is something that an API key is allowed to do.
+type Scope string
+
+const (
+	// ScopeSearch allows searching through indexes.
+	ScopeSearch Scope = "search"
+	// ScopeDownload allows downloading torrents and reading the feeds.
+	ScopeDownload Scope = "download"
+	// ScopeAdmin allows changing settings and managing keys. It includes all the other scopes.
+	ScopeAdmin Scope = "admin"
+)
+
+var allScopes = []Scope{ScopeSearch, ScopeDownload, ScopeAdmin}
+
+// ParseScopes parses a comma separated list of scopes.
+func ParseScopes(value string) ([]Scope, error) {
+	var scopes []Scope
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		scope := Scope(part)
+		if !isValidScope(scope) {
+			return nil, fmt.Errorf("unknown scope `%s`, the scopes are: %s", part, joinScopes(allScopes))
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required: %s", joinScopes(allScopes))
+	}
+	return scopes, nil
+}
+
+func isValidScope(scope Scope) bool {
+	for _, validScope := range allScopes {
+		if scope == validScope {
+			return true
+		}
+	}
+	return false
+}
+
+func joinScopes(scopes []Scope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ",")
+}
+
+// Key is an API key that can be used with the server.
+// Only the hash of the secret is stored, the secret itself is shown once, when the key is created.
+type Key struct {
+	KeyID  string
+	Label  string
+	Hash   string
+	Scopes []Scope
+	// Indexes are the only indexes that the key can use. If it's empty, all of them can be used.
+	Indexes   []string
+	CreatedAt time.Time
+	LastUsed  time.Time
+	Uses      uint64
+	Revoked   bool
+	ModelData search.ModelData
+	UUIDValue string
+	RecordID  uint32
+	isNew     bool
+	isUpdate  bool
+}
+
+// HasScope checks if the key has a scope, admin keys have all of them.
+func (k *Key) HasScope(scope Scope) bool {
+	for _, keyScope := range k.Scopes {
+		if keyScope == scope || keyScope == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsIndex checks if the key can use an index.
+// The index can also be a comma separated list of indexes, in which case all of them need to be allowed.
+// An empty index means that no specific index is used.
+func (k *Key) AllowsIndex(index string) bool {
+	if len(k.Indexes) == 0 || index == "" {
+		return true
+	}
+	for _, name := range strings.Split(index, ",") {
+		name = strings.TrimSpace(name)
+		allowed := false
+		for _, keyIndex := range k.Indexes {
+			if strings.EqualFold(keyIndex, name) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// Allows checks if the key can be used for something in a scope, with an index.
+func (k *Key) Allows(scope Scope, index string) bool {
+	return !k.Revoked && k.HasScope(scope) && k.AllowsIndex(index)
+}
+
+func (k *Key) matches(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(k.Hash), []byte(hashSecret(secret))) == 1
+}
+
+func hashSecret(secret string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(secret)))
+}
+
+func (k *Key) UUID() string {
+	return k.UUIDValue
+}
+
+func (k *Key) SetUUID(s string) {
+	k.UUIDValue = s
+}
+
+func (k *Key) GetID() uint32 {
+	return k.RecordID
+}
+
+func (k *Key) SetID(u uint32) {
+	k.RecordID = u
+}
+
+func (k *Key) SetState(new, updated bool) {
+	k.isNew = new
+	k.isUpdate = updated
+}
+
+func (k *Key) IsNew() bool {
+	return k.isNew
+}
+
+func (k *Key) IsUpdate() bool {
+	return k.isUpdate
+}
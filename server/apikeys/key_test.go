@@ -0,0 +1,36 @@
+package apikeys
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseScopes(t *testing.T) {
+	g := NewWithT(t)
+
+	scopes, err := ParseScopes("search, Download")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(scopes).To(Equal([]Scope{ScopeSearch, ScopeDownload}))
+
+	_, err = ParseScopes("search,upload")
+	g.Expect(err).To(HaveOccurred())
+	_, err = ParseScopes("")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestKey_Allows(t *testing.T) {
+	g := NewWithT(t)
+	key := &Key{Scopes: []Scope{ScopeSearch}, Indexes: []string{"rutracker.org", "zamunda"}}
+
+	g.Expect(key.Allows(ScopeSearch, "rutracker.org")).To(BeTrue())
+	g.Expect(key.Allows(ScopeSearch, "zamunda,rutracker.org")).To(BeTrue())
+	g.Expect(key.Allows(ScopeSearch, "zamunda,other")).To(BeFalse())
+	g.Expect(key.Allows(ScopeSearch, "all")).To(BeFalse())
+	g.Expect(key.Allows(ScopeDownload, "zamunda")).To(BeFalse())
+
+	admin := &Key{Scopes: []Scope{ScopeAdmin}}
+	g.Expect(admin.Allows(ScopeDownload, "anything")).To(BeTrue())
+	admin.Revoked = true
+	g.Expect(admin.Allows(ScopeSearch, "")).To(BeFalse())
+}
@@ -0,0 +1,217 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/storage/bolt"
+	"github.com/sp0x/torrentd/storage/indexing"
+)
+
+const namespace = "__apikeys"
+
+// Store keeps the API keys in the storage.
+// Usage is only counted in memory, until Flush is called.
+type Store struct {
+	storage storage.ItemStorage
+	mux     sync.RWMutex
+	keys    map[string]*Key
+	// dirty are the keys with usage that isn't stored yet.
+	dirty map[string]bool
+}
+
+// DatabasePath is the database with the API keys.
+// This is the `apikeys_db` config value, by default the keys are next to the results database.
+func DatabasePath(conf config.Config) string {
+	endpoint := conf.GetString("apikeys_db")
+	if endpoint == "" {
+		resultsEndpoint := conf.GetString("storageendpoint")
+		if resultsEndpoint == "" {
+			resultsEndpoint = bolt.GetDefaultDatabasePath()
+		}
+		endpoint = filepath.Join(filepath.Dir(resultsEndpoint), "apikeys.db")
+	}
+	return endpoint
+}
+
+// NewStore opens the API keys storage.
+func NewStore(conf config.Config) *Store {
+	builder := storage.NewBuilder(conf).
+		WithNamespace(namespace).
+		WithPK(indexing.NewKey("KeyID")).
+		WithEndpoint(DatabasePath(conf)).
+		WithRecord(&Key{})
+	if storageType := conf.GetString("storage"); storageType != "" {
+		builder = builder.WithBacking(storageType)
+	}
+	return newStore(builder.Build())
+}
+
+func newStore(itemStorage storage.ItemStorage) *Store {
+	s := &Store{
+		storage: itemStorage,
+		keys:    make(map[string]*Key),
+		dirty:   make(map[string]bool),
+	}
+	itemStorage.ForEachInNamespaces(func(ns string, record search.Record) bool {
+		if ns != namespace {
+			return false
+		}
+		if key, ok := record.(*Key); ok {
+			s.keys[key.KeyID] = key
+		}
+		return true
+	})
+	return s
+}
+
+// Len is the number of keys that can be used.
+func (s *Store) Len() int {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	count := 0
+	for _, key := range s.keys {
+		if !key.Revoked {
+			count++
+		}
+	}
+	return count
+}
+
+// Create makes a new key and returns it, with its secret.
+// The secret isn't stored, so this is the only time it's available.
+func (s *Store) Create(label string, scopes []Scope, indexes []string) (*Key, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !isValidScope(scope) {
+			return nil, "", fmt.Errorf("unknown scope `%s`", scope)
+		}
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	var keyID string
+	for keyID == "" || s.keys[keyID] != nil {
+		keyID = randomHex(4)
+	}
+	secret := keyID + "." + randomHex(16)
+	key := &Key{
+		KeyID:     keyID,
+		Label:     label,
+		Hash:      hashSecret(secret),
+		Scopes:    scopes,
+		Indexes:   indexes,
+		CreatedAt: time.Now(),
+		ModelData: search.ModelData{},
+	}
+	if err := s.storage.Add(key); err != nil {
+		return nil, "", err
+	}
+	s.keys[keyID] = key
+	keyCopy := *key
+	return &keyCopy, secret, nil
+}
+
+// List gets all the keys, including the revoked ones, ordered by their creation time.
+func (s *Store) List() []Key {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	keys := make([]Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, *key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// Get finds a key by its ID.
+func (s *Store) Get(keyID string) (*Key, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, false
+	}
+	keyCopy := *key
+	return &keyCopy, true
+}
+
+// Revoke disables a key, it's kept so that its usage is still visible.
+func (s *Store) Revoke(keyID string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	key, ok := s.keys[keyID]
+	if !ok {
+		return fmt.Errorf("key `%s` not found", keyID)
+	}
+	key.Revoked = true
+	delete(s.dirty, keyID)
+	return s.storage.Add(key)
+}
+
+// Authenticate finds the key of a secret. Revoked keys aren't returned.
+func (s *Store) Authenticate(secret string) (*Key, bool) {
+	separator := strings.Index(secret, ".")
+	if separator <= 0 {
+		return nil, false
+	}
+	key, ok := s.Get(secret[:separator])
+	if !ok || key.Revoked || !key.matches(secret) {
+		return nil, false
+	}
+	return key, true
+}
+
+// Use counts a use of a key.
+func (s *Store) Use(keyID string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	key, ok := s.keys[keyID]
+	if !ok {
+		return
+	}
+	key.Uses++
+	key.LastUsed = time.Now()
+	s.dirty[keyID] = true
+}
+
+// Flush stores the usage of the keys that were used since the last flush.
+func (s *Store) Flush() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for keyID := range s.dirty {
+		if err := s.storage.Add(s.keys[keyID]); err != nil {
+			return err
+		}
+		delete(s.dirty, keyID)
+	}
+	return nil
+}
+
+// Close flushes the usage of the keys and closes the storage.
+func (s *Store) Close() {
+	if err := s.Flush(); err != nil {
+		log.Warningf("Couldn't store the usage of the api keys: %v", err)
+	}
+	s.storage.Close()
+}
+
+func randomHex(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
@@ -0,0 +1,67 @@
+package apikeys
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/storage/indexing"
+)
+
+func openTestStore(dbFile string) *Store {
+	return newStore(storage.NewBuilder(nil).
+		WithNamespace(namespace).
+		WithPK(indexing.NewKey("KeyID")).
+		WithEndpoint(dbFile).
+		WithRecord(&Key{}).
+		Build())
+}
+
+func TestStore(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := ioutil.TempDir("", "apikeys-")
+	defer os.RemoveAll(dir)
+	dbFile := filepath.Join(dir, "apikeys.db")
+
+	store := openTestStore(dbFile)
+	g.Expect(store.Len()).To(Equal(0))
+	key, secret, err := store.Create("sonarr", []Scope{ScopeSearch, ScopeDownload}, []string{"zamunda"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(secret).To(HavePrefix(key.KeyID + "."))
+	g.Expect(key.Hash).ToNot(ContainSubstring(secret))
+	_, _, err = store.Create("nothing", nil, nil)
+	g.Expect(err).To(HaveOccurred())
+
+	found, ok := store.Authenticate(secret)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(found.Label).To(Equal("sonarr"))
+	_, ok = store.Authenticate(key.KeyID + ".wrong")
+	g.Expect(ok).To(BeFalse())
+	_, ok = store.Authenticate("")
+	g.Expect(ok).To(BeFalse())
+
+	store.Use(key.KeyID)
+	store.Use(key.KeyID)
+	store.Close()
+
+	// The keys and their usage should be kept.
+	store = openTestStore(dbFile)
+	g.Expect(store.Len()).To(Equal(1))
+	found, ok = store.Get(key.KeyID)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(found.Uses).To(Equal(uint64(2)))
+	g.Expect(found.LastUsed.IsZero()).To(BeFalse())
+	g.Expect(found.Indexes).To(Equal([]string{"zamunda"}))
+
+	g.Expect(store.Revoke(key.KeyID)).To(Succeed())
+	g.Expect(store.Revoke("missing")).ToNot(Succeed())
+	_, ok = store.Authenticate(secret)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(store.Len()).To(Equal(0))
+	g.Expect(store.List()).To(HaveLen(1))
+	store.Close()
+}
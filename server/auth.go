@@ -1,15 +1,38 @@
 package server
 
 import (
+	"crypto/rand"
 	"crypto/sha1"
+	"errors"
 	"fmt"
-	"math/rand"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/server/apikeys"
+)
+
+// configKeyID is the ID of the key that's given with `api_key` or a passphrase in the config.
+const configKeyID = "config"
+
+var (
+	errMissingAPIKey = errors.New("an api key is required")
+	errInvalidAPIKey = errors.New("invalid api key")
+	errKeyNotAllowed = errors.New("the api key isn't allowed to do this")
+	errNoAdminKey    = errors.New("an api key with the admin scope is required, create one with `torrentd keys create --scopes admin`")
 )
 
+// configKey is the key from the config, it can do everything.
+var configKey = &apikeys.Key{KeyID: configKeyID, Label: "api_key from the config", Scopes: []apikeys.Scope{apikeys.ScopeAdmin}}
+
 // sharedKey gets the hash of the api key or passphrase that's configured in our server.
-// If no key or passphrase is given, an array of 16 random bytes is returned.
+// If no key or passphrase is given, a random secret is used. It's kept in the cache directory,
+// so that the download links that we give out keep working after a restart.
 func (s *Server) sharedKey() []byte {
 	var b []byte
 	switch {
@@ -20,21 +43,131 @@ func (s *Server) sharedKey() []byte {
 		b = hash[0:16]
 		b = []byte(fmt.Sprintf("%x", b))
 	default:
-		b = make([]byte, 16)
-		for i := range b {
-			b[i] = byte(rand.Intn(256))
-		}
-		b = []byte(fmt.Sprintf("%x", b))
+		s.secretOnce.Do(func() {
+			s.secret = loadTokenSecret(filepath.Join(config.GetCachePath("auth"), "token_secret"))
+		})
+		b = s.secret
 	}
 	return b
 }
 
+// loadTokenSecret reads the secret from a file, or creates it if there isn't one yet.
+func loadTokenSecret(file string) []byte {
+	if secret, err := ioutil.ReadFile(file); err == nil && len(secret) > 0 {
+		return secret
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	secret := []byte(fmt.Sprintf("%x", b))
+	if err := ioutil.WriteFile(file, secret, 0600); err != nil {
+		log.Warningf("Couldn't store the token secret, download links won't work after a restart: %v", err)
+	}
+	return secret
+}
+
 func (s *Server) checkAPIKey(inputKey string) bool {
 	k := s.sharedKey()
 	keyToMatch := string(k)
 	if inputKey == keyToMatch {
 		return true
 	}
-	log.Printf("Incorrect api key, expected %x", k)
+	log.Printf("Incorrect api key")
 	return false
 }
+
+// hasConfigKey is true if there's an api key or a passphrase in the config.
+func (s *Server) hasConfigKey() bool {
+	return s.Params.APIKey != nil || s.Params.Passphrase != ""
+}
+
+// authEnabled is true if any api key is set up. Without one, searching and downloading can be done without a key.
+func (s *Server) authEnabled() bool {
+	return s.hasConfigKey() || (s.keys != nil && s.keys.Len() > 0)
+}
+
+// authorize finds the key of a request, and checks if it can be used in a scope, with an index.
+// If auth isn't enabled, no key is returned. The admin scope always needs a key, so that the first
+// one can't be created by anyone who reaches the server.
+func (s *Server) authorize(secret string, scope apikeys.Scope, index string) (*apikeys.Key, error) {
+	if !s.authEnabled() {
+		if scope == apikeys.ScopeAdmin {
+			return nil, errNoAdminKey
+		}
+		return nil, nil
+	}
+	if secret == "" {
+		return nil, errMissingAPIKey
+	}
+	var key *apikeys.Key
+	if s.keys != nil {
+		key, _ = s.keys.Authenticate(secret)
+	}
+	if key == nil {
+		if !s.hasConfigKey() || !s.checkAPIKey(secret) {
+			return nil, errInvalidAPIKey
+		}
+		return configKey, nil
+	}
+	if !key.Allows(scope, index) {
+		return nil, errKeyNotAllowed
+	}
+	s.keys.Use(key.KeyID)
+	return key, nil
+}
+
+// authorizeToken checks if the key of a download token can still be used to download from its index.
+// Tokens that aren't made for a key are only signed with our shared key, so they don't need any checks.
+func (s *Server) authorizeToken(t *token) error {
+	if !s.authEnabled() || t.KeyID == "" {
+		return nil
+	}
+	if t.KeyID == configKeyID {
+		if s.hasConfigKey() {
+			return nil
+		}
+		return errKeyNotAllowed
+	}
+	if s.keys == nil {
+		return errKeyNotAllowed
+	}
+	key, ok := s.keys.Get(t.KeyID)
+	if !ok || !key.Allows(apikeys.ScopeDownload, t.IndexName) {
+		return errKeyNotAllowed
+	}
+	s.keys.Use(key.KeyID)
+	return nil
+}
+
+// requestAPIKey gets the api key of a request, from the `apikey` parameter or the X-Api-Key header.
+func requestAPIKey(c *gin.Context) string {
+	if key := c.Query("apikey"); key != "" {
+		return key
+	}
+	return c.GetHeader("X-Api-Key")
+}
+
+// authStatus is the http status for an authorization error.
+func authStatus(err error) int {
+	if err == errKeyNotAllowed {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
+
+// flushKeyUsage stores the usage of the api keys every once in a while.
+func (s *Server) flushKeyUsage(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := s.keys.Flush(); err != nil {
+			log.Warningf("Couldn't store the usage of the api keys: %v", err)
+		}
+	}
+}
+
+func keyID(key *apikeys.Key) string {
+	if key == nil {
+		return ""
+	}
+	return key.KeyID
+}
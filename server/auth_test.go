@@ -1,12 +1,16 @@
 package server
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/gomega"
 
 	"github.com/sp0x/torrentd/config/mocks"
+	"github.com/sp0x/torrentd/server/apikeys"
 )
 
 func TestServer_checkAPIKey(t *testing.T) {
@@ -52,8 +56,9 @@ func TestServer_sharedKey(t *testing.T) {
 	bytes := s.sharedKey()
 	bytes2 := s.sharedKey()
 
+	// Without a key, the same secret should be used, so that download links keep working.
 	g.Expect(len(bytes)).Should(Equal(32))
-	g.Expect(bytes).ShouldNot(Equal(bytes2))
+	g.Expect(bytes).Should(Equal(bytes2))
 
 	s.Params.APIKey = []byte("demokey")
 	bytes = s.sharedKey()
@@ -65,3 +70,54 @@ func TestServer_sharedKey(t *testing.T) {
 	g.Expect(len(bytes)).Should(Equal(32))
 	g.Expect(bytes).Should(Equal([]byte("cd2234c6b7755b8dd230bdbc84544c38")))
 }
+
+func TestServer_authorize(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	dir, _ := ioutil.TempDir("", "apikeys-")
+	defer os.RemoveAll(dir)
+	config := mocks.NewMockConfig(ctrl)
+	config.EXPECT().GetString("apikeys_db").Return(filepath.Join(dir, "apikeys.db"))
+	config.EXPECT().GetString(gomock.Any()).Return("").AnyTimes()
+	s := &Server{config: config, keys: apikeys.NewStore(config)}
+	defer s.keys.Close()
+
+	// Without any keys, searching and downloading are allowed, but admin actions aren't.
+	key, err := s.authorize("", apikeys.ScopeSearch, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key).To(BeNil())
+	_, err = s.authorize("", apikeys.ScopeDownload, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = s.authorize("", apikeys.ScopeAdmin, "")
+	g.Expect(err).To(Equal(errNoAdminKey))
+
+	created, secret, err := s.keys.Create("sonarr", []apikeys.Scope{apikeys.ScopeSearch}, []string{"zamunda"})
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = s.authorize("", apikeys.ScopeSearch, "zamunda")
+	g.Expect(err).To(Equal(errMissingAPIKey))
+	_, err = s.authorize("wrong", apikeys.ScopeSearch, "zamunda")
+	g.Expect(err).To(Equal(errInvalidAPIKey))
+	_, err = s.authorize(secret, apikeys.ScopeSearch, "rutracker.org")
+	g.Expect(err).To(Equal(errKeyNotAllowed))
+	_, err = s.authorize(secret, apikeys.ScopeAdmin, "")
+	g.Expect(err).To(Equal(errKeyNotAllowed))
+	key, err = s.authorize(secret, apikeys.ScopeSearch, "zamunda")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key.KeyID).To(Equal(created.KeyID))
+	used, _ := s.keys.Get(created.KeyID)
+	g.Expect(used.Uses).To(Equal(uint64(1)))
+
+	// The key from the config can do everything.
+	s.Params.APIKey = []byte("demokey")
+	key, err = s.authorize("demokey", apikeys.ScopeAdmin, "")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(key.KeyID).To(Equal(configKeyID))
+
+	// Download tokens need a key that can still download from the index.
+	tkn := &token{IndexName: "zamunda", Link: "http://zamunda.net/1", KeyID: created.KeyID}
+	g.Expect(s.authorizeToken(tkn)).To(Equal(errKeyNotAllowed))
+	g.Expect(s.keys.Revoke(created.KeyID)).To(Succeed())
+	_, err = s.authorize(secret, apikeys.ScopeSearch, "zamunda")
+	g.Expect(err).To(Equal(errInvalidAPIKey))
+}
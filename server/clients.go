@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sp0x/torrentd/clients"
+	"github.com/sp0x/torrentd/server/apikeys"
+)
+
+type addTorrentRequest struct {
+	// ID is the UUID of a stored result.
+	ID string `json:"id"`
+	// Link is a magnet link, a download link of the server, or the link of a .torrent file on the index.
+	Link     string `json:"link"`
+	Index    string `json:"index"`
+	Client   string `json:"client"`
+	Category string `json:"category"`
+	SavePath string `json:"savepath"`
+	Paused   bool   `json:"paused"`
+}
+
+type addTorrentResponse struct {
+	Client string `json:"client"`
+	Name   string `json:"name,omitempty"`
+}
+
+// listClients godoc
+// @Summary      List torrent clients
+// @Description  List the names of the torrent clients that results can be sent to
+// @Tags         clients
+// @Accept       */*
+// @param 	  	 apikey query string true "API key with the download scope"
+// @Produce      json
+// @Success      200  {array}  string
+// @Router       /api/clients [get]
+func (s *Server) listClients(c *gin.Context) {
+	if _, err := s.authorize(requestAPIKey(c), apikeys.ScopeDownload, ""); err != nil {
+		c.JSON(authStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, clients.Names(s.config))
+}
+
+// addToClient godoc
+// @Summary      Send a torrent to a client
+// @Description  Send a stored result, a magnet link or a download link to a torrent client. Files are downloaded through their index.
+// @Tags         clients
+// @Accept       json
+// @param 	  	 apikey query string true "API key with the download scope"
+// @Produce      json
+// @Success      200  {object}  addTorrentResponse
+// @Router       /api/clients/add [post]
+func (s *Server) addToClient(c *gin.Context) {
+	var request addTorrentRequest
+	if err := c.BindJSON(&request); err != nil {
+		return
+	}
+	// The key is checked before the source is looked up, the index of the source is checked once it's known.
+	key, err := s.authorize(requestAPIKey(c), apikeys.ScopeDownload, "")
+	if err != nil {
+		c.JSON(authStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	src, err := s.clientSource(&request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if key != nil && !key.AllowsIndex(src.Index) {
+		c.JSON(authStatus(errKeyNotAllowed), gin.H{"error": errKeyNotAllowed.Error()})
+		return
+	}
+	client, err := clients.FromConfig(s.config, request.Client)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	torrent, err := clients.Fetch(c.Request.Context(), s.indexerFacade.IndexScope, s.config, s.torrentCache, src)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	options := &clients.AddOptions{
+		Category: request.Category,
+		SavePath: request.SavePath,
+		Paused:   request.Paused,
+	}
+	if err := client.Add(torrent, options); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, addTorrentResponse{Client: client.Name(), Name: src.Name})
+}
+
+// clientSource finds the torrent of a request, by the id of a stored result or by a link.
+// The download links of the server have the index and link of the torrent in their token.
+func (s *Server) clientSource(request *addTorrentRequest) (*clients.Source, error) {
+	if request.ID != "" {
+		item, err := clients.FindResult(s.resultStorage(), request.ID)
+		if err != nil {
+			return nil, err
+		}
+		return clients.ResultSource(item), nil
+	}
+	if request.Link == "" {
+		return nil, errors.New("an id or a link is required")
+	}
+	src := &clients.Source{Index: request.Index, Link: request.Link}
+	if src.IsMagnet() {
+		return src, nil
+	}
+	if src.Index != "" {
+		// The link is downloaded through the index, so it has to be one of its own.
+		if err := clients.CheckSite(s.indexerFacade.IndexScope, s.config, src); err != nil {
+			return nil, err
+		}
+		return src, nil
+	}
+	t, err := decodeToken(tokenFromLink(request.Link), s.sharedKey())
+	if err != nil || t.Link == "" {
+		return nil, errors.New("the index of the link is required")
+	}
+	if s.tokens != nil && s.tokens.IsRevoked(t.ID) {
+		return nil, errTokenRevoked
+	}
+	return &clients.Source{Index: t.IndexName, Link: t.Link}, nil
+}
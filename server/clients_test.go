@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+)
+
+func TestServer_addToClient_ShouldAuthorizeBeforeLookingUpTheSource(t *testing.T) {
+	g := gomega.NewWithT(t)
+	gin.SetMode(gin.TestMode)
+	// The server has no storage or indexes, so the request fails if the source is looked up.
+	s := &Server{Params: Params{APIKey: []byte("secret")}}
+	for _, body := range []string{`{"id":"a-result"}`, `{"link":"http://localhost/t/token/file.torrent"}`} {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/clients/add?apikey=wrong", strings.NewReader(body))
+		s.addToClient(c)
+		g.Expect(recorder.Code).To(gomega.Equal(http.StatusUnauthorized), body)
+	}
+}
@@ -1,12 +1,30 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/sp0x/torrentd/indexer"
 	"github.com/sp0x/torrentd/server/http"
+	"github.com/sp0x/torrentd/server/tokens"
+)
+
+// downloadStartTimeout is how long to wait for a download to start.
+const downloadStartTimeout = 20 * time.Second
+
+var (
+	errTokenRevoked        = errors.New("the download link was revoked")
+	errNoRange             = errors.New("no valid range")
+	errRangeNotSatisfiable = errors.New("the range is not satisfiable")
 )
 
 func (s *Server) downloadHandler(c http.Context) {
@@ -23,6 +41,10 @@ func (s *Server) downloadHandler(c http.Context) {
 
 	apiKey := s.sharedKey()
 	t, err := decodeToken(token, apiKey)
+	if err == errTokenExpired {
+		c.String(410, err.Error())
+		return
+	}
 	if err != nil {
 		_ = c.Error(err)
 		return
@@ -31,39 +53,209 @@ func (s *Server) downloadHandler(c http.Context) {
 		c.String(404, "Indexes link not found")
 		return
 	}
-	indexes, err := s.indexerFacade.IndexScope.Lookup(s.config, t.IndexName)
-	if err != nil {
-		_ = c.Error(err)
+	if s.tokens != nil && s.tokens.IsRevoked(t.ID) {
+		s.logDownload(c, t, filename, 410, errTokenRevoked)
+		c.String(410, errTokenRevoked.Error())
 		return
 	}
-	if indexes == nil {
-		_ = c.Error(errors.New("indexer not found"))
+	if err := s.authorizeToken(t); err != nil {
+		s.logDownload(c, t, filename, 403, err)
+		c.String(403, err.Error())
 		return
 	}
-	firstIndex := indexes[0]
+	if c.RequestMethod() == "HEAD" {
+		// The headers are answered without downloading the torrent.
+		s.headTorrent(c, t, filename)
+		return
+	}
+	ctx := c.RequestContext()
+	open := func(ctx context.Context) (*indexer.ResponseProxy, error) {
+		indexes, err := s.indexerFacade.IndexScope.Lookup(s.config, t.IndexName)
+		if err != nil {
+			return nil, err
+		}
+		if indexes == nil {
+			return nil, errors.New("indexer not found")
+		}
+		return indexes[0].Download(ctx, t.Link)
+	}
+	if s.torrentCache != nil {
+		// The file is only downloaded if it's not already cached.
+		if content, ok := s.torrentCache.Get(t.IndexName, t.Link); ok {
+			status := sendTorrent(c, filename, int64(len(content)), bytes.NewReader(content))
+			s.logDownload(c, t, filename, status, nil)
+			return
+		}
+	}
 
-	downloadProxy, err := firstIndex.Download(t.Link)
+	downloadProxy, err := open(ctx)
 	if err != nil {
+		s.logDownload(c, t, filename, 500, err)
 		_ = c.Error(err)
 		return
 	}
 	if downloadProxy == nil || downloadProxy.Reader == nil {
-		_ = c.Error(errors.New("couldn't open stream for download"))
+		err = errors.New("couldn't open stream for download")
+		s.logDownload(c, t, filename, 500, err)
+		_ = c.Error(err)
 		return
 	}
+	handled := make(chan struct{})
 	defer func() {
+		close(handled)
 		_ = downloadProxy.Reader.Close()
 	}()
+	// The download is stopped if the client disconnects.
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = downloadProxy.Reader.Close()
+		case <-handled:
+		}
+	}()
 
 	log.WithFields(log.Fields{"link": t.Link}).
 		Infof("Waiting for download")
+	timer := time.NewTimer(downloadStartTimeout)
+	defer timer.Stop()
 	select {
 	case length := <-downloadProxy.ContentLengthChan:
-		c.Header("Content-Type", "application/x-bittorrent")
-		c.Header("Content-Disposition", "attachment; filename="+filename)
-		c.Header("Content-Transfer-Encoding", "binary")
-		c.DataFromReader(200, length, "application/x-bittorrent", downloadProxy.Reader, nil)
-	case <-time.After(20 * time.Second):
+		var reader io.Reader = downloadProxy.Reader
+		if s.torrentCache != nil {
+			// The download is cached while it's sent.
+			cachingReader := s.torrentCache.NewReader(t.IndexName, t.Link, reader)
+			defer cachingReader.Close()
+			reader = cachingReader
+		}
+		status := sendTorrent(c, filename, length, reader)
+		s.logDownload(c, t, filename, status, nil)
+	case <-ctx.Done():
+		s.logDownload(c, t, filename, 499, ctx.Err())
+	case <-timer.C:
+		s.logDownload(c, t, filename, 408, errors.New("timed out waiting for download"))
 		c.String(408, "Timed out waiting for download")
 	}
 }
+
+// headTorrent answers a HEAD request for a download.
+// The length is only known if the torrent is cached.
+func (s *Server) headTorrent(c http.Context, t *token, filename string) {
+	setTorrentHeaders(c, filename)
+	if s.torrentCache != nil {
+		if content, ok := s.torrentCache.Get(t.IndexName, t.Link); ok {
+			c.Header("Content-Length", strconv.Itoa(len(content)))
+			c.Header("Accept-Ranges", "bytes")
+		}
+	}
+	c.Status(200)
+}
+
+func setTorrentHeaders(c http.Context, filename string) {
+	c.Header("Content-Type", "application/x-bittorrent")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Transfer-Encoding", "binary")
+}
+
+// sendTorrent sends the torrent, or the requested range of it, and returns the status of the response.
+// Ranges are only served if the length of the torrent is known.
+func sendTorrent(c http.Context, filename string, length int64, reader io.Reader) int {
+	setTorrentHeaders(c, filename)
+	if length < 0 {
+		c.DataFromReader(200, length, "application/x-bittorrent", reader, nil)
+		return 200
+	}
+	start, end, err := parseRange(c.GetHeader("Range"), length)
+	switch err {
+	case nil:
+		if _, err := io.CopyN(ioutil.Discard, reader, start); err != nil {
+			_ = c.Error(err)
+			return 500
+		}
+		size := end - start + 1
+		c.DataFromReader(206, size, "application/x-bittorrent", io.LimitReader(reader, size), map[string]string{
+			"Accept-Ranges": "bytes",
+			"Content-Range": fmt.Sprintf("bytes %d-%d/%d", start, end, length),
+		})
+		return 206
+	case errRangeNotSatisfiable:
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", length))
+		c.String(416, err.Error())
+		return 416
+	default:
+		// Without a valid range, the whole torrent is sent.
+		c.DataFromReader(200, length, "application/x-bittorrent", reader, map[string]string{
+			"Accept-Ranges": "bytes",
+		})
+		return 200
+	}
+}
+
+// parseRange parses a `Range` header with a single range of bytes.
+// errNoRange is returned if there's no range that can be served, so the whole content should be sent.
+func parseRange(header string, length int64) (int64, int64, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return 0, 0, errNoRange
+	}
+	spec := strings.TrimSpace(header[len(prefix):])
+	separator := strings.Index(spec, "-")
+	if separator < 0 || strings.Contains(spec, ",") {
+		return 0, 0, errNoRange
+	}
+	startValue := strings.TrimSpace(spec[:separator])
+	endValue := strings.TrimSpace(spec[separator+1:])
+	if startValue == "" {
+		// A suffix range, with the last bytes of the content.
+		suffix, err := strconv.ParseInt(endValue, 10, 64)
+		if err != nil || suffix < 0 {
+			return 0, 0, errNoRange
+		}
+		if suffix == 0 || length == 0 {
+			return 0, 0, errRangeNotSatisfiable
+		}
+		if suffix > length {
+			suffix = length
+		}
+		return length - suffix, length - 1, nil
+	}
+	start, err := strconv.ParseInt(startValue, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, errNoRange
+	}
+	end := length - 1
+	if endValue != "" {
+		end, err = strconv.ParseInt(endValue, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, errNoRange
+		}
+		if end >= length {
+			end = length - 1
+		}
+	}
+	if start >= length {
+		return 0, 0, errRangeNotSatisfiable
+	}
+	return start, end, nil
+}
+
+// logDownload adds a download to the audit log of downloads.
+func (s *Server) logDownload(c http.Context, t *token, filename string, status int, err error) {
+	if s.tokens == nil {
+		return
+	}
+	download := &tokens.Download{
+		TokenID:  t.ID,
+		KeyID:    t.KeyID,
+		Index:    t.IndexName,
+		Link:     t.Link,
+		Filename: filename,
+		ClientIP: c.ClientIP(),
+		Status:   status,
+	}
+	if err != nil {
+		download.Error = err.Error()
+	}
+	if err := s.tokens.LogDownload(download); err != nil {
+		log.Warningf("Couldn't log the download of %s: %v", t.Link, err)
+	}
+}
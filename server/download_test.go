@@ -1,17 +1,38 @@
 package server
 
 import (
+	stdcontext "context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
 
 	"github.com/sp0x/torrentd/config/mocks"
 	"github.com/sp0x/torrentd/indexer"
 	httpMocks "github.com/sp0x/torrentd/server/http/mocks"
+	"github.com/sp0x/torrentd/server/tokens"
+	"github.com/sp0x/torrentd/torrent"
 )
 
-func prepareTestServer(ctrl *gomock.Controller, config *mocks.MockConfig) (*Server, *httpMocks.MockContext) {
+// newRequestContext mocks the context of a request, with its method and `Range` header.
+func newRequestContext(ctrl *gomock.Controller, method, rangeHeader string) *httpMocks.MockContext {
 	context := httpMocks.NewMockContext(ctrl)
+	context.EXPECT().RequestMethod().Return(method).AnyTimes()
+	context.EXPECT().RequestContext().Return(stdcontext.Background()).AnyTimes()
+	context.EXPECT().GetHeader("Range").Return(rangeHeader).AnyTimes()
+	return context
+}
+
+func prepareTestServer(ctrl *gomock.Controller, config *mocks.MockConfig) (*Server, *httpMocks.MockContext) {
+	context := newRequestContext(ctrl, "GET", "")
 	config.EXPECT().Get("indexLoader").Return(nil).AnyTimes()
 	config.EXPECT().GetInt("workerCount").Return(2).AnyTimes()
 	config.EXPECT().GetInt("port").Return(3333)
@@ -116,9 +137,262 @@ func TestServer_downloadHandler(t *testing.T) {
 	context.EXPECT().Header("Content-Transfer-Encoding", gomock.Any())
 	context.EXPECT().DataFromReader(200, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(1)
 	scopeMock.EXPECT().Lookup(gomock.Any(), "rutracker.org").Return([]indexer.Indexer{mockedIndexer}, nil)
-	mockedIndexer.EXPECT().Download(tkn.Link).
+	mockedIndexer.EXPECT().Download(gomock.Any(), tkn.Link).
 		Return(responseProxy, nil)
 
 	server.indexerFacade.IndexScope = scopeMock
 	server.downloadHandler(context)
 }
+
+func TestServer_downloadHandler_Should_Reject_RevokedAndExpiredTokens(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	config := mocks.NewMockConfig(ctrl)
+	server, context := prepareTestServer(ctrl, config)
+	dir, _ := ioutil.TempDir("", "tokens-")
+	defer os.RemoveAll(dir)
+	config.EXPECT().GetString("apikeys_db").Return(filepath.Join(dir, "apikeys.db")).AnyTimes()
+	config.EXPECT().GetString(gomock.Any()).Return("").AnyTimes()
+	server.tokens = tokens.NewStore(config)
+	defer server.tokens.Close()
+
+	tkn := token{IndexName: "rutracker.org", Link: "http://rutracker.org"}
+	tokenString, _ := tkn.Encode([]byte("demotoken"))
+	_, err := server.tokens.Revoke(tkn.ID, "leaked")
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	context.EXPECT().Param("token").Return(tokenString)
+	context.EXPECT().Param("filename").Return("file.torrent")
+	context.EXPECT().ClientIP().Return("127.0.0.1")
+	context.EXPECT().String(410, errTokenRevoked.Error())
+	server.downloadHandler(context)
+
+	// The attempt should be in the download log.
+	downloads := server.tokens.Downloads(10)
+	g.Expect(downloads).To(gomega.HaveLen(1))
+	g.Expect(downloads[0].TokenID).To(gomega.Equal(tkn.ID))
+	g.Expect(downloads[0].Status).To(gomega.Equal(410))
+	g.Expect(downloads[0].ClientIP).To(gomega.Equal("127.0.0.1"))
+
+	expired := token{IndexName: "rutracker.org", Link: "http://rutracker.org", Expires: time.Now().Add(-time.Hour)}
+	tokenString, _ = expired.Encode([]byte("demotoken"))
+	context.EXPECT().Param("token").Return(tokenString)
+	context.EXPECT().Param("filename").Return("file.torrent")
+	context.EXPECT().String(410, errTokenExpired.Error())
+	server.downloadHandler(context)
+}
+
+func TestTokenFromLink(t *testing.T) {
+	g := gomega.NewWithT(t)
+	g.Expect(tokenFromLink("http://localhost:5000/d/abc.def.ghi/file.torrent")).To(gomega.Equal("abc.def.ghi"))
+	g.Expect(tokenFromLink("/download/abc.def.ghi/file.torrent")).To(gomega.Equal("abc.def.ghi"))
+	g.Expect(tokenFromLink("abc.def.ghi")).To(gomega.Equal("abc.def.ghi"))
+}
+
+func TestServer_downloadHandler_Should_Use_TheTorrentCache(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	config := mocks.NewMockConfig(ctrl)
+	server, context := prepareTestServer(ctrl, config)
+	dir, _ := ioutil.TempDir("", "torrents-")
+	defer os.RemoveAll(dir)
+	torrentCache, err := torrent.NewFileCache(dir, time.Hour, 1024*1024)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	content, err := ioutil.ReadFile(filepath.Join("..", "torrent", "testdata", "sample.torrent"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	_, err = torrentCache.Put("rutracker.org", "http://rutracker.org", content)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	server.torrentCache = torrentCache
+
+	// The index shouldn't be used, since the file is cached.
+	scopeMock := indexer.NewMockScope(ctrl)
+	server.indexerFacade.IndexScope = scopeMock
+	tkn := token{IndexName: "rutracker.org", Link: "http://rutracker.org"}
+	tokenString, _ := tkn.Encode([]byte("demotoken"))
+	context.EXPECT().Param("token").Return(tokenString)
+	context.EXPECT().Param("filename").Return("file.torrent")
+	context.EXPECT().Header(gomock.Any(), gomock.Any()).Times(3)
+	context.EXPECT().DataFromReader(200, int64(len(content)), "application/x-bittorrent", gomock.Any(), gomock.Any())
+	server.downloadHandler(context)
+}
+
+func TestServer_downloadHandler_Should_Serve_RangesAndHEAD_FromTheCache(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	config := mocks.NewMockConfig(ctrl)
+	server, _ := prepareTestServer(ctrl, config)
+	dir, _ := ioutil.TempDir("", "torrents-")
+	defer os.RemoveAll(dir)
+	torrentCache, err := torrent.NewFileCache(dir, time.Hour, 1024*1024)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	content, err := ioutil.ReadFile(filepath.Join("..", "torrent", "testdata", "sample.torrent"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	_, err = torrentCache.Put("rutracker.org", "http://rutracker.org", content)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	server.torrentCache = torrentCache
+	server.indexerFacade.IndexScope = indexer.NewMockScope(ctrl)
+	tkn := token{IndexName: "rutracker.org", Link: "http://rutracker.org"}
+	tokenString, _ := tkn.Encode([]byte("demotoken"))
+
+	context := newRequestContext(ctrl, "GET", "bytes=10-19")
+	context.EXPECT().Param("token").Return(tokenString)
+	context.EXPECT().Param("filename").Return("file.torrent")
+	context.EXPECT().Header(gomock.Any(), gomock.Any()).Times(3)
+	context.EXPECT().DataFromReader(206, int64(10), "application/x-bittorrent", gomock.Any(), map[string]string{
+		"Accept-Ranges": "bytes",
+		"Content-Range": fmt.Sprintf("bytes 10-19/%d", len(content)),
+	}).Do(func(code int, length int64, contentType string, reader io.Reader, headers map[string]string) {
+		sent, _ := ioutil.ReadAll(reader)
+		g.Expect(sent).To(gomega.Equal(content[10:20]))
+	})
+	server.downloadHandler(context)
+
+	context = newRequestContext(ctrl, "HEAD", "")
+	context.EXPECT().Param("token").Return(tokenString)
+	context.EXPECT().Param("filename").Return("file.torrent")
+	context.EXPECT().Header(gomock.Any(), gomock.Any()).Times(3)
+	context.EXPECT().Header("Content-Length", strconv.Itoa(len(content)))
+	context.EXPECT().Header("Accept-Ranges", "bytes")
+	context.EXPECT().Status(200)
+	server.downloadHandler(context)
+}
+
+func TestServer_downloadHandler_Should_NotDownload_OnHEAD(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	config := mocks.NewMockConfig(ctrl)
+	server, _ := prepareTestServer(ctrl, config)
+	// The index isn't used, so the download doesn't start.
+	server.indexerFacade.IndexScope = indexer.NewMockScope(ctrl)
+	tkn := token{IndexName: "rutracker.org", Link: "http://rutracker.org"}
+	tokenString, _ := tkn.Encode([]byte("demotoken"))
+
+	context := newRequestContext(ctrl, "HEAD", "")
+	context.EXPECT().Param("token").Return(tokenString)
+	context.EXPECT().Param("filename").Return("file.torrent")
+	context.EXPECT().Header(gomock.Any(), gomock.Any()).Times(3)
+	context.EXPECT().Status(200)
+	server.downloadHandler(context)
+}
+
+func TestServer_downloadHandler_Should_Stop_When_TheClientDisconnects(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	config := mocks.NewMockConfig(ctrl)
+	server, _ := prepareTestServer(ctrl, config)
+	scopeMock := indexer.NewMockScope(ctrl)
+	mockedIndexer := indexer.NewMockIndexer(ctrl)
+	server.indexerFacade.IndexScope = scopeMock
+	tkn := token{IndexName: "rutracker.org", Link: "http://rutracker.org"}
+	tokenString, _ := tkn.Encode([]byte("demotoken"))
+	// The download never starts.
+	responseProxy, _ := indexer.NewResponseProxy()
+	ctx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	cancel()
+
+	context := httpMocks.NewMockContext(ctrl)
+	context.EXPECT().RequestMethod().Return("GET").AnyTimes()
+	context.EXPECT().RequestContext().Return(ctx).AnyTimes()
+	context.EXPECT().Param("token").Return(tokenString)
+	context.EXPECT().Param("filename").Return("file.torrent")
+	context.EXPECT().String(gomock.Any(), gomock.Any()).Times(0)
+	scopeMock.EXPECT().Lookup(gomock.Any(), "rutracker.org").Return([]indexer.Indexer{mockedIndexer}, nil)
+	mockedIndexer.EXPECT().Download(gomock.Any(), tkn.Link).Return(responseProxy, nil)
+	server.downloadHandler(context)
+}
+
+func TestParseRange(t *testing.T) {
+	g := gomega.NewWithT(t)
+	start, end, err := parseRange("bytes=0-99", 1000)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect([]int64{start, end}).To(gomega.Equal([]int64{0, 99}))
+	start, end, err = parseRange("bytes=900-", 1000)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect([]int64{start, end}).To(gomega.Equal([]int64{900, 999}))
+	start, end, err = parseRange("bytes=-100", 1000)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect([]int64{start, end}).To(gomega.Equal([]int64{900, 999}))
+	start, end, err = parseRange("bytes=500-5000", 1000)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect([]int64{start, end}).To(gomega.Equal([]int64{500, 999}))
+
+	_, _, err = parseRange("bytes=1000-", 1000)
+	g.Expect(err).To(gomega.Equal(errRangeNotSatisfiable))
+	for _, header := range []string{"", "items=0-1", "bytes=0-1,5-6", "bytes=9-2", "bytes=a-b"} {
+		_, _, err = parseRange(header, 1000)
+		g.Expect(err).To(gomega.Equal(errNoRange), header)
+	}
+}
+
+func TestServer_downloadHandler_Should_PropagateDownloadErrors(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	config := mocks.NewMockConfig(ctrl)
+	server, context := prepareTestServer(ctrl, config)
+	scopeMock := indexer.NewMockScope(ctrl)
+	mockedIndexer := indexer.NewMockIndexer(ctrl)
+	server.indexerFacade.IndexScope = scopeMock
+	tkn := token{IndexName: "rutracker.org", Link: "http://rutracker.org"}
+	tokenString, _ := tkn.Encode([]byte("demotoken"))
+	downloadErr := errors.New("connection reset")
+	responseProxy, pipeWr := indexer.NewResponseProxy()
+	responseProxy.ContentLengthChan <- -1
+	_ = pipeWr.CloseWithError(downloadErr)
+
+	context.EXPECT().Param("token").Return(tokenString)
+	context.EXPECT().Param("filename").Return("file.torrent")
+	context.EXPECT().Header(gomock.Any(), gomock.Any()).Times(3)
+	context.EXPECT().DataFromReader(200, int64(-1), "application/x-bittorrent", gomock.Any(), gomock.Any()).
+		Do(func(code int, length int64, contentType string, reader io.Reader, headers map[string]string) {
+			_, err := ioutil.ReadAll(reader)
+			g.Expect(err).To(gomega.Equal(downloadErr))
+		})
+	scopeMock.EXPECT().Lookup(gomock.Any(), "rutracker.org").Return([]indexer.Indexer{mockedIndexer}, nil)
+	mockedIndexer.EXPECT().Download(gomock.Any(), tkn.Link).Return(responseProxy, nil)
+	server.downloadHandler(context)
+}
+
+func TestServer_downloadHandler_Should_Cache_TheStreamedDownload(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	config := mocks.NewMockConfig(ctrl)
+	server, context := prepareTestServer(ctrl, config)
+	dir, _ := ioutil.TempDir("", "torrents-")
+	defer os.RemoveAll(dir)
+	torrentCache, err := torrent.NewFileCache(dir, time.Hour, 1024*1024)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	server.torrentCache = torrentCache
+	content, err := ioutil.ReadFile(filepath.Join("..", "torrent", "testdata", "sample.torrent"))
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	scopeMock := indexer.NewMockScope(ctrl)
+	mockedIndexer := indexer.NewMockIndexer(ctrl)
+	server.indexerFacade.IndexScope = scopeMock
+	tkn := token{IndexName: "rutracker.org", Link: "http://rutracker.org"}
+	tokenString, _ := tkn.Encode([]byte("demotoken"))
+	responseProxy, pipeWr := indexer.NewResponseProxy()
+	responseProxy.ContentLengthChan <- int64(len(content))
+	go func() {
+		_, _ = pipeWr.Write(content)
+		_ = pipeWr.Close()
+	}()
+
+	context.EXPECT().Param("token").Return(tokenString)
+	context.EXPECT().Param("filename").Return("file.torrent")
+	context.EXPECT().Header(gomock.Any(), gomock.Any()).Times(3)
+	context.EXPECT().DataFromReader(200, int64(len(content)), "application/x-bittorrent", gomock.Any(), gomock.Any()).
+		Do(func(code int, length int64, contentType string, reader io.Reader, headers map[string]string) {
+			sent, _ := ioutil.ReadAll(reader)
+			g.Expect(sent).To(gomega.Equal(content))
+		})
+	scopeMock.EXPECT().Lookup(gomock.Any(), "rutracker.org").Return([]indexer.Indexer{mockedIndexer}, nil)
+	mockedIndexer.EXPECT().Download(gomock.Any(), tkn.Link).Return(responseProxy, nil)
+	server.downloadHandler(context)
+
+	cached, ok := torrentCache.Get("rutracker.org", "http://rutracker.org")
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(cached).To(gomega.Equal(content))
+}
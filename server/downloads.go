@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultDownloadsLimit = 50
+
+type revokeTokenRequest struct {
+	// Token can be the token, or the download link with it.
+	Token string `json:"token"`
+	// ID is the id of the token, like in the download log.
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// revokeToken godoc
+// @Summary      Revoke a download link
+// @Description  Revoke the token of a download link, by giving the link, the token or its id
+// @Tags         downloads
+// @Accept       json
+// @param 	  	 apikey query string true "API key with the admin scope"
+// @Produce      json
+// @Success      200  {object}  tokens.Revocation
+// @Router       /api/tokens/revoke [post]
+func (s *Server) revokeToken(c *gin.Context) {
+	if !s.authorizeAdmin(c) {
+		return
+	}
+	if s.tokens == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "download tokens aren't available"})
+		return
+	}
+	var request revokeTokenRequest
+	if err := c.BindJSON(&request); err != nil {
+		return
+	}
+	tokenID := request.ID
+	if request.Token != "" {
+		t, err := decodeToken(tokenFromLink(request.Token), s.sharedKey())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tokenID = t.ID
+	}
+	if tokenID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a token or its id is required"})
+		return
+	}
+	revocation, err := s.tokens.Revoke(tokenID, request.Reason)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, revocation)
+}
+
+// tokenFromLink gets the token from a download link, other values are used as they are.
+func tokenFromLink(value string) string {
+	parts := strings.Split(value, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == "d" || parts[i] == "download" {
+			return parts[i+1]
+		}
+	}
+	return value
+}
+
+// listDownloads godoc
+// @Summary      Download log
+// @Description  List the newest downloads through the server, with the key and the client of each one
+// @Tags         downloads
+// @Accept       */*
+// @param 	  	 limit query string false "Limit the number of downloads, defaults to 50"
+// @param 	  	 apikey query string true "API key with the admin scope"
+// @Produce      json
+// @Success      200  {array}  tokens.Download
+// @Router       /api/downloads [get]
+func (s *Server) listDownloads(c *gin.Context) {
+	if !s.authorizeAdmin(c) {
+		return
+	}
+	if s.tokens == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "the download log isn't available"})
+		return
+	}
+	limit := defaultDownloadsLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit: " + value})
+			return
+		}
+		limit = parsed
+	}
+	c.JSON(http.StatusOK, s.tokens.Downloads(limit))
+}
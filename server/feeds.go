@@ -0,0 +1,111 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/server/apikeys"
+	http2 "github.com/sp0x/torrentd/server/http"
+	"github.com/sp0x/torrentd/server/rss"
+)
+
+// serveFeed serves the newest stored results of a category feed.
+// Clients can use conditional requests, so that the feed is only sent if it changed.
+// Feeds have download links, so a key that can download is needed when auth is enabled.
+func (s *Server) serveFeed(feed *rss.CategoryFeed) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, err := s.authorize(requestAPIKey(c), apikeys.ScopeDownload, "")
+		if err != nil {
+			c.String(authStatus(err), err.Error())
+			return
+		}
+		options, err := rss.NewFeedOptions(c.Query("limit"), c.Query("q"))
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		if key != nil {
+			options.Allows = func(item *search.TorrentResultItem) bool {
+				return key.AllowsIndex(item.Site)
+			}
+		}
+		items := feed.LatestItems(s.resultStorage(), options)
+
+		// The links are signed again in every window, so clients get new ones before the old ones expire.
+		signedSince := s.feedLinksWindow(time.Now())
+		etag := rss.ETag(feed.Name, items, signedSince)
+		lastModified := rss.LastModified(items)
+		if !lastModified.IsZero() && lastModified.Before(signedSince) {
+			lastModified = signedSince
+		}
+		c.Header("ETag", etag)
+		if !lastModified.IsZero() {
+			c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+		}
+		if isNotModified(c.Request, etag, lastModified) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		if err := s.rewriteFeedLinks(c.Request, items, key); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		rss.SendRssFeed(c.Request.Host, feed.Name, items, http2.GinContext{Context: c})
+	}
+}
+
+// feedLinksWindow is the start of the window that the download links of feeds are signed in.
+// A window is half of the expiry of the links, so the links that a client has are always valid for that long.
+func (s *Server) feedLinksWindow(now time.Time) time.Time {
+	ttl := s.tokenTTL
+	if ttl <= 0 {
+		ttl = downloadTokenTTL
+	}
+	return now.Truncate(ttl / 2)
+}
+
+// isNotModified checks the conditional headers of a request.
+// If-None-Match is used when it's given, like the http spec says, otherwise If-Modified-Since is checked.
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.IsZero() {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// rewriteFeedLinks makes the download links of stored results go through us, like the ones of search results.
+func (s *Server) rewriteFeedLinks(r *http.Request, items []*search.TorrentResultItem, key *apikeys.Key) error {
+	results := make([]search.ResultItemBase, len(items))
+	for ix, item := range items {
+		// Stored results only have the links of the torrent, since they shadow the ones of the scraped item.
+		scrapeItem := item.AsScrapeItem()
+		if scrapeItem.Link == "" {
+			scrapeItem.Link = item.Link
+		}
+		if scrapeItem.SourceLink == "" {
+			scrapeItem.SourceLink = item.SourceLink
+		}
+		results[ix] = item
+	}
+	if _, err := s.rewriteLinks(r, results, key); err != nil {
+		return err
+	}
+	for _, item := range items {
+		item.Link = item.AsScrapeItem().Link
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+)
+
+func TestServer_feedLinksWindow(t *testing.T) {
+	g := gomega.NewWithT(t)
+	s := &Server{tokenTTL: 24 * time.Hour}
+	now := time.Date(2020, 6, 1, 13, 30, 0, 0, time.UTC)
+	g.Expect(s.feedLinksWindow(now)).To(gomega.Equal(time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)))
+	g.Expect(s.feedLinksWindow(now.Add(10 * time.Hour))).To(gomega.Equal(time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)))
+	g.Expect(s.feedLinksWindow(now.Add(11 * time.Hour))).To(gomega.Equal(time.Date(2020, 6, 2, 0, 0, 0, 0, time.UTC)))
+
+	// A feed that was fetched in an older window is sent again, even if its items didn't change.
+	request, _ := http.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("If-Modified-Since", now.Add(-2*time.Hour).Format(http.TimeFormat))
+	g.Expect(isNotModified(request, `"etag"`, s.feedLinksWindow(now))).To(gomega.BeFalse())
+	request.Header.Set("If-Modified-Since", now.Format(http.TimeFormat))
+	g.Expect(isNotModified(request, `"etag"`, s.feedLinksWindow(now))).To(gomega.BeTrue())
+}
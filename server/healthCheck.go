@@ -11,7 +11,7 @@ func (s *Server) HealthCheck(c *gin.Context) {
 	indexes := s.indexerFacade.IndexScope.Indexes()
 	output := make(map[string]indexHealthCheckResponse)
 	for _, indexGroup := range indexes {
-		err := indexGroup.HealthCheck()
+		err := indexGroup.HealthCheck(c.Request.Context())
 		firstIndex := indexGroup[0]
 
 		output[firstIndex.Site()] = indexHealthCheckResponse{
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"io"
 
 	"github.com/gin-gonic/gin"
@@ -15,4 +16,27 @@ type Context interface {
 	Param(s string) string
 	Error(err error) *gin.Error
 	DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string)
+	// ClientIP is the address of the client
+	ClientIP() string
+	// GetHeader gets a header of the request
+	GetHeader(key string) string
+	// Status sets the status of a response without a body
+	Status(code int)
+	// RequestMethod is the http method of the request
+	RequestMethod() string
+	// RequestContext is done once the client disconnects
+	RequestContext() context.Context
+}
+
+// GinContext is a Context of a gin request.
+type GinContext struct {
+	*gin.Context
+}
+
+func (c GinContext) RequestMethod() string {
+	return c.Request.Method
+}
+
+func (c GinContext) RequestContext() context.Context {
+	return c.Request.Context()
 }
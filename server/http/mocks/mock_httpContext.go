@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	gin "github.com/gin-gonic/gin"
 	gomock "github.com/golang/mock/gomock"
 	io "io"
@@ -102,3 +103,71 @@ func (mr *MockContextMockRecorder) DataFromReader(code, contentLength, contentTy
 	//mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataFromReader", reflect.TypeOf((*MockContext)(nil).DataFromReader), code, contentLength, contentType, reader, extraHeaders)
 }
+
+// ClientIP mocks base method
+func (m *MockContext) ClientIP() string {
+	//m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClientIP")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ClientIP indicates an expected call of ClientIP
+func (mr *MockContextMockRecorder) ClientIP() *gomock.Call {
+	//mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClientIP", reflect.TypeOf((*MockContext)(nil).ClientIP))
+}
+
+// GetHeader mocks base method
+func (m *MockContext) GetHeader(key string) string {
+	//m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeader", key)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetHeader indicates an expected call of GetHeader
+func (mr *MockContextMockRecorder) GetHeader(key interface{}) *gomock.Call {
+	//mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeader", reflect.TypeOf((*MockContext)(nil).GetHeader), key)
+}
+
+// Status mocks base method
+func (m *MockContext) Status(code int) {
+	//m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Status", code)
+}
+
+// Status indicates an expected call of Status
+func (mr *MockContextMockRecorder) Status(code interface{}) *gomock.Call {
+	//mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockContext)(nil).Status), code)
+}
+
+// RequestMethod mocks base method
+func (m *MockContext) RequestMethod() string {
+	//m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestMethod")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// RequestMethod indicates an expected call of RequestMethod
+func (mr *MockContextMockRecorder) RequestMethod() *gomock.Call {
+	//mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestMethod", reflect.TypeOf((*MockContext)(nil).RequestMethod))
+}
+
+// RequestContext mocks base method
+func (m *MockContext) RequestContext() context.Context {
+	//m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestContext")
+	ret0, _ := ret[0].(context.Context)
+	return ret0
+}
+
+// RequestContext indicates an expected call of RequestContext
+func (mr *MockContextMockRecorder) RequestContext() *gomock.Call {
+	//mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestContext", reflect.TypeOf((*MockContext)(nil).RequestContext))
+}
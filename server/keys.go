@@ -0,0 +1,142 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sp0x/torrentd/server/apikeys"
+)
+
+type keyInfo struct {
+	ID        string          `json:"id"`
+	Label     string          `json:"label"`
+	Scopes    []apikeys.Scope `json:"scopes"`
+	Indexes   []string        `json:"indexes,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	LastUsed  *time.Time      `json:"last_used,omitempty"`
+	Uses      uint64          `json:"uses"`
+	Revoked   bool            `json:"revoked"`
+	// Secret is only given when the key is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type createKeyRequest struct {
+	Label   string   `json:"label"`
+	Scopes  []string `json:"scopes"`
+	Indexes []string `json:"indexes"`
+}
+
+func newKeyInfo(key *apikeys.Key) keyInfo {
+	info := keyInfo{
+		ID:        key.KeyID,
+		Label:     key.Label,
+		Scopes:    key.Scopes,
+		Indexes:   key.Indexes,
+		CreatedAt: key.CreatedAt,
+		Uses:      key.Uses,
+		Revoked:   key.Revoked,
+	}
+	if !key.LastUsed.IsZero() {
+		lastUsed := key.LastUsed
+		info.LastUsed = &lastUsed
+	}
+	return info
+}
+
+// authorizeAdmin checks that the request has a key with the admin scope.
+func (s *Server) authorizeAdmin(c *gin.Context) bool {
+	if _, err := s.authorize(requestAPIKey(c), apikeys.ScopeAdmin, ""); err != nil {
+		c.JSON(authStatus(err), gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// authorizeKeysAdmin checks that the request can manage keys, and that the keys storage is open.
+func (s *Server) authorizeKeysAdmin(c *gin.Context) bool {
+	if !s.authorizeAdmin(c) {
+		return false
+	}
+	if s.keys == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "api keys aren't available"})
+		return false
+	}
+	return true
+}
+
+// listKeys godoc
+// @Summary      List API keys
+// @Description  List the API keys with their usage, including the revoked ones
+// @Tags         keys
+// @Accept       */*
+// @param 	  	 apikey query string true "API key with the admin scope"
+// @Produce      json
+// @Success      200  {array}  keyInfo
+// @Router       /api/keys [get]
+func (s *Server) listKeys(c *gin.Context) {
+	if !s.authorizeKeysAdmin(c) {
+		return
+	}
+	keys := s.keys.List()
+	infos := make([]keyInfo, len(keys))
+	for i := range keys {
+		infos[i] = newKeyInfo(&keys[i])
+	}
+	c.JSON(http.StatusOK, infos)
+}
+
+// createKey godoc
+// @Summary      Create an API key
+// @Description  Create an API key with scopes (search, download, admin), that can be limited to some indexes. The secret of the key is only returned here.
+// @Tags         keys
+// @Accept       json
+// @param 	  	 apikey query string true "API key with the admin scope"
+// @Produce      json
+// @Success      201  {object}  keyInfo
+// @Router       /api/keys [post]
+func (s *Server) createKey(c *gin.Context) {
+	if !s.authorizeKeysAdmin(c) {
+		return
+	}
+	var request createKeyRequest
+	if err := c.BindJSON(&request); err != nil {
+		return
+	}
+	scopes := make([]apikeys.Scope, len(request.Scopes))
+	for i, scope := range request.Scopes {
+		scopes[i] = apikeys.Scope(scope)
+	}
+	key, secret, err := s.keys.Create(request.Label, scopes, request.Indexes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	info := newKeyInfo(key)
+	info.Secret = secret
+	c.JSON(http.StatusCreated, info)
+}
+
+// revokeKey godoc
+// @Summary      Revoke an API key
+// @Description  Revoke an API key, it can't be used after that
+// @Tags         keys
+// @Accept       */*
+// @param        id path string true "Key ID"
+// @param 	  	 apikey query string true "API key with the admin scope"
+// @Produce      json
+// @Success      200  {object}  keyInfo
+// @Router       /api/keys/{id} [delete]
+func (s *Server) revokeKey(c *gin.Context) {
+	if !s.authorizeKeysAdmin(c) {
+		return
+	}
+	id := c.Param("id")
+	if err := s.keys.Revoke(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	key, _ := s.keys.Get(id)
+	c.JSON(http.StatusOK, newKeyInfo(key))
+}
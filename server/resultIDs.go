@@ -0,0 +1,101 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/metadata"
+	"github.com/sp0x/torrentd/storage"
+)
+
+// resultIDIndex finds the stored results by their IMDb and TVDB ids.
+// It's read from the results storage once, and the results of the searches after that are added to it,
+// so that id searches don't go through all the stored results.
+type resultIDIndex struct {
+	once   sync.Once
+	mux    sync.RWMutex
+	loaded bool
+	// items are the results by their UUID, and uuids are the UUIDs of the results by their ids.
+	items map[string]*search.TorrentResultItem
+	uuids map[string][]string
+}
+
+// load reads the results that have ids from the storage, it's only done the first time.
+func (x *resultIDIndex) load(store storage.ItemStorage) {
+	x.once.Do(func() {
+		x.mux.Lock()
+		defer x.mux.Unlock()
+		x.items = map[string]*search.TorrentResultItem{}
+		x.uuids = map[string][]string{}
+		store.ForEachInNamespaces(func(_ string, record search.Record) bool {
+			if item, ok := record.(*search.TorrentResultItem); ok {
+				x.addItem(item)
+			}
+			return true
+		})
+		x.loaded = true
+	})
+}
+
+// add adds the stored results of a search, it does nothing until the index is loaded.
+func (x *resultIDIndex) add(results []search.ResultItemBase) {
+	x.mux.Lock()
+	defer x.mux.Unlock()
+	if !x.loaded {
+		return
+	}
+	for _, result := range results {
+		if item, ok := result.(*search.TorrentResultItem); ok && item.UUID() != "" {
+			x.addItem(item)
+		}
+	}
+}
+
+func (x *resultIDIndex) addItem(item *search.TorrentResultItem) {
+	keys := resultIDKeys(metadata.IDs{IMDBID: metadata.NormalizeIMDBID(item.IMDBID), TVDBID: item.TVDBID})
+	_, known := x.items[item.UUID()]
+	if len(keys) == 0 && !known {
+		return
+	}
+	x.items[item.UUID()] = item
+	for _, key := range keys {
+		if known && containsString(x.uuids[key], item.UUID()) {
+			continue
+		}
+		x.uuids[key] = append(x.uuids[key], item.UUID())
+	}
+}
+
+// find gets the results that have any of the ids.
+// The ids of a result can change when it's stored again, so the found results have to be checked.
+func (x *resultIDIndex) find(ids metadata.IDs) []*search.TorrentResultItem {
+	x.mux.RLock()
+	defer x.mux.RUnlock()
+	var found []*search.TorrentResultItem
+	for _, key := range resultIDKeys(ids) {
+		for _, uuid := range x.uuids[key] {
+			found = append(found, x.items[uuid])
+		}
+	}
+	return found
+}
+
+func resultIDKeys(ids metadata.IDs) []string {
+	var keys []string
+	if ids.IMDBID != "" {
+		keys = append(keys, "imdb:"+ids.IMDBID)
+	}
+	if ids.TVDBID != "" {
+		keys = append(keys, "tvdb:"+ids.TVDBID)
+	}
+	return keys
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/metadata"
+	"github.com/sp0x/torrentd/storage"
+)
+
+// resultsStorage only implements going through the namespaces of a storage.
+type resultsStorage struct {
+	storage.ItemStorage
+	items []search.Record
+}
+
+func (s *resultsStorage) ForEachInNamespaces(callback func(namespace string, record search.Record) bool) {
+	for _, item := range s.items {
+		if !callback("", item) {
+			return
+		}
+	}
+}
+
+func TestResultIDIndex(t *testing.T) {
+	g := gomega.NewWithT(t)
+	index := &resultIDIndex{}
+	first := &search.TorrentResultItem{IMDBID: "tt0133093"}
+	first.SetUUID("first")
+	// Nothing is added until the index is loaded.
+	index.add([]search.ResultItemBase{first})
+	g.Expect(index.find(metadata.IDs{IMDBID: "tt0133093"})).To(gomega.BeEmpty())
+
+	index.load(&resultsStorage{items: []search.Record{first}})
+	g.Expect(index.find(metadata.IDs{IMDBID: "tt0133093"})).To(gomega.Equal([]*search.TorrentResultItem{first}))
+
+	second := &search.TorrentResultItem{IMDBID: "tt0133093", TVDBID: "81189"}
+	second.SetUUID("second")
+	index.add([]search.ResultItemBase{second})
+	g.Expect(index.find(metadata.IDs{IMDBID: "tt0133093"})).To(gomega.Equal([]*search.TorrentResultItem{first, second}))
+	g.Expect(index.find(metadata.IDs{TVDBID: "81189"})).To(gomega.Equal([]*search.TorrentResultItem{second}))
+
+	// A result that's stored again is found as it's now.
+	updated := &search.TorrentResultItem{}
+	updated.SetUUID("first")
+	index.add([]search.ResultItemBase{updated})
+	g.Expect(index.find(metadata.IDs{IMDBID: "tt0133093"})).To(gomega.Equal([]*search.TorrentResultItem{updated, second}))
+}
@@ -3,7 +3,9 @@ package server
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/sp0x/torrentd/docs"
+	"github.com/sp0x/torrentd/server/http"
 	"github.com/sp0x/torrentd/server/rss"
+	"github.com/sp0x/torrentd/server/ui"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -11,13 +13,13 @@ import (
 func (s *Server) setupRoutes(r *gin.Engine) {
 	docs.SwaggerInfo.BasePath = "/"
 	// Rss
-	r.GET("/all", func(c *gin.Context) { rss.ServerAll(c) })
-	r.GET("/movies", func(c *gin.Context) { rss.ServeMovies(c) })
-	r.GET("/shows", func(c *gin.Context) { rss.ServeShows(c) })
-	r.GET("/music", func(c *gin.Context) { rss.ServeMusic(c) })
-	r.GET("/anime", func(c *gin.Context) { rss.ServeAnime(c) })
+	r.GET("/all", s.serveFeed(rss.AllFeed))
+	r.GET("/movies", s.serveFeed(rss.MoviesFeed))
+	r.GET("/shows", s.serveFeed(rss.ShowsFeed))
+	r.GET("/music", s.serveFeed(rss.MusicFeed))
+	r.GET("/anime", s.serveFeed(rss.AnimeFeed))
 	r.GET("/search/:name", func(c *gin.Context) {
-		rss.SearchAndServe(s.indexerFacade, s.indexerFacade.GetDefaultSearchOptions(), c)
+		rss.SearchAndServe(s.indexerFacade, s.indexerFacade.GetDefaultSearchOptions(), http.GinContext{Context: c})
 	})
 	r.GET("/status", s.Status)
 	r.GET("/health", s.HealthCheck)
@@ -29,14 +31,32 @@ func (s *Server) setupRoutes(r *gin.Engine) {
 		torznab.GET("/:indexes", s.torznabHandler)
 		torznab.GET("/:indexes/api", s.torznabHandler)
 	}
+	api := r.Group("api")
+	{
+		api.GET("/indexes", s.listIndexes)
+		api.GET("/indexes/:name/settings", s.indexSettings)
+		api.PUT("/indexes/:name/settings", s.updateIndexSettings)
+		api.GET("/search", s.searchIndexes)
+		api.GET("/search/stream", s.streamSearch)
+		api.GET("/keys", s.listKeys)
+		api.POST("/keys", s.createKey)
+		api.DELETE("/keys/:id", s.revokeKey)
+		api.POST("/tokens/revoke", s.revokeToken)
+		api.GET("/downloads", s.listDownloads)
+		api.GET("/clients", s.listClients)
+		api.POST("/clients/add", s.addToClient)
+	}
 	// Aggregated indexers info
 	r.GET("t/all/status", s.aggregatesStatus)
 
 	// download routes
-	r.HEAD("/download/:token/:filename", func(c *gin.Context) { s.downloadHandler(c) })
-	r.GET("/download/:token/:filename", func(c *gin.Context) { s.downloadHandler(c) })
-	r.HEAD("/d/:token/:filename", func(c *gin.Context) { s.downloadHandler(c) })
-	r.GET("/d/:token/:filename", func(c *gin.Context) { s.downloadHandler(c) })
+	r.HEAD("/download/:token/:filename", func(c *gin.Context) { s.downloadHandler(http.GinContext{Context: c}) })
+	r.GET("/download/:token/:filename", func(c *gin.Context) { s.downloadHandler(http.GinContext{Context: c}) })
+	r.HEAD("/d/:token/:filename", func(c *gin.Context) { s.downloadHandler(http.GinContext{Context: c}) })
+	r.GET("/d/:token/:filename", func(c *gin.Context) { s.downloadHandler(http.GinContext{Context: c}) })
+
+	// Web ui
+	ui.Register(r)
 
 	url := ginSwagger.URL("/swagger/doc.json") // The url pointing to API definition
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler, url))
@@ -0,0 +1,155 @@
+package rss
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sp0x/torrentd/indexer/categories"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/storage"
+)
+
+const (
+	defaultFeedLimit = 50
+	maxFeedLimit     = 500
+)
+
+// CategoryFeed is a feed of the newest stored results in some categories, and their subcategories.
+type CategoryFeed struct {
+	Name       string
+	Categories []categories.Category
+}
+
+var (
+	AllFeed    = &CategoryFeed{Name: "all"}
+	MoviesFeed = &CategoryFeed{Name: "movies", Categories: []categories.Category{categories.CategoryMovies}}
+	ShowsFeed  = &CategoryFeed{Name: "shows", Categories: []categories.Category{categories.CategoryTV}}
+	MusicFeed  = &CategoryFeed{Name: "music", Categories: []categories.Category{categories.CategoryAudio}}
+	AnimeFeed  = &CategoryFeed{Name: "anime", Categories: []categories.Category{categories.CategoryTVAnime}}
+)
+
+// FeedOptions filter the items of a feed.
+type FeedOptions struct {
+	Limit int
+	// Query has keywords that all need to be in the title of an item.
+	Query string
+	// Allows filters out items, like the ones from indexes that a key can't use.
+	// It's checked before the limit, so that the filtered items don't take the place of other ones.
+	Allows func(item *search.TorrentResultItem) bool
+}
+
+// NewFeedOptions parses the `limit` and `q` parameters of a feed.
+func NewFeedOptions(limit, query string) (*FeedOptions, error) {
+	options := &FeedOptions{Limit: defaultFeedLimit, Query: query}
+	if limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 {
+			return nil, fmt.Errorf("invalid limit: %s", limit)
+		}
+		options.Limit = value
+	}
+	if options.Limit > maxFeedLimit {
+		options.Limit = maxFeedLimit
+	}
+	return options, nil
+}
+
+// LatestItems finds the newest stored results of the feed, across all the namespaces of the storage.
+// Results that are stored in more than one namespace are only listed once.
+func (f *CategoryFeed) LatestItems(store storage.ItemStorage, options *FeedOptions) []*search.TorrentResultItem {
+	keywords := strings.Fields(strings.ToLower(options.Query))
+	var items []*search.TorrentResultItem
+	currentNamespace := ""
+	namespaceCount := 0
+	store.ForEachInNamespaces(func(namespace string, record search.Record) bool {
+		if namespace != currentNamespace {
+			currentNamespace = namespace
+			namespaceCount = 0
+		}
+		item, ok := record.(*search.TorrentResultItem)
+		if !ok || !f.matches(item, keywords) || (options.Allows != nil && !options.Allows(item)) {
+			return true
+		}
+		items = append(items, item)
+		namespaceCount++
+		// Each namespace goes from the newest item, so the rest of it can't be in the feed.
+		return namespaceCount < options.Limit
+	})
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return itemTime(items[i]).After(itemTime(items[j]))
+	})
+	seen := make(map[string]bool, len(items))
+	result := make([]*search.TorrentResultItem, 0, options.Limit)
+	for _, item := range items {
+		key := item.Site + "/" + item.LocalID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, item)
+		if len(result) == options.Limit {
+			break
+		}
+	}
+	return result
+}
+
+func (f *CategoryFeed) matches(item *search.TorrentResultItem, keywords []string) bool {
+	if len(f.Categories) > 0 {
+		inCategory := false
+		for _, category := range f.Categories {
+			if category.Contains(item.Category) {
+				inCategory = true
+				break
+			}
+		}
+		if !inCategory {
+			return false
+		}
+	}
+	title := strings.ToLower(item.Title)
+	for _, keyword := range keywords {
+		if !strings.Contains(title, keyword) {
+			return false
+		}
+	}
+	return true
+}
+
+// ETag identifies the items of a feed, so that clients can skip feeds that didn't change.
+// The links of the items expire, so the tag also changes with the time that they're signed since.
+func ETag(name string, items []*search.TorrentResultItem, signedSince time.Time) string {
+	hash := sha1.New()
+	_, _ = fmt.Fprintf(hash, "%s\n%d\n", name, signedSince.Unix())
+	for _, item := range items {
+		_, _ = fmt.Fprintf(hash, "%s\n%s\n%d\n%d\n%d\n", item.UUID(), item.Title, item.PublishDate, item.Seeders, item.Peers)
+	}
+	return fmt.Sprintf("\"%x\"", hash.Sum(nil))
+}
+
+// LastModified is the time of the newest item in a feed.
+func LastModified(items []*search.TorrentResultItem) time.Time {
+	var newest time.Time
+	for _, item := range items {
+		if updated := itemTime(item); updated.After(newest) {
+			newest = updated
+		}
+	}
+	return newest
+}
+
+func itemTime(item *search.TorrentResultItem) time.Time {
+	var published time.Time
+	if item.PublishDate > 0 {
+		published = time.Unix(item.PublishDate, 0)
+	}
+	if item.UpdatedAt.After(published) {
+		return item.UpdatedAt
+	}
+	return published
+}
@@ -0,0 +1,122 @@
+package rss
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/indexer/categories"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/storage"
+)
+
+type namespaceItems struct {
+	name  string
+	items []*search.TorrentResultItem
+}
+
+// feedStorage only implements going through the namespaces of a storage.
+type feedStorage struct {
+	storage.ItemStorage
+	namespaces []namespaceItems
+	visited    int
+}
+
+func (s *feedStorage) ForEachInNamespaces(callback func(namespace string, record search.Record) bool) {
+	for _, namespace := range s.namespaces {
+		for _, item := range namespace.items {
+			s.visited++
+			if !callback(namespace.name, item) {
+				break
+			}
+		}
+	}
+}
+
+func newFeedItem(site, id, title string, category int, published int64) *search.TorrentResultItem {
+	item := &search.TorrentResultItem{Title: title, Category: category}
+	item.Site = site
+	item.LocalID = id
+	item.PublishDate = published
+	return item
+}
+
+func TestCategoryFeed_LatestItems(t *testing.T) {
+	g := gomega.NewWithT(t)
+	store := &feedStorage{namespaces: []namespaceItems{
+		{name: "first", items: []*search.TorrentResultItem{
+			newFeedItem("first", "3", "Movie HD", categories.CategoryMoviesHD.ID, 300),
+			newFeedItem("first", "2", "Show", categories.CategoryTV.ID, 200),
+			newFeedItem("first", "1", "Old movie", categories.CategoryMovies.ID, 100),
+			newFeedItem("first", "0", "Older movie", categories.CategoryMovies.ID, 50),
+		}},
+		{name: "first,second", items: []*search.TorrentResultItem{
+			newFeedItem("second", "9", "Movie UHD", categories.CategoryMoviesUHD.ID, 250),
+			newFeedItem("first", "3", "Movie HD", categories.CategoryMoviesHD.ID, 300),
+		}},
+	}}
+
+	options, _ := NewFeedOptions("2", "")
+	items := MoviesFeed.LatestItems(store, options)
+	g.Expect(titles(items)).To(gomega.Equal([]string{"Movie HD", "Movie UHD"}))
+	// The oldest movie isn't visited, since the namespace already has enough newer ones.
+	g.Expect(store.visited).To(gomega.Equal(5))
+
+	options, _ = NewFeedOptions("", "movie uhd")
+	items = MoviesFeed.LatestItems(store, options)
+	g.Expect(titles(items)).To(gomega.Equal([]string{"Movie UHD"}))
+
+	options, _ = NewFeedOptions("10", "")
+	g.Expect(titles(AllFeed.LatestItems(store, options))).To(gomega.HaveLen(5))
+	g.Expect(titles(ShowsFeed.LatestItems(store, options))).To(gomega.Equal([]string{"Show"}))
+}
+
+func TestCategoryFeed_LatestItems_ShouldFilterBeforeTheLimit(t *testing.T) {
+	g := gomega.NewWithT(t)
+	store := &feedStorage{namespaces: []namespaceItems{
+		{name: "first,second", items: []*search.TorrentResultItem{
+			newFeedItem("first", "3", "Newest", categories.CategoryMovies.ID, 300),
+			newFeedItem("second", "2", "Newer", categories.CategoryMovies.ID, 200),
+			newFeedItem("second", "1", "New", categories.CategoryMovies.ID, 100),
+		}},
+	}}
+
+	options, _ := NewFeedOptions("2", "")
+	options.Allows = func(item *search.TorrentResultItem) bool {
+		return item.Site == "second"
+	}
+	items := AllFeed.LatestItems(store, options)
+	g.Expect(titles(items)).To(gomega.Equal([]string{"Newer", "New"}))
+}
+
+func TestNewFeedOptions(t *testing.T) {
+	g := gomega.NewWithT(t)
+	options, err := NewFeedOptions("", "")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(options.Limit).To(gomega.Equal(defaultFeedLimit))
+	options, _ = NewFeedOptions("100000", "")
+	g.Expect(options.Limit).To(gomega.Equal(maxFeedLimit))
+	_, err = NewFeedOptions("-1", "")
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestETag_ShouldChangeWithTheItems(t *testing.T) {
+	g := gomega.NewWithT(t)
+	items := []*search.TorrentResultItem{newFeedItem("site", "1", "Title", 0, 100)}
+	signed := time.Unix(1000, 0)
+	etag := ETag("all", items, signed)
+	g.Expect(ETag("all", items, signed)).To(gomega.Equal(etag))
+	g.Expect(ETag("all", items, signed.Add(time.Hour))).ToNot(gomega.Equal(etag))
+	items[0].Seeders = 10
+	g.Expect(ETag("all", items, signed)).ToNot(gomega.Equal(etag))
+	g.Expect(LastModified(items).Unix()).To(gomega.Equal(int64(100)))
+}
+
+func titles(items []*search.TorrentResultItem) []string {
+	var result []string
+	for _, item := range items {
+		result = append(result, item.Title)
+	}
+	return result
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"text/tabwriter"
 	"time"
 
@@ -16,12 +17,6 @@ import (
 	http2 "github.com/sp0x/torrentd/server/http"
 )
 
-func ServerAll(c http2.Context) {
-	// keyedStorage := storage.NewKeyedStorage(nil)
-	// torrents := sqlite.DefaultStorageBacking().GetTorrentsInCategories([]int{})
-	// SendRssFeed("", "torrents", torrents, c)
-}
-
 func SearchAndServe(indexFacade *indexer.Facade, options *indexer.GenericSearchOptions, c http2.Context) {
 	tabWriter := new(tabwriter.Writer)
 	tabWriter.Init(os.Stdout, 0, 8, 0, '\t', 0)
@@ -32,7 +27,7 @@ func SearchAndServe(indexFacade *indexer.Facade, options *indexer.GenericSearchO
 	var items []*search.TorrentResultItem
 
 	//for {
-	resultsChannel, err := indexFacade.SearchWithKeywords(name, 1, 1)
+	resultsChannel, err := indexFacade.SearchWithKeywords(c.RequestContext(), name, 1, 1)
 	if err != nil {
 		log.Warningf("Error while searching for torrent: %s . %s", name, err)
 		switch err.(type) {
@@ -68,55 +63,16 @@ func SearchAndServe(indexFacade *indexer.Facade, options *indexer.GenericSearchO
 	SendRssFeed("", name, items, c)
 }
 
-func ServeShows(c http2.Context) {
-	// torrents := sqlite.DefaultStorageBacking().GetTorrentsInCategories([]int{
-	//	189,  //Foreign shows
-	//	2366, //Foreign shows in HD
-	//	2100, //Asian shows
-	// })
-	// SendRssFeed("", "shows", torrents, c)
-}
-
-func ServeMusic(c http2.Context) {
-	// torrents := sqlite.DefaultStorageBacking().GetTorrentsInCategories([]int{
-	//	409,  // Classical and modern academic music
-	//	1125, // Folklore, national and ethnical music
-	//	1849, //New age, relax, meditative and flamenco
-	//	408,  //Rap, hip-hop and rnb
-	//	1760, //Raggae, ska, dub
-	//	416,  // OST, karaoke and musicals
-	//	413,  //Other music
-	//	2497, //Popular foreign music
-	// })
-	// SendRssFeed("", "music", torrents, c)
-}
-
-func ServeAnime(c http2.Context) {
-	// torrents := sqlite.DefaultStorageBacking().GetTorrentsInCategories([]int{
-	//	33, // Anime
-	// })
-	// SendRssFeed("", "anime", torrents, c)
-}
-
-func ServeMovies(c http2.Context) {
-	// torrents := sqlite.DefaultStorageBacking().GetTorrentsInCategories([]int{
-	//	7,    //foreign films
-	//	124,  //art-house and author movies
-	//	93,   //DVD
-	//	2198, //HD Video
-	//	4,    //Multifilms
-	//	352,  //3d/stereo movies, video, tv and sports
-	// })
-	// SendRssFeed("", "movies", torrents, c)
-}
-
 func SendRssFeed(hostname, name string, torrents []*search.TorrentResultItem, c http2.Context) {
 	feed := &feeds.Feed{
 		Title:       fmt.Sprintf("%s from Rutracker", name),
 		Link:        &feeds.Link{Href: fmt.Sprintf("http://%s/%s", hostname, name)},
 		Description: name,
 		// Author:      &feeds.Author{},
-		Created: time.Now(),
+		Created: LastModified(torrents),
+	}
+	if feed.Created.IsZero() {
+		feed.Created = time.Now()
 	}
 	feed.Items = make([]*feeds.Item, len(torrents))
 	for i, torr := range torrents {
@@ -128,6 +84,13 @@ func SendRssFeed(hostname, name string, torrents []*search.TorrentResultItem, c
 			Author:      &feeds.Author{Name: torr.Author},
 			Created:     timep,
 		}
+		if torr.Link != "" {
+			feedItem.Enclosure = &feeds.Enclosure{
+				Url:    torr.Link,
+				Length: strconv.FormatUint(uint64(torr.Size), 10),
+				Type:   "application/x-bittorrent",
+			}
+		}
 		feed.Items[i] = feedItem
 	}
 	rss, err := feed.ToAtom()
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"github.com/sp0x/torrentd/indexer"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/server/apikeys"
+)
+
+// The events of a streamed search
+const (
+	// searchProgress has a page of the results of an index.
+	searchProgress = "progress"
+	// searchError has the error of an index, the other indexes are still searched.
+	searchError = "error"
+	// searchDone is sent once an index is searched, with its number of results.
+	searchDone = "done"
+	// searchEnd is the last event, with the number of results of all the indexes.
+	searchEnd = "end"
+)
+
+// searchEvent is a message of a streamed search.
+type searchEvent struct {
+	Event   string         `json:"event"`
+	Index   string         `json:"index,omitempty"`
+	Page    uint           `json:"page"`
+	Results []searchResult `json:"results,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Count   int            `json:"count"`
+}
+
+// streamSearch godoc
+// @Summary      Streamed search
+// @Description  Search like /api/search, but send the results of each index as soon as they're found.
+// @Description  The events are sent with server-sent events, or as json messages if the request is a websocket.
+// @Tags         search
+// @Accept       */*
+// @param 	  	 q query string false "Search query"
+// @param 	  	 indexes query string false "Index name(s) to search through"
+// @param 	  	 cat query string false "Categories"
+// @param 	  	 limit query string false "Limit the number of results, defaults to 20"
+// @param 	  	 apikey query string true "API key"
+// @Produce      text/event-stream
+// @Success      200  {object}  searchEvent
+// @Router       /api/search/stream [get]
+func (s *Server) streamSearch(c *gin.Context) {
+	facade, query, key, ok := s.apiSearchQuery(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := s.searchContext(c.Request)
+	defer cancel()
+	events, err := s.searchEvents(ctx, c.Request, facade, query, key)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		streamWebsocket(c, events, cancel)
+	} else {
+		streamServerSentEvents(c, events)
+	}
+}
+
+// searchEvents starts a search, and gets its events in the order that they happen.
+// The channel has to be read until it's closed, since the search waits for its events to be read.
+// Once the context is done, the indexes that are left get their error and the events end.
+func (s *Server) searchEvents(ctx context.Context, r *http.Request, facade *indexer.Facade, query *search.Query,
+	key *apikeys.Key) (<-chan searchEvent, error) {
+	events := make(chan searchEvent, len(facade.CurrentIndexes()))
+	// The progress is reported by one worker at a time, so the counts don't need a lock.
+	counts := make(map[string]int)
+	total := 0
+	resultsChan, _, err := facade.SearchWithProgress(ctx, query, func(step indexer.IndexProgress) {
+		site := step.Index.Site()
+		if step.Done {
+			events <- searchEvent{Event: searchDone, Index: site, Count: counts[site]}
+			return
+		}
+		if step.Err != nil {
+			events <- searchEvent{Event: searchError, Index: site, Page: step.Page, Error: step.Err.Error()}
+			return
+		}
+		results, err := s.rewriteLinks(r, step.Results, key)
+		if err != nil {
+			events <- searchEvent{Event: searchError, Index: site, Page: step.Page, Error: err.Error()}
+			return
+		}
+		page := make([]searchResult, len(results))
+		for i, item := range results {
+			page[i] = newSearchResult(item)
+		}
+		counts[site] += len(page)
+		total += len(page)
+		events <- searchEvent{Event: searchProgress, Index: site, Page: step.Page, Results: page, Count: counts[site]}
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		// The results are also in the progress events.
+		for range resultsChan {
+		}
+		events <- searchEvent{Event: searchEnd, Count: total}
+		close(events)
+	}()
+	return events, nil
+}
+
+// streamServerSentEvents sends the events until the client disconnects.
+// The search stops with the request, its events are still read until they end.
+func streamServerSentEvents(c *gin.Context, events <-chan searchEvent) {
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	disconnected := c.Request.Context().Done()
+	for event := range events {
+		select {
+		case <-disconnected:
+			continue
+		default:
+		}
+		c.SSEvent(event.Event, event)
+		c.Writer.Flush()
+	}
+}
+
+// streamWebsocket sends the events as json messages, until the websocket is closed.
+// The request isn't done once it's upgraded, so the search is cancelled once the client closes the websocket.
+func streamWebsocket(c *gin.Context, events <-chan searchEvent, cancel context.CancelFunc) {
+	server := websocket.Server{
+		// Searches are authorized with their api key, so they can come from any origin.
+		Handshake: func(*websocket.Config, *http.Request) error {
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+			go func() {
+				// The client doesn't send anything, so reading only ends once it's gone.
+				var message []byte
+				for websocket.Message.Receive(conn, &message) == nil {
+				}
+				cancel()
+			}()
+			closed := false
+			for event := range events {
+				if closed {
+					continue
+				}
+				closed = websocket.JSON.Send(conn, event) != nil
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+	// The handler doesn't run if the handshake failed.
+	for range events {
+	}
+}
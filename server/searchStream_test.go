@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+)
+
+func testEvents(events ...searchEvent) <-chan searchEvent {
+	channel := make(chan searchEvent, len(events))
+	for _, event := range events {
+		channel <- event
+	}
+	close(channel)
+	return channel
+}
+
+func TestStreamServerSentEvents(t *testing.T) {
+	g := gomega.NewWithT(t)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/search/stream?q=test", nil)
+
+	streamServerSentEvents(c, testEvents(
+		searchEvent{Event: searchProgress, Index: "rutracker", Page: 1, Results: []searchResult{{Title: "Test"}}, Count: 1},
+		searchEvent{Event: searchError, Index: "zamunda", Page: 1, Error: "login failed"},
+		searchEvent{Event: searchDone, Index: "rutracker", Count: 1},
+		searchEvent{Event: searchEnd, Count: 1},
+	))
+	body := recorder.Body.String()
+	g.Expect(recorder.Header().Get("Content-Type")).To(gomega.Equal("text/event-stream"))
+	g.Expect(body).To(gomega.ContainSubstring("event:progress\ndata:{\"event\":\"progress\",\"index\":\"rutracker\",\"page\":1"))
+	g.Expect(body).To(gomega.ContainSubstring("event:error\ndata:{\"event\":\"error\",\"index\":\"zamunda\",\"page\":1,\"error\":\"login failed\",\"count\":0}"))
+	g.Expect(body).To(gomega.ContainSubstring("event:done\n"))
+	g.Expect(body).To(gomega.HaveSuffix("event:end\ndata:{\"event\":\"end\",\"page\":0,\"count\":1}\n\n"))
+}
+
+func TestStreamServerSentEvents_ReadsAllEventsAfterDisconnecting(t *testing.T) {
+	g := gomega.NewWithT(t)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/search/stream?q=test", nil).WithContext(ctx)
+
+	events := testEvents(searchEvent{Event: searchDone, Index: "rutracker"}, searchEvent{Event: searchEnd})
+	streamServerSentEvents(c, events)
+	g.Expect(recorder.Body.String()).To(gomega.BeEmpty())
+	g.Expect(events).To(gomega.BeClosed())
+}
@@ -1,12 +1,15 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
+	"sync"
 	"text/tabwriter"
+	"time"
 
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
@@ -15,6 +18,19 @@ import (
 	// swagger embed files
 	"github.com/sp0x/torrentd/config"
 	"github.com/sp0x/torrentd/indexer"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/metadata"
+	"github.com/sp0x/torrentd/server/apikeys"
+	"github.com/sp0x/torrentd/server/tokens"
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/torrent"
+)
+
+const (
+	// keyUsageFlushInterval is how often the usage of the api keys is stored.
+	keyUsageFlushInterval = time.Minute
+	// defaultSearchTimeout is how long a search can take if the `search_timeout` config isn't valid.
+	defaultSearchTimeout = time.Minute
 )
 
 type Server struct {
@@ -29,6 +45,22 @@ type Server struct {
 	PathPrefix string
 	Password   string
 	version    string
+	keys       *apikeys.Store
+	tokens     *tokens.Store
+	secretOnce sync.Once
+	secret     []byte
+	// tokenTTL is how long download links are valid.
+	tokenTTL time.Duration
+	// bindTokens makes download links only valid for the api key that got them.
+	bindTokens bool
+	// searchTimeout is how long the searches of a request can take, the indexes that don't finish in time are skipped.
+	searchTimeout time.Duration
+	torrentCache  *torrent.FileCache
+	metadata      *metadata.Resolver
+	// results are the stored results of all the indexes, they're shared by the handlers that read them.
+	results     storage.ItemStorage
+	resultsOnce sync.Once
+	resultIDs   resultIDIndex
 }
 
 type Params struct {
@@ -67,19 +99,77 @@ func (s *Server) Listen(tracker *indexer.Facade) error {
 	r := gin.Default()
 	// Register pprof so we can profile our app.
 	pprof.Register(r)
+	s.keys = apikeys.NewStore(s.config)
+	defer s.keys.Close()
+	s.tokens = tokens.NewStore(s.config)
+	defer s.tokens.Close()
+	s.tokenTTL = parseTokenTTL(s.config.GetString("download_token_ttl"))
+	s.bindTokens = s.config.GetBool("bind_download_tokens")
+	s.searchTimeout = parseSearchTimeout(s.config.GetString("search_timeout"))
+	torrentCache, err := torrent.NewFileCacheFromConfig(s.config)
+	if err != nil {
+		log.Warningf("Couldn't open the torrent cache, downloads won't be cached: %v", err)
+	}
+	s.torrentCache = torrentCache
+	s.metadata = metadata.FromConfig(s.config)
+	defer s.metadata.Close()
+	defer s.closeResultStorage()
+	tracker.Enricher = s.metadata
+	go s.flushKeyUsage(keyUsageFlushInterval)
 	s.setupRoutes(r)
 	log.Info("Starting server...")
-	key := s.sharedKey()
-	if len(key) > 0 {
-		log.Infof("API Key: %s", key)
-	} else {
+	switch {
+	case s.hasConfigKey():
+		log.Infof("API Key: %s", s.sharedKey())
+	case s.keys.Len() > 0:
+		log.Infof("Using %d API keys", s.keys.Len())
+	default:
 		log.Infof("Running without API Key")
 	}
 
-	err := r.Run(fmt.Sprintf("%s:%d", s.Hostname, s.Port))
+	err = r.Run(fmt.Sprintf("%s:%d", s.Hostname, s.Port))
 	return err
 }
 
+// resultStorage gets the storage with the stored results, it's opened the first time it's needed.
+func (s *Server) resultStorage() storage.ItemStorage {
+	s.resultsOnce.Do(func() {
+		s.results = storage.NewBuilder(s.config).
+			WithRecord(&search.TorrentResultItem{}).
+			Build()
+	})
+	return s.results
+}
+
+func (s *Server) closeResultStorage() {
+	if s.results != nil {
+		s.results.Close()
+	}
+}
+
+// parseSearchTimeout parses the timeout of searches from the config, like `30s`.
+// Searches don't time out without it.
+func parseSearchTimeout(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		log.Warningf("Invalid search_timeout `%s`, using %s", value, defaultSearchTimeout)
+		return defaultSearchTimeout
+	}
+	return timeout
+}
+
+// searchContext is the context of the searches of a request.
+// They stop once the client disconnects, or once the search timeout passes.
+func (s *Server) searchContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if s.searchTimeout > 0 {
+		return context.WithTimeout(r.Context(), s.searchTimeout)
+	}
+	return context.WithCancel(r.Context())
+}
+
 func (s *Server) baseURL(r *http.Request, appendPath string) (*url.URL, error) {
 	proto := "http"
 	if r.TLS != nil {
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sp0x/torrentd/indexer"
+	"github.com/sp0x/torrentd/server/apikeys"
+)
+
+const maskedSettingValue = "********"
+
+type indexSettingsResponse struct {
+	Name     string                  `json:"name"`
+	Settings []indexer.SettingsField `json:"settings"`
+	// Values are the configured values, passwords are masked.
+	Values   map[string]string `json:"values"`
+	Problems []string          `json:"problems,omitempty"`
+}
+
+// indexSettings godoc
+// @Summary      Index settings
+// @Description  Get the settings of an index with their configured values, so that a config form can be shown for it
+// @Tags         indexes
+// @Accept       */*
+// @param        name path string true "Index name"
+// @param 	  	 apikey query string true "API key"
+// @Produce      json
+// @Success      200  {object}  indexSettingsResponse
+// @Router       /api/indexes/{name}/settings [get]
+func (s *Server) indexSettings(c *gin.Context) {
+	name := c.Param("name")
+	// The settings hold the credentials of the index, so only admins can see them.
+	if _, err := s.authorize(requestAPIKey(c), apikeys.ScopeAdmin, name); err != nil {
+		c.JSON(authStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	s.writeIndexSettings(c, name)
+}
+
+func (s *Server) writeIndexSettings(c *gin.Context, name string) {
+	def, siteConfig, err := s.indexSiteConfig(name)
+	if def == nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	response := indexSettingsResponse{
+		Name:     name,
+		Settings: def.Settings,
+	}
+	if _, err := def.ResolveSettings(siteConfig); err != nil {
+		if settingsErr, ok := err.(*indexer.SettingsError); ok {
+			response.Problems = settingsErr.Problems
+		}
+	}
+	response.Values = maskSettings(def, siteConfig)
+	c.JSON(http.StatusOK, response)
+}
+
+// maskSettings picks the values of an index's settings, masking the passwords.
+func maskSettings(def *indexer.Definition, values map[string]string) map[string]string {
+	masked := map[string]string{}
+	for _, setting := range def.Settings {
+		value, ok := values[setting.Name]
+		if !ok {
+			continue
+		}
+		if setting.Type == "password" && value != "" {
+			value = maskedSettingValue
+		}
+		masked[setting.Name] = value
+	}
+	return masked
+}
+
+// siteConfigSaver is implemented by configs that can persist the settings of an index.
+type siteConfigSaver interface {
+	SaveSite(name string) error
+}
+
+// updateIndexSettings godoc
+// @Summary      Update index settings
+// @Description  Validate and save the settings of an index. Masked passwords keep their current value.
+// @Tags         indexes
+// @Accept       json
+// @param        name path string true "Index name"
+// @param 	  	 apikey query string true "API key"
+// @Produce      json
+// @Success      200  {object}  indexSettingsResponse
+// @Failure      400  {object}  indexSettingsResponse
+// @Router       /api/indexes/{name}/settings [put]
+func (s *Server) updateIndexSettings(c *gin.Context) {
+	name := c.Param("name")
+	if _, err := s.authorize(requestAPIKey(c), apikeys.ScopeAdmin, name); err != nil {
+		c.JSON(authStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	def, siteConfig, err := s.indexSiteConfig(name)
+	if def == nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var values map[string]string
+	if err := c.BindJSON(&values); err != nil {
+		return
+	}
+	updated := make(map[string]string, len(siteConfig))
+	for key, value := range siteConfig {
+		updated[key] = value
+	}
+	for _, setting := range def.Settings {
+		value, ok := values[setting.Name]
+		if !ok || (setting.Type == "password" && value == maskedSettingValue) {
+			continue
+		}
+		updated[setting.Name] = value
+	}
+	if _, err := def.ResolveSettings(updated); err != nil {
+		response := indexSettingsResponse{Name: name, Settings: def.Settings, Values: maskSettings(def, values)}
+		if settingsErr, ok := err.(*indexer.SettingsError); ok {
+			response.Problems = settingsErr.Problems
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	for key, value := range updated {
+		if siteConfig[key] == value {
+			continue
+		}
+		if err := s.config.SetSiteOption(def.Name, key, value); err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+	if saver, ok := s.config.(siteConfigSaver); ok {
+		if err := saver.SaveSite(def.Name); err != nil {
+			_ = c.Error(err)
+			return
+		}
+	}
+	if err := s.indexerFacade.ReloadIndex(name); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	s.writeIndexSettings(c, name)
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/config/mocks"
+	"github.com/sp0x/torrentd/indexer"
+)
+
+const settingsTestDefinition = `---
+site: settingstest
+name: Settings Test
+links:
+  - http://localhost/
+login:
+  path: /login.php
+  method: post
+settings:
+  - name: username
+    type: text
+    required: true
+  - name: password
+    type: password
+    required: true
+  - name: pages
+    type: int
+    default: 2
+`
+
+func newSettingsTestServer(t *testing.T, ctrl *gomock.Controller, site map[string]string) (*Server, func()) {
+	dir, _ := ioutil.TempDir("", "settings-")
+	err := ioutil.WriteFile(filepath.Join(dir, "settingstest.yml"), []byte(settingsTestDefinition), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := mocks.NewMockConfig(ctrl)
+	conf.EXPECT().Get("indexLoader").Return(&indexer.FileIndexLoader{Directories: []string{dir}}).AnyTimes()
+	conf.EXPECT().GetInt(gomock.Any()).Return(0).AnyTimes()
+	conf.EXPECT().GetBool(gomock.Any()).Return(false).AnyTimes()
+	conf.EXPECT().GetString(gomock.Any()).Return("").AnyTimes()
+	// The config of the site is under the name of the definition, not the one of its file.
+	conf.EXPECT().GetSite("Settings Test").DoAndReturn(func(string) (map[string]string, error) {
+		values := map[string]string{}
+		for key, value := range site {
+			values[key] = value
+		}
+		return values, nil
+	}).AnyTimes()
+	conf.EXPECT().SetSiteOption("Settings Test", gomock.Any(), gomock.Any()).DoAndReturn(func(_, key, value string) error {
+		site[key] = value
+		return nil
+	}).AnyTimes()
+
+	s := &Server{config: conf, indexerFacade: indexer.NewEmptyFacade(conf)}
+	s.Params.APIKey = []byte("key")
+	return s, func() { _ = os.RemoveAll(dir) }
+}
+
+func putSettings(s *Server, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/indexes/settingstest/settings?apikey=key", strings.NewReader(body))
+	c.Params = gin.Params{{Key: "name", Value: "settingstest"}}
+	s.updateIndexSettings(c)
+	return w
+}
+
+func TestServer_UpdateIndexSettings(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	site := map[string]string{"username": "user", "password": "secret"}
+	s, cleanup := newSettingsTestServer(t, ctrl, site)
+	defer cleanup()
+
+	w := putSettings(s, `{"username": "other", "password": "********", "pages": "3"}`)
+
+	g.Expect(w.Code).To(gomega.Equal(http.StatusOK))
+	// The masked password keeps the configured one.
+	g.Expect(site).To(gomega.Equal(map[string]string{"username": "other", "password": "secret", "pages": "3"}))
+	var response indexSettingsResponse
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(gomega.Succeed())
+	g.Expect(response.Values).To(gomega.HaveKeyWithValue("password", maskedSettingValue))
+	g.Expect(response.Problems).To(gomega.BeEmpty())
+}
+
+func TestServer_UpdateIndexSettings_ShouldRejectInvalidValues(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	site := map[string]string{"username": "user", "password": "secret"}
+	s, cleanup := newSettingsTestServer(t, ctrl, site)
+	defer cleanup()
+
+	w := putSettings(s, `{"username": "", "pages": "many"}`)
+
+	g.Expect(w.Code).To(gomega.Equal(http.StatusBadRequest))
+	g.Expect(site).To(gomega.Equal(map[string]string{"username": "user", "password": "secret"}))
+	var response indexSettingsResponse
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(gomega.Succeed())
+	g.Expect(response.Problems).To(gomega.ConsistOf("username is required", `pages must be a number, got "many"`))
+
+	s.Params.APIKey = []byte("other")
+	w = putSettings(s, `{"pages": "3"}`)
+	g.Expect(w.Code).To(gomega.Equal(http.StatusUnauthorized))
+}
+
+func TestServer_IndexSettings_ShouldRequireAnAdminKey(t *testing.T) {
+	g := gomega.NewWithT(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	site := map[string]string{"username": "user", "password": "secret"}
+	s, cleanup := newSettingsTestServer(t, ctrl, site)
+	defer cleanup()
+	getSettings := func(apiKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/indexes/settingstest/settings?apikey="+apiKey, nil)
+		c.Params = gin.Params{{Key: "name", Value: "settingstest"}}
+		s.indexSettings(c)
+		return w
+	}
+
+	g.Expect(getSettings("").Code).To(gomega.Equal(http.StatusUnauthorized))
+	w := getSettings("key")
+	g.Expect(w.Code).To(gomega.Equal(http.StatusOK))
+	var response indexSettingsResponse
+	g.Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(gomega.Succeed())
+	g.Expect(response.Values).To(gomega.HaveKeyWithValue("username", "user"))
+}
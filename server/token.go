@@ -1,28 +1,59 @@
 package server
 
 import (
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sp0x/torrentd/server/apikeys"
 )
 
+// downloadTokenTTL is how long download links stay valid, if their token doesn't have an expiry.
+const downloadTokenTTL = 24 * time.Hour
+
+var errTokenExpired = errors.New("the download link expired")
+
 type token struct {
+	// ID identifies the token, so that it can be revoked.
+	ID        string `json:"jti,omitempty"`
 	IndexName string `json:"s,omitempty"`
 	Link      string `json:"l,omitempty"`
+	// KeyID is the API key that the token was made for.
+	KeyID   string    `json:"k,omitempty"`
+	Expires time.Time `json:"exp,omitempty"`
 }
 
+// Encode signs the token. Tokens without an ID get a random one, and tokens without an expiry get the default one.
 func (t *token) Encode(sharedKey []byte) (string, error) {
+	if t.ID == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return "", err
+		}
+		t.ID = fmt.Sprintf("%x", id)
+	}
+	expires := t.Expires
+	if expires.IsZero() {
+		expires = time.Now().Add(downloadTokenTTL)
+	}
 	j := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti": t.ID,
 		"s":   t.IndexName,
 		"l":   t.Link,
+		"k":   t.KeyID,
 		"nbf": time.Now().Unix(),
+		"exp": expires.Unix(),
 	})
 
 	return j.SignedString(sharedKey)
 }
 
+// decodeToken checks the signature and the expiry of a token, and decodes it.
+// Tokens without an expiry or an ID were made before links expired, and couldn't be revoked, so they're expired.
 func decodeToken(ts string, sharedKey []byte) (*token, error) {
 	j, err := jwt.Parse(ts, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -30,6 +61,10 @@ func decodeToken(ts string, sharedKey []byte) (*token, error) {
 		}
 		return sharedKey, nil
 	})
+	// Expiry is only reported for tokens that are signed by us.
+	if validationErr, ok := err.(*jwt.ValidationError); ok && validationErr.Errors == jwt.ValidationErrorExpired {
+		return nil, errTokenExpired
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -39,5 +74,46 @@ func decodeToken(ts string, sharedKey []byte) (*token, error) {
 		return nil, errors.New("invalid token")
 	}
 
-	return &token{claims["s"].(string), claims["l"].(string)}, nil
+	t := &token{
+		ID:        claimString(claims, "jti"),
+		IndexName: claimString(claims, "s"),
+		Link:      claimString(claims, "l"),
+		KeyID:     claimString(claims, "k"),
+	}
+	expires, ok := claims["exp"].(float64)
+	if !ok || t.ID == "" {
+		return nil, errTokenExpired
+	}
+	t.Expires = time.Unix(int64(expires), 0)
+	return t, nil
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// parseTokenTTL parses the expiry of download links from the config, like `12h`.
+func parseTokenTTL(value string) time.Duration {
+	if value == "" {
+		return downloadTokenTTL
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		log.Warningf("Invalid download_token_ttl `%s`, using %s", value, downloadTokenTTL)
+		return downloadTokenTTL
+	}
+	return ttl
+}
+
+// newDownloadToken creates the base of the tokens for the download links that are given to a key.
+func (s *Server) newDownloadToken(key *apikeys.Key) token {
+	t := token{}
+	if s.tokenTTL > 0 {
+		t.Expires = time.Now().Add(s.tokenTTL)
+	}
+	if s.bindTokens {
+		t.KeyID = keyID(key)
+	}
+	return t
 }
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	. "github.com/onsi/gomega"
+)
+
+func TestToken_Encode(t *testing.T) {
+	g := NewGomegaWithT(t)
+	secret := []byte("demotoken")
+
+	tkn := &token{IndexName: "zamunda", Link: "http://zamunda.net/1", KeyID: "ab12cd34"}
+	encoded, err := tkn.Encode(secret)
+	g.Expect(err).ToNot(HaveOccurred())
+	decoded, err := decodeToken(encoded, secret)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(decoded.IndexName).To(Equal("zamunda"))
+	g.Expect(decoded.Link).To(Equal("http://zamunda.net/1"))
+	g.Expect(decoded.KeyID).To(Equal("ab12cd34"))
+	g.Expect(decoded.ID).To(HaveLen(16))
+	g.Expect(decoded.ID).To(Equal(tkn.ID))
+	// Tokens expire by default.
+	g.Expect(decoded.Expires).To(BeTemporally("~", time.Now().Add(downloadTokenTTL), time.Minute))
+
+	_, err = decodeToken(encoded, []byte("other"))
+	g.Expect(err).To(HaveOccurred())
+
+	tkn.Expires = time.Now().Add(-time.Minute)
+	encoded, err = tkn.Encode(secret)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = decodeToken(encoded, secret)
+	g.Expect(err).To(Equal(errTokenExpired))
+}
+
+func TestDecodeToken_LegacyTokensAreExpired(t *testing.T) {
+	g := NewGomegaWithT(t)
+	secret := []byte("demotoken")
+
+	// Tokens from before the expiry and the ID were added.
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"s":   "zamunda",
+		"l":   "http://zamunda.net/1",
+		"nbf": time.Now().Add(-time.Hour).Unix(),
+	}).SignedString(secret)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = decodeToken(legacy, secret)
+	g.Expect(err).To(Equal(errTokenExpired))
+
+	withoutID, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"s":   "zamunda",
+		"l":   "http://zamunda.net/1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(secret)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = decodeToken(withoutID, secret)
+	g.Expect(err).To(Equal(errTokenExpired))
+}
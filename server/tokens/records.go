@@ -0,0 +1,96 @@
+package tokens
+
+import (
+	"time"
+
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+// Revocation is a download token that can't be used anymore.
+type Revocation struct {
+	TokenID   string
+	Reason    string
+	RevokedAt time.Time
+	ModelData search.ModelData
+	UUIDValue string
+	RecordID  uint32
+	isNew     bool
+	isUpdate  bool
+}
+
+func (r *Revocation) UUID() string {
+	return r.UUIDValue
+}
+
+func (r *Revocation) SetUUID(s string) {
+	r.UUIDValue = s
+}
+
+func (r *Revocation) GetID() uint32 {
+	return r.RecordID
+}
+
+func (r *Revocation) SetID(u uint32) {
+	r.RecordID = u
+}
+
+func (r *Revocation) SetState(new, updated bool) {
+	r.isNew = new
+	r.isUpdate = updated
+}
+
+func (r *Revocation) IsNew() bool {
+	return r.isNew
+}
+
+func (r *Revocation) IsUpdate() bool {
+	return r.isUpdate
+}
+
+// Download is an entry in the log of downloads through the server.
+type Download struct {
+	Time     time.Time
+	TokenID  string
+	KeyID    string
+	Index    string
+	Link     string
+	Filename string
+	ClientIP string
+	// Status is the http status of the response to the download.
+	Status    int
+	Error     string
+	ModelData search.ModelData
+	UUIDValue string
+	RecordID  uint32
+	isNew     bool
+	isUpdate  bool
+}
+
+func (d *Download) UUID() string {
+	return d.UUIDValue
+}
+
+func (d *Download) SetUUID(s string) {
+	d.UUIDValue = s
+}
+
+func (d *Download) GetID() uint32 {
+	return d.RecordID
+}
+
+func (d *Download) SetID(u uint32) {
+	d.RecordID = u
+}
+
+func (d *Download) SetState(new, updated bool) {
+	d.isNew = new
+	d.isUpdate = updated
+}
+
+func (d *Download) IsNew() bool {
+	return d.isNew
+}
+
+func (d *Download) IsUpdate() bool {
+	return d.isUpdate
+}
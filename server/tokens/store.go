@@ -0,0 +1,134 @@
+package tokens
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/server/apikeys"
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/storage/indexing"
+)
+
+const (
+	revocationsNamespace = "__revoked_tokens"
+	downloadsNamespace   = "__downloads"
+)
+
+// Store keeps the revoked download tokens, and the log of the downloads.
+// They're in the same database as the API keys.
+type Store struct {
+	revocations storage.ItemStorage
+	downloads   storage.ItemStorage
+	mux         sync.RWMutex
+	revoked     map[string]*Revocation
+}
+
+// NewStore opens the storage of the download tokens.
+func NewStore(conf config.Config) *Store {
+	storageType := conf.GetString("storage")
+	newBuilder := func(namespace string, record interface{}) *storage.Builder {
+		builder := storage.NewBuilder(conf).
+			WithNamespace(namespace).
+			WithEndpoint(apikeys.DatabasePath(conf)).
+			WithRecord(record)
+		if storageType != "" {
+			builder = builder.WithBacking(storageType)
+		}
+		return builder
+	}
+	revocations := newBuilder(revocationsNamespace, &Revocation{}).
+		WithPK(indexing.NewKey("TokenID")).
+		Build()
+	downloads := newBuilder(downloadsNamespace, &Download{}).Build()
+	return newStore(revocations, downloads)
+}
+
+func newStore(revocations, downloads storage.ItemStorage) *Store {
+	s := &Store{
+		revocations: revocations,
+		downloads:   downloads,
+		revoked:     make(map[string]*Revocation),
+	}
+	revocations.ForEachInNamespaces(func(ns string, record search.Record) bool {
+		if ns != revocationsNamespace {
+			return false
+		}
+		if revocation, ok := record.(*Revocation); ok {
+			s.revoked[revocation.TokenID] = revocation
+		}
+		return true
+	})
+	return s
+}
+
+// Revoke stops a token from being used, before it expires.
+func (s *Store) Revoke(tokenID, reason string) (*Revocation, error) {
+	if tokenID == "" {
+		return nil, errors.New("the token has no id, so it can't be revoked")
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if revocation, ok := s.revoked[tokenID]; ok {
+		return revocation, nil
+	}
+	revocation := &Revocation{
+		TokenID:   tokenID,
+		Reason:    reason,
+		RevokedAt: time.Now(),
+		ModelData: search.ModelData{},
+	}
+	if err := s.revocations.Add(revocation); err != nil {
+		return nil, err
+	}
+	s.revoked[tokenID] = revocation
+	return revocation, nil
+}
+
+// IsRevoked checks if a token was revoked.
+func (s *Store) IsRevoked(tokenID string) bool {
+	if tokenID == "" {
+		return false
+	}
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	_, revoked := s.revoked[tokenID]
+	return revoked
+}
+
+// LogDownload adds a download to the log.
+func (s *Store) LogDownload(download *Download) error {
+	if download.Time.IsZero() {
+		download.Time = time.Now()
+	}
+	if download.ModelData == nil {
+		download.ModelData = search.ModelData{}
+	}
+	return s.downloads.Add(download)
+}
+
+// Downloads gets the newest downloads from the log.
+func (s *Store) Downloads(limit int) []*Download {
+	var downloads []*Download
+	if limit <= 0 {
+		return downloads
+	}
+	s.downloads.ForEachInNamespaces(func(ns string, record search.Record) bool {
+		if ns != downloadsNamespace {
+			return false
+		}
+		if download, ok := record.(*Download); ok {
+			downloads = append(downloads, download)
+		}
+		return len(downloads) < limit
+	})
+	return downloads
+}
+
+// Close closes the storage.
+func (s *Store) Close() {
+	s.revocations.Close()
+	s.downloads.Close()
+}
@@ -0,0 +1,71 @@
+package tokens
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/storage"
+	"github.com/sp0x/torrentd/storage/indexing"
+)
+
+func openTestStore(dbFile string) *Store {
+	revocations := storage.NewBuilder(nil).
+		WithNamespace(revocationsNamespace).
+		WithPK(indexing.NewKey("TokenID")).
+		WithEndpoint(dbFile).
+		WithRecord(&Revocation{}).
+		Build()
+	downloads := storage.NewBuilder(nil).
+		WithNamespace(downloadsNamespace).
+		WithEndpoint(dbFile).
+		WithRecord(&Download{}).
+		Build()
+	return newStore(revocations, downloads)
+}
+
+func TestStore_Revoke(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := ioutil.TempDir("", "tokens-")
+	defer os.RemoveAll(dir)
+	dbFile := filepath.Join(dir, "apikeys.db")
+
+	store := openTestStore(dbFile)
+	g.Expect(store.IsRevoked("abc")).To(BeFalse())
+	_, err := store.Revoke("", "leaked")
+	g.Expect(err).To(HaveOccurred())
+	revocation, err := store.Revoke("abc", "leaked")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(revocation.Reason).To(Equal("leaked"))
+	g.Expect(store.IsRevoked("abc")).To(BeTrue())
+	store.Close()
+
+	// Revoked tokens should stay revoked.
+	store = openTestStore(dbFile)
+	defer store.Close()
+	g.Expect(store.IsRevoked("abc")).To(BeTrue())
+	g.Expect(store.IsRevoked("other")).To(BeFalse())
+}
+
+func TestStore_Downloads(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := ioutil.TempDir("", "tokens-")
+	defer os.RemoveAll(dir)
+	store := openTestStore(filepath.Join(dir, "apikeys.db"))
+	defer store.Close()
+
+	for _, index := range []string{"first", "second", "third"} {
+		g.Expect(store.LogDownload(&Download{TokenID: index, Index: index, Status: 200})).To(Succeed())
+	}
+
+	downloads := store.Downloads(2)
+	g.Expect(downloads).To(HaveLen(2))
+	// The newest downloads come first.
+	g.Expect(downloads[0].Index).To(Equal("third"))
+	g.Expect(downloads[1].Index).To(Equal("second"))
+	g.Expect(downloads[0].Time.IsZero()).To(BeFalse())
+	g.Expect(store.Downloads(0)).To(BeEmpty())
+}
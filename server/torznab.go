@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 
@@ -13,7 +14,9 @@ import (
 	"github.com/sp0x/torrentd/indexer"
 	"github.com/sp0x/torrentd/indexer/cache"
 	"github.com/sp0x/torrentd/indexer/search"
-	"github.com/sp0x/torrentd/torrent"
+	"github.com/sp0x/torrentd/indexer/source/series"
+	"github.com/sp0x/torrentd/metadata"
+	"github.com/sp0x/torrentd/server/apikeys"
 	"github.com/sp0x/torrentd/torznab"
 )
 
@@ -34,6 +37,7 @@ func (s *Server) aggregatesStatus(c *gin.Context) {
 	c.JSON(200, statusObj)
 }
 
+// searchCache keeps the feeds of searches without their download links, which are signed for each response.
 var searchCache, _ = cache.NewTTL(100, 1*time.Hour)
 
 // torznabIndexCapabilities godoc
@@ -54,9 +58,7 @@ func (s *Server) torznabIndexCapabilities(c *gin.Context) {
 		return
 	}
 
-	searchIndex := searchIndexes[0]
-
-	searchIndex.Capabilities().ServeHTTP(c.Writer, c.Request)
+	searchIndexes.Capabilities().ServeHTTP(c.Writer, c.Request)
 }
 
 // torznabHandler godoc
@@ -65,17 +67,24 @@ func (s *Server) torznabIndexCapabilities(c *gin.Context) {
 // @Tags         torznab
 // @Accept       */*
 // @param        indexes path string false "Index name(s) to search through"
-// @param        t query string false "Type of search. Can be caps, search, tvsearch, tv-search, movie, movie-search, moviesearch. Defaults to caps, returning the capabilities."
+// @param        t query string false "Type of search. Can be caps, search, tvsearch, tv-search, movie, movie-search, moviesearch, music, music-search, book, book-search. Defaults to caps, returning the capabilities."
 // @param 	  	 q query string false "Search query"
 // @param 	  	 cat query string false "Category"
 // @param 	  	 format query string false "The output format to use"
 // @param 	  	 imdbid query string false "IMDB ID"
 // @param 	  	 tmdbid query string false "TMDB ID"
 // @param 	  	 rid query string false "TVDB ID"
+// @param 	  	 artist query string false "Artist name"
+// @param 	  	 album query string false "Album name"
+// @param 	  	 label query string false "Record label"
+// @param 	  	 track query string false "Track name"
+// @param 	  	 author query string false "Book author"
+// @param 	  	 title query string false "Book title"
 // @param 	  	 season query string false "Season number"
 // @param 	  	 ep query string false "Episode number"
 // @param 	  	 limit query string false "Limit the number of results, defaults to 20"
 // @param 	  	 offset query string false "Offset the results"
+// @param 	  	 sort query string false "Sort the results by date or seeders, defaults to date"
 // @param 	  	 minage query string false "Minimum age of the torrent"
 // @param 	  	 maxage query string false "Maximum age of the torrent"
 // @param 	  	 minsize query string false "Minimum size of the torrent"
@@ -105,43 +114,49 @@ func (s *Server) torznabHandler(c *gin.Context) {
 		return
 	}
 
-	searchIndex := searchIndexes[0]
-	if !s.checkAPIKey(c.Query("apikey")) {
-		torznab.Error(c, "Invalid apikey parameter", torznab.ErrInsufficientPrivs)
+	key, err := s.authorize(requestAPIKey(c), apikeys.ScopeSearch, indexerID)
+	if err != nil {
+		torznab.Error(c, err.Error(), torznab.ErrInsufficientPrivs)
 		return
 	}
 
-	switch t {
-	case "search", "tvsearch", "tv-search", "movie", "movie-search", "moviesearch":
+	switch search.SearchMode(t) {
+	case "search", "tv-search", "movie-search", "music-search", "book-search":
 		query, err := search.NewQueryFromUrl(c.Request.URL.Query())
 		if err != nil {
 			torznab.Error(c, "Invalid query", torznab.ErrInsufficientPrivs)
 			return
 		}
-		err = torrent.EnrichMovieAndShowData(query)
-		if err != nil {
-			torznab.Error(c, "Invalid query", torznab.ErrInsufficientPrivs)
-			return
-		}
 
 		var feed *torznab.ResultFeed
-		if cachedFeed, ok := searchCache.Get(query.UniqueKey()); ok {
+		// The stored results are filtered by the indexes of the key, so each key has its own cache.
+		cacheKey := fmt.Sprintf("%s|%s|%v", keyID(key), indexerID, query.UniqueKey())
+		if cachedFeed, ok := searchCache.Get(cacheKey); ok {
 			feed = cachedFeed.(*torznab.ResultFeed)
 		} else {
-			feed, err = s.torznabSearch(c.Request, query, s.indexerFacade)
-			searchCache.Add(query.UniqueKey(), feed)
+			feed, err = s.torznabSearch(c.Request, query, s.indexerFacade.WithIndexes(searchIndexes), key)
+			if err != nil {
+				torznab.Error(c, err.Error(), torznab.ErrUnknownError)
+				return
+			}
+			// Feeds with failed indexes aren't cached, so that the indexes are searched again.
+			if len(feed.Warnings) == 0 {
+				searchCache.Add(cacheKey, feed)
+			}
 		}
+		feed, err = s.withDownloadLinks(c.Request, feed, key)
 		if err != nil {
 			torznab.Error(c, err.Error(), torznab.ErrUnknownError)
 			return
 		}
+		encoding := feedEncoding(searchIndexes)
 		switch c.Query("format") {
 		case "atom":
 			atomOutput(c, feed)
 		case "", "xml":
-			xmlOutput(c, feed, searchIndex.GetEncoding())
+			xmlOutput(c, feed, encoding)
 		case "json":
-			jsonOutput(c.Writer, feed, searchIndex.GetEncoding())
+			jsonOutput(c.Writer, feed, encoding)
 		}
 
 	default:
@@ -155,16 +170,46 @@ func formatEncoding(nm string) string {
 	return nm
 }
 
-func (s *Server) torznabSearch(r *http.Request, query *search.Query, indexFacade *indexer.Facade) (*torznab.ResultFeed, error) {
-	resultsChan, err := indexFacade.Search(query)
+// feedEncoding is the encoding of the indexes, or utf-8 if they have different ones.
+func feedEncoding(indexes indexer.IndexCollection) string {
+	encoding := ""
+	for _, index := range indexes {
+		if encoding != "" && !strings.EqualFold(encoding, index.GetEncoding()) {
+			return "utf-8"
+		}
+		encoding = index.GetEncoding()
+	}
+	return encoding
+}
+
+// torznabSearch searches the indexes of the facade, and merges their results into a feed.
+// The results are sorted and paged after they're merged, the indexes that failed or timed out are in the warnings of the feed.
+// The links of the results aren't changed, use withDownloadLinks to make them go through the server.
+func (s *Server) torznabSearch(r *http.Request, query *search.Query, indexFacade *indexer.Facade, key *apikeys.Key) (*torznab.ResultFeed, error) {
+	ctx, cancel := s.searchContext(r)
+	defer cancel()
+	// The indexes need enough results for the whole page, the offset is for the merged results.
+	indexQuery := *query
+	indexQuery.Limit = query.Offset + query.Limit
+	indexQuery.Offset = 0
+	resultsChan, indexErrors, err := indexFacade.SearchWithErrors(ctx, &indexQuery)
 	if err != nil {
 		return nil, err
 	}
-	nfo := indexFacade.Indexes.Info()
 	var results []search.ResultItemBase
 	for resultPage := range resultsChan {
 		results = append(results, resultPage...)
 	}
+	s.resultIDs.add(results)
+	results = append(results, s.storedResultsWithIDs(&indexQuery, key, results)...)
+	indexes := indexFacade.CurrentIndexes()
+	warnings := indexWarnings(indexErrors.All())
+	if len(results) == 0 && len(warnings) > 0 && len(warnings) == len(indexes) {
+		return nil, fmt.Errorf("all the indexes failed, %s: %s", warnings[0].Index, warnings[0].Description)
+	}
+	sortResults(results, query.Sort)
+	results = pageResults(results, query.Offset, query.Limit)
+	nfo := indexes.Info()
 
 	feed := &torznab.ResultFeed{
 		Info: torznab.Info{
@@ -175,28 +220,122 @@ func (s *Server) torznabSearch(r *http.Request, query *search.Query, indexFacade
 			Language:    nfo.GetLanguage(),
 			Category:    "",
 		},
-		Items: results,
+		Items:    results,
+		Warnings: warnings,
 	}
 	feed.Info.Category = query.Type
+	return feed, nil
+}
 
-	rewritten, err := s.rewriteLinks(r, results)
+// withDownloadLinks copies a feed, with download links that go through the server for the key.
+// The links expire, so they're made for each response, and the original feed can still be cached.
+func (s *Server) withDownloadLinks(r *http.Request, feed *torznab.ResultFeed, key *apikeys.Key) (*torznab.ResultFeed, error) {
+	items := make([]search.ResultItemBase, len(feed.Items))
+	for i, item := range feed.Items {
+		items[i] = copyResult(item)
+	}
+	items, err := s.rewriteLinks(r, items, key)
 	if err != nil {
 		return nil, err
 	}
+	signed := *feed
+	signed.Items = items
+	return &signed, nil
+}
 
-	feed.Items = rewritten
+// copyResult makes a copy of a result, so that its links can be changed.
+func copyResult(item search.ResultItemBase) search.ResultItemBase {
+	switch result := item.(type) {
+	case *search.TorrentResultItem:
+		resultCopy := *result
+		return &resultCopy
+	case *search.ScrapeResultItem:
+		resultCopy := *result
+		return &resultCopy
+	}
+	return item
+}
 
-	return feed, err
+// indexWarnings are the errors of the indexes, sorted by their index.
+func indexWarnings(indexErrors map[string]error) []torznab.Warning {
+	var warnings []torznab.Warning
+	for index, err := range indexErrors {
+		warnings = append(warnings, torznab.Warning{Index: index, Description: err.Error()})
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Index < warnings[j].Index
+	})
+	return warnings
+}
+
+// sortResults sorts the results of several indexes, the newest ones are first unless they're sorted by seeders.
+func sortResults(results []search.ResultItemBase, order string) {
+	seeders := func(item search.ResultItemBase) int {
+		if torrent, ok := item.(*search.TorrentResultItem); ok {
+			return torrent.Seeders
+		}
+		return 0
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if order == search.SortBySeeders && seeders(results[i]) != seeders(results[j]) {
+			return seeders(results[i]) > seeders(results[j])
+		}
+		return results[i].AsScrapeItem().PublishDate > results[j].AsScrapeItem().PublishDate
+	})
+}
+
+// pageResults gets the results of a page, a limit of 0 gets all the results after the offset.
+func pageResults(results []search.ResultItemBase, offset, limit uint) []search.ResultItemBase {
+	if offset >= uint(len(results)) {
+		return []search.ResultItemBase{}
+	}
+	results = results[offset:]
+	if limit > 0 && limit < uint(len(results)) {
+		results = results[:limit]
+	}
+	return results
+}
+
+// storedResultsWithIDs finds the stored results that have the IMDb or TVDB id of a query,
+// so that id searches also get the results of earlier searches and work when the metadata providers don't.
+// The results that were already found aren't repeated.
+func (s *Server) storedResultsWithIDs(query *search.Query, key *apikeys.Key, found []search.ResultItemBase) []search.ResultItemBase {
+	ids := metadata.QueryIDs(query)
+	if ids.IMDBID == "" && ids.TVDBID == "" {
+		return nil
+	}
+	s.resultIDs.load(s.resultStorage())
+	seen := make(map[string]bool, len(found))
+	for _, item := range found {
+		seen[item.UUID()] = true
+	}
+	var results []search.ResultItemBase
+	for _, item := range s.resultIDs.find(ids) {
+		if query.HasEnoughResults(uint(len(found) + len(results))) {
+			break
+		}
+		if seen[item.UUID()] || !metadata.MatchesQuery(query, item) || !series.MatchesQuery(query, item) {
+			continue
+		}
+		if key != nil && !key.AllowsIndex(item.Site) {
+			continue
+		}
+		seen[item.UUID()] = true
+		results = append(results, item)
+	}
+	return results
 }
 
 // Rewrites the download links so that the download goes through us.
 // This is required since only we can access the torrent ( the site might need authorization )
-func (s *Server) rewriteLinks(r *http.Request, items []search.ResultItemBase) ([]search.ResultItemBase, error) {
+// The links are only valid for the given api key, if there is one.
+func (s *Server) rewriteLinks(r *http.Request, items []search.ResultItemBase, key *apikeys.Key) ([]search.ResultItemBase, error) {
 	baseURL, err := s.baseURL(r, "/d")
 	if err != nil {
 		return nil, err
 	}
 	apiKey := s.sharedKey()
+	baseToken := s.newDownloadToken(key)
 	// rewrite non-magnet links to use the server
 	for idx, item := range items {
 		scrapeItem := item.AsScrapeItem()
@@ -204,7 +343,7 @@ func (s *Server) rewriteLinks(r *http.Request, items []search.ResultItemBase) ([
 			continue
 		}
 		// itemTmp := item
-		tokenValue, err := getTokenValue(scrapeItem, apiKey)
+		tokenValue, err := getTokenValue(scrapeItem, apiKey, baseToken)
 		if err != nil {
 			return nil, err
 		}
@@ -215,22 +354,14 @@ func (s *Server) rewriteLinks(r *http.Request, items []search.ResultItemBase) ([
 	return items, nil
 }
 
-func getTokenValue(item *search.ScrapeResultItem, apiKey []byte) (string, error) {
+func getTokenValue(item *search.ScrapeResultItem, apiKey []byte, t token) (string, error) {
 	sourceLink := item.SourceLink
 	if sourceLink == "" {
 		sourceLink = item.Link
 	}
-	indexerName := ""
-	if item.Indexer != nil {
-		indexerName = item.Indexer.Name
-	} else {
-		indexerName = item.Site
-	}
 	// Encode the site and source of the torrent as a JWT token
-	t := &token{
-		IndexName: indexerName,
-		Link:      sourceLink,
-	}
+	t.IndexName = item.IndexName()
+	t.Link = sourceLink
 
 	te, err := t.Encode(apiKey)
 	if err != nil {
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/indexer/search"
+	"github.com/sp0x/torrentd/torznab"
+)
+
+func testResult(id string, published int64, seeders int) *search.TorrentResultItem {
+	item := &search.TorrentResultItem{Seeders: seeders}
+	item.LocalID = id
+	item.PublishDate = published
+	return item
+}
+
+func localIDs(results []search.ResultItemBase) []string {
+	ids := make([]string, len(results))
+	for i, item := range results {
+		ids[i] = item.AsScrapeItem().LocalID
+	}
+	return ids
+}
+
+func TestSortAndPageResults(t *testing.T) {
+	g := gomega.NewWithT(t)
+	results := []search.ResultItemBase{testResult("old", 100, 50), testResult("new", 300, 1), testResult("middle", 200, 10)}
+
+	sortResults(results, "")
+	g.Expect(localIDs(results)).To(gomega.Equal([]string{"new", "middle", "old"}))
+	sortResults(results, search.SortBySeeders)
+	g.Expect(localIDs(results)).To(gomega.Equal([]string{"old", "middle", "new"}))
+
+	g.Expect(localIDs(pageResults(results, 1, 1))).To(gomega.Equal([]string{"middle"}))
+	g.Expect(localIDs(pageResults(results, 1, 0))).To(gomega.Equal([]string{"middle", "new"}))
+	g.Expect(pageResults(results, 3, 20)).To(gomega.BeEmpty())
+}
+
+func TestIndexWarnings(t *testing.T) {
+	g := gomega.NewWithT(t)
+	warnings := indexWarnings(map[string]error{"zamunda": errors.New("login failed"), "rutracker": errors.New("timeout")})
+	g.Expect(warnings).To(gomega.Equal([]torznab.Warning{
+		{Index: "rutracker", Description: "timeout"},
+		{Index: "zamunda", Description: "login failed"},
+	}))
+	g.Expect(indexWarnings(nil)).To(gomega.BeEmpty())
+
+	output, err := xml.Marshal(&torznab.ResultFeed{Warnings: warnings})
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(string(output)).To(gomega.ContainSubstring(`<torznab:warning index="rutracker" description="timeout"></torznab:warning>`))
+}
+
+func TestServer_searchContext(t *testing.T) {
+	g := gomega.NewWithT(t)
+	g.Expect(parseSearchTimeout("")).To(gomega.BeZero())
+	g.Expect(parseSearchTimeout("30s")).To(gomega.Equal(30 * time.Second))
+	g.Expect(parseSearchTimeout("soon")).To(gomega.Equal(defaultSearchTimeout))
+
+	requestCtx, disconnect := context.WithCancel(context.Background())
+	request := httptest.NewRequest("GET", "/torznab/all", nil).WithContext(requestCtx)
+	s := &Server{}
+	ctx, cancel := s.searchContext(request)
+	defer cancel()
+	_, hasDeadline := ctx.Deadline()
+	g.Expect(hasDeadline).To(gomega.BeFalse())
+	disconnect()
+	g.Expect(ctx.Err()).To(gomega.Equal(context.Canceled))
+
+	s.searchTimeout = time.Millisecond
+	ctx, cancel = s.searchContext(httptest.NewRequest("GET", "/torznab/all", nil))
+	defer cancel()
+	<-ctx.Done()
+	g.Expect(ctx.Err()).To(gomega.Equal(context.DeadlineExceeded))
+}
+
+func TestServer_withDownloadLinks_ShouldKeepTheFeedUnsigned(t *testing.T) {
+	g := gomega.NewWithT(t)
+	s := &Server{}
+	s.Params.APIKey = []byte("key")
+	item := testResult("1", 100, 1)
+	item.Link = "http://tracker.example/dl/1.torrent"
+	item.AsScrapeItem().Link = item.Link
+	item.Site = "tracker"
+	feed := &torznab.ResultFeed{Items: []search.ResultItemBase{item}}
+
+	signed, err := s.withDownloadLinks(httptest.NewRequest("GET", "/torznab/all", nil), feed, nil)
+
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(signed.Items[0].AsScrapeItem().Link).To(gomega.HavePrefix("http://example.com/d/"))
+	// The cached feed still has the original links, so that it can be signed again.
+	g.Expect(feed.Items[0].AsScrapeItem().Link).To(gomega.Equal("http://tracker.example/dl/1.torrent"))
+}
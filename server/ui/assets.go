@@ -0,0 +1,320 @@
+package ui
+
+// The assets are kept as strings, so that they're embedded in the binary.
+// They can't contain backticks, so the script doesn't use template literals.
+
+const indexHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>torrentd</title>
+  <link rel="stylesheet" href="style.css">
+</head>
+<body>
+<header>
+  <h1>torrentd</h1>
+  <nav>
+    <a href="#search" data-tab="search">Search</a>
+    <a href="#indexes" data-tab="indexes">Indexes</a>
+    <a href="#latest" data-tab="latest">Latest</a>
+  </nav>
+  <label class="apikey">API key <input id="apikey" type="password" autocomplete="off"></label>
+</header>
+<main>
+  <section id="tab-search" class="tab">
+    <form id="search-form">
+      <input id="search-query" type="search" placeholder="Search" autofocus>
+      <select id="search-indexes" multiple size="3" title="Indexes to search through, none for all"></select>
+      <input id="search-categories" type="text" placeholder="Categories, e.g. 2000,5000">
+      <input id="search-limit" type="number" min="1" value="50" title="Limit">
+      <button type="submit">Search</button>
+    </form>
+    <p id="search-status" class="status"></p>
+    <table id="search-results">
+      <thead><tr><th>Title</th><th>Index</th><th>Category</th><th>Size</th><th>Seeders</th><th>Peers</th><th>Published</th></tr></thead>
+      <tbody></tbody>
+    </table>
+  </section>
+
+  <section id="tab-indexes" class="tab">
+    <div class="toolbar">
+      <button id="refresh-indexes" type="button">Refresh</button>
+      <button id="health-check" type="button">Run health checks</button>
+    </div>
+    <p id="indexes-status" class="status"></p>
+    <table id="indexes">
+      <thead><tr><th>Index</th><th>Loaded</th><th>Results</th><th>Health</th><th>Problems</th><th></th></tr></thead>
+      <tbody></tbody>
+    </table>
+    <form id="settings-form" hidden>
+      <h2>Settings of <span id="settings-name"></span></h2>
+      <div id="settings-fields"></div>
+      <ul id="settings-problems" class="problems"></ul>
+      <button type="submit">Save</button>
+      <button id="settings-cancel" type="button">Close</button>
+    </form>
+  </section>
+
+  <section id="tab-latest" class="tab">
+    <div class="toolbar"><button id="refresh-latest" type="button">Refresh</button></div>
+    <table id="latest">
+      <thead><tr><th>Name</th><th>Index</th><th>Description</th></tr></thead>
+      <tbody></tbody>
+    </table>
+  </section>
+</main>
+<script src="app.js"></script>
+</body>
+</html>
+`
+
+const styleCSS = `* { box-sizing: border-box; }
+body { margin: 0; font: 14px/1.4 -apple-system, "Segoe UI", Roboto, sans-serif; color: #222; background: #f6f7f9; }
+header { display: flex; align-items: center; gap: 24px; padding: 8px 16px; background: #1f2933; color: #fff; }
+header h1 { margin: 0; font-size: 18px; }
+header nav a { color: #cbd2d9; margin-right: 12px; text-decoration: none; }
+header nav a.active { color: #fff; border-bottom: 2px solid #3ebd93; }
+header .apikey { margin-left: auto; font-size: 12px; }
+main { padding: 16px; }
+.tab { display: none; }
+.tab.active { display: block; }
+form#search-form, .toolbar { display: flex; gap: 8px; align-items: flex-start; margin-bottom: 12px; }
+#search-query { flex: 1; }
+input, select, button { font: inherit; padding: 4px 8px; }
+table { width: 100%; border-collapse: collapse; background: #fff; }
+th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #e4e7eb; }
+th { background: #e4e7eb; }
+td.number { text-align: right; }
+.status { color: #52606d; }
+.ok { color: #199473; }
+.failed, .problems { color: #cf1124; }
+#settings-form { margin-top: 16px; padding: 12px; background: #fff; border: 1px solid #e4e7eb; }
+#settings-form label { display: block; margin-bottom: 8px; }
+#settings-form label span { display: inline-block; width: 160px; }
+`
+
+const appJS = `(function () {
+  "use strict";
+
+  var apiKeyInput = document.getElementById("apikey");
+  apiKeyInput.value = localStorage.getItem("torrentd.apikey") || "";
+  apiKeyInput.addEventListener("change", function () {
+    localStorage.setItem("torrentd.apikey", apiKeyInput.value);
+  });
+
+  function $(id) { return document.getElementById(id); }
+
+  function escapeHTML(value) {
+    return String(value === undefined || value === null ? "" : value)
+      .replace(/&/g, "&amp;").replace(/</g, "&lt;").replace(/>/g, "&gt;")
+      .replace(/"/g, "&quot;").replace(/'/g, "&#39;");
+  }
+
+  function withKey(url) {
+    return url + (url.indexOf("?") < 0 ? "?" : "&") + "apikey=" + encodeURIComponent(apiKeyInput.value);
+  }
+
+  function request(method, url, body) {
+    var options = { method: method, headers: {} };
+    if (body !== undefined) {
+      options.headers["Content-Type"] = "application/json";
+      options.body = JSON.stringify(body);
+    }
+    return fetch(url, options).then(function (response) {
+      return response.text().then(function (text) {
+        var data = null;
+        try { data = text ? JSON.parse(text) : null; } catch (e) { data = { error: text }; }
+        if (!response.ok) {
+          var err = new Error((data && data.error) || response.statusText);
+          err.data = data;
+          throw err;
+        }
+        return data;
+      });
+    });
+  }
+
+  function formatSize(bytes) {
+    if (!bytes) { return ""; }
+    var units = ["B", "KB", "MB", "GB", "TB"];
+    var ix = 0;
+    while (bytes >= 1024 && ix < units.length - 1) { bytes /= 1024; ix++; }
+    return bytes.toFixed(ix ? 1 : 0) + " " + units[ix];
+  }
+
+  function formatDate(unix) {
+    return unix ? new Date(unix * 1000).toLocaleString() : "";
+  }
+
+  // Tabs
+  function showTab(name) {
+    var tabs = document.querySelectorAll(".tab");
+    for (var i = 0; i < tabs.length; i++) {
+      tabs[i].classList.toggle("active", tabs[i].id === "tab-" + name);
+    }
+    var links = document.querySelectorAll("nav a");
+    for (var j = 0; j < links.length; j++) {
+      links[j].classList.toggle("active", links[j].getAttribute("data-tab") === name);
+    }
+    if (name === "indexes") { loadIndexes(); }
+    if (name === "latest") { loadLatest(); }
+  }
+  window.addEventListener("hashchange", function () { showTab(location.hash.substring(1) || "search"); });
+
+  // Search
+  function loadIndexOptions() {
+    request("GET", "/api/indexes").then(function (indexes) {
+      $("search-indexes").innerHTML = indexes.map(function (index) {
+        return "<option>" + escapeHTML(index.name) + "</option>";
+      }).join("");
+    });
+  }
+
+  $("search-form").addEventListener("submit", function (event) {
+    event.preventDefault();
+    var params = ["q=" + encodeURIComponent($("search-query").value)];
+    var selected = [];
+    var options = $("search-indexes").selectedOptions;
+    for (var i = 0; i < options.length; i++) { selected.push(options[i].value); }
+    if (selected.length) { params.push("indexes=" + encodeURIComponent(selected.join(","))); }
+    if ($("search-categories").value) { params.push("cat=" + encodeURIComponent($("search-categories").value)); }
+    if ($("search-limit").value) { params.push("limit=" + encodeURIComponent($("search-limit").value)); }
+    $("search-status").textContent = "Searching...";
+    request("GET", withKey("/api/search?" + params.join("&"))).then(function (response) {
+      $("search-status").textContent = response.results.length + " results";
+      $("search-results").tBodies[0].innerHTML = response.results.map(function (result) {
+        var title = result.link ? "<a href=\"" + escapeHTML(result.link) + "\">" + escapeHTML(result.title) + "</a>" : escapeHTML(result.title);
+        if (result.details) { title += " <a href=\"" + escapeHTML(result.details) + "\" target=\"_blank\" rel=\"noopener\">details</a>"; }
+        return "<tr><td>" + title + "</td><td>" + escapeHTML(result.site) + "</td><td>" + escapeHTML(result.category) +
+          "</td><td class=\"number\">" + formatSize(result.size) + "</td><td class=\"number\">" + escapeHTML(result.seeders) +
+          "</td><td class=\"number\">" + escapeHTML(result.peers) + "</td><td>" + formatDate(result.published) + "</td></tr>";
+      }).join("");
+    }).catch(function (err) {
+      $("search-status").textContent = "Search failed: " + err.message;
+    });
+  });
+
+  // Indexes
+  var health = {};
+
+  function loadIndexes() {
+    $("indexes-status").textContent = "Loading...";
+    Promise.all([request("GET", "/api/indexes"), request("GET", "/status")]).then(function (responses) {
+      var statuses = {};
+      (responses[1].indexes || []).forEach(function (status) { statuses[status.index] = status; });
+      $("indexes-status").textContent = "";
+      $("indexes").tBodies[0].innerHTML = responses[0].map(function (index) {
+        var status = statuses[index.name] || {};
+        var problems = (index.problems || []).concat(status.errors || []);
+        var healthCell = "";
+        if (health[index.name] !== undefined) {
+          healthCell = health[index.name] ? "<span class=\"ok\">ok</span>" : "<span class=\"failed\">failed</span>";
+        }
+        return "<tr><td>" + escapeHTML(index.name) + "</td><td>" + (index.loaded ? "yes" : "") +
+          "</td><td class=\"number\">" + escapeHTML(status.size || "") + "</td><td>" + healthCell +
+          "</td><td class=\"problems\">" + problems.map(escapeHTML).join("<br>") +
+          "</td><td><button type=\"button\" data-settings=\"" + escapeHTML(index.name) + "\">Settings</button></td></tr>";
+      }).join("");
+    }).catch(function (err) {
+      $("indexes-status").textContent = "Couldn't load the indexes: " + err.message;
+    });
+  }
+
+  $("refresh-indexes").addEventListener("click", loadIndexes);
+
+  $("health-check").addEventListener("click", function () {
+    $("indexes-status").textContent = "Checking...";
+    request("GET", "/health").then(function (results) {
+      health = {};
+      Object.keys(results).forEach(function (site) { health[site] = results[site].ok; });
+      loadIndexes();
+    }).catch(function (err) {
+      $("indexes-status").textContent = "Health check failed: " + err.message;
+    });
+  });
+
+  $("indexes").addEventListener("click", function (event) {
+    var name = event.target.getAttribute("data-settings");
+    if (name) { openSettings(name); }
+  });
+
+  function renderSettings(settings) {
+    $("settings-name").textContent = settings.name;
+    $("settings-form").setAttribute("data-index", settings.name);
+    var values = settings.values || {};
+    $("settings-fields").innerHTML = (settings.settings || []).map(function (field) {
+      var value = values[field.name] !== undefined ? values[field.name] : (field.default || "");
+      var name = escapeHTML(field.name);
+      var input;
+      switch (field.type) {
+        case "checkbox":
+          input = "<input type=\"checkbox\" name=\"" + name + "\"" + (value === "true" ? " checked" : "") + ">";
+          break;
+        case "select":
+          input = "<select name=\"" + name + "\">" + (field.options || []).map(function (option) {
+            return "<option value=\"" + escapeHTML(option.value) + "\"" + (option.value === value ? " selected" : "") + ">" +
+              escapeHTML(option.label) + "</option>";
+          }).join("") + "</select>";
+          break;
+        case "password":
+          input = "<input type=\"password\" autocomplete=\"off\" name=\"" + name + "\" value=\"" + escapeHTML(value) + "\">";
+          break;
+        case "int":
+          input = "<input type=\"number\" name=\"" + name + "\" value=\"" + escapeHTML(value) + "\">";
+          break;
+        default:
+          input = "<input type=\"text\" name=\"" + name + "\" value=\"" + escapeHTML(value) + "\">";
+      }
+      return "<label><span>" + escapeHTML(field.label || field.name) + (field.required ? " *" : "") + "</span>" + input + "</label>";
+    }).join("");
+    $("settings-problems").innerHTML = (settings.problems || []).map(function (problem) {
+      return "<li>" + escapeHTML(problem) + "</li>";
+    }).join("");
+    $("settings-form").hidden = false;
+  }
+
+  function openSettings(name) {
+    request("GET", withKey("/api/indexes/" + encodeURIComponent(name) + "/settings")).then(renderSettings).catch(function (err) {
+      $("indexes-status").textContent = "Couldn't load the settings: " + err.message;
+    });
+  }
+
+  $("settings-form").addEventListener("submit", function (event) {
+    event.preventDefault();
+    var name = $("settings-form").getAttribute("data-index");
+    var values = {};
+    var inputs = $("settings-fields").querySelectorAll("input, select");
+    for (var i = 0; i < inputs.length; i++) {
+      values[inputs[i].name] = inputs[i].type === "checkbox" ? String(inputs[i].checked) : inputs[i].value;
+    }
+    request("PUT", withKey("/api/indexes/" + encodeURIComponent(name) + "/settings"), values).then(function (settings) {
+      renderSettings(settings);
+      loadIndexes();
+    }).catch(function (err) {
+      if (err.data && err.data.settings) {
+        renderSettings(err.data);
+      } else {
+        $("settings-problems").innerHTML = "<li>" + escapeHTML(err.message) + "</li>";
+      }
+    });
+  });
+
+  $("settings-cancel").addEventListener("click", function () { $("settings-form").hidden = true; });
+
+  // Latest results
+  function loadLatest() {
+    request("GET", "/status").then(function (status) {
+      $("latest").tBodies[0].innerHTML = (status.latest || []).map(function (item) {
+        return "<tr><td><a href=\"" + escapeHTML(item.link) + "\" target=\"_blank\" rel=\"noopener\">" + escapeHTML(item.name) +
+          "</a></td><td>" + escapeHTML(item.site) + "</td><td>" + escapeHTML(item.desc) + "</td></tr>";
+      }).join("");
+    });
+  }
+
+  $("refresh-latest").addEventListener("click", loadLatest);
+
+  loadIndexOptions();
+  showTab(location.hash.substring(1) || "search");
+})();
+`
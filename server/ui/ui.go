@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Path is where the web ui is served from.
+const Path = "/ui/"
+
+// Register adds the routes of the web ui.
+// The assets are embedded in the binary, the ui itself only uses the json api of the server.
+func Register(r gin.IRoutes) {
+	r.GET("/", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, Path)
+	})
+	r.GET(Path, serveAsset("text/html; charset=utf-8", indexHTML))
+	r.GET(Path+"app.js", serveAsset("application/javascript; charset=utf-8", appJS))
+	r.GET(Path+"style.css", serveAsset("text/css; charset=utf-8", styleCSS))
+}
+
+func serveAsset(contentType, content string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+		c.Data(http.StatusOK, contentType, []byte(content))
+	}
+}
@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/onsi/gomega"
+)
+
+func TestRegister(t *testing.T) {
+	g := gomega.NewWithT(t)
+	r := gin.New()
+	Register(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	g.Expect(w.Code).To(gomega.Equal(http.StatusFound))
+	g.Expect(w.Header().Get("Location")).To(gomega.Equal(Path))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Path, nil))
+	g.Expect(w.Code).To(gomega.Equal(http.StatusOK))
+	g.Expect(w.Body.String()).To(gomega.ContainSubstring(`<script src="app.js">`))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Path+"app.js", nil))
+	g.Expect(w.Header().Get("Content-Type")).To(gomega.HavePrefix("application/javascript"))
+}
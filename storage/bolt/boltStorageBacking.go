@@ -8,9 +8,11 @@ import (
 	"os"
 	"path"
 	"reflect"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/sp0x/torrentd/indexer/categories"
 	"github.com/sp0x/torrentd/indexer/search"
@@ -31,6 +33,10 @@ const (
 	namespaceResultsBucketName = "results"
 	metaBucketName             = "__meta"
 	categoriesBucketName       = "__categories"
+	// openTimeout is how long to wait for a database that another process has open.
+	openTimeout = 30 * time.Second
+	// lockCheckTimeout is how long to wait for a database, to know if another process has it open.
+	lockCheckTimeout = time.Second
 )
 
 var categoriesInitialized = false
@@ -61,7 +67,7 @@ func NewBoltDbStorage(dbPath string, recordTypePtr interface{}) (*Storage, error
 		return nil, errors.New("recordTypePtr must be a pointer type")
 	}
 	ensurePathExists(dbPath)
-	dbInstance, err := GetBoltDB(dbPath)
+	dbInstance, err := openSharedDatabase(dbPath)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +86,10 @@ func NewBoltDbStorage(dbPath string, recordTypePtr interface{}) (*Storage, error
 }
 
 func GetBoltDB(file string) (*bolt.DB, error) {
-	dbInstance, err := bolt.Open(file, 0600, nil)
+	dbInstance, err := bolt.Open(file, 0600, &bolt.Options{Timeout: openTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("the database %s is used by another process", file)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -91,6 +100,18 @@ func GetBoltDB(file string) (*bolt.DB, error) {
 	return dbInstance, nil
 }
 
+// IsLocked checks if another process has the database open, since only one process can open it.
+func IsLocked(file string) bool {
+	if _, err := os.Stat(file); err != nil {
+		return false
+	}
+	db, err := bolt.Open(file, 0600, &bolt.Options{Timeout: lockCheckTimeout, ReadOnly: true})
+	if err == nil {
+		_ = db.Close()
+	}
+	return err == bolt.ErrTimeout
+}
+
 func setupCategories(db *bolt.DB) error {
 	// Setup our DB
 	err := db.Update(func(tx *bolt.Tx) error {
@@ -119,7 +140,7 @@ func (b *Storage) Close() {
 	if b.Database == nil {
 		return
 	}
-	_ = b.Database.Close()
+	_ = closeSharedDatabase(b.Database)
 }
 
 // Find records by it's index keys.
@@ -341,6 +362,35 @@ func (b *Storage) ForEach(callback func(record search.Record)) {
 	})
 }
 
+// ForEachInNamespaces goes through the records of all the namespaces, from the newest to the oldest one in each namespace.
+// Going through a namespace stops when the callback returns false.
+func (b *Storage) ForEachInNamespaces(callback func(namespace string, record search.Record) bool) {
+	_ = b.Database.View(func(tx *bolt.Tx) error {
+		for _, namespace := range b.getNamespaces(tx) {
+			bucket := b.GetRootBucket(tx, namespace, namespaceResultsBucketName)
+			if bucket == nil {
+				continue
+			}
+			cursor := bucket.Cursor()
+			for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+				// The indexes are buckets in the results bucket, and the metadata is next to the records.
+				if value == nil || string(key) == metaBucketName {
+					continue
+				}
+				record, err := b.marshaler.Unmarshal(value)
+				if err != nil {
+					log.Warningf("Couldn't deserialize item from bolt storage: %v", err)
+					continue
+				}
+				if !callback(namespace, record.(search.Record)) {
+					break
+				}
+			}
+		}
+		return nil
+	})
+}
+
 func GetDefaultDatabasePath() string {
 	cwd, _ := os.Getwd()
 	return path.Join(cwd, "db", "bolt.db")
@@ -202,6 +202,19 @@ func TestNewBoltStorage(t *testing.T) {
 	}
 }
 
+func TestIsLocked(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	file := tempfile()
+	defer os.Remove(file)
+	g.Expect(IsLocked(file)).To(gomega.BeFalse())
+
+	db, err := GetBoltDB(file)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(IsLocked(file)).To(gomega.BeTrue())
+	_ = db.Close()
+	g.Expect(IsLocked(file)).To(gomega.BeFalse())
+}
+
 func Test_getItemKey(t *testing.T) {
 	type args struct {
 		item search.Record
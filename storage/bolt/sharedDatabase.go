@@ -0,0 +1,58 @@
+package bolt
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/boltdb/bolt"
+)
+
+// sharedDatabases keeps the open databases, so that all the storages of a file use the same connection.
+// Bolt locks its file while it's open, so opening it a second time from the same process would block.
+var sharedDatabases = struct {
+	sync.Mutex
+	byPath map[string]*sharedDatabase
+}{byPath: make(map[string]*sharedDatabase)}
+
+type sharedDatabase struct {
+	db   *bolt.DB
+	refs int
+}
+
+// openSharedDatabase opens a database file, or reuses the connection to it if it's already open.
+func openSharedDatabase(file string) (*bolt.DB, error) {
+	dbPath, err := filepath.Abs(file)
+	if err != nil {
+		dbPath = file
+	}
+	sharedDatabases.Lock()
+	defer sharedDatabases.Unlock()
+	if shared, ok := sharedDatabases.byPath[dbPath]; ok {
+		shared.refs++
+		return shared.db, nil
+	}
+	db, err := GetBoltDB(file)
+	if err != nil {
+		return nil, err
+	}
+	sharedDatabases.byPath[dbPath] = &sharedDatabase{db: db, refs: 1}
+	return db, nil
+}
+
+// closeSharedDatabase closes the connection to a database, once none of its storages use it.
+func closeSharedDatabase(db *bolt.DB) error {
+	sharedDatabases.Lock()
+	defer sharedDatabases.Unlock()
+	for dbPath, shared := range sharedDatabases.byPath {
+		if shared.db != db {
+			continue
+		}
+		shared.refs--
+		if shared.refs > 0 {
+			return nil
+		}
+		delete(sharedDatabases.byPath, dbPath)
+		break
+	}
+	return db.Close()
+}
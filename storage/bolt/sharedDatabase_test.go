@@ -0,0 +1,53 @@
+package bolt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/indexer/search"
+)
+
+func TestNewBoltDbStorage_ShouldShareTheDatabaseOfAFile(t *testing.T) {
+	g := gomega.NewWithT(t)
+	file := tempfile()
+	first, err := NewBoltDbStorage(file, &search.TorrentResultItem{})
+	g.Expect(err).To(gomega.BeNil())
+	// Opening the same file again would block if the database wasn't shared.
+	second, err := NewBoltDbStorage(file, &search.TorrentResultItem{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(second.Database).To(gomega.BeIdenticalTo(first.Database))
+
+	first.Close()
+	g.Expect(second.SetNamespace("index")).To(gomega.Succeed())
+	second.Close()
+	g.Expect(sharedDatabases.byPath).To(gomega.BeEmpty())
+}
+
+func TestStorage_ForEachInNamespaces(t *testing.T) {
+	g := gomega.NewWithT(t)
+	file := tempfile()
+	for _, namespace := range []string{"first", "second"} {
+		store, err := NewBoltDbStorage(file, &search.TorrentResultItem{})
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(store.SetNamespace(namespace)).To(gomega.Succeed())
+		for ix := 0; ix < 3; ix++ {
+			item := &search.TorrentResultItem{Title: fmt.Sprintf("%s-%d", namespace, ix)}
+			g.Expect(store.Create(item, nil)).To(gomega.Succeed())
+		}
+		store.Close()
+	}
+	store, _ := NewBoltDbStorage(file, &search.TorrentResultItem{})
+	defer store.Close()
+
+	var titles []string
+	store.ForEachInNamespaces(func(namespace string, record search.Record) bool {
+		item := record.(*search.TorrentResultItem)
+		titles = append(titles, item.Title)
+		return item.Title != "first-1"
+	})
+
+	// Every namespace starts from its newest record.
+	g.Expect(titles).To(gomega.Equal([]string{"first-2", "first-1", "second-2", "second-1", "second-0"}))
+}
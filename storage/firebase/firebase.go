@@ -131,6 +131,33 @@ func (f *FirestoreStorage) ForEach(callback func(record search.Record)) {
 	}
 }
 
+// ForEachInNamespaces goes through the documents of all the collections, from the newest to the oldest one in each collection.
+// Going through a collection stops when the callback returns false.
+func (f *FirestoreStorage) ForEachInNamespaces(callback func(namespace string, record search.Record) bool) {
+	collections := f.client.Collections(f.context)
+	for {
+		collection, err := collections.Next()
+		if err != nil {
+			break
+		}
+		documents := collection.OrderBy("ID", firestore.Desc).Documents(f.context)
+		for {
+			document, err := documents.Next()
+			if err != nil {
+				break
+			}
+			record := f.marshaler.New()
+			if err = document.DataTo(record); err != nil {
+				continue
+			}
+			if !callback(collection.ID, record.(search.Record)) {
+				break
+			}
+		}
+		documents.Stop()
+	}
+}
+
 func (f *FirestoreStorage) transformIndexQueryToFirestoreQuery(query indexing.Query, limit int) *firestore.Query {
 	collection := f.getCollection()
 	var fireQuery *firestore.Query
@@ -16,6 +16,7 @@ type ItemStorage interface {
 	SetKey(index *indexing.Key) error
 	GetLatest(count int) []search.ResultItemBase
 	ForEach(callback func(record search.Record))
+	ForEachInNamespaces(callback func(namespace string, record search.Record) bool)
 	GetStats(showDebugInfo bool) *stats.Stats
 	Truncate() error
 }
@@ -36,6 +37,9 @@ type ItemStorageBacking interface {
 	GetLatest(count int) []search.ResultItemBase
 	Close()
 	ForEach(callback func(record search.Record))
+	// ForEachInNamespaces goes through the records of all namespaces, from the newest to the oldest one in each namespace.
+	// Going through a namespace stops when the callback returns false.
+	ForEachInNamespaces(callback func(namespace string, record search.Record) bool)
 	GetStats(showDebugInfo bool) *stats.Stats
 	Truncate() error
 }
@@ -53,6 +53,10 @@ func (s *KeyedStorage) ForEach(callback func(record search.Record)) {
 	s.backing.ForEach(callback)
 }
 
+func (s *KeyedStorage) ForEachInNamespaces(callback func(namespace string, record search.Record) bool) {
+	s.backing.ForEachInNamespaces(callback)
+}
+
 func (s *KeyedStorage) GetStats(showDebugInfo bool) *stats.Stats {
 	return s.backing.GetStats(showDebugInfo)
 }
@@ -0,0 +1,367 @@
+package torrent
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sp0x/torrentd/config"
+	"github.com/sp0x/torrentd/indexer"
+)
+
+const (
+	defaultFileCacheTTL  = 7 * 24 * time.Hour
+	defaultFileCacheSize = 256 * 1024 * 1024
+	// MaxDownloadSize is the size of the biggest .torrent file that is downloaded.
+	MaxDownloadSize = 10 * 1024 * 1024
+	// DownloadTimeout is how long a .torrent file can take to download.
+	DownloadTimeout = 20 * time.Second
+)
+
+// FileCache is an on-disk cache of .torrent files.
+// The files are stored by their info hash, and the links that they were downloaded from point to them,
+// so a torrent that's linked from more than one place is only stored once.
+type FileCache struct {
+	dir string
+	// TTL is how long a downloaded link is cached, and how long unused files are kept.
+	TTL time.Duration
+	// MaxSize is the maximum size of all the cached files, the least recently used ones are removed first.
+	MaxSize int64
+	// MaxFileSize is the size of the biggest file that is cached.
+	MaxFileSize int64
+	mux         sync.Mutex
+}
+
+// NewFileCache creates a cache in a directory.
+func NewFileCache(dir string, ttl time.Duration, maxSize int64) (*FileCache, error) {
+	for _, subdir := range []string{"files", "links"} {
+		if err := os.MkdirAll(filepath.Join(dir, subdir), os.ModePerm); err != nil {
+			return nil, err
+		}
+	}
+	return &FileCache{
+		dir:         dir,
+		TTL:         ttl,
+		MaxSize:     maxSize,
+		MaxFileSize: MaxDownloadSize,
+	}, nil
+}
+
+// NewFileCacheFromConfig creates the cache with the `torrent_cache_dir`, `torrent_cache_ttl` and `torrent_cache_size_mb` config values.
+// If `torrent_cache_disabled` is set, no cache is returned.
+func NewFileCacheFromConfig(conf config.Config) (*FileCache, error) {
+	if conf.GetBool("torrent_cache_disabled") {
+		return nil, nil
+	}
+	dir, err := homedir.Expand(conf.GetString("torrent_cache_dir"))
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = config.GetCachePath("torrents")
+	}
+	ttl := defaultFileCacheTTL
+	if value := conf.GetString("torrent_cache_ttl"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid torrent_cache_ttl: %s", value)
+		}
+		ttl = parsed
+	}
+	maxSize := int64(defaultFileCacheSize)
+	if sizeMB := conf.GetInt("torrent_cache_size_mb"); sizeMB > 0 {
+		maxSize = int64(sizeMB) * 1024 * 1024
+	}
+	return NewFileCache(dir, ttl, maxSize)
+}
+
+// Get finds the cached file of a link from an index.
+func (c *FileCache) Get(index, link string) ([]byte, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	linkFile := c.linkPath(index, link)
+	stat, err := os.Stat(linkFile)
+	if err != nil || time.Since(stat.ModTime()) > c.TTL {
+		return nil, false
+	}
+	infoHash, err := ioutil.ReadFile(linkFile)
+	if err != nil {
+		return nil, false
+	}
+	return c.readFile(string(infoHash))
+}
+
+func (c *FileCache) readFile(infoHash string) ([]byte, bool) {
+	file := c.filePath(infoHash)
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	// The modification time is used to find the least recently used files.
+	now := time.Now()
+	_ = os.Chtimes(file, now, now)
+	return content, true
+}
+
+// Put caches the file of a link from an index. Only valid .torrent files are cached.
+func (c *FileCache) Put(index, link string, content []byte) (*Definition, error) {
+	def, err := c.decode(content)
+	if err != nil {
+		return nil, err
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if err := writeFileAtomic(c.filePath(def.InfoHash), content); err != nil {
+		return nil, err
+	}
+	return def, c.putLink(index, link, def.InfoHash)
+}
+
+// decode checks that the content is a .torrent file that can be cached.
+func (c *FileCache) decode(content []byte) (*Definition, error) {
+	if int64(len(content)) > c.MaxFileSize {
+		return nil, fmt.Errorf("the file is too big to cache: %d bytes", len(content))
+	}
+	def, err := decodeTorrentBuff(content)
+	if err != nil {
+		return nil, err
+	}
+	if def.Info.Pieces == "" {
+		return nil, errors.New("the file isn't a torrent")
+	}
+	return def, nil
+}
+
+// putLink points a link to a cached file. The lock of the cache must be held.
+func (c *FileCache) putLink(index, link, infoHash string) error {
+	if err := writeFileAtomic(c.linkPath(index, link), []byte(infoHash)); err != nil {
+		return err
+	}
+	c.prune()
+	return nil
+}
+
+// NewReader reads a download and caches it at the same time, so that it can be streamed while it's downloaded.
+// The download is cached once the reader is closed, if it was read until its end and it's a valid .torrent file.
+// Closing the reader doesn't close the download.
+func (c *FileCache) NewReader(index, link string, download io.Reader) *CachingReader {
+	r := &CachingReader{cache: c, index: index, link: link, download: download}
+	r.file, r.err = ioutil.TempFile(filepath.Join(c.dir, "files"), ".tmp-")
+	return r
+}
+
+// CachingReader reads a download and writes it to a temporary file in the cache.
+type CachingReader struct {
+	cache    *FileCache
+	index    string
+	link     string
+	download io.Reader
+	file     *os.File
+	size     int64
+	// err is the reason why the download can't be cached anymore.
+	err  error
+	done bool
+}
+
+func (r *CachingReader) Read(p []byte) (int, error) {
+	n, err := r.download.Read(p)
+	if n > 0 && r.err == nil {
+		r.size += int64(n)
+		if r.size > r.cache.MaxFileSize {
+			r.err = fmt.Errorf("the file is too big to cache: more than %d bytes", r.cache.MaxFileSize)
+		} else if _, writeErr := r.file.Write(p[:n]); writeErr != nil {
+			r.err = writeErr
+		}
+	}
+	switch {
+	case err == io.EOF:
+		r.done = true
+	case err != nil && r.err == nil:
+		r.err = err
+	}
+	return n, err
+}
+
+// Close caches the download if it was read completely, and removes its temporary file.
+func (r *CachingReader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	defer func() {
+		_ = os.Remove(r.file.Name())
+	}()
+	_ = r.file.Close()
+	if r.err == nil && !r.done {
+		r.err = errors.New("the download wasn't read until its end")
+	}
+	if r.err == nil {
+		r.err = r.commit()
+	}
+	if r.err != nil {
+		log.WithFields(log.Fields{"link": r.link, "index": r.index}).
+			Debugf("Not caching the download: %v", r.err)
+	}
+	return nil
+}
+
+func (r *CachingReader) commit() error {
+	content, err := ioutil.ReadFile(r.file.Name())
+	if err != nil {
+		return err
+	}
+	def, err := r.cache.decode(content)
+	if err != nil {
+		return err
+	}
+	r.cache.mux.Lock()
+	defer r.cache.mux.Unlock()
+	if err := os.Rename(r.file.Name(), r.cache.filePath(def.InfoHash)); err != nil {
+		return err
+	}
+	return r.cache.putLink(r.index, r.link, def.InfoHash)
+}
+
+// Fetch gets the file of a link from the cache, or downloads it with the open function and caches it.
+// The download is opened with the context, and it's stopped if the context is done.
+func (c *FileCache) Fetch(ctx context.Context, index, link string, open func(ctx context.Context) (*indexer.ResponseProxy, error)) ([]byte, error) {
+	if content, ok := c.Get(index, link); ok {
+		return content, nil
+	}
+	proxy, err := open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proxy == nil || proxy.Reader == nil {
+		return nil, errors.New("couldn't open stream for download")
+	}
+	content, err := ReadDownload(ctx, proxy, DownloadTimeout, c.MaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Put(index, link, content); err != nil {
+		log.WithFields(log.Fields{"link": link, "index": index}).
+			Debugf("Not caching the download: %v", err)
+	}
+	return content, nil
+}
+
+// Size is the size of all the cached files.
+func (c *FileCache) Size() int64 {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	var size int64
+	for _, file := range c.listFiles("files") {
+		size += file.Size()
+	}
+	return size
+}
+
+// prune removes the expired links and files, and the least recently used files if the cache is too big.
+func (c *FileCache) prune() {
+	for _, link := range c.listFiles("links") {
+		if time.Since(link.ModTime()) > c.TTL {
+			_ = os.Remove(filepath.Join(c.dir, "links", link.Name()))
+		}
+	}
+	files := c.listFiles("files")
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+	var size int64
+	for _, file := range files {
+		size += file.Size()
+		if size > c.MaxSize || time.Since(file.ModTime()) > c.TTL {
+			// The links to the file are misses after this, and they're replaced once they're downloaded again.
+			_ = os.Remove(filepath.Join(c.dir, "files", file.Name()))
+		}
+	}
+}
+
+func (c *FileCache) listFiles(subdir string) []os.FileInfo {
+	files, err := ioutil.ReadDir(filepath.Join(c.dir, subdir))
+	if err != nil {
+		return nil
+	}
+	result := files[:0]
+	for _, file := range files {
+		if !file.IsDir() && !strings.HasPrefix(file.Name(), ".") {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+func (c *FileCache) filePath(infoHash string) string {
+	return filepath.Join(c.dir, "files", infoHash+".torrent")
+}
+
+func (c *FileCache) linkPath(index, link string) string {
+	return filepath.Join(c.dir, "links", fmt.Sprintf("%x", sha1.Sum([]byte(index+"\n"+link))))
+}
+
+// writeFileAtomic writes to a temporary file first, so that a file is never read while it's partially written.
+func writeFileAtomic(file string, content []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// ReadDownload reads a download, until it's done, the context is done or until the timeout.
+// Downloads that are bigger than maxSize fail, so that big error pages aren't read into memory.
+func ReadDownload(ctx context.Context, proxy *indexer.ResponseProxy, timeout time.Duration, maxSize int64) ([]byte, error) {
+	defer func() {
+		_ = proxy.Reader.Close()
+	}()
+	type readResult struct {
+		content []byte
+		err     error
+	}
+	done := make(chan readResult, 1)
+	go func() {
+		content, err := ioutil.ReadAll(io.LimitReader(proxy.Reader, maxSize+1))
+		if err == nil && int64(len(content)) > maxSize {
+			content, err = nil, fmt.Errorf("the download is bigger than %d bytes", maxSize)
+		}
+		done <- readResult{content, err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-proxy.ContentLengthChan:
+			// The content comes after its length.
+		case result := <-done:
+			if result.err == nil && len(result.content) == 0 {
+				result.err = errors.New("the download failed")
+			}
+			return result.content, result.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, errors.New("timed out waiting for the download")
+		}
+	}
+}
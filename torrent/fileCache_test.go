@@ -0,0 +1,180 @@
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	"github.com/sp0x/torrentd/indexer"
+)
+
+func newTestFileCache(t *testing.T) (*FileCache, []byte, func()) {
+	dir, _ := ioutil.TempDir("", "torrents-")
+	cache, err := NewFileCache(dir, time.Hour, 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join("testdata", "sample.torrent"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache, content, func() { _ = os.RemoveAll(dir) }
+}
+
+func TestFileCache_Put(t *testing.T) {
+	g := gomega.NewWithT(t)
+	cache, content, cleanup := newTestFileCache(t)
+	defer cleanup()
+
+	_, ok := cache.Get("index", "http://index/1.torrent")
+	g.Expect(ok).To(gomega.BeFalse())
+	_, err := cache.Put("index", "http://index/1.torrent", content)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	_, err = cache.Put("index", "http://index/2.torrent", content)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+
+	cached, ok := cache.Get("index", "http://index/1.torrent")
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(cached).To(gomega.Equal(content))
+	_, ok = cache.Get("other", "http://index/1.torrent")
+	g.Expect(ok).To(gomega.BeFalse())
+	// Both links point to the same file.
+	g.Expect(cache.Size()).To(gomega.BeEquivalentTo(len(content)))
+
+	_, err = cache.Put("index", "http://index/error", []byte("<html>Not found</html>"))
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestFileCache_Limits(t *testing.T) {
+	g := gomega.NewWithT(t)
+	cache, content, cleanup := newTestFileCache(t)
+	defer cleanup()
+
+	_, err := cache.Put("index", "http://index/1.torrent", content)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	// Expired links are misses.
+	cache.TTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	_, ok := cache.Get("index", "http://index/1.torrent")
+	g.Expect(ok).To(gomega.BeFalse())
+
+	// Files that don't fit in the cache are removed.
+	cache.TTL = time.Hour
+	cache.MaxSize = int64(len(content) - 1)
+	_, err = cache.Put("index", "http://index/1.torrent", content)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	_, ok = cache.Get("index", "http://index/1.torrent")
+	g.Expect(ok).To(gomega.BeFalse())
+	g.Expect(cache.Size()).To(gomega.BeEquivalentTo(0))
+
+	cache.MaxFileSize = 10
+	_, err = cache.Put("index", "http://index/1.torrent", content)
+	g.Expect(err).To(gomega.HaveOccurred())
+}
+
+func TestFileCache_Fetch(t *testing.T) {
+	g := gomega.NewWithT(t)
+	cache, content, cleanup := newTestFileCache(t)
+	defer cleanup()
+	opened := 0
+	open := func(context.Context) (*indexer.ResponseProxy, error) {
+		opened++
+		proxy, pipeW := indexer.NewResponseProxy()
+		go func() {
+			proxy.ContentLengthChan <- int64(len(content))
+			_, _ = pipeW.Write(content)
+			_ = pipeW.Close()
+		}()
+		return proxy, nil
+	}
+
+	fetched, err := cache.Fetch(context.Background(), "index", "http://index/1.torrent", open)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(fetched).To(gomega.Equal(content))
+	fetched, err = cache.Fetch(context.Background(), "index", "http://index/1.torrent", open)
+	g.Expect(err).ToNot(gomega.HaveOccurred())
+	g.Expect(fetched).To(gomega.Equal(content))
+	g.Expect(opened).To(gomega.Equal(1))
+
+	// Failed downloads aren't cached.
+	_, err = cache.Fetch(context.Background(), "index", "http://index/2.torrent", func(context.Context) (*indexer.ResponseProxy, error) {
+		proxy, pipeW := indexer.NewResponseProxy()
+		_ = pipeW.Close()
+		return proxy, nil
+	})
+	g.Expect(err).To(gomega.HaveOccurred())
+	// The error of a failed download is returned.
+	downloadErr := errors.New("connection reset")
+	_, err = cache.Fetch(context.Background(), "index", "http://index/3.torrent", func(context.Context) (*indexer.ResponseProxy, error) {
+		proxy, pipeW := indexer.NewResponseProxy()
+		proxy.ContentLengthChan <- 100
+		_ = pipeW.CloseWithError(downloadErr)
+		return proxy, nil
+	})
+	g.Expect(err).To(gomega.Equal(downloadErr))
+	// Downloads that are too big aren't read.
+	cache.MaxFileSize = 10
+	_, err = cache.Fetch(context.Background(), "index", "http://index/5.torrent", func(context.Context) (*indexer.ResponseProxy, error) {
+		proxy, pipeW := indexer.NewResponseProxy()
+		go func() {
+			_, _ = pipeW.Write([]byte("<html>Too many requests</html>"))
+			_ = pipeW.Close()
+		}()
+		return proxy, nil
+	})
+	g.Expect(err).To(gomega.MatchError("the download is bigger than 10 bytes"))
+	cache.MaxFileSize = MaxDownloadSize
+
+	// A download that doesn't finish is stopped once the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cache.Fetch(ctx, "index", "http://index/4.torrent", func(context.Context) (*indexer.ResponseProxy, error) {
+		proxy, _ := indexer.NewResponseProxy()
+		return proxy, nil
+	})
+	g.Expect(err).To(gomega.Equal(context.Canceled))
+}
+
+func TestFileCache_NewReader(t *testing.T) {
+	g := gomega.NewWithT(t)
+	cache, content, cleanup := newTestFileCache(t)
+	defer cleanup()
+	read := func(link string, download []byte, size int64) []byte {
+		reader := cache.NewReader("index", link, bytes.NewReader(download))
+		defer reader.Close()
+		sent, err := ioutil.ReadAll(io.LimitReader(reader, size))
+		g.Expect(err).ToNot(gomega.HaveOccurred())
+		return sent
+	}
+
+	g.Expect(read("http://index/1.torrent", content, int64(len(content))+1)).To(gomega.Equal(content))
+	cached, ok := cache.Get("index", "http://index/1.torrent")
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(cached).To(gomega.Equal(content))
+
+	// Downloads that weren't read until their end aren't cached.
+	read("http://index/2.torrent", content, 10)
+	_, ok = cache.Get("index", "http://index/2.torrent")
+	g.Expect(ok).To(gomega.BeFalse())
+	// Neither are files that aren't torrents, or ones that are too big, but they're still read.
+	page := []byte("<html>Not found</html>")
+	g.Expect(read("http://index/3.torrent", page, 100)).To(gomega.Equal(page))
+	_, ok = cache.Get("index", "http://index/3.torrent")
+	g.Expect(ok).To(gomega.BeFalse())
+	cache.MaxFileSize = 10
+	g.Expect(read("http://index/4.torrent", content, int64(len(content))+1)).To(gomega.Equal(content))
+	_, ok = cache.Get("index", "http://index/4.torrent")
+	g.Expect(ok).To(gomega.BeFalse())
+
+	// The temporary files are removed.
+	files, _ := ioutil.ReadDir(filepath.Join(cache.dir, "files"))
+	g.Expect(files).To(gomega.HaveLen(1))
+}
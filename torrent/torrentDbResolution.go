@@ -1,6 +1,7 @@
 package torrent
 
 import (
+	"context"
 	"reflect"
 
 	log "github.com/sirupsen/logrus"
@@ -18,10 +19,15 @@ func ResolveTorrents(index indexer.IndexCollection, cfg config.Config) []search.
 		Build()
 	defer store.Close()
 	results := store.GetLatest(20)
-	if err := index.HealthCheck(); err != nil {
+	ctx := context.Background()
+	if err := index.HealthCheck(ctx); err != nil {
 		log.Errorf("Failed while checking indexer %s. Err: %s\n", reflect.TypeOf(index), err)
 		return nil
 	}
+	torrentCache, err := NewFileCacheFromConfig(cfg)
+	if err != nil {
+		log.Warningf("Couldn't open the torrent cache: %v", err)
+	}
 	indexScope := indexer.NewScope(nil)
 	for i, searchItem := range results {
 		// Skip already resolved results.
@@ -40,21 +46,16 @@ func ResolveTorrents(index indexer.IndexCollection, cfg config.Config) []search.
 				Warningf("Couldn'item find indexer.")
 			continue
 		}
-		err = index.HealthCheck()
+		err = index.HealthCheck(ctx)
 		if err != nil {
 			log.WithFields(log.Fields{"err": err, "site": item.Site}).
 				Warningf("Error while checking indexer.")
 			continue
 		}
-		responsePxy, err := index.Open(item)
-		if err != nil {
-			log.Debugf("Couldn'item open result [%v] %v", item.LocalID, item.Title)
-			continue
-		}
 		log.
 			WithFields(log.Fields{"link": item.SourceLink, "name": item.Title}).
 			Info("Resolving")
-		def, err := ParseTorrentFromStream(responsePxy.Reader)
+		def, err := openTorrent(ctx, torrentCache, index, item)
 		if err != nil {
 			log.Debugf("Could not resolve result: [%v] %v", item.LocalID, item.Title)
 			continue
@@ -74,3 +75,25 @@ func ResolveTorrents(index indexer.IndexCollection, cfg config.Config) []search.
 	}
 	return results
 }
+
+// openTorrent gets the definition of a result, from the torrent cache if it's there.
+func openTorrent(ctx context.Context, torrentCache *FileCache, index indexer.IndexCollection, item *search.TorrentResultItem) (*Definition, error) {
+	if torrentCache == nil {
+		responsePxy, err := index.Open(ctx, item)
+		if err != nil {
+			return nil, err
+		}
+		return ParseTorrentFromStream(responsePxy.Reader)
+	}
+	link := item.SourceLink
+	if link == "" {
+		link = item.Link
+	}
+	content, err := torrentCache.Fetch(ctx, item.IndexName(), link, func(ctx context.Context) (*indexer.ResponseProxy, error) {
+		return index.Open(ctx, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ParseTorrent(string(content))
+}
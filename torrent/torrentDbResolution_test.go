@@ -16,7 +16,7 @@ func TestResolveTorrents(t *testing.T) {
 	defer ctrl.Finish()
 	index := indexer.NewMockIndexer(ctrl)
 	indexInfo := indexer.NewMockInfo(ctrl)
-	index.EXPECT().HealthCheck().Return(nil)
+	index.EXPECT().HealthCheck(gomock.Any()).Return(nil)
 	index.EXPECT().Info().Return(indexInfo)
 	indexInfo.EXPECT().GetID().Return("IndexID1")
 	cfg := &config.ViperConfig{}
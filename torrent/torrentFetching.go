@@ -1,6 +1,7 @@
 package torrent
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"text/tabwriter"
@@ -24,7 +25,7 @@ func GetNewScrapeItems(facade *indexer.Facade, fetchOptions *indexer.GenericSear
 	query := search.NewQuery()
 	query.StopOnStale = true
 
-	resultsChannel, err := facade.Search(query)
+	resultsChannel, err := facade.Search(context.Background(), query)
 	if err != nil {
 		log.Warningf("Could not fetch page %d\n", page)
 		if _, ok := err.(*indexer.LoginError); ok {
@@ -2,6 +2,7 @@ package torrent
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha1"
 	"errors"
 	"fmt"
@@ -32,8 +33,8 @@ func ParseTorrentFromStream(stream io.ReadCloser) (*Definition, error) {
 	return ParseTorrent(string(body))
 }
 
-func ParseTorrentFromURL(index indexer.Indexer, torrentURL string) (*Definition, error) {
-	respProxy, err := index.Download(torrentURL)
+func ParseTorrentFromURL(ctx context.Context, index indexer.Indexer, torrentURL string) (*Definition, error) {
+	respProxy, err := index.Download(ctx, torrentURL)
 	if err != nil {
 		return nil, err
 	}
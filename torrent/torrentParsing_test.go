@@ -2,6 +2,7 @@ package torrent
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"path"
@@ -27,8 +28,8 @@ func Test_ParseTorrentFromURL(t *testing.T) {
 		_, _ = pipeWr.Write(torrentBody)
 		_ = pipeWr.Close()
 	}()
-	index.EXPECT().Download(torrentURL).Return(responseProxy, nil)
-	def, err := ParseTorrentFromURL(index, torrentURL)
+	index.EXPECT().Download(gomock.Any(), torrentURL).Return(responseProxy, nil)
+	def, err := ParseTorrentFromURL(context.Background(), index, torrentURL)
 
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(def).ToNot(gomega.BeNil())
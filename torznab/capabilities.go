@@ -11,8 +11,84 @@ import (
 )
 
 type Capabilities struct {
-	SearchModes []search.Capability
-	Categories  categories.Categories
+	Server       ServerInfo
+	Limits       Limits
+	Registration Registration
+	SearchModes  []search.Capability
+	Categories   categories.Categories
+}
+
+// ServerInfo describes the index, or the indexes, that the capabilities are for.
+type ServerInfo struct {
+	Title     string
+	Strapline string
+	URL       string
+}
+
+// Limits are the default and the maximum number of results of a search.
+type Limits struct {
+	Max     uint
+	Default uint
+}
+
+// Registration tells if new users can register in the index.
+type Registration struct {
+	Available bool
+	Open      bool
+}
+
+// Union merges the capabilities of several indexes, with the categories and search modes of all of them.
+// A search mode is available if it's available in one of the indexes, with the params of all the indexes that have it.
+// The default limit is the smallest one, so that it's valid for all the indexes.
+func Union(server ServerInfo, all ...Capabilities) Capabilities {
+	union := Capabilities{
+		Server:      server,
+		SearchModes: []search.Capability{},
+		Categories:  categories.Categories{},
+	}
+	modes := make(map[string]int)
+	for _, caps := range all {
+		for id, cat := range caps.Categories {
+			union.Categories[id] = cat
+		}
+		for _, mode := range caps.SearchModes {
+			idx, ok := modes[mode.Key]
+			if !ok {
+				modes[mode.Key] = len(union.SearchModes)
+				union.SearchModes = append(union.SearchModes, search.Capability{
+					Key:             mode.Key,
+					Available:       mode.Available,
+					SupportedParams: append([]string{}, mode.SupportedParams...),
+				})
+				continue
+			}
+			merged := &union.SearchModes[idx]
+			merged.Available = merged.Available || mode.Available
+			for _, param := range mode.SupportedParams {
+				if !containsString(merged.SupportedParams, param) {
+					merged.SupportedParams = append(merged.SupportedParams, param)
+				}
+			}
+		}
+		if caps.Limits.Max > union.Limits.Max {
+			union.Limits.Max = caps.Limits.Max
+		}
+		if caps.Limits.Default > 0 && (union.Limits.Default == 0 || caps.Limits.Default < union.Limits.Default) {
+			union.Limits.Default = caps.Limits.Default
+		}
+		union.Registration.Available = union.Registration.Available || caps.Registration.Available
+		union.Registration.Open = union.Registration.Open || caps.Registration.Open
+	}
+	return union
+}
+
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+	return false
 }
 
 func (c Capabilities) HasCategory(cat categories.Category) bool {
@@ -55,9 +131,56 @@ func (c Capabilities) HasMovies() bool {
 	return false
 }
 
+func (c Capabilities) HasMusic() bool {
+	for _, cat := range c.Categories {
+		if cat.ID >= 3000 && cat.ID < 4000 {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Capabilities) HasBooks() bool {
+	for _, cat := range c.Categories {
+		if cat.ID >= 7000 && cat.ID < 8000 {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsCategories checks if the index has one of the categories, or one of their subcategories.
+// All the indexes support searches without categories.
+func (c Capabilities) SupportsCategories(ids []int) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	for _, id := range ids {
+		for _, cat := range c.Categories {
+			if cat.Contains(id) || (categories.Category{ID: id}).Contains(cat.ID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (c Capabilities) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	var cx struct {
-		XMLName   struct{} `xml:"caps"`
+		XMLName struct{} `xml:"caps"`
+		Server  struct {
+			Title     string `xml:"title,attr,omitempty"`
+			Strapline string `xml:"strapline,attr,omitempty"`
+			URL       string `xml:"url,attr,omitempty"`
+		} `xml:"server"`
+		Limits *struct {
+			Max     uint `xml:"max,attr"`
+			Default uint `xml:"default,attr"`
+		} `xml:"limits"`
+		Registration struct {
+			Available string `xml:"available,attr"`
+			Open      string `xml:"open,attr"`
+		} `xml:"registration"`
 		Searching struct {
 			Values []interface{}
 		} `xml:"searching"`
@@ -66,11 +189,20 @@ func (c Capabilities) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 		} `xml:"categories"`
 	}
 
+	cx.Server.Title = c.Server.Title
+	cx.Server.Strapline = c.Server.Strapline
+	cx.Server.URL = c.Server.URL
+	if c.Limits.Max > 0 {
+		cx.Limits = &struct {
+			Max     uint `xml:"max,attr"`
+			Default uint `xml:"default,attr"`
+		}{c.Limits.Max, c.Limits.Default}
+	}
+	cx.Registration.Available = yesNo(c.Registration.Available)
+	cx.Registration.Open = yesNo(c.Registration.Open)
+
 	for _, mode := range c.SearchModes {
-		available := "no"
-		if mode.Available {
-			available = "yes"
-		}
+		available := yesNo(mode.Available)
 		cx.Searching.Values = append(cx.Searching.Values, struct {
 			XMLName         xml.Name
 			Available       string `xml:"available,attr"`
@@ -82,8 +214,11 @@ func (c Capabilities) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 		})
 	}
 
-	cats := c.Categories
-	sort.Sort(cats)
+	// The categories are a map, so they're sorted by their ids
+	cats := c.Categories.Items()
+	sort.Slice(cats, func(i, j int) bool {
+		return cats[i].ID < cats[j].ID
+	})
 
 	for _, cat := range cats {
 		cx.Categories.Values = append(cx.Categories.Values, struct {
@@ -100,6 +235,13 @@ func (c Capabilities) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	return err
 }
 
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
 func (c Capabilities) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	x, err := xml.MarshalIndent(c, "", "  ")
 	if err != nil {
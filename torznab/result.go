@@ -18,16 +18,25 @@ type Info struct {
 type ResultFeed struct {
 	Info  Info
 	Items []search.ResultItemBase
+	// Warnings are the errors of the indexes that couldn't be searched.
+	Warnings []Warning `json:",omitempty"`
+}
+
+// Warning is the error of an index, the feed still has the results of the other indexes.
+type Warning struct {
+	Index       string `xml:"index,attr"`
+	Description string `xml:"description,attr"`
 }
 
 func (rf ResultFeed) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	channelView := struct {
-		XMLName     struct{} `xml:"channel"`
-		Title       string   `xml:"title,omitempty"`
-		Description string   `xml:"description,omitempty"`
-		Link        string   `xml:"link,omitempty"`
-		Language    string   `xml:"language,omitempty"`
-		Category    string   `xml:"category,omitempty"`
+		XMLName     struct{}  `xml:"channel"`
+		Title       string    `xml:"title,omitempty"`
+		Description string    `xml:"description,omitempty"`
+		Link        string    `xml:"link,omitempty"`
+		Language    string    `xml:"language,omitempty"`
+		Category    string    `xml:"category,omitempty"`
+		Warnings    []Warning `xml:"torznab:warning"`
 		Items       []search.ResultItemBase
 	}{
 		Title:       rf.Info.Title,
@@ -35,6 +44,7 @@ func (rf ResultFeed) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 		Link:        rf.Info.Link,
 		Language:    rf.Info.Language,
 		Category:    rf.Info.Category,
+		Warnings:    rf.Warnings,
 		Items:       rf.Items,
 	}
 